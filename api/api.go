@@ -4,25 +4,38 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/httputil"
+	"github.com/github/git-lfs/sftp"
 	"github.com/github/git-lfs/tools"
 
 	"github.com/rubyist/tracerx"
 )
 
+// BatchCompressThreshold is the size above which batch request bodies are
+// gzipped, if lfs.batch.requestcompress is enabled.
+const BatchCompressThreshold = 64 * 1024
+
+// batchCompressRejected is set once the server answers a gzipped batch request
+// with a 415, so that the rest of the batches in this process are sent
+// uncompressed. The rejection is also cached in the server's Capabilities for
+// later commands.
+var batchCompressRejected int32
+
 // BatchOrLegacy calls the Batch API and falls back on the Legacy API
 // This is for simplicity, legacy route is not most optimal (serial)
 // TODO LEGACY API: remove when legacy API removed
 func BatchOrLegacy(objects []*ObjectResource, operation string, transferAdapters []string) (objs []*ObjectResource, transferAdapter string, e error) {
 	cfg := config.Config
-	if !cfg.BatchTransfer() {
+	if !cfg.BatchTransfer() && !sftp.IsSftpUrl(cfg.Endpoint(operation).Url) {
 		objs, err := Legacy(objects, operation)
 		return objs, "", err
 	}
@@ -57,6 +70,10 @@ func Batch(objects []*ObjectResource, operation string, transferAdapters []strin
 
 	cfg := config.Config
 
+	if endpointUrl := cfg.Endpoint(operation).Url; sftp.IsSftpUrl(endpointUrl) {
+		return sftpBatch(objects, operation, endpointUrl)
+	}
+
 	// Compatibility; omit transfers list when only basic
 	// older schemas included `additionalproperties=false`
 	if len(transferAdapters) == 1 && transferAdapters[0] == "basic" {
@@ -74,6 +91,24 @@ func Batch(objects []*ObjectResource, operation string, transferAdapters []strin
 		return nil, "", errutil.Error(err)
 	}
 
+	endpointUrl := cfg.Endpoint(operation).Url
+	caps := EndpointCapabilities(endpointUrl)
+	gzipRejected := caps != nil && caps.BatchGzip != nil && !*caps.BatchGzip
+
+	compressed := false
+	if cfg.BatchRequestCompress() && len(by) > BatchCompressThreshold &&
+		atomic.LoadInt32(&batchCompressRejected) == 0 && !gzipRejected {
+		gz, err := gzipBytes(by)
+		if err != nil {
+			return nil, "", errutil.Error(err)
+		}
+
+		tracerx.Printf("api: batch request body %d bytes, gzipped to %d bytes", len(by), len(gz))
+		req.Header.Set("Content-Encoding", "gzip")
+		by = gz
+		compressed = true
+	}
+
 	req.Header.Set("Content-Type", MediaType)
 	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
 	req.ContentLength = int64(len(by))
@@ -93,6 +128,16 @@ func Batch(objects []*ObjectResource, operation string, transferAdapters []strin
 			return nil, "", errutil.NewRetriableError(err)
 		}
 
+		if compressed && res.StatusCode == 415 {
+			tracerx.Printf("api: server does not accept gzipped batch requests, resubmitting uncompressed")
+			atomic.StoreInt32(&batchCompressRejected, 1)
+			RecordCapabilities(endpointUrl, func(c *Capabilities) {
+				accepted := false
+				c.BatchGzip = &accepted
+			})
+			return Batch(objects, operation, transferAdapters)
+		}
+
 		if errutil.IsAuthError(err) {
 			httputil.SetAuthType(cfg, req, res)
 			return Batch(objects, operation, transferAdapters)
@@ -113,9 +158,27 @@ func Batch(objects []*ObjectResource, operation string, transferAdapters []strin
 		return nil, "", errutil.Error(fmt.Errorf("Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode))
 	}
 
+	if compressed {
+		RecordCapabilities(endpointUrl, func(c *Capabilities) {
+			c.BatchGzip = &compressed
+		})
+	}
+
 	return bresp.Objects, bresp.TransferAdapterName, nil
 }
 
+func gzipBytes(by []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(by); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // Legacy calls the legacy API serially and returns ObjectResources
 // TODO LEGACY API: remove when legacy API removed
 func Legacy(objects []*ObjectResource, operation string) ([]*ObjectResource, error) {
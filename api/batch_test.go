@@ -0,0 +1,106 @@
+package api_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// largeBatch returns enough objects for the request body to be over the
+// compression threshold.
+func largeBatch() []*api.ObjectResource {
+	objects := make([]*api.ObjectResource, 0)
+	for i := 0; i < api.BatchCompressThreshold/64; i++ {
+		objects = append(objects, &api.ObjectResource{Oid: fmt.Sprintf("%064d", i), Size: int64(i)})
+	}
+	return objects
+}
+
+func newBatchServer(t *testing.T, acceptGzip bool, encodings *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		*encodings = append(*encodings, encoding)
+
+		if encoding == "gzip" && !acceptGzip {
+			w.WriteHeader(415)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = gz
+		}
+
+		req := struct {
+			Objects []*api.ObjectResource `json:"objects"`
+		}{}
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		by, err := json.Marshal(map[string]interface{}{"objects": req.Objects})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Header().Set("Content-Type", api.MediaType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(by)))
+		w.WriteHeader(200)
+		w.Write(by)
+	}))
+}
+
+func TestBatchCompressesLargeRequests(t *testing.T) {
+	var encodings []string
+	server := newBatchServer(t, true, &encodings)
+	defer server.Close()
+
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.url", server.URL)
+	config.Config.SetConfig("lfs.batch.requestcompress", "true")
+
+	objects := largeBatch()
+	objs, _, err := api.Batch(objects, "download", []string{"basic"})
+	assert.Nil(t, err)
+	assert.Equal(t, len(objects), len(objs))
+
+	// small requests are not worth compressing
+	_, _, err = api.Batch(objects[:1], "download", []string{"basic"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"gzip", ""}, encodings)
+}
+
+func TestBatchFallsBackToUncompressedOn415(t *testing.T) {
+	var encodings []string
+	server := newBatchServer(t, false, &encodings)
+	defer server.Close()
+
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.url", server.URL)
+	config.Config.SetConfig("lfs.batch.requestcompress", "true")
+
+	objects := largeBatch()
+	objs, _, err := api.Batch(objects, "download", []string{"basic"})
+	assert.Nil(t, err)
+	assert.Equal(t, len(objects), len(objs))
+
+	// the rejection is remembered for the rest of the process
+	_, _, err = api.Batch(objects, "download", []string{"basic"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"gzip", "", ""}, encodings)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/github/git-lfs/config"
+
+	"github.com/rubyist/tracerx"
+)
+
+// Capabilities are what a Git LFS server has been found to support, as learned
+// from its responses. They are cached per endpoint for
+// config.CapabilitiesTTL(), so that later commands need not discover them
+// again. Only batch request compression is cached: the transfer adapter is
+// negotiated in every batch response anyway, and batch API support and the
+// authentication type are kept in Git config as lfs.batch and
+// lfs.<url>.access.
+type Capabilities struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// BatchGzip is whether the server accepts gzipped batch requests, or nil
+	// if that is not yet known.
+	BatchGzip *bool `json:"batch_gzip,omitempty"`
+}
+
+// capabilitiesMu guards reads and writes of the capabilities cache file.
+var capabilitiesMu sync.Mutex
+
+func capabilitiesFile() string {
+	if len(config.LocalGitStorageDir) == 0 {
+		return ""
+	}
+	return filepath.Join(config.LocalGitStorageDir, "lfs", "capabilities.json")
+}
+
+func readCapabilities() map[string]*Capabilities {
+	caps := make(map[string]*Capabilities)
+
+	file := capabilitiesFile()
+	if len(file) == 0 {
+		return caps
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return caps
+	}
+
+	if err := json.Unmarshal(data, &caps); err != nil {
+		tracerx.Printf("api: ignoring unreadable capabilities cache %s: %s", file, err)
+		return make(map[string]*Capabilities)
+	}
+	return caps
+}
+
+// CachedCapabilities returns every cached entry keyed by endpoint URL,
+// including expired ones.
+func CachedCapabilities() map[string]*Capabilities {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	return readCapabilities()
+}
+
+// EndpointCapabilities returns the cached capabilities for the endpoint URL,
+// or nil if none are cached or they are older than config.CapabilitiesTTL().
+func EndpointCapabilities(endpointUrl string) *Capabilities {
+	ttl := config.Config.CapabilitiesTTL()
+	if ttl <= 0 {
+		return nil
+	}
+
+	caps := CachedCapabilities()[endpointUrl]
+	if caps == nil || time.Since(caps.CheckedAt) > ttl {
+		return nil
+	}
+	return caps
+}
+
+// RecordCapabilities updates the cached capabilities for the endpoint URL with
+// update, discarding anything cached for it which has expired. The cache is
+// only written if update changes it.
+func RecordCapabilities(endpointUrl string, update func(*Capabilities)) {
+	if config.Config.CapabilitiesTTL() <= 0 {
+		return
+	}
+
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	file := capabilitiesFile()
+	if len(file) == 0 {
+		return
+	}
+
+	all := readCapabilities()
+	caps := all[endpointUrl]
+	if caps == nil || time.Since(caps.CheckedAt) > config.Config.CapabilitiesTTL() {
+		caps = &Capabilities{}
+	}
+
+	before, _ := json.Marshal(caps)
+	update(caps)
+	if after, _ := json.Marshal(caps); string(before) == string(after) {
+		return
+	}
+
+	caps.CheckedAt = time.Now()
+	all[endpointUrl] = caps
+	writeCapabilities(file, all)
+}
+
+// ClearCapabilities removes the cached capabilities for the endpoint URL, so
+// that they are discovered again.
+func ClearCapabilities(endpointUrl string) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	file := capabilitiesFile()
+	if len(file) == 0 {
+		return
+	}
+
+	all := readCapabilities()
+	if _, ok := all[endpointUrl]; ok {
+		delete(all, endpointUrl)
+		writeCapabilities(file, all)
+	}
+}
+
+func writeCapabilities(file string, all map[string]*Capabilities) {
+	data, err := json.Marshal(all)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(file), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(file, data, 0644)
+	}
+	if err != nil {
+		tracerx.Printf("api: unable to write capabilities cache %s: %s", file, err)
+	}
+}
@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func withCapabilitiesDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "capabilities")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	return func() {
+		config.LocalGitStorageDir = oldDir
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRecordCapabilities(t *testing.T) {
+	defer withCapabilitiesDir(t)()
+	defer config.Config.ResetConfig()
+
+	assert.Nil(t, api.EndpointCapabilities("https://example.com/lfs"))
+
+	api.RecordCapabilities("https://example.com/lfs", func(c *api.Capabilities) {
+		accepted := false
+		c.BatchGzip = &accepted
+	})
+
+	caps := api.EndpointCapabilities("https://example.com/lfs")
+	if assert.NotNil(t, caps) && assert.NotNil(t, caps.BatchGzip) {
+		assert.False(t, *caps.BatchGzip)
+		assert.True(t, time.Since(caps.CheckedAt) < time.Minute)
+	}
+	assert.Nil(t, api.EndpointCapabilities("https://example.com/other"))
+
+	api.ClearCapabilities("https://example.com/lfs")
+	assert.Nil(t, api.EndpointCapabilities("https://example.com/lfs"))
+}
+
+func TestCapabilitiesExpire(t *testing.T) {
+	defer withCapabilitiesDir(t)()
+	defer config.Config.ResetConfig()
+
+	api.RecordCapabilities("https://example.com/lfs", func(c *api.Capabilities) {
+		accepted := false
+		c.BatchGzip = &accepted
+	})
+	assert.NotNil(t, api.EndpointCapabilities("https://example.com/lfs"))
+
+	config.Config.SetConfig("lfs.capabilitiesttl", "0")
+	assert.Nil(t, api.EndpointCapabilities("https://example.com/lfs"))
+	assert.Len(t, api.CachedCapabilities(), 1)
+}
+
+func TestBatchRecordsNothingUncompressed(t *testing.T) {
+	defer withCapabilitiesDir(t)()
+
+	var encodings []string
+	server := newBatchServer(t, true, &encodings)
+	defer server.Close()
+
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.url", server.URL)
+
+	_, _, err := api.Batch(largeBatch()[:1], "upload", []string{"basic"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{""}, encodings)
+	assert.Nil(t, api.EndpointCapabilities(server.URL))
+	assert.Empty(t, api.CachedCapabilities())
+}
@@ -120,9 +120,10 @@ func (l *HttpLifecycle) Execute(req *http.Request, into interface{}) (Response,
 	return WrapHttpResponse(resp), nil
 }
 
-// Cleanup implements the Lifecycle.Cleanup function by closing the Body
-// attached to the response.
+// Cleanup implements the Lifecycle.Cleanup function by draining and closing the
+// Body attached to the response, so that its connection can be reused.
 func (l *HttpLifecycle) Cleanup(resp Response) error {
+	io.Copy(ioutil.Discard, resp.Body())
 	return resp.Body().Close()
 }
 
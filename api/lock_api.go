@@ -99,6 +99,26 @@ func (s *LockService) Unlock(id string, force bool) (*RequestSchema, *UnlockResp
 	}, &resp
 }
 
+// Renew generates a *RequestSchema that is used to preform the "renew lease"
+// API method, extending the lease on a particular lock by leaseSeconds from
+// now. If leaseSeconds is 0, the server extends it by the lock's original
+// lease.
+//
+// This method's corresponding response type will either contain the renewed
+// lock, with its new lease expiry, or an error that was experienced by the
+// server in renewing it.
+func (s *LockService) Renew(id string, leaseSeconds int64) (*RequestSchema, *RenewResponse) {
+	var resp RenewResponse
+
+	return &RequestSchema{
+		Method:    "POST",
+		Path:      fmt.Sprintf("/locks/%s/renew", id),
+		Operation: UploadOperation,
+		Body:      &RenewRequest{id, leaseSeconds},
+		Into:      &resp,
+	}, &resp
+}
+
 // Lock represents a single lock that against a particular path.
 //
 // Locks returned from the API may or may not be currently active, according to
@@ -126,6 +146,11 @@ type Lock struct {
 	// the server can either a) not send this field, or b) send the
 	// zero-value of time.Time.
 	UnlockedAt time.Time `json:"unlocked_at,omitempty"`
+	// LeaseExpiresAt is an optional parameter that represents the instant
+	// in time at which the server will release this lock, unless its lease
+	// is renewed first. Locks without a lease are sent without this field,
+	// or with the zero-value of time.Time.
+	LeaseExpiresAt time.Time `json:"lease_expires_at,omitempty"`
 }
 
 // Active returns whether or not the given lock is still active against the file
@@ -134,6 +159,12 @@ func (l *Lock) Active() bool {
 	return l.UnlockedAt.IsZero()
 }
 
+// Leased returns whether or not the given lock will be released by the server
+// when its lease expires.
+func (l *Lock) Leased() bool {
+	return !l.LeaseExpiresAt.IsZero()
+}
+
 // Committer represents a "First Last <email@domain.com>" pair.
 type Committer struct {
 	// Name is the name of the individual who would like to obtain the
@@ -166,6 +197,11 @@ type LockRequest struct {
 	LatestRemoteCommit string `json:"latest_remote_commit"`
 	// Committer is the individual that wishes to obtain the lock.
 	Committer Committer `json:"committer"`
+	// LeaseSeconds is the optional number of seconds after which the
+	// server should release the lock, unless its lease is renewed. Servers
+	// which support leases set the LeaseExpiresAt field of the returned
+	// lock; servers which don't ignore this field.
+	LeaseSeconds int64 `json:"lease_seconds,omitempty"`
 }
 
 // LockResponse encapsulates the information sent over the API in response to
@@ -214,6 +250,28 @@ type UnlockResponse struct {
 	Err string `json:"error,omitempty"`
 }
 
+// RenewRequest encapsulates the data sent in an API request to extend the lease
+// on a lock.
+type RenewRequest struct {
+	// Id is the Id of the lock that the user wishes to renew.
+	Id string `json:"id"`
+	// LeaseSeconds is the number of seconds from now that the lease should
+	// be extended to. If 0, the lock's original lease is used.
+	LeaseSeconds int64 `json:"lease_seconds,omitempty"`
+}
+
+// RenewResponse is the result sent back from the API when asked to renew the
+// lease on a lock.
+type RenewResponse struct {
+	// Lock is the renewed lock, holding its new LeaseExpiresAt. If no
+	// matching lock was found, or the lock has no lease, this field will
+	// take the zero-value of Lock, and Err will be non-nil.
+	Lock *Lock `json:"lock"`
+	// Err is an optional field which holds any error that was experienced
+	// while renewing the lease.
+	Err string `json:"error,omitempty"`
+}
+
 // Filter represents a single qualifier to apply against a set of locks.
 type Filter struct {
 	// Property is the property to search against.
@@ -89,6 +89,21 @@ func TestUnlockingALock(t *testing.T) {
 	}, got)
 }
 
+func TestRenewingALockLease(t *testing.T) {
+	got, body := LockService.Renew("some-lock-id", 7200)
+
+	AssertRequestSchema(t, &api.RequestSchema{
+		Method:    "POST",
+		Path:      "/locks/some-lock-id/renew",
+		Operation: api.UploadOperation,
+		Body: &api.RenewRequest{
+			Id:           "some-lock-id",
+			LeaseSeconds: 7200,
+		},
+		Into: body,
+	}, got)
+}
+
 func TestLockRequest(t *testing.T) {
 	schema.Validate(t, schema.LockRequestSchema, &api.LockRequest{
 		Path:               "/path/to/lock",
@@ -100,6 +115,18 @@ func TestLockRequest(t *testing.T) {
 	})
 }
 
+func TestLockRequestWithLease(t *testing.T) {
+	schema.Validate(t, schema.LockRequestSchema, &api.LockRequest{
+		Path:               "/path/to/lock",
+		LatestRemoteCommit: "deadbeef",
+		Committer: api.Committer{
+			Name:  "Jane Doe",
+			Email: "jane@example.com",
+		},
+		LeaseSeconds: 7200,
+	})
+}
+
 func TestLockResponseWithLockedLock(t *testing.T) {
 	schema.Validate(t, schema.LockResponseSchema, &api.LockResponse{
 		Lock: &api.Lock{
@@ -129,6 +156,21 @@ func TestLockResponseWithUnlockedLock(t *testing.T) {
 	})
 }
 
+func TestLockResponseWithLeasedLock(t *testing.T) {
+	schema.Validate(t, schema.LockResponseSchema, &api.LockResponse{
+		Lock: &api.Lock{
+			Id:   "some-lock-id",
+			Path: "/lock/path",
+			Committer: api.Committer{
+				Name:  "Jane Doe",
+				Email: "jane@example.com",
+			},
+			LockedAt:       time.Now(),
+			LeaseExpiresAt: time.Now().Add(2 * time.Hour),
+		},
+	})
+}
+
 func TestLockResponseWithError(t *testing.T) {
 	schema.Validate(t, schema.LockResponseSchema, &api.LockResponse{
 		Err: "some error",
@@ -178,6 +220,42 @@ func TestUnlockResponseDoesNotAllowLockAndError(t *testing.T) {
 	})
 }
 
+func TestRenewRequest(t *testing.T) {
+	schema.Validate(t, schema.RenewRequestSchema, &api.RenewRequest{
+		Id:           "some-lock-id",
+		LeaseSeconds: 7200,
+	})
+}
+
+func TestRenewResponseWithLock(t *testing.T) {
+	schema.Validate(t, schema.RenewResponseSchema, &api.RenewResponse{
+		Lock: &api.Lock{
+			Id:             "some-lock-id",
+			Path:           "/lock/path",
+			LockedAt:       time.Now(),
+			LeaseExpiresAt: time.Now().Add(2 * time.Hour),
+		},
+	})
+}
+
+func TestRenewResponseWithError(t *testing.T) {
+	schema.Validate(t, schema.RenewResponseSchema, &api.RenewResponse{
+		Err: "some-error",
+	})
+}
+
+func TestRenewResponseDoesNotAllowLockAndError(t *testing.T) {
+	schema.Refute(t, schema.RenewResponseSchema, &api.RenewResponse{
+		Lock: &api.Lock{
+			Id:             "some-lock-id",
+			Path:           "/lock/path",
+			LockedAt:       time.Now(),
+			LeaseExpiresAt: time.Now().Add(2 * time.Hour),
+		},
+		Err: "some-error",
+	})
+}
+
 func TestLockListWithLocks(t *testing.T) {
 	schema.Validate(t, schema.LockListSchema, &api.LockList{
 		Locks: []api.Lock{
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/httputil"
+)
+
+// UploadMetadata sends the JSON metadata sidecar for obj to its "metadata" API
+// link relation, if the server provided one.
+func UploadMetadata(obj *ObjectResource, by []byte) error {
+	if _, ok := obj.Rel("metadata"); !ok {
+		return nil
+	}
+
+	req, err := obj.NewRequest("metadata", "POST")
+	if err != nil {
+		return errutil.Error(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = ioutil.NopCloser(bytes.NewReader(by))
+	res, err := DoRequest(req, true)
+	if err != nil {
+		return err
+	}
+
+	httputil.LogTransfer(config.Config, "lfs.data.metadata", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	return nil
+}
+
+// DownloadMetadata fetches the JSON metadata sidecar for obj from its
+// "metadata" API link relation. It returns nil if the server did not provide
+// one.
+func DownloadMetadata(obj *ObjectResource) ([]byte, error) {
+	if _, ok := obj.Rel("metadata"); !ok {
+		return nil, nil
+	}
+
+	req, err := obj.NewRequest("metadata", "GET")
+	if err != nil {
+		return nil, errutil.Error(err)
+	}
+
+	res, err := DoRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	httputil.LogTransfer(config.Config, "lfs.data.metadata", res)
+	defer res.Body.Close()
+
+	if res.StatusCode == 204 {
+		return nil, nil
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/httputil"
 )
 
@@ -19,11 +22,12 @@ func (e *ObjectError) Error() string {
 }
 
 type ObjectResource struct {
-	Oid     string                   `json:"oid,omitempty"`
-	Size    int64                    `json:"size"`
-	Actions map[string]*LinkRelation `json:"actions,omitempty"`
-	Links   map[string]*LinkRelation `json:"_links,omitempty"`
-	Error   *ObjectError             `json:"error,omitempty"`
+	Oid        string                   `json:"oid,omitempty"`
+	Size       int64                    `json:"size"`
+	ModifiedAt *time.Time               `json:"modified_at,omitempty"`
+	Actions    map[string]*LinkRelation `json:"actions,omitempty"`
+	Links      map[string]*LinkRelation `json:"_links,omitempty"`
+	Error      *ObjectError             `json:"error,omitempty"`
 }
 
 // TODO LEGACY API: remove when legacy API removed
@@ -77,4 +81,72 @@ type LinkRelation struct {
 	Href      string            `json:"href"`
 	Header    map[string]string `json:"header,omitempty"`
 	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+	// Args holds adapter specific parameters chosen by the server, such as
+	// the part size of a multipart upload. It is nil if the server sent none.
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// ArgString returns the named entry of Args as a string, if it is one.
+func (l *LinkRelation) ArgString(name string) (string, bool) {
+	s, ok := l.Args[name].(string)
+	return s, ok
+}
+
+// ArgInt returns the named entry of Args as an integer. Both JSON numbers and
+// strings holding a decimal integer are accepted.
+func (l *LinkRelation) ArgInt(name string) (int64, bool) {
+	switch v := l.Args[name].(type) {
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// ArgStrings returns the named entry of Args as a list of strings, if it is a
+// JSON array holding only strings.
+func (l *LinkRelation) ArgStrings(name string) ([]string, bool) {
+	values, ok := l.Args[name].([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, s)
+	}
+	return strs, true
+}
+
+// IsSigned returns true if this link relation is a pre-signed URL that carries
+// its own authorization in the query string. Such links are returned with no
+// header map and point somewhere other than the LFS API for the given
+// operation. Requests to signed links must not include the client's
+// credentials, since some storage servers reject requests that carry both.
+func (l *LinkRelation) IsSigned(operation string) bool {
+	if len(l.Header) > 0 {
+		return false
+	}
+
+	u, err := url.Parse(l.Href)
+	if err != nil || !u.IsAbs() || len(u.RawQuery) == 0 {
+		return false
+	}
+
+	apiUrl, err := url.Parse(config.Config.Endpoint(operation).Url)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme != apiUrl.Scheme || u.Host != apiUrl.Host
 }
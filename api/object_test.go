@@ -1,10 +1,12 @@
 package api_test
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,3 +49,78 @@ func TestObjectsWithExpirationDatesAreExpired(t *testing.T) {
 
 	assert.True(t, o.IsExpired(now))
 }
+
+func TestSignedLinkRelations(t *testing.T) {
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.url", "https://lfs-server.com/media")
+
+	signed := &api.LinkRelation{Href: "https://storage.com/oid?X-Amz-Signature=abc"}
+	assert.True(t, signed.IsSigned("download"))
+
+	withHeader := &api.LinkRelation{
+		Href:   "https://storage.com/oid?X-Amz-Signature=abc",
+		Header: map[string]string{"Authorization": "Token abc"},
+	}
+	assert.False(t, withHeader.IsSigned("download"))
+
+	noQuery := &api.LinkRelation{Href: "https://storage.com/oid"}
+	assert.False(t, noQuery.IsSigned("download"))
+
+	sameHost := &api.LinkRelation{Href: "https://lfs-server.com/media/objects/oid?r=repo"}
+	assert.False(t, sameHost.IsSigned("download"))
+}
+
+func TestLinkRelationArgs(t *testing.T) {
+	var o api.ObjectResource
+	err := json.Unmarshal([]byte(`{
+		"oid": "some-oid",
+		"size": 1234,
+		"actions": {
+			"upload": {
+				"href": "https://storage.example.com/upload",
+				"args": {"part_size": 5242880, "parts": "3", "scheme": "multipart", "part_urls": ["https://storage.example.com/1", "https://storage.example.com/2"], "mixed": ["a", 1]}
+			},
+			"verify": {
+				"href": "https://lfs.example.com/verify"
+			}
+		}
+	}`), &o)
+	assert.Nil(t, err)
+
+	upload, ok := o.Rel("upload")
+	assert.True(t, ok)
+
+	size, ok := upload.ArgInt("part_size")
+	assert.True(t, ok)
+	assert.Equal(t, int64(5242880), size)
+
+	parts, ok := upload.ArgInt("parts")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), parts)
+
+	scheme, ok := upload.ArgString("scheme")
+	assert.True(t, ok)
+	assert.Equal(t, "multipart", scheme)
+
+	_, ok = upload.ArgInt("scheme")
+	assert.False(t, ok)
+
+	urls, ok := upload.ArgStrings("part_urls")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"https://storage.example.com/1", "https://storage.example.com/2"}, urls)
+
+	_, ok = upload.ArgStrings("mixed")
+	assert.False(t, ok)
+	_, ok = upload.ArgStrings("scheme")
+	assert.False(t, ok)
+
+	verify, ok := o.Rel("verify")
+	assert.True(t, ok)
+	assert.Nil(t, verify.Args)
+	_, ok = verify.ArgString("scheme")
+	assert.False(t, ok)
+
+	by, err := json.Marshal(verify)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(by), "args")
+}
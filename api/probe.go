@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/httputil"
+	"github.com/github/git-lfs/tools"
+
+	"github.com/rubyist/tracerx"
+)
+
+// BatchProbe is the outcome of a ProbeBatch request.
+type BatchProbe struct {
+	// Request is the final request sent, including any credentials.
+	Request    *http.Request
+	StatusCode int
+	Status     string
+	// Duration is the round-trip time of the final request.
+	Duration time.Duration
+	// TransferAdapterName is the transfer adapter chosen by the server, or
+	// blank if it did not choose one.
+	TransferAdapterName string
+}
+
+// ProbeBatch sends a batch request with no objects to the endpoint for the
+// given operation, to check that the server is reachable and accepts the
+// credentials Git LFS would use. As with Batch, a 401 marks the endpoint as
+// requiring authentication and the request is resent with credentials.
+// Any capabilities cached for the endpoint are discarded, so that later
+// commands discover them again. Unlike Batch, HTTP error statuses are reported
+// in the BatchProbe rather than returned as errors; an error means no response
+// was received at all.
+func ProbeBatch(operation string, transferAdapters []string) (*BatchProbe, error) {
+	ClearCapabilities(config.Config.Endpoint(operation).Url)
+	return probeBatch(operation, transferAdapters, false)
+}
+
+func probeBatch(operation string, transferAdapters []string, retried bool) (*BatchProbe, error) {
+	cfg := config.Config
+
+	o := &batchRequest{Operation: operation, Objects: []*ObjectResource{}, TransferAdapterNames: transferAdapters}
+	by, err := json.Marshal(o)
+	if err != nil {
+		return nil, errutil.Error(err)
+	}
+
+	req, err := NewBatchRequest(operation)
+	if err != nil {
+		return nil, errutil.Error(err)
+	}
+
+	req.Header.Set("Content-Type", MediaType)
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = tools.NewReadSeekCloserWrapper(bytes.NewReader(by))
+
+	tracerx.Printf("api: probing batch endpoint for %s", operation)
+
+	start := time.Now()
+	res, bresp, err := DoBatchRequest(req)
+	duration := time.Since(start)
+
+	if res == nil {
+		return nil, err
+	}
+
+	if errutil.IsAuthError(err) && !retried {
+		httputil.SetAuthType(cfg, req, res)
+		return probeBatch(operation, transferAdapters, true)
+	}
+
+	probe := &BatchProbe{
+		Request:    req,
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Duration:   duration,
+	}
+	if err == nil && bresp != nil {
+		probe.TransferAdapterName = bresp.TransferAdapterName
+	}
+
+	return probe, nil
+}
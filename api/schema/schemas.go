@@ -19,6 +19,8 @@ const (
 	LockListSchema       = "lock_list_schema.json"
 	LockRequestSchema    = "lock_request_schema.json"
 	LockResponseSchema   = "lock_response_schema.json"
+	RenewRequestSchema   = "renew_request_schema.json"
+	RenewResponseSchema  = "renew_response_schema.json"
 	UnlockRequestSchema  = "unlock_request_schema.json"
 	UnlockResponseSchema = "unlock_response_schema.json"
 )
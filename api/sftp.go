@@ -0,0 +1,45 @@
+package api
+
+import "github.com/github/git-lfs/sftp"
+
+// sftpBatch stands in for the batch API when the endpoint is an sftp:// URL.
+// Object paths are derived from their OIDs, so the objects come back with an
+// action pointing at their path on the server, and the server is only asked
+// which of them it already has. Objects to upload which are already there get
+// no action, and objects to download which aren't get a 404 error, just as the
+// batch API would return them.
+func sftpBatch(objects []*ObjectResource, operation, endpointUrl string) ([]*ObjectResource, string, error) {
+	remote, err := sftp.NewRemote(endpointUrl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	oids := make([]string, 0, len(objects))
+	for _, o := range objects {
+		oids = append(oids, o.Oid)
+	}
+
+	sizes, err := remote.Stat(oids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	objs := make([]*ObjectResource, 0, len(objects))
+	for _, o := range objects {
+		obj := &ObjectResource{Oid: o.Oid, Size: o.Size}
+		size, exists := sizes[o.Oid]
+
+		switch {
+		case operation == "upload" && exists && size == o.Size:
+		case operation == "download" && !exists:
+			obj.Error = &ObjectError{Code: 404, Message: "Object does not exist on the SFTP server"}
+		default:
+			obj.Actions = map[string]*LinkRelation{
+				operation: &LinkRelation{Href: remote.ObjectUrl(o.Oid)},
+			}
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, sftp.AdapterName, nil
+}
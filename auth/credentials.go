@@ -14,6 +14,7 @@ import (
 
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/git"
 	"github.com/rubyist/tracerx"
 )
 
@@ -194,7 +195,7 @@ type CredentialFunc func(*config.Configuration, Creds, string) (Creds, error)
 
 func execCredsCommand(cfg *config.Configuration, input Creds, subCommand string) (Creds, error) {
 	output := new(bytes.Buffer)
-	cmd := exec.Command("git", "credential", subCommand)
+	cmd := exec.Command("git", credentialCommandArgs(cfg, input, subCommand)...)
 	cmd.Stdin = input.Buffer()
 	cmd.Stdout = output
 	/*
@@ -240,6 +241,31 @@ func execCredsCommand(cfg *config.Configuration, input Creds, subCommand string)
 	return creds, nil
 }
 
+// credentialCommandArgs returns the arguments to git for the given 'git
+// credential' subcommand. If lfs.<url>.credentialhelper is set for the URL
+// being authenticated, every helper from credential.helper is cleared and that
+// one is used instead. Git before 2.9 can't clear the list of helpers, so
+// there it is tried after them.
+func credentialCommandArgs(cfg *config.Configuration, input Creds, subCommand string) []string {
+	rawurl := fmt.Sprintf("%s://%s", input["protocol"], input["host"])
+	if len(input["path"]) > 0 {
+		rawurl += "/" + input["path"]
+	}
+
+	helper := cfg.CredentialHelper(rawurl)
+	if len(helper) == 0 {
+		return []string{"credential", subCommand}
+	}
+
+	if !git.Config.IsGitVersionAtLeast("2.9.0") {
+		tracerx.Printf("creds: trying credential helper %q for %s after credential.helper, which needs Git 2.9 to clear", helper, rawurl)
+		return []string{"-c", "credential.helper=" + helper, "credential", subCommand}
+	}
+
+	tracerx.Printf("creds: using credential helper %q for %s", helper, rawurl)
+	return []string{"-c", "credential.helper=", "-c", "credential.helper=" + helper, "credential", subCommand}
+}
+
 func setRequestAuthFromUrl(cfg *config.Configuration, req *http.Request, u *url.URL) bool {
 	if !cfg.NtlmAccess(GetOperationForRequest(req)) && u.User != nil {
 		if pass, ok := u.User.Password(); ok {
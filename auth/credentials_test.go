@@ -277,6 +277,65 @@ func checkGetCredentials(t *testing.T, getCredsFunc func(*config.Configuration,
 	}
 }
 
+func TestCredentialCommandArgs(t *testing.T) {
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.https://lfs.example.com.credentialhelper":             "lfs-host",
+		"lfs.https://lfs.example.com/team/repo.credentialhelper":   "lfs-repo",
+		"lfs.https://lfs.example.com.evil.com.credentialhelper":    "evil",
+		"lfs.http://other.example.com/path/more.credentialhelper":  "other",
+		"lfs.https://upper.example.com/Some/Path.credentialhelper": "upper",
+	})
+
+	tests := []struct {
+		Input    Creds
+		Expected []string
+	}{
+		{
+			Creds{"protocol": "https", "host": "git.example.com", "path": "team/repo"},
+			[]string{"credential", "fill"},
+		},
+		{
+			Creds{"protocol": "https", "host": "lfs.example.com", "path": "team/other"},
+			[]string{"-c", "credential.helper=", "-c", "credential.helper=lfs-host", "credential", "fill"},
+		},
+		{
+			Creds{"protocol": "https", "host": "lfs.example.com"},
+			[]string{"-c", "credential.helper=", "-c", "credential.helper=lfs-host", "credential", "fill"},
+		},
+		{
+			Creds{"protocol": "https", "host": "lfs.example.com", "path": "team/repo/info/lfs"},
+			[]string{"-c", "credential.helper=", "-c", "credential.helper=lfs-repo", "credential", "fill"},
+		},
+		{
+			Creds{"protocol": "https", "host": "lfs.example.com", "path": "team/repository"},
+			[]string{"-c", "credential.helper=", "-c", "credential.helper=lfs-host", "credential", "fill"},
+		},
+		{
+			Creds{"protocol": "http", "host": "lfs.example.com"},
+			[]string{"credential", "fill"},
+		},
+		{
+			Creds{"protocol": "http", "host": "other.example.com", "path": "path"},
+			[]string{"credential", "fill"},
+		},
+		{
+			Creds{"protocol": "https", "host": "Upper.Example.com", "path": "Some/Path"},
+			[]string{"-c", "credential.helper=", "-c", "credential.helper=upper", "credential", "fill"},
+		},
+		{
+			Creds{"protocol": "https", "host": "upper.example.com", "path": "some/path"},
+			[]string{"credential", "fill"},
+		},
+	}
+
+	for _, test := range tests {
+		args := credentialCommandArgs(cfg, test.Input, "fill")
+		if strings.Join(args, " ") != strings.Join(test.Expected, " ") {
+			t.Errorf("args for %v: expected %v, got %v", test.Input, test.Expected, args)
+		}
+	}
+}
+
 type getCredentialCheck struct {
 	Desc          string
 	Config        map[string]string
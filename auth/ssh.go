@@ -7,8 +7,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
 	"github.com/rubyist/tracerx"
 )
 
@@ -29,13 +32,43 @@ func SshAuthenticate(cfg *config.Configuration, operation, oid string) (SshAuthR
 		return res, endpoint, nil
 	}
 
+	if cfg.Offline() {
+		return res, endpoint, errutil.NewFatalError(fmt.Errorf("lfs.offline is set, refusing to contact %s", endpoint.SshUserAndHost))
+	}
+
 	tracerx.Printf("ssh: %s git-lfs-authenticate %s %s %s",
 		endpoint.SshUserAndHost, endpoint.SshPath, operation, oid)
 
-	exe, args := sshGetExeAndArgs(cfg, endpoint)
-	args = append(args,
-		fmt.Sprintf("git-lfs-authenticate %s %s %s", endpoint.SshPath, operation, oid))
+	exe, args := SshAuthenticateCommand(cfg, endpoint, operation, oid)
+	retries := cfg.SshRetries()
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		res, err = runSshAuthenticate(exe, args)
+		if err == nil || attempt >= retries || !sshAuthRetriable(err, res.Message) {
+			break
+		}
+
+		delay := sshRetryDelay << uint(attempt)
+		if delay > sshMaxRetryDelay {
+			delay = sshMaxRetryDelay
+		}
+		tracerx.Printf("ssh: git-lfs-authenticate failed (attempt %d of %d), retrying in %s: %s",
+			attempt+1, retries+1, delay, strings.TrimSpace(res.Message))
+		time.Sleep(delay)
+	}
 
+	return res, endpoint, err
+}
+
+// sshRetryDelay is how long to wait before retrying git-lfs-authenticate the
+// first time. It doubles for each further attempt, up to sshMaxRetryDelay.
+var sshRetryDelay = 500 * time.Millisecond
+
+const sshMaxRetryDelay = 30 * time.Second
+
+func runSshAuthenticate(exe string, args []string) (SshAuthResponse, error) {
+	res := SshAuthResponse{}
 	cmd := exec.Command(exe, args...)
 
 	// Save stdout and stderr in separate buffers
@@ -56,7 +89,55 @@ func SshAuthenticate(cfg *config.Configuration, operation, oid string) (SshAuthR
 		err = json.Unmarshal(outbuf.Bytes(), &res)
 	}
 
-	return res, endpoint, err
+	return res, err
+}
+
+// sshAuthRetriable returns whether git-lfs-authenticate failed in a way which
+// may succeed if run again. ssh exits with 255 when it fails itself, such as
+// when the connection drops, rather than with the status of the remote
+// command, which exits otherwise to reject the request. ssh refusing the user's
+// keys also exits with 255, but is not transient.
+func sshAuthRetriable(err error, stderr string) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || status.ExitStatus() != 255 {
+		return false
+	}
+
+	for _, rejection := range sshAuthRejections {
+		if strings.Contains(stderr, rejection) {
+			return false
+		}
+	}
+	return true
+}
+
+// sshAuthRejections are messages ssh writes when it gives up because the user
+// can't be authenticated, or the host can't be found or trusted, none of which
+// change by trying again.
+var sshAuthRejections = []string{
+	"Permission denied",
+	"Too many authentication failures",
+	"No supported authentication methods available",
+	"Host key verification failed",
+	"Could not resolve hostname",
+}
+
+// SshAuthenticateCommand returns the command run to authenticate the given
+// operation against an endpoint derived from an SSH remote, or "" if the
+// endpoint isn't one.
+func SshAuthenticateCommand(cfg *config.Configuration, endpoint config.Endpoint, operation, oid string) (string, []string) {
+	exe, args := sshGetExeAndArgs(cfg, endpoint)
+	if len(exe) == 0 {
+		return "", nil
+	}
+
+	return exe, append(args,
+		fmt.Sprintf("git-lfs-authenticate %s %s %s", endpoint.SshPath, operation, oid))
 }
 
 // Return the executable name for ssh on this machine and the base args
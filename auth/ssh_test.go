@@ -1,8 +1,14 @@
 package auth
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/github/git-lfs/config"
 	"github.com/stretchr/testify/assert"
@@ -219,3 +225,104 @@ func TestSSHGetExeAndArgsTortoisePlinkCommandCustomPort(t *testing.T) {
 
 	cfg.Setenv("GIT_SSH_COMMAND", oldGITSSHCommand)
 }
+
+// fakeSshAuthenticate sets GIT_SSH_COMMAND to a script which fails with the
+// given status and stderr until it has been run failures times, then
+// succeeds. It returns a func which reports how many times it was run.
+func fakeSshAuthenticate(t *testing.T, cfg *config.Configuration, failures, status int, stderr string) (func() int, func()) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script for ssh")
+	}
+
+	dir, err := ioutil.TempDir("", "lfs-ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "ssh.sh")
+	ioutil.WriteFile(script, []byte(fmt.Sprintf(`echo x >> %q
+if [ "$(wc -l < %q)" -le %d ]; then
+  echo %q >&2
+  exit %d
+fi
+echo '{"href": "https://foo.com/info/lfs"}'
+`, count, count, failures, stderr, status)), 0644)
+
+	oldGITSSHCommand := cfg.Getenv("GIT_SSH_COMMAND")
+	cfg.Setenv("GIT_SSH_COMMAND", "sh "+script)
+	cfg.SetManualEndpoint(config.Endpoint{
+		Url:            "https://foo.com/info/lfs",
+		SshUserAndHost: "user@foo.com",
+		SshPath:        "foo/bar",
+	})
+
+	oldDelay := sshRetryDelay
+	sshRetryDelay = time.Millisecond
+
+	runs := func() int {
+		by, _ := ioutil.ReadFile(count)
+		return strings.Count(string(by), "\n")
+	}
+	return runs, func() {
+		sshRetryDelay = oldDelay
+		cfg.Setenv("GIT_SSH_COMMAND", oldGITSSHCommand)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSSHAuthenticateRetriesConnectionFailures(t *testing.T) {
+	cfg := config.New()
+	cfg.SetConfig("lfs.ssh.retries", "2")
+	runs, cleanup := fakeSshAuthenticate(t, cfg, 2, 255, "ssh: connect to host foo.com port 22: Connection refused")
+	defer cleanup()
+
+	res, _, err := SshAuthenticate(cfg, "download", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://foo.com/info/lfs", res.Href)
+	assert.Equal(t, 3, runs())
+}
+
+func TestSSHAuthenticateGivesUpAfterRetries(t *testing.T) {
+	cfg := config.New()
+	cfg.SetConfig("lfs.ssh.retries", "1")
+	runs, cleanup := fakeSshAuthenticate(t, cfg, 5, 255, "ssh: connect to host foo.com port 22: Connection refused")
+	defer cleanup()
+
+	res, _, err := SshAuthenticate(cfg, "download", "")
+	assert.NotNil(t, err)
+	assert.Contains(t, res.Message, "Connection refused")
+	assert.Equal(t, 2, runs())
+}
+
+func TestSSHAuthenticateDoesNotRetryByDefault(t *testing.T) {
+	cfg := config.New()
+	runs, cleanup := fakeSshAuthenticate(t, cfg, 1, 255, "ssh: connect to host foo.com port 22: Connection refused")
+	defer cleanup()
+
+	_, _, err := SshAuthenticate(cfg, "download", "")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, runs())
+}
+
+func TestSSHAuthenticateDoesNotRetryRejections(t *testing.T) {
+	for desc, c := range map[string]struct {
+		Status int
+		Stderr string
+	}{
+		"ssh rejection":    {255, "user@foo.com: Permission denied (publickey)."},
+		"host key":         {255, "Host key verification failed."},
+		"unknown host":     {255, "ssh: Could not resolve hostname foo.com: Name or service not known"},
+		"remote rejection": {1, "Repository not found."},
+	} {
+		cfg := config.New()
+		cfg.SetConfig("lfs.ssh.retries", "3")
+		runs, cleanup := fakeSshAuthenticate(t, cfg, 1, c.Status, c.Stderr)
+
+		res, _, err := SshAuthenticate(cfg, "download", "")
+		assert.NotNil(t, err, desc)
+		assert.Contains(t, res.Message, c.Stderr, desc)
+		assert.Equal(t, 1, runs(), desc)
+		cleanup()
+	}
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"os"
+	"sort"
+
+	"github.com/github/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleCmd = &cobra.Command{
+		Use: "bundle",
+		Run: bundleCommand,
+	}
+
+	bundleCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Pack the objects reachable from a ref into a bundle file",
+		Run:   bundleCreateCommand,
+	}
+
+	bundleUnbundleCmd = &cobra.Command{
+		Use:   "unbundle",
+		Short: "Import the objects in a bundle file into the local store",
+		Run:   bundleUnbundleCommand,
+	}
+)
+
+func bundleCommand(cmd *cobra.Command, args []string) {
+	cmd.Usage()
+}
+
+func bundleCreateCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) != 2 {
+		Exit("Usage: git lfs bundle create <file> <ref>")
+	}
+	file, ref := args[0], args[1]
+
+	opts := lfs.NewScanRefsOptions()
+	opts.ScanMode = lfs.ScanRefsMode
+	opts.SkipDeletedBlobs = false
+	pointers, err := lfs.ScanRefs(ref, "", opts)
+	if err != nil {
+		Exit("Could not scan for Git LFS files in %s: %s", ref, err)
+	}
+
+	seen := make(map[string]bool, len(pointers))
+	bundled := make([]*lfs.Pointer, 0, len(pointers))
+	var missing []string
+	for _, p := range pointers {
+		if seen[p.Oid] {
+			continue
+		}
+		seen[p.Oid] = true
+
+		if !lfs.ObjectExistsOfSize(p.Oid, p.Pointer.Size) {
+			missing = append(missing, p.Name+" ("+p.Oid+")")
+			continue
+		}
+		bundled = append(bundled, p.Pointer)
+	}
+
+	if len(missing) > 0 {
+		for _, m := range missing {
+			Error("Missing object: %s", m)
+		}
+		Exit("%d object(s) reachable from %s are not in the local store, run 'git lfs fetch --all' first", len(missing), ref)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		Exit("Could not create bundle: %s", err)
+	}
+
+	err = lfs.WriteBundle(f, bundled)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file)
+		Exit("Could not write bundle: %s", err)
+	}
+
+	Print("Bundled %d object(s) reachable from %s into %s", len(bundled), ref, file)
+}
+
+func bundleUnbundleCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) != 1 {
+		Exit("Usage: git lfs bundle unbundle <file>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		Exit("Could not open bundle: %s", err)
+	}
+	defer f.Close()
+
+	result, err := lfs.Unbundle(f)
+	if result != nil {
+		sort.Sort(byRejectedOid(result.Rejected))
+		for _, r := range result.Rejected {
+			Error("Rejected %s: %s", r.Oid, r.Reason)
+		}
+		Print("Imported %d object(s), %d already present", result.Imported, result.Existing)
+	}
+
+	if err != nil {
+		Exit("Could not unbundle %s: %s", args[0], err)
+	}
+
+	if len(result.Rejected) > 0 {
+		Exit("%d object(s) in the bundle were rejected", len(result.Rejected))
+	}
+}
+
+type byRejectedOid []*lfs.BundleRejection
+
+func (r byRejectedOid) Len() int           { return len(r) }
+func (r byRejectedOid) Less(i, j int) bool { return r[i].Oid < r[j].Oid }
+func (r byRejectedOid) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+func init() {
+	bundleCmd.AddCommand(bundleCreateCmd, bundleUnbundleCmd)
+	RootCmd.AddCommand(bundleCmd)
+}
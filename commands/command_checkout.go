@@ -2,15 +2,19 @@ package commands
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"sort"
 	"sync"
 
 	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
 	"github.com/github/git-lfs/progress"
+	"github.com/github/git-lfs/subprocess"
 	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
@@ -20,11 +24,33 @@ var (
 		Use: "checkout",
 		Run: checkoutCommand,
 	}
+	checkoutStageArg      string
+	checkoutDryRunArg     bool
+	checkoutSkipErrorsArg bool
+	checkoutIndexArg      bool
+
+	checkoutStageNames = map[string]int{
+		"1": 1, "base": 1,
+		"2": 2, "ours": 2,
+		"3": 3, "theirs": 3,
+	}
+	checkoutStageLabels = []string{"", "base", "ours", "theirs"}
 )
 
 func checkoutCommand(cmd *cobra.Command, args []string) {
 	requireInRepo()
 
+	if len(checkoutStageArg) > 0 {
+		if checkoutDryRunArg {
+			Exit("Cannot combine --dry-run with --stage")
+		}
+		if checkoutIndexArg {
+			Exit("Cannot combine --index with --stage")
+		}
+		checkoutStage(checkoutStageArg, args)
+		return
+	}
+
 	// Parameters are filters
 	// firstly convert any pathspecs to the root of the repo, in case this is being executed in a sub-folder
 	var rootedpaths []string
@@ -39,20 +65,222 @@ func checkoutCommand(cmd *cobra.Command, args []string) {
 		rootedpaths = append(rootedpaths, <-outchan)
 	}
 	close(inchan)
+
+	if checkoutIndexArg {
+		if checkoutDryRunArg {
+			Exit("Cannot combine --dry-run with --index")
+		}
+		checkoutIndex(rootedpaths, nil)
+		return
+	}
+	if checkoutDryRunArg {
+		checkoutDryRun(rootedpaths, nil)
+		return
+	}
 	checkoutWithIncludeExclude(rootedpaths, nil)
 }
 
+// checkoutIndex checks out the Git LFS files matching include and exclude at
+// the versions in the index, rather than at HEAD, such as after a partial git
+// add or a git read-tree. Conflicted paths are skipped, since there is no one
+// version of them to check out; --stage checks out a side of those. Files
+// whose objects aren't local are listed, and make checkout exit non-zero.
+func checkoutIndex(include, exclude []string) {
+	pointers, conflicted, err := lfs.ScanIndexTree()
+	if err != nil {
+		Panic(err, "Could not scan the index for Git LFS files")
+	}
+
+	var local []*lfs.WrappedPointer
+	var missing []*lfs.WrappedPointer
+	for _, pointer := range pointers {
+		if !lfs.FilenamePassesIncludeExcludeFilter(pointer.Name, include, exclude) {
+			continue
+		}
+		if lfs.ObjectExistsOfSize(pointer.Oid, pointer.Size) {
+			local = append(local, pointer)
+		} else {
+			missing = append(missing, pointer)
+		}
+	}
+
+	skipped := checkoutPointers(local, nil, nil)
+
+	for _, pointer := range conflicted {
+		if lfs.FilenamePassesIncludeExcludeFilter(pointer.Name, include, exclude) {
+			Error("Skipped %s, it is conflicted. Use --stage to check out one side.", pointer.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		Error("Could not check out %d files in the index, their objects are not local:", len(missing))
+		for _, pointer := range missing {
+			Error("  %s (%s)", pointer.Name, pointer.Oid)
+		}
+		Error("Run git lfs fetch to download them first")
+	}
+
+	reportSkippedCheckouts(skipped)
+	if len(missing) > 0 {
+		os.Exit(2)
+	}
+}
+
+// checkoutDryRun reports what checkout would do to each Git LFS file matching
+// include and exclude, without writing anything: whether it would be checked
+// out, is already up to date or has other content which checkout leaves alone,
+// or can't be checked out because its object isn't local.
+func checkoutDryRun(include, exclude []string) {
+	ref, err := git.CurrentRef()
+	if err != nil {
+		Panic(err, "Could not checkout")
+	}
+
+	pointers, err := lfs.ScanTree(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	repopathchan := make(chan string, 1)
+	cwdpathchan, err := lfs.ConvertRepoFilesRelativeToCwd(repopathchan)
+	if err != nil {
+		Panic(err, "Could not convert file paths")
+	}
+	defer close(repopathchan)
+
+	var checkout, upToDate, missing int
+	var checkoutBytes int64
+	for _, pointer := range pointers {
+		if !lfs.FilenamePassesIncludeExcludeFilter(pointer.Name, include, exclude) {
+			continue
+		}
+
+		repopathchan <- pointer.Name
+		cwdfilepath := <-cwdpathchan
+
+		// The same checks as checkoutWithChan
+		filepointer, err := lfs.DecodePointerFromFile(cwdfilepath)
+		if err != nil && !os.IsNotExist(err) {
+			if errutil.IsNotAPointerError(err) {
+				Print("up to date: %s", pointer.Name)
+				upToDate++
+				continue
+			}
+			LoggedError(err, "Problem accessing %v", pointer.Name)
+			continue
+		}
+
+		if filepointer != nil && filepointer.Oid != pointer.Oid {
+			Print("up to date: %s (a pointer to another object, left alone)", pointer.Name)
+			upToDate++
+			continue
+		}
+
+		if !lfs.ObjectExistsOfSize(pointer.Oid, pointer.Size) {
+			Print("missing: %s (%s)", pointer.Name, pointer.Oid)
+			missing++
+			continue
+		}
+
+		Print("checkout: %s (%s)", pointer.Name, humanizeBytes(pointer.Size))
+		checkout++
+		checkoutBytes += pointer.Size
+	}
+
+	Print("%d files would be checked out (%s), %d up to date, %d missing locally", checkout, humanizeBytes(checkoutBytes), upToDate, missing)
+	if missing > 0 {
+		Print("Run git lfs fetch to download the missing objects first")
+	}
+}
+
+// checkoutStage writes the content of the Git LFS object recorded at the given
+// merge stage of each conflicted path to the working copy, overwriting whatever
+// is there. The index is left alone, so the paths stay conflicted until the
+// user resolves them with git add.
+func checkoutStage(stageArg string, paths []string) {
+	stage, ok := checkoutStageNames[stageArg]
+	if !ok {
+		Exit("Invalid --stage %q, expected 1 (base), 2 (ours) or 3 (theirs)", stageArg)
+	}
+
+	if len(paths) == 0 {
+		Exit("Usage: git lfs checkout --stage=<1|2|3> <path>...")
+	}
+
+	failed := false
+	for _, path := range paths {
+		if err := checkoutPathAtStage(path, stage); err != nil {
+			Error("%s", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(2)
+	}
+}
+
+func checkoutPathAtStage(path string, stage int) error {
+	label := checkoutStageLabels[stage]
+
+	stages, err := git.IndexStages(path)
+	if err != nil {
+		return fmt.Errorf("Could not read the index for %s: %v", path, err)
+	}
+
+	if len(stages) == 0 {
+		return fmt.Errorf("%s is not in the index", path)
+	}
+
+	if _, ok := stages[0]; ok {
+		return fmt.Errorf("%s is not in a conflicted state", path)
+	}
+
+	sha, ok := stages[stage]
+	if !ok {
+		return fmt.Errorf("%s has no stage %d (%s) in the index", path, stage, label)
+	}
+
+	blob, err := subprocess.ExecCommand("git", "cat-file", "blob", sha).Output()
+	if err != nil {
+		return fmt.Errorf("Could not read stage %d (%s) of %s: %v", stage, label, path, err)
+	}
+
+	ptr, err := lfs.DecodePointer(bytes.NewReader(blob))
+	if err != nil {
+		return fmt.Errorf("Stage %d (%s) of %s is not a Git LFS pointer", stage, label, path)
+	}
+
+	err = lfs.PointerSmudgeToFile(path, ptr, false, nil)
+	if errutil.IsDownloadDeclinedError(err) {
+		return fmt.Errorf("Content for stage %d (%s) of %s is not local (%s). Use fetch to download.", stage, label, path, ptr.Oid)
+	} else if err != nil {
+		return fmt.Errorf("Could not checkout stage %d (%s) of %s: %v", stage, label, path, err)
+	}
+
+	Print("Checked out stage %d (%s) of %s", stage, label, path)
+	return nil
+}
+
 func init() {
+	checkoutCmd.Flags().StringVarP(&checkoutStageArg, "stage", "", "", "Check out the version at a merge stage (1, 2, 3 or base, ours, theirs)")
+	checkoutCmd.Flags().BoolVarP(&checkoutDryRunArg, "dry-run", "d", false, "Report what would be checked out without writing anything")
+	checkoutCmd.Flags().BoolVarP(&checkoutSkipErrorsArg, "skip-errors", "", false, "Leave files which can't be checked out as pointers and continue")
+	checkoutCmd.Flags().BoolVarP(&checkoutIndexArg, "index", "", false, "Check out the versions in the index instead of at HEAD")
+	addQuietFlag(checkoutCmd)
 	RootCmd.AddCommand(checkoutCmd)
 }
 
 // Checkout from items reported from the fetch process (in parallel)
 func checkoutAllFromFetchChan(c chan *lfs.WrappedPointer) {
 	tracerx.Printf("starting fetch/parallel checkout")
-	checkoutFromFetchChan(nil, nil, c)
+	reportSkippedCheckouts(checkoutFromFetchChan(nil, nil, c))
 }
 
-func checkoutFromFetchChan(include []string, exclude []string, in chan *lfs.WrappedPointer) {
+// checkoutFromFetchChan checks out the files for each object fetched, and
+// returns the files skipped as described for checkoutWithChan. When skipping
+// errors, that includes those whose objects weren't fetched.
+func checkoutFromFetchChan(include []string, exclude []string, in chan *lfs.WrappedPointer) []string {
 	ref, err := git.CurrentRef()
 	if err != nil {
 		Panic(err, "Could not checkout")
@@ -77,8 +305,9 @@ func checkoutFromFetchChan(include []string, exclude []string, in chan *lfs.Wrap
 	var wait sync.WaitGroup
 	wait.Add(1)
 
+	var skipped []string
 	go func() {
-		checkoutWithChan(c)
+		skipped = checkoutWithChan(c)
 		wait.Done()
 	}()
 
@@ -88,9 +317,19 @@ func checkoutFromFetchChan(include []string, exclude []string, in chan *lfs.Wrap
 		for _, fp := range mapping[p.Oid] {
 			c <- fp
 		}
+		delete(mapping, p.Oid)
 	}
 	close(c)
 	wait.Wait()
+
+	if checkoutSkipErrors() {
+		for _, pointers := range mapping {
+			for _, p := range pointers {
+				skipped = append(skipped, p.Name)
+			}
+		}
+	}
+	return skipped
 }
 
 func checkoutWithIncludeExclude(include []string, exclude []string) {
@@ -104,13 +343,21 @@ func checkoutWithIncludeExclude(include []string, exclude []string) {
 		Panic(err, "Could not scan for Git LFS files")
 	}
 
+	reportSkippedCheckouts(checkoutPointers(pointers, include, exclude))
+}
+
+// checkoutPointers checks out those of the pointers matching include and
+// exclude with checkoutWithChan, showing progress, and returns the files
+// skipped.
+func checkoutPointers(pointers []*lfs.WrappedPointer, include []string, exclude []string) []string {
 	var wait sync.WaitGroup
 	wait.Add(1)
 
 	c := make(chan *lfs.WrappedPointer, 1)
 
+	var skipped []string
 	go func() {
-		checkoutWithChan(c)
+		skipped = checkoutWithChan(c)
 		wait.Done()
 	}()
 
@@ -120,6 +367,8 @@ func checkoutWithIncludeExclude(include []string, exclude []string) {
 		totalBytes += pointer.Size
 	}
 	progress := progress.NewProgressMeter(len(pointers), totalBytes, false, cfg.Getenv("GIT_LFS_PROGRESS"))
+	progress.SetPlainProgress(cfg.ForceProgress(), cfg.ProgressInterval())
+	progress.SetQuiet(cfg.Quiet())
 	progress.Start()
 	totalBytes = 0
 	for _, pointer := range pointers {
@@ -139,6 +388,7 @@ func checkoutWithIncludeExclude(include []string, exclude []string) {
 	wait.Wait()
 	progress.Finish()
 
+	return skipped
 }
 
 func checkoutAll() {
@@ -148,11 +398,14 @@ func checkoutAll() {
 // Populate the working copy with the real content of objects where the file is
 // either missing, or contains a matching pointer placeholder, from a list of pointers.
 // If the file exists but has other content it is left alone
+// A file which can't be checked out is reported, and the rest are checked out.
+// If errors are being skipped, it is also left as a pointer, and returned
+// among the files skipped, as are files whose content isn't local.
 // Callers of this function MUST NOT Panic or otherwise exit the process
 // without waiting for this function to shut down.  If the process exits while
 // update-index is in the middle of processing a file the git index can be left
 // in a locked state.
-func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
+func checkoutWithChan(in <-chan *lfs.WrappedPointer) []string {
 	// Get a converter from repo-relative to cwd-relative
 	// Since writing data & calling git update-index must be relative to cwd
 	repopathchan := make(chan string, 1)
@@ -174,6 +427,9 @@ func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
 	// while update-index is in the middle of updating, the index can remain in a
 	// locked state.
 
+	skipErrors := checkoutSkipErrors()
+	var skipped []string
+
 	// As files come in, write them to the wd and update the index
 	for pointer := range in {
 
@@ -202,9 +458,24 @@ func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
 			if errutil.IsDownloadDeclinedError(err) {
 				// acceptable error, data not local (fetch not run or include/exclude)
 				LoggedError(err, "Skipped checkout for %v, content not local. Use fetch to download.", pointer.Name)
+				if skipErrors {
+					skipped = append(skipped, pointer.Name)
+				}
 			} else {
 				LoggedError(err, "Could not checkout file")
-				continue
+				if !skipErrors {
+					continue
+				}
+				skipped = append(skipped, pointer.Name)
+
+				// leave a pointer in place of a missing file
+				if _, statErr := os.Stat(cwdfilepath); !os.IsNotExist(statErr) {
+					continue
+				}
+				if writeErr := ioutil.WriteFile(cwdfilepath, []byte(pointer.Encoded()), 0644); writeErr != nil {
+					LoggedError(writeErr, "Could not write a pointer to %s", pointer.Name)
+					continue
+				}
 			}
 		}
 
@@ -234,4 +505,27 @@ func checkoutWithChan(in <-chan *lfs.WrappedPointer) {
 			LoggedError(err, "Error updating the git index:\n%s", updateIdxOut.String())
 		}
 	}
+
+	return skipped
+}
+
+// checkoutSkipErrors returns whether files which can't be checked out should
+// be left as pointers and listed once the checkout is done.
+func checkoutSkipErrors() bool {
+	return checkoutSkipErrorsArg || cfg.SkipDownloadErrors()
+}
+
+// reportSkippedCheckouts lists the files returned by checkoutWithChan, and
+// exits with an error if there are any.
+func reportSkippedCheckouts(skipped []string) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	sort.Strings(skipped)
+	Error("Skipped checking out %d files, which were left as pointers:", len(skipped))
+	for _, name := range skipped {
+		Error("  %s", name)
+	}
+	os.Exit(2)
 }
@@ -1,11 +1,16 @@
 package commands
 
 import (
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/lfs"
 	"github.com/github/git-lfs/progress"
+	"github.com/github/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -14,9 +19,13 @@ var (
 		Use: "clean",
 		Run: cleanCommand,
 	}
+
+	cleanPath    string
+	cleanNoStore bool
 )
 
 func cleanCommand(cmd *cobra.Command, args []string) {
+	warnLfsConfigErrors()
 	requireStdin("This command should be run by the Git 'clean' filter")
 	lfs.InstallHooks(false)
 
@@ -24,11 +33,29 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 	var cb progress.CopyCallback
 	var file *os.File
 	var fileSize int64
-	if len(args) > 0 {
+	if len(cleanPath) > 0 {
+		// Git runs the clean filter from the top of the working tree, so
+		// resolve the repository-relative path the same way.
+		if len(config.LocalWorkingDir) > 0 {
+			if err := os.Chdir(config.LocalWorkingDir); err != nil {
+				Panic(err, "Unable to change to %s", config.LocalWorkingDir)
+			}
+		}
+		fileName = filepath.FromSlash(cleanPath)
+	} else if len(args) > 0 {
 		fileName = args[0]
+	}
+
+	if len(fileName) > 0 {
 
 		stat, err := os.Stat(fileName)
 		if err == nil && stat != nil {
+			if ptr := lfs.UnchangedPointer(fileName, stat); ptr != nil {
+				io.Copy(ioutil.Discard, os.Stdin)
+				lfs.EncodePointer(os.Stdout, ptr)
+				return
+			}
+
 			fileSize = stat.Size()
 
 			localCb, localFile, err := lfs.CopyCallbackFile("clean", fileName, 1, 1)
@@ -41,6 +68,11 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if cleanNoStore {
+		cleanWithoutStoring(fileName, fileSize, cb, file)
+		return
+	}
+
 	cleaned, err := lfs.PointerClean(os.Stdin, fileName, fileSize, cb)
 	if file != nil {
 		file.Close()
@@ -56,7 +88,7 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 	}
 
 	if err != nil {
-		Panic(err, "Error cleaning asset.")
+		Panic(err, "Error cleaning asset: %s", err)
 	}
 
 	tmpfile := cleaned.Filename
@@ -71,16 +103,46 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 		}
 		Debug("%s exists", mediafile)
 	} else {
-		if err := os.Rename(tmpfile, mediafile); err != nil {
+		if err := tools.RenameFileCopyPermissions(tmpfile, mediafile); err != nil {
 			Panic(err, "Unable to move %s to %s\n", tmpfile, mediafile)
 		}
 
 		Debug("Writing %s", mediafile)
 	}
 
+	if len(fileName) > 0 && config.Config.MetadataEnabled() {
+		if err := lfs.WriteObjectMetadata(cleaned.Oid, fileName); err != nil {
+			Debug("Unable to record metadata for %s: %s", fileName, err)
+		}
+	}
+
 	lfs.EncodePointer(os.Stdout, cleaned.Pointer)
 }
 
+// cleanWithoutStoring writes the pointer for the content on stdin, discarding
+// the content rather than moving it into the local store. The object must be
+// supplied by some other means before the pointer is pushed.
+func cleanWithoutStoring(fileName string, fileSize int64, cb progress.CopyCallback, file *os.File) {
+	ptr, err := lfs.PointerHash(os.Stdin, fileName, fileSize, cb)
+	if file != nil {
+		file.Close()
+	}
+
+	if errutil.IsCleanPointerError(err) {
+		os.Stdout.Write(errutil.ErrorGetContext(err, "bytes").([]byte))
+		return
+	}
+
+	if err != nil {
+		Panic(err, "Error cleaning asset: %s", err)
+	}
+
+	Debug("Not storing %s", ptr.Oid)
+	lfs.EncodePointer(os.Stdout, ptr)
+}
+
 func init() {
+	cleanCmd.Flags().StringVarP(&cleanPath, "path", "", "", "Repository-relative path of the file being cleaned")
+	cleanCmd.Flags().BoolVarP(&cleanNoStore, "no-store", "", false, "Write the pointer without keeping the object in the local store")
 	RootCmd.AddCommand(cleanCmd)
 }
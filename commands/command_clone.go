@@ -78,6 +78,7 @@ func cloneCommand(cmd *cobra.Command, args []string) {
 	if cloneFlags.NoCheckout || cloneFlags.Bare {
 		// If --no-checkout or --bare then we shouldn't check out, just fetch instead
 		fetchRef("HEAD", include, exclude)
+		enforceStorageCap()
 	} else {
 		pull(include, exclude)
 
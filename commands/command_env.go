@@ -1,21 +1,63 @@
 package commands
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/auth"
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/transfer"
 	"github.com/spf13/cobra"
 )
 
 var (
+	envCheckEndpoint bool
+	envInstalled     bool
+	envRemoteArg     string
+
 	envCmd = &cobra.Command{
 		Use: "env",
 		Run: envCommand,
 	}
+
+	// envRedactedHeaders are the request headers whose values are masked when
+	// echoed by --check-endpoint.
+	envRedactedHeaders = map[string]bool{
+		"Authorization":       true,
+		"Proxy-Authorization": true,
+	}
 )
 
+// envRedacted replaces the credentials in headers and URLs echoed by
+// --check-endpoint.
+const envRedacted = "* * * * *"
+
 func envCommand(cmd *cobra.Command, args []string) {
 	config.ShowConfigWarnings = true
+
+	if envCheckEndpoint {
+		envCheckEndpointCommand(args)
+		return
+	}
+
+	if len(envRemoteArg) > 0 {
+		envRemoteCommand(envRemoteArg)
+		return
+	}
+
+	if envInstalled {
+		envInstalledCommand()
+		return
+	}
+
 	endpoint := cfg.Endpoint("download")
 
 	gitV, err := git.Config.Version()
@@ -35,13 +77,15 @@ func envCommand(cmd *cobra.Command, args []string) {
 	}
 
 	for _, remote := range cfg.Remotes() {
-		remoteEndpoint := cfg.RemoteEndpoint(remote, "download")
+		remoteEndpoint := cfg.ResolvedRemoteEndpoint(remote, "download")
 		Print("Endpoint (%s)=%s (auth=%s)", remote, remoteEndpoint.Url, cfg.EndpointAccess(remoteEndpoint))
 		if len(remoteEndpoint.SshUserAndHost) > 0 {
 			Print("  SSH=%s:%s", remoteEndpoint.SshUserAndHost, remoteEndpoint.SshPath)
 		}
 	}
 
+	printCachedCapabilities()
+
 	for _, env := range lfs.Environ() {
 		Print(env)
 	}
@@ -52,6 +96,228 @@ func envCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// printCachedCapabilities lists the server capabilities cached for each
+// endpoint, and how long ago they were discovered.
+func printCachedCapabilities() {
+	cached := api.CachedCapabilities()
+	urls := make([]string, 0, len(cached))
+	for u := range cached {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	ttl := cfg.CapabilitiesTTL()
+	for _, u := range urls {
+		caps := cached[u]
+		age := time.Since(caps.CheckedAt) / time.Second * time.Second
+		if age > ttl {
+			Print("Capabilities (%s) discovered %s ago, expired", u, age)
+		} else {
+			Print("Capabilities (%s) discovered %s ago", u, age)
+		}
+
+		if caps.BatchGzip != nil {
+			Print("  batch.gzip=%t", *caps.BatchGzip)
+		}
+	}
+}
+
+// envRemoteCommand prints how the endpoints, authentication and concurrency
+// for the named remote are resolved, with the config key each value comes
+// from.
+func envRemoteCommand(remote string) {
+	if git.ValidateRemote(remote) != nil && !isConfiguredRemote(remote) {
+		Exit("Invalid remote name %q", remote)
+	}
+	cfg.CurrentRemote = remote
+
+	Print("Remote=%s", remote)
+	for _, operation := range []string{"download", "upload"} {
+		endpoint, source := cfg.ResolvedRemoteEndpointSource(remote, operation)
+		if len(endpoint.Url) == 0 {
+			Print("  Endpoint (%s)=none", operation)
+			continue
+		}
+		Print("  Endpoint (%s)=%s (%s)", operation, redactedURL(endpoint.Url), envSource(source))
+
+		accessKey := fmt.Sprintf("lfs.%s.access", endpoint.Url)
+		if _, ok := cfg.GitConfig(accessKey); ok {
+			Print("    auth=%s (%s)", cfg.EndpointAccess(endpoint), envSource(accessKey))
+		} else {
+			Print("    auth=%s (%s)", cfg.EndpointAccess(endpoint), envSource(""))
+		}
+
+		if helper := cfg.CredentialHelper(endpoint.Url); len(helper) > 0 {
+			Print("    credentialhelper=%s", helper)
+		}
+
+		if exe, args := auth.SshAuthenticateCommand(cfg, endpoint, operation, ""); len(exe) > 0 {
+			Print("    SSH=%s:%s", endpoint.SshUserAndHost, endpoint.SshPath)
+			Print("    authenticate=%s %s", exe, strings.TrimSpace(strings.Join(args, " ")))
+		}
+	}
+
+	source := ""
+	if cfg.NtlmAccess("download") {
+		source = "NTLM authentication allows one transfer at a time"
+	} else {
+		for _, key := range []string{"remote." + remote + ".lfsconcurrenttransfers", "lfs.concurrenttransfers"} {
+			if v, ok := cfg.GitConfig(key); ok {
+				if n, err := strconv.Atoi(v); err == nil && n > 0 {
+					source = key
+					break
+				}
+			}
+		}
+	}
+	Print("  ConcurrentTransfers=%d (%s)", cfg.RemoteConcurrentTransfers(remote), envSource(source))
+
+	if rate := cfg.RemoteMaxBandwidth(remote); rate > 0 {
+		Print("  MaxBandwidth=%s/s (%s)", humanizeBytes(rate), envSource("remote."+remote+".lfsmaxbandwidth"))
+	}
+}
+
+// envInstalledCommand reports whether each filter and hook Git LFS needs is
+// present, missing or stale, exiting with an error if any aren't present.
+func envInstalledCommand() {
+	checks := lfs.CheckFilters()
+	if lfs.InRepo() {
+		checks = append(checks, lfs.CheckHooks()...)
+	} else {
+		Print("Not in a git repository, so hooks were not checked")
+	}
+
+	ok := true
+	for _, check := range checks {
+		Print("%s: %s (%s)", check.Name, check.Status, check.Detail)
+		ok = ok && check.Status == lfs.InstallPresent
+	}
+
+	if !ok {
+		Exit("Git LFS is not fully installed. Run `git lfs install` to fix this.")
+	}
+}
+
+// envSource describes where a value printed by envRemoteCommand comes from.
+func envSource(key string) string {
+	switch {
+	case len(key) == 0:
+		return "default"
+	case strings.Contains(key, " "):
+		return key
+	}
+	return "from " + key
+}
+
+// isConfiguredRemote returns whether the remote has any remote.<name>.* keys,
+// such as a remote which only has an lfsurl.
+func isConfiguredRemote(remote string) bool {
+	for _, r := range cfg.Remotes() {
+		if r == remote {
+			return true
+		}
+	}
+	_, ok := cfg.GitConfig("remote." + remote + ".url")
+	return ok
+}
+
+// envCheckEndpointCommand probes the batch API of the endpoint for the given
+// remote, or the default remote, and reports how the server responded.
+func envCheckEndpointCommand(args []string) {
+	requireInRepo()
+
+	if len(args) > 0 {
+		if err := git.ValidateRemote(args[0]); err != nil {
+			Exit("Invalid remote name %q", args[0])
+		}
+		cfg.CurrentRemote = args[0]
+	} else if remote, err := git.DefaultRemote(); err == nil {
+		cfg.CurrentRemote = remote
+	}
+
+	endpoint := cfg.Endpoint("download")
+	if len(endpoint.Url) == 0 {
+		Exit("No Git LFS endpoint for remote %q", cfg.CurrentRemote)
+	}
+
+	Print("Checking endpoint for %s: %s", cfg.CurrentRemote, redactedURL(endpoint.Url))
+
+	probe, err := api.ProbeBatch("download", transfer.GetDownloadAdapterNames())
+	if err != nil {
+		Exit("Unable to reach the endpoint: %s", err)
+	}
+
+	Print("  %s %s", probe.Request.Method, redactedURL(probe.Request.URL.String()))
+	for _, line := range redactedHeaders(probe.Request.Header) {
+		Print("  > %s", line)
+	}
+
+	Print("  Status: %s (%s)", probe.Status, probe.Duration/time.Millisecond*time.Millisecond)
+
+	_, sentCreds := probe.Request.Header["Authorization"]
+	if user := probe.Request.URL.User; user != nil {
+		_, hasPassword := user.Password()
+		sentCreds = sentCreds || hasPassword
+	}
+
+	switch {
+	case probe.StatusCode == 401 || probe.StatusCode == 403:
+		Print("  Authentication: failed")
+	case sentCreds:
+		Print("  Authentication: ok")
+	default:
+		Print("  Authentication: not required")
+	}
+
+	if len(probe.TransferAdapterName) > 0 {
+		Print("  Transfer adapter: %s", probe.TransferAdapterName)
+	} else if probe.StatusCode == 200 {
+		Print("  Transfer adapter: basic (none announced)")
+	}
+
+	if probe.StatusCode != 200 {
+		Exit("Endpoint check failed with HTTP %d", probe.StatusCode)
+	}
+}
+
+// redactedHeaders returns the headers as sorted "Key: value" lines, with the
+// credentials in any envRedactedHeaders masked but their scheme kept.
+func redactedHeaders(header http.Header) []string {
+	lines := make([]string, 0, len(header))
+	for key, values := range header {
+		for _, value := range values {
+			if envRedactedHeaders[key] {
+				scheme := strings.SplitN(value, " ", 2)[0]
+				value = scheme + " " + envRedacted
+			}
+			lines = append(lines, key+": "+value)
+		}
+	}
+
+	sort.Strings(lines)
+	return lines
+}
+
+// redactedURL returns rawurl with any password in it masked.
+func redactedURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return rawurl
+	}
+
+	if _, ok := u.User.Password(); !ok {
+		return rawurl
+	}
+
+	// url.UserPassword would escape the spaces in the mask
+	userinfo := url.User(u.User.Username()).String() + ":" + envRedacted + "@"
+	u.User = nil
+	return strings.Replace(u.String(), "://", "://"+userinfo, 1)
+}
+
 func init() {
+	envCmd.Flags().BoolVarP(&envCheckEndpoint, "check-endpoint", "", false, "Send a test batch request to the endpoint.")
+	envCmd.Flags().BoolVarP(&envInstalled, "installed", "", false, "Check that the Git LFS filters and hooks are installed.")
+	envCmd.Flags().StringVarP(&envRemoteArg, "remote", "", "", "Show how the endpoints, auth and concurrency are resolved for this remote.")
 	RootCmd.AddCommand(envCmd)
 }
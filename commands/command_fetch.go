@@ -2,11 +2,24 @@ package commands
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
 	"github.com/github/git-lfs/progress"
+	"github.com/github/git-lfs/subprocess"
+	"github.com/github/git-lfs/transfer"
 	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
@@ -20,11 +33,36 @@ var (
 	fetchExcludeArg string
 	fetchRecentArg  bool
 	fetchAllArg     bool
+	fetchAllTagsArg bool
 	fetchPruneArg   bool
+	fetchSubmodules bool
+	fetchSinceArg   string
+
+	fetchChangedSinceArg string
+	// fetchChangedSince is the parsed --changed-since, or zero for none.
+	fetchChangedSince time.Time
+
+	fetchReferenceArg string
+	fetchVerifyArg    bool
+
+	fetchExistsOnlyArg bool
+	fetchManifestArg   string
+
+	fetchBackgroundArg bool
+	fetchStatusArg     bool
 )
 
 func fetchCommand(cmd *cobra.Command, args []string) {
 	requireInRepo()
+	setupHttpStats()
+
+	if fetchStatusArg {
+		if fetchBackgroundArg {
+			Exit("Cannot combine --status with --background")
+		}
+		backgroundFetchStatus()
+		return
+	}
 
 	var refs []*git.Ref
 
@@ -49,7 +87,7 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 			Panic(err, "Invalid ref argument: %v", args[1:])
 		}
 		refs = resolvedrefs
-	} else if !fetchAllArg {
+	} else if !fetchAllArg && !fetchAllTagsArg {
 		ref, err := git.CurrentRef()
 		if err != nil {
 			Panic(err, "Could not fetch")
@@ -57,11 +95,88 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		refs = []*git.Ref{ref}
 	}
 
+	var since time.Time
+	if len(fetchSinceArg) > 0 {
+		s, err := git.ParseApproxDate(fetchSinceArg)
+		if err != nil {
+			Exit("Invalid --since date: %s", err)
+		}
+		since = s
+	}
+
+	if len(fetchChangedSinceArg) > 0 {
+		s, err := git.ParseApproxDate(fetchChangedSinceArg)
+		if err != nil {
+			Exit("Invalid --changed-since date: %s", err)
+		}
+		fetchChangedSince = s
+	}
+
+	if fetchBackgroundArg {
+		if fetchExistsOnlyArg || len(fetchManifestArg) > 0 || fetchVerifyArg {
+			Exit("Cannot combine --background with --exists-only or --verify")
+		}
+		if len(cfg.Getenv(backgroundFetchEnv)) > 0 {
+			superviseBackgroundFetch()
+		} else {
+			startBackgroundFetch()
+		}
+		return
+	}
+
+	if fetchExistsOnlyArg || len(fetchManifestArg) > 0 {
+		if !fetchExistsOnlyArg || len(fetchManifestArg) == 0 {
+			Exit("--exists-only and --manifest must be given together")
+		}
+		if fetchAllArg || fetchAllTagsArg || fetchRecentArg || fetchPruneArg || fetchSubmodules || fetchVerifyArg ||
+			len(args) > 1 || !since.IsZero() || !fetchChangedSince.IsZero() || len(fetchReferenceArg) > 0 ||
+			fetchIncludeArg != "" || fetchExcludeArg != "" {
+			Exit("Cannot combine --exists-only with ref arguments or other options")
+		}
+
+		if !fetchExistsOnly(fetchManifestArg) {
+			os.Exit(2)
+		}
+		return
+	}
+
 	success := true
-	if fetchAllArg {
+	includePaths, excludePaths := determineIncludeExcludePaths(cfg, fetchIncludeArg, fetchExcludeArg)
+	if fetchVerifyArg {
+		if fetchAllArg || fetchAllTagsArg || fetchRecentArg || fetchPruneArg || fetchSubmodules || !since.IsZero() ||
+			!fetchChangedSince.IsZero() || len(fetchReferenceArg) > 0 {
+			Exit("Cannot combine --verify with --all, --all-tags, --recent, --since, --changed-since, --reference, --prune or --include-submodules")
+		}
+
+		for _, ref := range refs {
+			s := verifyRef(ref, includePaths, excludePaths)
+			success = success && s
+		}
+
+		if !success {
+			Exit("Warning: errors occurred")
+		}
+		return
+	}
+
+	if (fetchAllArg || fetchAllTagsArg || fetchRecentArg || cfg.FetchPruneConfig().FetchRecentAlways || !since.IsZero()) &&
+		git.IsShallow(config.LocalGitStorageDir) {
+		Print("This is a shallow clone, so Git LFS objects only in commits beyond its boundary can't be found or fetched")
+	}
+
+	if fetchAllTagsArg {
+		if fetchAllArg || fetchRecentArg || len(args) > 1 || !since.IsZero() {
+			Exit("Cannot combine --all-tags with ref arguments, --all, --recent or --since")
+		}
+		success = fetchAllTags(includePaths, excludePaths)
+
+	} else if fetchAllArg {
 		if fetchRecentArg || len(args) > 1 {
 			Exit("Cannot combine --all with ref arguments or --recent")
 		}
+		if !since.IsZero() {
+			Exit("Cannot combine --all with --since")
+		}
 		if fetchIncludeArg != "" || fetchExcludeArg != "" {
 			Exit("Cannot combine --all with --include or --exclude")
 		}
@@ -71,10 +186,14 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		success = fetchAll()
 
 	} else { // !all
-		includePaths, excludePaths := determineIncludeExcludePaths(cfg, fetchIncludeArg, fetchExcludeArg)
-
 		// Fetch refs sequentially per arg order; duplicates in later refs will be ignored
 		for _, ref := range refs {
+			if !since.IsZero() {
+				s := fetchRefSince(ref, since, includePaths, excludePaths)
+				success = success && s
+				continue
+			}
+
 			Print("Fetching %v", ref.Name)
 			s := fetchRef(ref.Sha, includePaths, excludePaths)
 			success = success && s
@@ -86,12 +205,19 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if fetchSubmodules || cfg.FetchPruneConfig().FetchSubmodulesAlways {
+		s := fetchInSubmodules(includePaths, excludePaths)
+		success = success && s
+	}
+
 	if fetchPruneArg {
 		verify := cfg.FetchPruneConfig().PruneVerifyRemoteAlways
 		// no dry-run or verbose options in fetch, assume false
-		prune(verify, false, false)
+		prune(verify, false, false, false)
 	}
 
+	enforceStorageCap()
+
 	if !success {
 		Exit("Warning: errors occurred")
 	}
@@ -102,10 +228,208 @@ func init() {
 	fetchCmd.Flags().StringVarP(&fetchExcludeArg, "exclude", "X", "", "Exclude a list of paths")
 	fetchCmd.Flags().BoolVarP(&fetchRecentArg, "recent", "r", false, "Fetch recent refs & commits")
 	fetchCmd.Flags().BoolVarP(&fetchAllArg, "all", "a", false, "Fetch all LFS files ever referenced")
+	fetchCmd.Flags().BoolVarP(&fetchAllTagsArg, "all-tags", "", false, "Fetch the LFS files referenced by every tag")
 	fetchCmd.Flags().BoolVarP(&fetchPruneArg, "prune", "p", false, "After fetching, prune old data")
+	fetchCmd.Flags().BoolVarP(&fetchSubmodules, "include-submodules", "", false, "Also fetch in initialized submodules")
+	fetchCmd.Flags().StringVarP(&fetchSinceArg, "since", "", "", "Only fetch objects for commits at or after this date")
+	fetchCmd.Flags().StringVarP(&fetchChangedSinceArg, "changed-since", "", "", "Skip objects the server reports were not modified since this date")
+	fetchCmd.Flags().StringVarP(&fetchReferenceArg, "reference", "", "", "Take objects from this local repository before downloading them")
+	fetchCmd.Flags().BoolVarP(&fetchVerifyArg, "verify", "", false, "Check the local objects for the refs instead of downloading them")
+	fetchCmd.Flags().BoolVarP(&fetchExistsOnlyArg, "exists-only", "", false, "Ask the server which of the objects in --manifest it has, without downloading")
+	fetchCmd.Flags().StringVarP(&fetchManifestArg, "manifest", "", "", "Read the OIDs for --exists-only from this file, or - for stdin")
+	fetchCmd.Flags().BoolVarP(&fetchBackgroundArg, "background", "", false, "Fetch in a detached process, logging to .git/lfs/fetch-background.log")
+	fetchCmd.Flags().BoolVarP(&fetchStatusArg, "status", "", false, "Report the state of the latest background fetch")
+	addHttpStatsFlag(fetchCmd)
+	addTransferFailureFlags(fetchCmd)
+	addQuietFlag(fetchCmd)
 	RootCmd.AddCommand(fetchCmd)
 }
 
+// fetchExistsOnly sends batch download requests for the OIDs listed in the
+// manifest, one per line and optionally followed by a size, and prints
+// "<oid> present <size>", "<oid> missing" or "<oid> error <message>" for each.
+// Nothing is downloaded. It returns false if any object couldn't be checked.
+func fetchExistsOnly(manifest string) bool {
+	var data []byte
+	var err error
+	if manifest == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(manifest)
+	}
+	if err != nil {
+		Exit("Could not read OIDs from %s: %s", manifest, err)
+	}
+
+	success := true
+	var objects []*api.ObjectResource
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		oid := strings.ToLower(fields[0])
+		if !existsOidRE.MatchString(oid) || len(fields) > 2 {
+			Error("%s:%d: expected <oid> [<size>], skipping: %s", manifest, i+1, strings.TrimSpace(line))
+			success = false
+			continue
+		}
+
+		var size int64
+		if len(fields) > 1 {
+			if size, err = strconv.ParseInt(fields[1], 10, 64); err != nil || size < 0 {
+				Error("%s:%d: invalid size %q, skipping", manifest, i+1, fields[1])
+				success = false
+				continue
+			}
+		}
+
+		if seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		objects = append(objects, &api.ObjectResource{Oid: oid, Size: size})
+	}
+
+	adapters := transfer.GetDownloadAdapterNames()
+	for start := 0; start < len(objects); start += existsBatchSize {
+		end := start + existsBatchSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+		batch := objects[start:end]
+
+		objs, _, err := api.Batch(batch, "download", adapters)
+		if err != nil {
+			if errutil.IsNotImplementedError(err) {
+				Exit("The server does not support the batch API, which --exists-only needs")
+			}
+			for _, o := range batch {
+				Print("%s error %s", o.Oid, err)
+			}
+			success = false
+			continue
+		}
+
+		results := make(map[string]*api.ObjectResource, len(objs))
+		for _, o := range objs {
+			results[o.Oid] = o
+		}
+
+		for _, o := range batch {
+			res, ok := results[o.Oid]
+			switch {
+			case !ok:
+				Print("%s error not in the server's response", o.Oid)
+				success = false
+			case res.Error != nil && res.Error.Code == 404:
+				Print("%s missing", o.Oid)
+			case res.Error != nil:
+				Print("%s error %s", o.Oid, res.Error)
+				success = false
+			default:
+				if _, ok := res.Rel("download"); ok {
+					Print("%s present %d", o.Oid, res.Size)
+				} else {
+					Print("%s missing", o.Oid)
+				}
+			}
+		}
+	}
+
+	return success
+}
+
+// existsBatchSize is the number of objects fetchExistsOnly asks about in each
+// batch request.
+const existsBatchSize = 100
+
+var existsOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+// fetchInSubmodules runs `git lfs fetch` in each initialized submodule which
+// uses Git LFS, with the same remote (if the submodule has one by that name),
+// include/exclude filters and options as this fetch. A new git-lfs process is
+// used for each so it picks up the submodule's own config and storage.
+func fetchInSubmodules(include, exclude []string) bool {
+	paths, err := git.SubmodulePaths()
+	if err != nil {
+		Panic(err, "Could not list submodules")
+	}
+
+	root, _ := git.RootDir()
+	success := true
+	var results []string
+	for _, path := range paths {
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			name = path
+		}
+
+		if !git.UsesLfs(path) {
+			results = append(results, fmt.Sprintf("%s: skipped, does not use Git LFS", name))
+			continue
+		}
+
+		Print("Fetching submodule %s", name)
+		cmd := subprocess.ExecCommand("git", submoduleFetchArgs(path, include, exclude)...)
+		cmd.Dir = path
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			success = false
+			results = append(results, fmt.Sprintf("%s: failed (%v)", name, err))
+		} else {
+			results = append(results, fmt.Sprintf("%s: ok", name))
+		}
+	}
+
+	if len(results) > 0 {
+		Print("Submodules:")
+		for _, r := range results {
+			Print("  %s", r)
+		}
+	}
+
+	return success
+}
+
+func submoduleFetchArgs(path string, include, exclude []string) []string {
+	args := []string{"lfs", "fetch", "--include-submodules"}
+	if fetchAllArg {
+		// --all ignores include/exclude, and can't be combined with them
+		args = append(args, "--all")
+	} else {
+		if len(include) > 0 {
+			args = append(args, "--include", strings.Join(include, ","))
+		}
+		if len(exclude) > 0 {
+			args = append(args, "--exclude", strings.Join(exclude, ","))
+		}
+		if fetchRecentArg {
+			args = append(args, "--recent")
+		}
+		if fetchAllTagsArg {
+			args = append(args, "--all-tags")
+		}
+		if len(fetchSinceArg) > 0 {
+			args = append(args, "--since", fetchSinceArg)
+		}
+	}
+	if len(fetchChangedSinceArg) > 0 {
+		args = append(args, "--changed-since", fetchChangedSinceArg)
+	}
+
+	remote := subprocess.ExecCommand("git", "config", "--get", fmt.Sprintf("remote.%s.url", cfg.CurrentRemote))
+	remote.Dir = path
+	if remote.Run() == nil {
+		args = append(args, cfg.CurrentRemote)
+	}
+
+	return args
+}
+
 func pointersToFetchForRef(ref string) ([]*lfs.WrappedPointer, error) {
 	// Use SkipDeletedBlobs to avoid fetching ALL previous versions of modified files
 	opts := lfs.NewScanRefsOptions()
@@ -114,16 +438,27 @@ func pointersToFetchForRef(ref string) ([]*lfs.WrappedPointer, error) {
 	return lfs.ScanRefs(ref, "", opts)
 }
 
-func fetchRefToChan(ref string, include, exclude []string) chan *lfs.WrappedPointer {
+// fetchRefToChan fetches the objects for the given ref in the background,
+// sending each pointer to the returned channel once its object is available.
+// Whether the fetch succeeded is sent to the second channel when it is done.
+func fetchRefToChan(ref string, include, exclude []string) (chan *lfs.WrappedPointer, <-chan bool) {
 	c := make(chan *lfs.WrappedPointer)
 	pointers, err := pointersToFetchForRef(ref)
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS files")
 	}
 
-	go fetchAndReportToChan(pointers, include, exclude, c)
+	return c, fetchAndReportToChanAsync(pointers, include, exclude, c)
+}
 
-	return c
+// fetchAndReportToChanAsync runs fetchAndReportToChan in the background,
+// sending its result to the returned channel.
+func fetchAndReportToChanAsync(pointers []*lfs.WrappedPointer, include, exclude []string, out chan<- *lfs.WrappedPointer) <-chan bool {
+	done := make(chan bool, 1)
+	go func() {
+		done <- fetchAndReportToChan(pointers, include, exclude, out)
+	}()
+	return done
 }
 
 // Fetch all binaries for a given ref (that we don't have already)
@@ -135,6 +470,59 @@ func fetchRef(ref string, include, exclude []string) bool {
 	return fetchPointers(pointers, include, exclude)
 }
 
+// verifyRef checks that every object the given ref needs is in the local
+// store, and that its content matches its OID, without downloading anything or
+// touching the working copy. Missing and corrupt objects are reported
+// separately, and it returns whether there were none.
+func verifyRef(ref *git.Ref, include, exclude []string) bool {
+	pointers, err := pointersToFetchForRef(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	Print("Verifying %v", ref.Name)
+
+	var objects []*fsckObject
+	var totalBytes int64
+	seen := make(map[string]bool, len(pointers))
+	missing := 0
+	for _, p := range pointers {
+		if seen[p.Oid] || !lfs.FilenamePassesIncludeExcludeFilter(p.Name, include, exclude) {
+			continue
+		}
+		seen[p.Oid] = true
+
+		path := lfs.LocalMediaPathReadOnly(p.Oid)
+		if _, err := os.Stat(path); err != nil {
+			Print("Object %s (%s) is missing", p.Name, p.Oid)
+			missing++
+			continue
+		}
+
+		objects = append(objects, &fsckObject{Oid: p.Oid, Name: p.Name, Path: path, Size: p.Size})
+		totalBytes += p.Size
+	}
+	sort.Sort(fsckObjectsByName(objects))
+
+	meter := newFsckMeter(len(objects), totalBytes)
+	fsckVerifyObjects(objects, cfg.FsckConcurrency(), meter)
+	meter.Finish()
+
+	corrupt := 0
+	for _, obj := range objects {
+		if obj.Err != nil {
+			Print("Object %s (%s) could not be checked: %s", obj.Name, obj.Oid, obj.Err)
+			corrupt++
+		} else if obj.Recalculated != obj.Oid {
+			Print("Object %s (%s) is corrupt", obj.Name, obj.Oid)
+			corrupt++
+		}
+	}
+
+	Print("%d object(s) checked for %v: %d missing, %d corrupt", len(seen), ref.Name, missing, corrupt)
+	return missing == 0 && corrupt == 0
+}
+
 // Fetch all previous versions of objects from since to ref (not including final state at ref)
 // So this will fetch all the '-' sides of the diff from since to ref
 func fetchPreviousVersions(ref string, since time.Time, include, exclude []string) bool {
@@ -145,6 +533,26 @@ func fetchPreviousVersions(ref string, since time.Time, include, exclude []strin
 	return fetchPointers(pointers, include, exclude)
 }
 
+// Fetch the objects needed to check out any commit reachable from ref whose
+// committer date is at or after since; that is, the objects at ref itself,
+// plus every previous version replaced by a commit since then. Nothing is
+// fetched if the commit at ref is older than since.
+func fetchRefSince(ref *git.Ref, since time.Time, include, exclude []string) bool {
+	summ, err := git.GetCommitSummary(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not scan commits at %v", ref.Name)
+	}
+
+	if summ.CommitDate.Before(since) {
+		Print("Skipping %v, no commits since %v", ref.Name, since.Format("2006-01-02 15:04:05 -0700"))
+		return true
+	}
+
+	Print("Fetching %v for commits since %v", ref.Name, since.Format("2006-01-02 15:04:05 -0700"))
+	ok := fetchRef(ref.Sha, include, exclude)
+	return fetchPreviousVersions(ref.Sha, since, include, exclude) && ok
+}
+
 // Fetch recent objects based on config
 func fetchRecent(alreadyFetchedRefs []*git.Ref, include, exclude []string) bool {
 	fetchconf := cfg.FetchPruneConfig()
@@ -206,6 +614,55 @@ func fetchAll() bool {
 	return fetchPointers(pointers, nil, nil)
 }
 
+// fetchAllTags fetches the objects needed to check out any local tag, each
+// object once however many tags refer to it.
+func fetchAllTags(include, exclude []string) bool {
+	refs, err := git.LocalRefs()
+	if err != nil {
+		Panic(err, "Could not list tags")
+	}
+
+	var tags int
+	var pointers []*lfs.WrappedPointer
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Type != git.RefTypeLocalTag {
+			continue
+		}
+		tags++
+
+		tagPointers, err := pointersToFetchForRef(ref.Sha)
+		if err != nil {
+			Panic(err, "Could not scan for Git LFS files at %v", ref.Name)
+		}
+		for _, p := range tagPointers {
+			if !seen[p.Oid] && lfs.FilenamePassesIncludeExcludeFilter(p.Name, include, exclude) {
+				seen[p.Oid] = true
+				pointers = append(pointers, p)
+			}
+		}
+	}
+
+	var needed []*lfs.WrappedPointer
+	for _, p := range pointers {
+		if !lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			needed = append(needed, p)
+		}
+	}
+
+	Print("Fetching %d objects for %d tags", len(pointers), tags)
+	ok := fetchPointers(pointers, include, exclude)
+
+	fetched := 0
+	for _, p := range needed {
+		if lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			fetched++
+		}
+	}
+	Print("%d tags scanned, %d objects fetched, %d already present", tags, fetched, len(pointers)-len(needed))
+	return ok
+}
+
 func scanAll() []*lfs.WrappedPointer {
 	// converts to `git rev-list --all`
 	// We only pick up objects in real commits and not the reflog
@@ -242,14 +699,71 @@ func fetchPointers(pointers []*lfs.WrappedPointer, include, exclude []string) bo
 	return fetchAndReportToChan(pointers, include, exclude, nil)
 }
 
+// useReferenceRepo makes objects be linked or copied from the repository given
+// by --reference, or else lfs.referencerepo, in place of the one found through
+// the Git alternates of a `git clone --reference`.
+func useReferenceRepo() {
+	repo := fetchReferenceArg
+	if len(repo) == 0 {
+		repo = cfg.ReferenceRepo()
+	}
+	if len(repo) == 0 {
+		return
+	}
+
+	dir, err := config.ReferenceRepoObjectsDir(repo)
+	if err != nil {
+		Exit("%s", err)
+	}
+	config.LocalReferenceDir = dir
+}
+
+// openGlobalIndex returns the global object index set by lfs.globalindex, or
+// nil if there isn't one, or it can't be read.
+func openGlobalIndex() *lfs.GlobalIndex {
+	path := cfg.GlobalIndexPath()
+	if len(path) == 0 {
+		return nil
+	}
+
+	index, err := lfs.OpenGlobalIndex(path)
+	if err != nil {
+		Error("Could not read the global object index, not using it: %s", err)
+		return nil
+	}
+	return index
+}
+
 // Fetch and report completion of each OID to a channel (optional, pass nil to skip)
 // Returns true if all completed with no errors, false if errors were written to stderr/log
 func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []string, out chan<- *lfs.WrappedPointer) bool {
+	useReferenceRepo()
+	index := openGlobalIndex()
+
 	totalSize := int64(0)
 	for _, p := range pointers {
 		totalSize += p.Size
 	}
 	q := lfs.NewDownloadQueue(len(pointers), totalSize, false)
+	q.SetFailFast(transferFailFast())
+	if !fetchChangedSince.IsZero() {
+		q.SetModifiedSince(fetchChangedSince)
+	}
+	offline := cfg.Offline()
+	missing := 0
+
+	var journalwait sync.WaitGroup
+	if pullJournal != nil {
+		pullJournal.Begin(pointers, include, exclude)
+		journalc := q.Watch()
+		journalwait.Add(1)
+		go func() {
+			defer journalwait.Done()
+			for oid := range journalc {
+				pullJournal.Set(oid, journalDone)
+			}
+		}()
+	}
 
 	if out != nil {
 		dlwatch := q.Watch()
@@ -283,8 +797,18 @@ func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []str
 		passFilter := lfs.FilenamePassesIncludeExcludeFilter(p.Name, include, exclude)
 
 		lfs.LinkOrCopyFromReference(p.Oid, p.Size)
+		if passFilter && !lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			index.LinkOrCopy(p.Oid, p.Size)
+		}
 
 		if !lfs.ObjectExistsOfSize(p.Oid, p.Size) && passFilter {
+			if offline {
+				// Nothing may be downloaded, so the local store is all we have
+				Error("Object %s (%s) is not in the local store and lfs.offline is set", p.Oid, p.Name)
+				missing++
+				q.Skip(p.Size)
+				continue
+			}
 			tracerx.Printf("fetch %v [%v]", p.Name, p.Oid)
 			q.Add(lfs.NewDownloadable(p))
 		} else {
@@ -308,11 +832,39 @@ func fetchAndReportToChan(pointers []*lfs.WrappedPointer, include, exclude []str
 	processQueue := time.Now()
 	q.Wait()
 	tracerx.PerformanceSince("process queue", processQueue)
+	journalwait.Wait()
 
-	ok := true
-	for _, err := range q.Errors() {
-		ok = false
-		ExitWithError(err)
+	if index != nil {
+		// Record the objects now in this repository's own store, for
+		// fetches in other repositories to link
+		for _, p := range pointers {
+			index.Add(p.Oid, p.Size)
+		}
+		if err := index.Save(); err != nil {
+			Error("Could not update the global object index: %s", err)
+		}
 	}
-	return ok
+
+	declinedOids := make(map[string]bool)
+	if declined := q.Declined(); len(declined) > 0 {
+		Error("Skipped %d objects declined by lfs.download.filter:", len(declined))
+		for _, t := range declined {
+			Error("  %s (%s)", t.Name(), t.Oid())
+			declinedOids[t.Oid()] = true
+		}
+	}
+
+	if pullJournal != nil {
+		pullJournal.Finish(declinedOids)
+	}
+
+	if unchanged := q.Unchanged(); len(unchanged) > 0 {
+		Print("Skipped %d object(s) not modified on the server since %s", len(unchanged), git.FormatGitDate(fetchChangedSince))
+	}
+	if n := q.UnknownModified(); n > 0 {
+		Print("The server did not say when %d object(s) were modified, so they were fetched regardless of --changed-since", n)
+	}
+
+	errs := reportTransferErrors(q)
+	return missing == 0 && len(errs) == 0
 }
@@ -1,20 +1,29 @@
 package commands
 
 import (
-	"crypto/sha256"
+	"crypto/sha1"
 	"encoding/hex"
-	"io"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fsckDryRun bool
+	fsckDryRun       bool
+	fsckIncludeArg   string
+	fsckExcludeArg   string
+	fsckPointers     bool
+	fsckPointersOnly bool
+	fsckObjectsOnly  bool
+	fsckVerifyRemote bool
+	fsckDeep         bool
 
 	fsckCmd = &cobra.Command{
 		Use: "fsck",
@@ -22,7 +31,7 @@ var (
 	}
 )
 
-func doFsck() (bool, error) {
+func doFsck(include, exclude []string) (bool, error) {
 	requireInRepo()
 
 	ref, err := git.CurrentRef()
@@ -30,68 +39,99 @@ func doFsck() (bool, error) {
 		return false, err
 	}
 
-	// The LFS scanner methods return unexported *lfs.wrappedPointer objects.
-	// All we care about is the pointer OID and file name
-	pointerIndex := make(map[string]string)
-
 	pointers, err := lfs.ScanRefs(ref.Sha, "", nil)
 	if err != nil {
 		return false, err
 	}
 
-	for _, p := range pointers {
-		pointerIndex[p.Oid] = p.Name
-	}
-
 	// TODO(zeroshirts): do we want to look for LFS stuff in past commits?
 	p2, err := lfs.ScanIndex()
 	if err != nil {
 		return false, err
 	}
+	pointers = append(pointers, p2...)
 
-	for _, p := range p2 {
+	ok := true
+
+	// Several paths can share a pointer blob or object, so each is checked
+	// once, under the last matching path that refers to it.
+	pointerIndex := make(map[string]string)
+	sizeIndex := make(map[string]int64)
+	blobIndex := make(map[string]*lfs.WrappedPointer)
+	for _, p := range pointers {
+		if !lfs.FilenamePassesIncludeExcludeFilter(p.Name, include, exclude) {
+			continue
+		}
 		pointerIndex[p.Oid] = p.Name
+		sizeIndex[p.Oid] = p.Size
+		blobIndex[p.Sha1] = p
 	}
 
-	ok := true
+	if fsckVerifyRemote {
+		return fsckRemote(pointerIndex, sizeIndex)
+	}
 
+	if fsckPointers || fsckPointersOnly {
+		for _, p := range blobIndex {
+			if !fsckPointer(p) {
+				ok = false
+			}
+		}
+	}
+
+	if fsckPointersOnly {
+		return ok, nil
+	}
+
+	var objects []*fsckObject
+	var totalBytes int64
 	for oid, name := range pointerIndex {
 		path := lfs.LocalMediaPathReadOnly(oid)
 
 		Debug("Examining %v (%v)", name, path)
 
-		f, err := os.Open(path)
-		if pErr, pOk := err.(*os.PathError); pOk {
-			Print("Object %s (%s) could not be checked: %s", name, oid, pErr.Err)
+		objects = append(objects, &fsckObject{
+			Oid:  oid,
+			Name: name,
+			Path: path,
+			Size: sizeIndex[oid],
+		})
+		totalBytes += sizeIndex[oid]
+	}
+	sort.Sort(fsckObjectsByName(objects))
+
+	meter := newFsckMeter(len(objects), totalBytes)
+	fsckVerifyObjects(objects, cfg.FsckConcurrency(), meter)
+	meter.Finish()
+
+	for _, obj := range objects {
+		if pErr, pOk := obj.Err.(*os.PathError); pOk {
+			Print("Object %s (%s) could not be checked: %s", obj.Name, obj.Oid, pErr.Err)
 			ok = false
 			continue
 		}
-		if err != nil {
-			return false, err
-		}
-
-		oidHash := sha256.New()
-		_, err = io.Copy(oidHash, f)
-		f.Close()
-		if err != nil {
-			return false, err
+		if obj.Err != nil {
+			return false, obj.Err
 		}
 
-		recalculatedOid := hex.EncodeToString(oidHash.Sum(nil))
-		if recalculatedOid != oid {
+		if obj.Recalculated != obj.Oid {
 			ok = false
-			Print("Object %s (%s) is corrupt", name, oid)
+			Print("Object %s (%s) is corrupt", obj.Name, obj.Oid)
 			if fsckDryRun {
 				continue
 			}
+			if obj.Path == lfs.LocalBaseMediaPath(obj.Oid) {
+				Print("  not moved, it is in the read-only lfs.storage.base store")
+				continue
+			}
 
 			badDir := filepath.Join(config.LocalGitStorageDir, "lfs", "bad")
 			if err := os.MkdirAll(badDir, 0755); err != nil {
 				return false, err
 			}
 
-			badFile := filepath.Join(badDir, oid)
-			if err := os.Rename(path, badFile); err != nil {
+			badFile := filepath.Join(badDir, obj.Oid)
+			if err := os.Rename(obj.Path, badFile); err != nil {
 				return false, err
 			}
 			Print("  moved to %s", badFile)
@@ -100,16 +140,42 @@ func doFsck() (bool, error) {
 	return ok, nil
 }
 
-// TODO(zeroshirts): 'git fsck' reports status (percentage, current#/total) as
-// it checks... we should do the same, as we are rehashing potentially gigs and
-// gigs of content.
-//
+// fsckPointer reports whether the blob for p holds the canonical encoding of
+// its pointer, comparing the blob's SHA-1 with that of the re-encoded pointer.
+func fsckPointer(p *lfs.WrappedPointer) bool {
+	encoded := p.Encoded()
+	blobHash := sha1.New()
+	fmt.Fprintf(blobHash, "blob %d\x00%s", len(encoded), encoded)
+
+	if hex.EncodeToString(blobHash.Sum(nil)) != p.Sha1 {
+		Print("Pointer %s (%s) is not canonical", p.Name, p.Sha1)
+		return false
+	}
+	return true
+}
+
 // NOTE(zeroshirts): Ideally git would have hooks for fsck such that we could
 // chain a lfs-fsck, but I don't think it does.
 func fsckCommand(cmd *cobra.Command, args []string) {
 	lfs.InstallHooks(false)
 
-	ok, err := doFsck()
+	if (fsckPointers || fsckPointersOnly) && fsckObjectsOnly {
+		Exit("Cannot combine --pointers or --pointers-only with --objects-only")
+	}
+
+	if fsckVerifyRemote {
+		if fsckPointers || fsckPointersOnly || fsckObjectsOnly {
+			Exit("Cannot combine --verify-remote with --pointers, --pointers-only or --objects-only")
+		}
+		fsckSetRemote(args)
+	} else if fsckDeep {
+		Exit("--deep requires --verify-remote")
+	}
+
+	include := tools.CleanPaths(fsckIncludeArg, ",")
+	exclude := tools.CleanPaths(fsckExcludeArg, ",")
+
+	ok, err := doFsck(include, exclude)
 	if err != nil {
 		Panic(err, "Error checking Git LFS files")
 	}
@@ -119,7 +185,29 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// fsckSetRemote sets the remote --verify-remote checks, from the arguments or
+// the default remote.
+func fsckSetRemote(args []string) {
+	requireInRepo()
+
+	if len(args) > 0 {
+		if err := git.ValidateRemote(args[0]); err != nil {
+			Exit("Invalid remote name %q", args[0])
+		}
+		cfg.CurrentRemote = args[0]
+	} else if remote, err := git.DefaultRemote(); err == nil {
+		cfg.CurrentRemote = remote
+	}
+}
+
 func init() {
 	fsckCmd.Flags().BoolVarP(&fsckDryRun, "dry-run", "d", false, "List corrupt objects without deleting them.")
+	fsckCmd.Flags().StringVarP(&fsckIncludeArg, "include", "I", "", "Include a list of paths")
+	fsckCmd.Flags().StringVarP(&fsckExcludeArg, "exclude", "X", "", "Exclude a list of paths")
+	fsckCmd.Flags().BoolVarP(&fsckPointers, "pointers", "", false, "Also check that pointers are canonical.")
+	fsckCmd.Flags().BoolVarP(&fsckPointersOnly, "pointers-only", "", false, "Only check that pointers are canonical.")
+	fsckCmd.Flags().BoolVarP(&fsckObjectsOnly, "objects-only", "", false, "Only check the content of objects.")
+	fsckCmd.Flags().BoolVarP(&fsckVerifyRemote, "verify-remote", "", false, "Check that the remote has each object, instead of the local store.")
+	fsckCmd.Flags().BoolVarP(&fsckDeep, "deep", "", false, "With --verify-remote, download and hash each object.")
 	RootCmd.AddCommand(fsckCmd)
 }
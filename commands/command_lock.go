@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/github/git-lfs/api"
 	"github.com/github/git-lfs/config"
@@ -23,6 +24,9 @@ var (
 		c.CurrentRemote = lockRemote
 	}
 
+	lockLeaseArg string
+	lockRenewArg string
+
 	lockCmd = &cobra.Command{
 		Use: "lock",
 		Run: lockCommand,
@@ -32,8 +36,19 @@ var (
 func lockCommand(cmd *cobra.Command, args []string) {
 	setLockRemoteFor(cfg)
 
+	lease := lockLease()
+
+	if len(lockRenewArg) > 0 {
+		if len(args) > 0 {
+			Exit("Cannot combine --renew with a path.")
+		}
+		renewLock(lockRenewArg, lease)
+		return
+	}
+
 	if len(args) == 0 {
-		Print("Usage: git lfs lock <path>")
+		Print("Usage: git lfs lock [--lease=<duration>] <path>")
+		Print("       git lfs lock --renew <id> [--lease=<duration>]")
 		return
 	}
 
@@ -52,6 +67,7 @@ func lockCommand(cmd *cobra.Command, args []string) {
 		Path:               path,
 		Committer:          api.CurrentCommitter(),
 		LatestRemoteCommit: latest.Sha,
+		LeaseSeconds:       int64(lease / time.Second),
 	})
 
 	if _, err := API.Do(s); err != nil {
@@ -64,7 +80,63 @@ func lockCommand(cmd *cobra.Command, args []string) {
 		Exit("Server unable to create lock.")
 	}
 
+	if lease > 0 && !resp.Lock.Leased() {
+		// The server ignored the lease, so release the permanent lock it
+		// created rather than leave it behind.
+		s, unlockResp := API.Locks.Unlock(resp.Lock.Id, false)
+		if _, err := API.Do(s); err != nil || len(unlockResp.Err) > 0 {
+			Exit("Server does not support lock leases, and the permanent lock it created on '%s' (%s) could not be released.", args[0], resp.Lock.Id)
+		}
+		Exit("Server does not support lock leases, so '%s' was not locked.", args[0])
+	}
+
 	Print("\n'%s' was locked (%s)", args[0], resp.Lock.Id)
+	if resp.Lock.Leased() {
+		Print("Lease expires in %s", leaseRemaining(resp.Lock))
+	}
+}
+
+// lockLease parses the --lease flag, returning 0 if no lease was given.
+func lockLease() time.Duration {
+	if len(lockLeaseArg) == 0 {
+		return 0
+	}
+
+	lease, err := time.ParseDuration(lockLeaseArg)
+	if err != nil || lease < time.Second {
+		Exit("Invalid lease %q: expected a duration of at least 1s, such as 30m or 2h.", lockLeaseArg)
+	}
+	return lease
+}
+
+// renewLock extends the lease on the lock with the given id by lease, or by
+// the lock's original lease if lease is 0.
+func renewLock(id string, lease time.Duration) {
+	s, resp := API.Locks.Renew(id, int64(lease/time.Second))
+	if _, err := API.Do(s); err != nil {
+		Error("%s", err)
+		Exit("Error communicating with LFS API.")
+	}
+
+	if len(resp.Err) > 0 {
+		Error("%s", resp.Err)
+		Exit("Server unable to renew lock.")
+	}
+
+	if resp.Lock == nil || !resp.Lock.Leased() {
+		Exit("Server does not support lock leases.")
+	}
+
+	Print("Lock %s was renewed, lease expires in %s", id, leaseRemaining(resp.Lock))
+}
+
+// leaseRemaining returns how long is left on the lease of lock, to the second.
+func leaseRemaining(lock *api.Lock) string {
+	remaining := lock.LeaseExpiresAt.Sub(time.Now())
+	if remaining <= 0 {
+		return "0s"
+	}
+	return (remaining - remaining%time.Second).String()
 }
 
 // lockPaths relativizes the given filepath such that it is relative to the root
@@ -108,6 +180,8 @@ func lockPath(file string) (string, error) {
 
 func init() {
 	lockCmd.Flags().StringVarP(&lockRemote, "remote", "r", cfg.CurrentRemote, lockRemoteHelp)
+	lockCmd.Flags().StringVarP(&lockLeaseArg, "lease", "", "", "release the lock automatically after this long, unless renewed")
+	lockCmd.Flags().StringVarP(&lockRenewArg, "renew", "", "", "renew the lease on the lock with this ID")
 
 	if isCommandEnabled(cfg, "locks") {
 		RootCmd.AddCommand(lockCmd)
@@ -1,7 +1,15 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
 	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/git"
 	"github.com/spf13/cobra"
 )
 
@@ -16,29 +24,115 @@ var (
 func locksCommand(cmd *cobra.Command, args []string) {
 	setLockRemoteFor(cfg)
 
+	if locksCmdFlags.Verify {
+		if locksCmdFlags.Porcelain || locksCmdFlags.Absolute {
+			Exit("Cannot combine --verify with --porcelain or --absolute")
+		}
+		verifyLocks()
+		return
+	}
+	if locksCmdFlags.Nul && !locksCmdFlags.Porcelain {
+		Exit("-z can only be used with --porcelain")
+	}
+	if locksCmdFlags.Absolute {
+		requireInRepo()
+	}
+
 	filters, err := locksCmdFlags.Filters()
 	if err != nil {
 		Error(err.Error())
 	}
 
+	locks := searchLocks(filters, locksCmdFlags.Limit)
+
+	if locksCmdFlags.Porcelain {
+		for _, lock := range locks {
+			locksPrintPorcelain(lock)
+		}
+		return
+	}
+
+	Print("\n%d lock(s) matched query:", len(locks))
+	for _, lock := range locks {
+		path := locksPath(lock)
+		if lock.Leased() {
+			Print("%s\t%s <%s>\t(lease expires in %s)", path, lock.Committer.Name, lock.Committer.Email, leaseRemaining(&lock))
+		} else {
+			Print("%s\t%s <%s>", path, lock.Committer.Name, lock.Committer.Email)
+		}
+	}
+}
+
+// locksPrintPorcelain prints a lock as "<id> TAB <path> TAB <owner>", where
+// the owner is "Name <email>". Each line ends with a newline, and the path is
+// quoted if it contains spaces or special characters. With -z, each line ends
+// with a NUL character instead and the path is never quoted.
+func locksPrintPorcelain(lock api.Lock) {
+	path := locksPath(lock)
+	owner := fmt.Sprintf("%s <%s>", lock.Committer.Name, lock.Committer.Email)
+
+	if locksCmdFlags.Nul {
+		fmt.Fprintf(OutputWriter, "%s\t%s\t%s\x00", lock.Id, path, owner)
+		return
+	}
+	Print("%s\t%s\t%s", lock.Id, quotePorcelainPath(path), owner)
+}
+
+// locksPath returns the path of the locked file as given by the server,
+// relative to the root of the repository, or its absolute path in the working
+// tree with --absolute.
+func locksPath(lock api.Lock) string {
+	if locksCmdFlags.Absolute {
+		return filepath.Join(config.LocalWorkingDir, filepath.FromSlash(lock.Path))
+	}
+	return lock.Path
+}
+
+// quotePorcelainPath double quotes path, with backslash escapes, if it
+// contains a space, a quote or backslash, or a character which isn't
+// printable, so that it is a single field. Other paths are returned as they
+// are.
+func quotePorcelainPath(path string) string {
+	for _, r := range path {
+		if r == ' ' || r == '"' || r == '\\' || !strconv.IsPrint(r) {
+			return strconv.Quote(path)
+		}
+	}
+	return path
+}
+
+// searchLocks returns the locks on the server matching filters, following the
+// server's cursor across pages. limit caps the number of locks returned, if
+// positive. It exits if the server can't be reached.
+func searchLocks(filters []api.Filter, limit int) []api.Lock {
+	locks, err := findLocks(filters, limit)
+	if err != nil {
+		Error("%s", err)
+		Exit("Error communicating with LFS API.")
+	}
+	return locks
+}
+
+// findLocks is like searchLocks, but returns an error if the server can't be
+// reached, or responds with one, instead of exiting.
+func findLocks(filters []api.Filter, limit int) ([]api.Lock, error) {
 	var locks []api.Lock
 
 	query := &api.LockSearchRequest{Filters: filters}
 	for {
 		s, resp := API.Locks.Search(query)
 		if _, err := API.Do(s); err != nil {
-			Error(err.Error())
-			Exit("Error communicating with LFS API.")
+			return nil, err
 		}
 
 		if resp.Err != "" {
-			Error(resp.Err)
+			return nil, errors.New(resp.Err)
 		}
 
 		locks = append(locks, resp.Locks...)
 
-		if locksCmdFlags.Limit > 0 && len(locks) > locksCmdFlags.Limit {
-			locks = locks[:locksCmdFlags.Limit]
+		if limit > 0 && len(locks) > limit {
+			locks = locks[:limit]
 			break
 		}
 
@@ -49,10 +143,66 @@ func locksCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	Print("\n%d lock(s) matched query:", len(locks))
-	for _, lock := range locks {
-		Print("%s\t%s <%s>", lock.Path, lock.Committer.Name, lock.Committer.Email)
+	return locks, nil
+}
+
+// verifyLocks reports each file modified locally but not yet pushed which is
+// locked on the server, separating locks held by the current committer from
+// those held by others. It exits non-zero if any modified file is locked by
+// someone else, so it can be used to gate a push.
+func verifyLocks() {
+	requireInRepo()
+
+	modified, err := git.ModifiedPaths(cfg.CurrentRemote)
+	if err != nil {
+		Exit("Unable to determine modified files: %s", err)
+	}
+
+	locked := make(map[string]api.Lock)
+	for _, lock := range searchLocks(nil, 0) {
+		locked[filepath.ToSlash(lock.Path)] = lock
+	}
+
+	me := api.CurrentCommitter()
+	var ours, theirs []api.Lock
+	for _, path := range modified {
+		lock, ok := locked[path]
+		if !ok {
+			continue
+		}
+
+		if isCommitterLock(lock, me) {
+			ours = append(ours, lock)
+		} else {
+			theirs = append(theirs, lock)
+		}
+	}
+
+	if len(ours) > 0 {
+		Print("Modified files locked by you:")
+		for _, lock := range ours {
+			Print("    %s (%s)", lock.Path, lock.Id)
+		}
+	}
+
+	if len(theirs) > 0 {
+		Print("Modified files locked by others:")
+		for _, lock := range theirs {
+			Print("    %s\t%s <%s> (%s)", lock.Path, lock.Committer.Name, lock.Committer.Email, lock.Id)
+		}
+		Exit("%d modified file(s) locked by others", len(theirs))
+	}
+
+	Print("No modified files are locked by others")
+}
+
+// isCommitterLock returns whether lock is held by committer, comparing email
+// addresses where both are known, and names otherwise.
+func isCommitterLock(lock api.Lock, committer api.Committer) bool {
+	if len(lock.Committer.Email) > 0 && len(committer.Email) > 0 {
+		return strings.EqualFold(lock.Committer.Email, committer.Email)
 	}
+	return lock.Committer.Name == committer.Name
 }
 
 func init() {
@@ -61,6 +211,10 @@ func init() {
 	locksCmd.Flags().StringVarP(&locksCmdFlags.Path, "path", "p", "", "filter locks results matching a particular path")
 	locksCmd.Flags().StringVarP(&locksCmdFlags.Id, "id", "i", "", "filter locks results matching a particular ID")
 	locksCmd.Flags().IntVarP(&locksCmdFlags.Limit, "limit", "l", 0, "optional limit for number of results to return")
+	locksCmd.Flags().BoolVarP(&locksCmdFlags.Verify, "verify", "", false, "report modified files locked on the server, failing if any are locked by others")
+	locksCmd.Flags().BoolVarP(&locksCmdFlags.Porcelain, "porcelain", "", false, "list each lock as a tab separated id, path and owner, for scripts")
+	locksCmd.Flags().BoolVarP(&locksCmdFlags.Absolute, "absolute", "", false, "list the absolute path of each locked file in the working tree")
+	locksCmd.Flags().BoolVarP(&locksCmdFlags.Nul, "null", "z", false, "terminate each --porcelain line with a NUL character, without quoting paths")
 
 	if isCommandEnabled(cfg, "locks") {
 		RootCmd.AddCommand(locksCmd)
@@ -79,6 +233,16 @@ type locksFlags struct {
 	// limit is an optional request parameter sent to the server used to
 	// limit the
 	Limit int
+	// Verify reports modified files which are locked, instead of listing
+	// locks.
+	Verify bool
+	// Porcelain lists locks in a stable format for scripts.
+	Porcelain bool
+	// Absolute lists absolute paths in the working tree instead of paths
+	// relative to the repository root.
+	Absolute bool
+	// Nul terminates each porcelain line with a NUL character.
+	Nul bool
 }
 
 // Filters produces a slice of api.Filter instances based on the internal state
@@ -5,6 +5,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
@@ -36,8 +39,14 @@ var (
 		Use: "boomtown",
 		Run: logsBoomtownCommand,
 	}
+
+	logsClearOlderThanArg string
+	logsClearKeepArg      int
 )
 
+// logTimeFormat is the timestamp logPanic names each log with.
+const logTimeFormat = "20060102T150405.999999999"
+
 func logsCommand(cmd *cobra.Command, args []string) {
 	for _, path := range sortedLogs() {
 		Print(path)
@@ -71,6 +80,11 @@ func logsShowCommand(cmd *cobra.Command, args []string) {
 }
 
 func logsClearCommand(cmd *cobra.Command, args []string) {
+	if len(logsClearOlderThanArg) > 0 || logsClearKeepArg >= 0 {
+		logsClearSome()
+		return
+	}
+
 	err := os.RemoveAll(config.LocalLogDir)
 	if err != nil {
 		Panic(err, "Error clearing %s", config.LocalLogDir)
@@ -79,6 +93,70 @@ func logsClearCommand(cmd *cobra.Command, args []string) {
 	Print("Cleared %s", config.LocalLogDir)
 }
 
+// logsClearSome removes the logs selected by --older-than and --keep. A log
+// is removed if it is older than --older-than, or if --keep more recent logs
+// are kept; with both, it must be both.
+func logsClearSome() {
+	var cutoff time.Time
+	if len(logsClearOlderThanArg) > 0 {
+		age, err := parseLogsAge(logsClearOlderThanArg)
+		if err != nil {
+			Exit("Invalid --older-than %q: expected a duration such as 36h or 30d.", logsClearOlderThanArg)
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	logs := sortedLogs()
+	var removed int
+	var freed int64
+	for i, name := range logs {
+		if logsClearKeepArg >= 0 && i >= len(logs)-logsClearKeepArg {
+			break
+		}
+
+		path := filepath.Join(config.LocalLogDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !cutoff.IsZero() && !logTime(name, info).Before(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			LoggedError(err, "Error removing log %s", name)
+			continue
+		}
+		removed++
+		freed += info.Size()
+	}
+
+	Print("Removed %d of %d logs from %s (%s)", removed, len(logs), config.LocalLogDir, humanizeBytes(freed))
+}
+
+// parseLogsAge parses a --older-than duration, which may also be a number of
+// days such as "30d".
+func parseLogsAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, errors.New("invalid number of days")
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// logTime returns when the log was written, according to its name, or its
+// modification time if its name isn't a timestamp.
+func logTime(name string, info os.FileInfo) time.Time {
+	if t, err := time.ParseInLocation(logTimeFormat, strings.TrimSuffix(name, ".log"), time.Local); err == nil {
+		return t
+	}
+	return info.ModTime()
+}
+
 func logsBoomtownCommand(cmd *cobra.Command, args []string) {
 	Debug("Debug message")
 	err := errutil.Errorf(errors.New("Inner error message!"), "Error!")
@@ -104,6 +182,8 @@ func sortedLogs() []string {
 }
 
 func init() {
+	logsClearCmd.Flags().StringVarP(&logsClearOlderThanArg, "older-than", "", "", "Only clear logs older than this duration, such as 30d")
+	logsClearCmd.Flags().IntVarP(&logsClearKeepArg, "keep", "", -1, "Keep this many of the most recent logs")
 	logsCmd.AddCommand(logsLastCmd, logsShowCmd, logsClearCmd, logsBoomtownCmd)
 	RootCmd.AddCommand(logsCmd)
 }
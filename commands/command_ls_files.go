@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/github/git-lfs/git"
@@ -9,8 +11,14 @@ import (
 )
 
 var (
-	longOIDs   = false
-	lsFilesCmd = &cobra.Command{
+	longOIDs      = false
+	lsFilesAll    = false
+	lsFilesSize   = false
+	lsFilesName   = false
+	lsFilesNul    = false
+	lsFilesJson   = false
+	lsFilesVerify = false
+	lsFilesCmd    = &cobra.Command{
 		Use: "ls-files",
 		Run: lsFilesCommand,
 	}
@@ -19,6 +27,31 @@ var (
 func lsFilesCommand(cmd *cobra.Command, args []string) {
 	requireInRepo()
 
+	showOidLen := 10
+	if longOIDs {
+		showOidLen = 64
+	}
+
+	if lsFilesSize && (lsFilesName || lsFilesNul) {
+		Exit("Cannot combine --size with --name-only or -z")
+	}
+
+	if lsFilesJson && (lsFilesName || lsFilesNul) {
+		Exit("Cannot combine --json with --name-only or -z")
+	}
+
+	if lsFilesVerify && !lsFilesJson {
+		Exit("--verify requires --json")
+	}
+
+	if lsFilesAll {
+		if len(args) > 0 {
+			Exit("Cannot use --all with a ref")
+		}
+		lsAllFiles(showOidLen)
+		return
+	}
+
 	var ref string
 	var err error
 
@@ -32,19 +65,163 @@ func lsFilesCommand(cmd *cobra.Command, args []string) {
 		ref = fullref.Sha
 	}
 
-	showOidLen := 10
-	if longOIDs {
-		showOidLen = 64
-	}
-
 	files, err := lfs.ScanTree(ref)
 	if err != nil {
 		Panic(err, "Could not scan for Git LFS tree: %s", err)
 	}
 
+	seen := make(map[string]bool, len(files))
+	var totalSize int64
+	var entries []*lsFilesEntry
 	for _, p := range files {
-		Print("%s %s %s", p.Oid[0:showOidLen], lsFilesMarker(p), p.Name)
+		if lsFilesJson {
+			entry := newLsFilesEntry(p)
+			checkedOut := lsFilesMarker(p) == "*"
+			entry.CheckedOut = &checkedOut
+			entries = append(entries, entry)
+		} else if lsFilesName {
+			lsFilesPrint(p.Name)
+		} else {
+			lsFilesPrint(fmt.Sprintf("%s %s %s", p.Oid[0:showOidLen], lsFilesMarker(p), p.Name))
+		}
+		if !seen[p.Oid] {
+			seen[p.Oid] = true
+			totalSize += p.Size
+		}
+	}
+
+	if lsFilesJson {
+		lsFilesPrintJson(entries)
+	}
+	lsFilesPrintSize(len(seen), totalSize)
+}
+
+// lsAllFiles lists every unique Git LFS object referenced anywhere in the
+// history of all refs, with the most recent path it was seen at.
+func lsAllFiles(showOidLen int) {
+	opts := lfs.NewScanRefsOptions()
+	opts.ScanMode = lfs.ScanAllMode
+	opts.SkipDeletedBlobs = false
+
+	pointerchan, err := lfs.ScanRefsToChan("", "", opts)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	// rev-list walks history newest first, so the first path reported for
+	// an object is the last one it was seen at
+	seen := make(map[string]bool)
+	var totalSize int64
+	var entries []*lsFilesEntry
+	for p := range pointerchan.Results {
+		if seen[p.Oid] {
+			continue
+		}
+		seen[p.Oid] = true
+		totalSize += p.Size
+
+		if lsFilesJson {
+			entries = append(entries, newLsFilesEntry(p))
+		} else if lsFilesName {
+			lsFilesPrint(p.Name)
+		} else {
+			lsFilesPrint(fmt.Sprintf("%s %s (%s)", p.Oid[0:showOidLen], p.Name, humanizeBytes(p.Size)))
+		}
+	}
+
+	if err := pointerchan.Wait(); err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	if lsFilesJson {
+		lsFilesPrintJson(entries)
+	}
+	lsFilesPrintSize(len(seen), totalSize)
+}
+
+// lsFilesEntry is an entry of the output of `git lfs ls-files --json`.
+// CheckedOut is only given for the files in a tree, not with --all, and
+// Verified only with --verify.
+type lsFilesEntry struct {
+	Name       string `json:"name"`
+	Oid        string `json:"oid"`
+	Size       int64  `json:"size"`
+	Downloaded bool   `json:"downloaded"`
+	CheckedOut *bool  `json:"checked_out,omitempty"`
+	Verified   *bool  `json:"verified,omitempty"`
+}
+
+func newLsFilesEntry(p *lfs.WrappedPointer) *lsFilesEntry {
+	return &lsFilesEntry{
+		Name:       p.Name,
+		Oid:        p.Oid,
+		Size:       p.Size,
+		Downloaded: lfs.ObjectExistsOfSize(p.Oid, p.Size),
+	}
+}
+
+// lsFilesPrintJson prints the entries as a JSON array. With --verify, the
+// content of each downloaded object is hashed first, as fsck does, to check
+// that it matches its OID.
+func lsFilesPrintJson(entries []*lsFilesEntry) {
+	if entries == nil {
+		entries = make([]*lsFilesEntry, 0)
+	}
+
+	if lsFilesVerify {
+		objects := make(map[string]*fsckObject)
+		var toVerify []*fsckObject
+		for _, e := range entries {
+			if !e.Downloaded || objects[e.Oid] != nil {
+				continue
+			}
+			obj := &fsckObject{
+				Oid:  e.Oid,
+				Name: e.Name,
+				Path: lfs.LocalMediaPathReadOnly(e.Oid),
+				Size: e.Size,
+			}
+			objects[e.Oid] = obj
+			toVerify = append(toVerify, obj)
+		}
+
+		fsckVerifyObjects(toVerify, cfg.FsckConcurrency(), nil)
+
+		for _, e := range entries {
+			obj := objects[e.Oid]
+			verified := obj != nil && obj.Err == nil && obj.Recalculated == obj.Oid
+			e.Verified = &verified
+		}
+	}
+
+	enc, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		Panic(err, "Could not encode Git LFS files")
+	}
+	os.Stdout.Write(append(enc, '\n'))
+}
+
+// lsFilesPrintSize prints the number and total size of the objects listed,
+// with --size. With --json that goes to stderr, so that stdout is only JSON.
+func lsFilesPrintSize(count int, totalSize int64) {
+	if !lsFilesSize {
+		return
+	}
+	if lsFilesJson {
+		fmt.Fprintf(os.Stderr, "%d objects, %s\n", count, humanizeBytes(totalSize))
+		return
+	}
+	Print("%d objects, %s", count, humanizeBytes(totalSize))
+}
+
+// lsFilesPrint prints one entry of the listing, terminated by a NUL character
+// with -z, or a newline otherwise.
+func lsFilesPrint(entry string) {
+	if lsFilesNul {
+		fmt.Fprintf(OutputWriter, "%s\x00", entry)
+		return
 	}
+	Print("%s", entry)
 }
 
 func lsFilesMarker(p *lfs.WrappedPointer) string {
@@ -58,5 +235,11 @@ func lsFilesMarker(p *lfs.WrappedPointer) string {
 
 func init() {
 	lsFilesCmd.Flags().BoolVarP(&longOIDs, "long", "l", false, "")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesAll, "all", "a", false, "List objects referenced anywhere in history")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesSize, "size", "s", false, "Show the total size of the listed objects")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesName, "name-only", "n", false, "Only show the paths of the listed files")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesNul, "null", "z", false, "Terminate each entry with a NUL character instead of a newline")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesJson, "json", "j", false, "Give the listing as JSON")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesVerify, "verify", "", false, "With --json, check that downloaded objects match their OIDs")
 	RootCmd.AddCommand(lsFilesCmd)
 }
@@ -9,7 +9,9 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 
+	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/lfs"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +20,7 @@ var (
 	pointerFile    string
 	pointerCompare string
 	pointerStdin   bool
+	pointerHash    bool
 	pointerCmd     = &cobra.Command{
 		Use: "pointer",
 		Run: pointerCommand,
@@ -30,15 +33,25 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 	buildOid := ""
 	compareOid := ""
 
+	if pointerHash {
+		pointerHashCommand()
+		return
+	}
+
 	if len(pointerCompare) > 0 || pointerStdin {
 		comparing = true
 	}
 
+	var buildPointer, comparePointer []byte
+	buildName := pointerFile
+	if pointerFile == "-" {
+		buildName = "STDIN"
+	}
 	if len(pointerFile) > 0 {
 		something = true
-		buildFile, err := os.Open(pointerFile)
+		buildFile, err := pointerContentReader()
 		if err != nil {
-			Error(err.Error())
+			Error("%s", err)
 			os.Exit(1)
 		}
 
@@ -47,14 +60,15 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 		buildFile.Close()
 
 		if err != nil {
-			Error(err.Error())
+			Error("%s", err)
 			os.Exit(1)
 		}
 
 		ptr := lfs.NewPointer(hex.EncodeToString(oidHash.Sum(nil)), size, nil)
-		fmt.Fprintf(os.Stderr, "Git LFS pointer for %s\n\n", pointerFile)
+		fmt.Fprintf(os.Stderr, "Git LFS pointer for %s\n\n", buildName)
 		buf := &bytes.Buffer{}
 		lfs.EncodePointer(io.MultiWriter(os.Stdout, buf), ptr)
+		buildPointer = buf.Bytes()
 
 		if comparing {
 			buildOid = gitHashObject(buf.Bytes())
@@ -77,11 +91,7 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 		_, err = lfs.DecodePointer(tee)
 		compFile.Close()
 
-		pointerName := "STDIN"
-		if !pointerStdin {
-			pointerName = pointerCompare
-		}
-		fmt.Fprintf(os.Stderr, "Pointer from %s\n\n", pointerName)
+		fmt.Fprintf(os.Stderr, "Pointer from %s\n\n", pointerName())
 
 		if err != nil {
 			Error(err.Error())
@@ -89,6 +99,7 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 		}
 
 		fmt.Fprintf(os.Stderr, buf.String())
+		comparePointer = buf.Bytes()
 		if comparing {
 			compareOid = gitHashObject(buf.Bytes())
 			fmt.Fprintf(os.Stderr, "\nGit blob OID: %s\n", compareOid)
@@ -96,7 +107,8 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 	}
 
 	if comparing && buildOid != compareOid {
-		fmt.Fprintf(os.Stderr, "\nPointers do not match\n")
+		fmt.Fprintf(os.Stderr, "\nPointers do not match\n\n")
+		printPointerDiff(pointerName(), comparePointer, buildName, buildPointer)
 		os.Exit(1)
 	}
 
@@ -106,6 +118,114 @@ func pointerCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+// pointerHashCommand prints the OID and size that the clean filter would give
+// the content of --file or STDIN, without storing an object.
+func pointerHashCommand() {
+	if len(pointerCompare) > 0 {
+		Exit("Cannot combine --hash with --pointer.")
+	}
+
+	var reader io.Reader
+	var fileSize int64
+	fileName := pointerFile
+	if fileName == "-" {
+		fileName = ""
+	}
+	if len(fileName) > 0 {
+		if pointerStdin {
+			Exit("Cannot hash both --file and STDIN.")
+		}
+
+		file, err := os.Open(pointerFile)
+		if err != nil {
+			Exit("Error opening %s: %s", pointerFile, err)
+		}
+		defer file.Close()
+
+		if stat, err := file.Stat(); err == nil {
+			fileSize = stat.Size()
+		}
+		reader = file
+	} else {
+		requireStdin("The --hash flag expects --file or content through STDIN.")
+		reader = os.Stdin
+	}
+
+	// Use the clean filter's own code path, so that extensions and
+	// content which is already a pointer are handled just as `git add`
+	// would handle them
+	cleaned, err := lfs.PointerClean(reader, fileName, fileSize, nil)
+	if cleaned != nil {
+		defer cleaned.Teardown()
+	}
+
+	if errutil.IsCleanPointerError(err) {
+		// git add stores pointers as they are, so they already name
+		// their object
+		ptr := errutil.ErrorGetContext(err, "pointer").(*lfs.Pointer)
+		Print("sha256:%s %d", ptr.Oid, ptr.Size)
+		return
+	}
+
+	if err != nil {
+		Exit("Error hashing content: %s", err)
+	}
+
+	Print("sha256:%s %d", cleaned.Oid, cleaned.Size)
+}
+
+// pointerContentReader opens the content to build a pointer from: --file, or
+// STDIN if it is "-".
+func pointerContentReader() (io.ReadCloser, error) {
+	if pointerFile != "-" {
+		return os.Open(pointerFile)
+	}
+
+	if pointerStdin {
+		return nil, errors.New("Cannot read both --file and --stdin from STDIN.")
+	}
+
+	requireStdin("The --file=- flag expects content through STDIN.")
+
+	return os.Stdin, nil
+}
+
+// pointerName names where the pointer to compare against came from.
+func pointerName() string {
+	if pointerStdin {
+		return "STDIN"
+	}
+	return pointerCompare
+}
+
+// printPointerDiff prints the lines in which the expected pointer differs from
+// the one built from the content.
+func printPointerDiff(expectedName string, expected []byte, actualName string, actual []byte) {
+	expectedLines := strings.Split(strings.TrimSuffix(string(expected), "\n"), "\n")
+	actualLines := strings.Split(strings.TrimSuffix(string(actual), "\n"), "\n")
+
+	fmt.Fprintf(os.Stderr, "--- %s\n+++ %s\n", expectedName, actualName)
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var e, a string
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+
+		if e == a {
+			continue
+		}
+		if i < len(expectedLines) {
+			fmt.Fprintf(os.Stderr, "-%s\n", e)
+		}
+		if i < len(actualLines) {
+			fmt.Fprintf(os.Stderr, "+%s\n", a)
+		}
+	}
+}
+
 func pointerReader() (io.ReadCloser, error) {
 	if len(pointerCompare) > 0 {
 		if pointerStdin {
@@ -134,8 +254,9 @@ func gitHashObject(by []byte) string {
 
 func init() {
 	flags := pointerCmd.Flags()
-	flags.StringVarP(&pointerFile, "file", "f", "", "Path to a local file to generate the pointer from.")
+	flags.StringVarP(&pointerFile, "file", "f", "", "Path to a local file to generate the pointer from, or - for STDIN.")
 	flags.StringVarP(&pointerCompare, "pointer", "p", "", "Path to a local file containing a pointer built by another Git LFS implementation.")
 	flags.BoolVarP(&pointerStdin, "stdin", "", false, "Read a pointer built by another Git LFS implementation through STDIN.")
+	flags.BoolVarP(&pointerHash, "hash", "", false, "Print the OID and size the clean filter would give --file or STDIN, without storing it.")
 	RootCmd.AddCommand(pointerCmd)
 }
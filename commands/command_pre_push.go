@@ -41,6 +41,10 @@ var (
 //
 // In the case of deleting a branch, no attempts to push Git LFS objects will be
 // made.
+//
+// When GIT_LFS_SKIP_PUSH is set, no Git LFS objects are pushed at all, and the
+// objects which would have been are counted and reported instead, so that the
+// Git push can go ahead without them.
 func prePushCommand(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
 		Print("This should be run through Git's pre-push hook.  Run `git lfs update` to install it.")
@@ -53,12 +57,19 @@ func prePushCommand(cmd *cobra.Command, args []string) {
 	}
 
 	cfg.CurrentRemote = args[0]
+	skipPush := cfg.GetenvBool("GIT_LFS_SKIP_PUSH", false)
+	if cfg.Offline() && !prePushDryRun && !skipPush {
+		Exit("lfs.offline is set, refusing to push to %q", cfg.CurrentRemote)
+	}
+
 	ctx := newUploadContext(prePushDryRun)
 
 	scanOpt := lfs.NewScanRefsOptions()
 	scanOpt.ScanMode = lfs.ScanLeftToRemoteMode
 	scanOpt.RemoteName = cfg.CurrentRemote
 
+	skipped := make(map[string]int64)
+
 	// We can be passed multiple lines of refs
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
@@ -78,8 +89,40 @@ func prePushCommand(cmd *cobra.Command, args []string) {
 			Panic(err, "Error scanning for Git LFS files")
 		}
 
+		if skipPush {
+			for _, p := range pointers {
+				skipped[p.Oid] = p.Size
+			}
+			continue
+		}
+
 		upload(ctx, pointers)
 	}
+
+	if skipPush {
+		reportSkippedPush(skipped)
+	}
+}
+
+// reportSkippedPush warns that the objects were not uploaded because
+// GIT_LFS_SKIP_PUSH is set, so that nobody takes them to be on the server.
+func reportSkippedPush(skipped map[string]int64) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	var size int64
+	for _, s := range skipped {
+		size += s
+	}
+
+	Error("")
+	Error("WARNING: GIT_LFS_SKIP_PUSH is set, so %d Git LFS objects (%s) were NOT uploaded to %q.", len(skipped), humanizeBytes(size), cfg.CurrentRemote)
+	Error("The Git push will go ahead without them, and anyone fetching these commits will be")
+	Error("unable to download their Git LFS files until they are pushed, with for example:")
+	Error("")
+	Error("  git lfs push --all %s <branch>", cfg.CurrentRemote)
+	Error("")
 }
 
 // decodeRefs pulls the sha1s out of the line read from the pre-push
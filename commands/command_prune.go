@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"os"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/github/git-lfs/localstorage"
 	"github.com/github/git-lfs/progress"
 	"github.com/github/git-lfs/tools"
+	"github.com/github/git-lfs/transfer"
 	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +31,12 @@ var (
 	pruneVerboseArg     bool
 	pruneVerifyArg      bool
 	pruneDoNotVerifyArg bool
+	pruneUnpushedArg    bool
+	pruneYesArg         bool
+	pruneKeepDaysArg    int
+	pruneCachedOnlyArg  bool
+	pruneExcludeRefsArg string
+	pruneExplainArg     bool
 )
 
 func pruneCommand(cmd *cobra.Command, args []string) {
@@ -36,10 +46,32 @@ func pruneCommand(cmd *cobra.Command, args []string) {
 		Exit("Cannot specify both --verify-remote and --no-verify-remote")
 	}
 
+	if pruneCachedOnlyArg {
+		if pruneUnpushedArg {
+			Exit("Cannot combine --cached-only with --include-unpushed")
+		}
+		if pruneKeepDaysArg >= 0 {
+			Exit("Cannot combine --cached-only with --keep-days")
+		}
+		if len(pruneExcludeRefsArg) > 0 {
+			Exit("Cannot combine --cached-only with --exclude-refs")
+		}
+	}
+
+	if pruneExplainArg && !pruneDryRunArg {
+		Exit("--explain requires --dry-run")
+	}
+
+	for _, pattern := range pruneExcludeRefsPatterns() {
+		if _, err := path.Match(pattern, ""); err != nil {
+			Exit("Invalid --exclude-refs pattern %q: %v", pattern, err)
+		}
+	}
+
 	verify := !pruneDoNotVerifyArg &&
 		(cfg.FetchPruneConfig().PruneVerifyRemoteAlways || pruneVerifyArg)
 
-	prune(verify, pruneDryRunArg, pruneVerboseArg)
+	prune(verify, pruneDryRunArg, pruneVerboseArg, pruneUnpushedArg)
 
 }
 
@@ -58,17 +90,32 @@ type PruneProgress struct {
 }
 type PruneProgressChan chan PruneProgress
 
-func prune(verifyRemote, dryRun, verbose bool) {
+func prune(verifyRemote, dryRun, verbose, includeUnpushed bool) {
 	localObjects := make([]localstorage.Object, 0, 100)
 	retainedObjects := tools.NewStringSetWithCapacity(100)
+	headObjects := tools.NewStringSetWithCapacity(100)
 	var reachableObjects tools.StringSet
+	var unpushedObjects tools.StringSet
+	var excludedRefs []*git.Ref
 	var taskwait sync.WaitGroup
 
+	// In a shallow clone, commits beyond the boundary may still use objects
+	// which would be retained in a full clone, and there's no way to tell which,
+	// so only objects which some local commit refers to can be pruned
+	shallow := git.IsShallow(config.LocalGitStorageDir) && !pruneCachedOnlyArg
+
+	// --explain needs the reachable objects to tell which prunable objects
+	// are in old commits, and which aren't in any
+	explain := pruneExplainArg && dryRun
+	if explain {
+		pruneExplained = newPruneExplanation()
+	}
+
 	// Add all the base funcs to the waitgroup before starting them, in case
 	// one completes really fast & hits 0 unexpectedly
 	// each main process can Add() to the wg itself if it subdivides the task
 	taskwait.Add(4) // 1..4: localObjects, current & recent refs, unpushed, worktree
-	if verifyRemote {
+	if verifyRemote || shallow || explain {
 		taskwait.Add(1) // 5
 	}
 
@@ -87,10 +134,16 @@ func prune(verifyRemote, dryRun, verbose bool) {
 	// Now find files to be retained from many sources
 	retainChan := make(chan string, 100)
 
-	go pruneTaskGetRetainedCurrentAndRecentRefs(retainChan, errorChan, &taskwait)
-	go pruneTaskGetRetainedUnpushed(retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedCurrentAndRecentRefs(&headObjects, &excludedRefs, retainChan, errorChan, &taskwait)
+	if includeUnpushed {
+		// Unpushed objects are not retained, just remembered so we can warn
+		unpushedObjects = tools.NewStringSetWithCapacity(100)
+		go pruneTaskGetUnpushedObjects(&unpushedObjects, errorChan, &taskwait)
+	} else {
+		go pruneTaskGetRetainedUnpushed(retainChan, errorChan, &taskwait)
+	}
 	go pruneTaskGetRetainedWorktree(retainChan, errorChan, &taskwait)
-	if verifyRemote {
+	if verifyRemote || shallow || explain {
 		reachableObjects = tools.NewStringSetWithCapacity(100)
 		go pruneTaskGetReachableObjects(&reachableObjects, errorChan, &taskwait)
 	}
@@ -132,7 +185,13 @@ func prune(verifyRemote, dryRun, verbose bool) {
 		verifyc = verifyQueue.Watch()
 	}
 
+	var shallowKept int
 	for _, file := range localObjects {
+		if shallow && !retainedObjects.Contains(file.Oid) && !reachableObjects.Contains(file.Oid) {
+			tracerx.Printf("SHALLOW: %v", file.Oid)
+			shallowKept++
+			continue
+		}
 		if !retainedObjects.Contains(file.Oid) {
 			prunableObjects = append(prunableObjects, file.Oid)
 			totalSize += file.Size
@@ -170,10 +229,36 @@ func prune(verifyRemote, dryRun, verbose bool) {
 		progresswait.Wait()
 	}
 
+	if shallowKept > 0 {
+		Print("Keeping %d files which commits beyond the shallow clone boundary may need", shallowKept)
+	}
+
+	if pruneCachedOnlyArg {
+		kept := 0
+		for _, file := range localObjects {
+			if headObjects.Contains(file.Oid) {
+				kept++
+			}
+		}
+		Print("Keeping %d files needed by HEAD", kept)
+	}
+
+	if len(pruneExcludeRefsArg) > 0 {
+		pruneReportExcludedRefs(excludedRefs, prunableObjects, dryRun)
+	}
+
+	if explain {
+		pruneReportExplanation(localObjects, retainedObjects, reachableObjects, unpushedObjects, shallow)
+	}
+
 	if len(prunableObjects) == 0 {
+		pruneChunks(localObjects, prunableObjects, dryRun)
 		Print("Nothing to prune")
 		return
 	}
+	if includeUnpushed {
+		pruneCheckUnpushed(prunableObjects, unpushedObjects, dryRun)
+	}
 	if dryRun {
 		Print("%d files would be pruned (%v)", len(prunableObjects), humanizeBytes(totalSize))
 		if verbose {
@@ -185,10 +270,40 @@ func prune(verifyRemote, dryRun, verbose bool) {
 			Print(verboseOutput.String())
 		}
 		pruneDeleteFiles(prunableObjects)
+		if pruneCachedOnlyArg {
+			Print("Reclaimed %v", humanizeBytes(totalSize))
+		}
 	}
+	pruneChunks(localObjects, prunableObjects, dryRun)
 
 }
 
+// pruneChunks removes the chunks kept by the chunked transfer adapter which
+// none of the local objects that are left is made of.
+func pruneChunks(localObjects []localstorage.Object, prunableObjects []string, dryRun bool) {
+	kept := tools.NewStringSetWithCapacity(len(localObjects))
+	for _, file := range localObjects {
+		kept.Add(file.Oid)
+	}
+	for _, oid := range prunableObjects {
+		kept.Remove(oid)
+	}
+
+	count, size, err := transfer.PruneChunks(kept.Contains, dryRun)
+	if err != nil {
+		LoggedError(err, "Could not prune %s: %s", localstorage.ChunkDir, err)
+		return
+	}
+	if count == 0 {
+		return
+	}
+	if dryRun {
+		Print("%d chunks would be pruned (%v)", count, humanizeBytes(size))
+	} else {
+		Print("Pruned %d chunks (%v)", count, humanizeBytes(size))
+	}
+}
+
 func pruneCheckVerified(prunableObjects []string, reachableObjects, verifiedObjects tools.StringSet) {
 	// There's no issue if an object is not reachable and missing, only if reachable & missing
 	var problems bytes.Buffer
@@ -212,6 +327,167 @@ func pruneCheckVerified(prunableObjects []string, reachableObjects, verifiedObje
 	}
 }
 
+// pruneCheckUnpushed warns about any prunable objects which are only present
+// locally, listing the unpushed commits that use them, and asks the user to
+// confirm before continuing unless --yes was given.
+func pruneCheckUnpushed(prunableObjects []string, unpushedObjects tools.StringSet, dryRun bool) {
+	unpushedPrunable := tools.NewStringSet()
+	for _, oid := range prunableObjects {
+		if unpushedObjects.Contains(oid) {
+			unpushedPrunable.Add(oid)
+		}
+	}
+	unpushedCount := unpushedPrunable.Cardinality()
+	if unpushedCount == 0 {
+		return
+	}
+
+	remoteName := cfg.FetchPruneConfig().PruneRemoteName
+	commits, err := lfs.ScanUnpushedCommits(remoteName, unpushedPrunable)
+	if err != nil {
+		Panic(err, "Could not scan for unpushed commits")
+	}
+
+	Error("WARNING: %d files to be pruned have not been pushed to %q.", unpushedCount, remoteName)
+	Error("Deleting them is irreversible; these commits will no longer have their Git LFS content:\n")
+	for _, c := range commits {
+		Error(" * %s %s", c.ShortSha, c.Subject)
+	}
+	Error("")
+
+	if dryRun || pruneYesArg {
+		return
+	}
+
+	if !pruneConfirm("Delete unpushed Git LFS files? [y/N] ") {
+		Exit("Aborting prune, nothing was deleted")
+	}
+}
+
+// pruneReportExcludedRefs lists the refs which --exclude-refs dropped from
+// retention, and how many of the prunable objects are only there because of
+// it, i.e. those which the excluded refs would otherwise have retained.
+func pruneReportExcludedRefs(excludedRefs []*git.Ref, prunableObjects []string, dryRun bool) {
+	if len(excludedRefs) == 0 {
+		Print("No retained refs match --exclude-refs %q", pruneExcludeRefsArg)
+		return
+	}
+
+	Print("Excluded %d refs from retention:", len(excludedRefs))
+	for _, ref := range excludedRefs {
+		Print(" * %s", ref.Name)
+	}
+
+	excludedObjects := pruneExcludedRefObjects(excludedRefs)
+	var count int
+	for _, oid := range prunableObjects {
+		if excludedObjects.Contains(oid) {
+			count++
+		}
+	}
+
+	if dryRun {
+		Print("%d files would be pruned only because of the excluded refs", count)
+	} else {
+		Print("%d files will be pruned only because of the excluded refs", count)
+	}
+}
+
+// pruneExcludedRefObjects returns the objects which the given refs would have
+// retained, at each ref and in its recent previous versions.
+func pruneExcludedRefObjects(refs []*git.Ref) tools.StringSet {
+	objects := tools.NewStringSet()
+	_, pruneCommitDays := pruneRetainDays(cfg.FetchPruneConfig())
+
+	opts := lfs.NewScanRefsOptions()
+	opts.ScanMode = lfs.ScanRefsMode
+	opts.SkipDeletedBlobs = true
+
+	for _, ref := range refs {
+		pointers, err := lfs.ScanRefs(ref.Sha, "", opts)
+		if err != nil {
+			Panic(err, "Could not scan excluded ref %v", ref.Name)
+		}
+		for _, p := range pointers {
+			objects.Add(p.Oid)
+		}
+
+		if pruneCommitDays <= 0 {
+			continue
+		}
+
+		summ, err := git.GetCommitSummary(ref.Sha)
+		if err != nil {
+			Panic(err, "Could not scan commits at excluded ref %v", ref.Name)
+		}
+		refchan, err := lfs.ScanPreviousVersionsToChan(ref.Sha, summ.CommitDate.AddDate(0, 0, -pruneCommitDays))
+		if err != nil {
+			Panic(err, "Could not scan commits at excluded ref %v", ref.Name)
+		}
+		for wp := range refchan.Results {
+			objects.Add(wp.Pointer.Oid)
+		}
+		if err := refchan.Wait(); err != nil {
+			Panic(err, "Could not scan commits at excluded ref %v", ref.Name)
+		}
+	}
+
+	return objects
+}
+
+// pruneExcludeRefsPatterns returns the comma separated patterns given to
+// --exclude-refs.
+func pruneExcludeRefsPatterns() []string {
+	var patterns []string
+	for _, pattern := range strings.Split(pruneExcludeRefsArg, ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) > 0 {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// pruneRefExcluded returns whether the ref matches a pattern given to
+// --exclude-refs. Patterns match the short name of the ref, such as
+// "release/1.0", and "*" doesn't match "/". A remote branch also matches
+// without its remote's name, so that "release/*" excludes both release/1.0 and
+// origin/release/1.0.
+func pruneRefExcluded(ref *git.Ref) bool {
+	names := []string{ref.Name}
+	if ref.Type == git.RefTypeRemoteBranch {
+		if i := strings.Index(ref.Name, "/"); i >= 0 {
+			names = append(names, ref.Name[i+1:])
+		}
+	}
+
+	for _, pattern := range pruneExcludeRefsPatterns() {
+		for _, name := range names {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pruneConfirm asks the user a yes/no question on the terminal, returning
+// false if the answer isn't yes or Stdin isn't a terminal.
+func pruneConfirm(question string) bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil || (stat.Mode()&os.ModeCharDevice) == 0 {
+		Error("Cannot ask for confirmation without a terminal, use --yes to continue anyway.")
+		return false
+	}
+
+	fmt.Fprint(ErrorWriter, question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
 func pruneCheckErrors(taskErrors []error) {
 	if len(taskErrors) > 0 {
 		for _, err := range taskErrors {
@@ -275,14 +551,8 @@ func pruneDeleteFiles(prunableObjects []string) {
 	var deletedFiles int
 	for i, oid := range prunableObjects {
 		spinner.Print(OutputWriter, fmt.Sprintf("Deleting object %d/%d", i, len(prunableObjects)))
-		mediaFile, err := lfs.LocalMediaPath(oid)
-		if err != nil {
-			problems.WriteString(fmt.Sprintf("Unable to find media path for %v: %v\n", oid, err))
-			continue
-		}
-		err = os.Remove(mediaFile)
-		if err != nil {
-			problems.WriteString(fmt.Sprintf("Failed to remove file %v: %v\n", mediaFile, err))
+		if err := deleteLocalObject(oid); err != nil {
+			problems.WriteString(err.Error() + "\n")
 			continue
 		}
 		deletedFiles++
@@ -306,7 +576,9 @@ func pruneTaskGetLocalObjects(outLocalObjects *[]localstorage.Object, progChan P
 }
 
 // Background task, must call waitg.Done() once at end
-func pruneTaskGetRetainedAtRef(ref string, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
+// If outObjectSet is not nil, the objects are also added to it.
+// The reason is given to --explain for the objects retained.
+func pruneTaskGetRetainedAtRef(ref, reason string, outObjectSet *tools.StringSet, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
 
 	// Only files AT ref, recent is checked in pruneTaskGetRetainedRecentRefs
@@ -320,6 +592,10 @@ func pruneTaskGetRetainedAtRef(ref string, retainChan chan string, errorChan cha
 	}
 	for wp := range refchan.Results {
 		retainChan <- wp.Pointer.Oid
+		if outObjectSet != nil {
+			outObjectSet.Add(wp.Pointer.Oid)
+		}
+		pruneExplained.Add(wp.Pointer.Oid, reason)
 		tracerx.Printf("RETAIN: %v via ref %v", wp.Pointer.Oid, ref)
 	}
 	err = refchan.Wait()
@@ -329,7 +605,8 @@ func pruneTaskGetRetainedAtRef(ref string, retainChan chan string, errorChan cha
 }
 
 // Background task, must call waitg.Done() once at end
-func pruneTaskGetPreviousVersionsOfRef(ref string, since time.Time, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
+// The reason is given to --explain for the objects retained.
+func pruneTaskGetPreviousVersionsOfRef(ref, reason string, since time.Time, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
 
 	refchan, err := lfs.ScanPreviousVersionsToChan(ref, since)
@@ -339,6 +616,7 @@ func pruneTaskGetPreviousVersionsOfRef(ref string, since time.Time, retainChan c
 	}
 	for wp := range refchan.Results {
 		retainChan <- wp.Pointer.Oid
+		pruneExplained.Add(wp.Pointer.Oid, reason)
 		tracerx.Printf("RETAIN: %v via ref %v >= %v", wp.Pointer.Oid, ref, since)
 	}
 	err = refchan.Wait()
@@ -348,7 +626,9 @@ func pruneTaskGetPreviousVersionsOfRef(ref string, since time.Time, retainChan c
 }
 
 // Background task, must call waitg.Done() once at end
-func pruneTaskGetRetainedCurrentAndRecentRefs(retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
+// The objects at HEAD are also added to outHeadObjects, and the recent refs
+// which aren't retained because of --exclude-refs to outExcludedRefs.
+func pruneTaskGetRetainedCurrentAndRecentRefs(outHeadObjects *tools.StringSet, outExcludedRefs *[]*git.Ref, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
 
 	// We actually increment the waitg in this func since we kick off sub-goroutines
@@ -361,34 +641,55 @@ func pruneTaskGetRetainedCurrentAndRecentRefs(retainChan chan string, errorChan
 		return
 	}
 	commits.Add(ref.Sha)
-	waitg.Add(1)
-	go pruneTaskGetRetainedAtRef(ref.Sha, retainChan, errorChan, waitg)
+	order := []string{ref.Sha}
+	// The refs at each commit, to explain why objects are retained
+	labels := map[string][]string{ref.Sha: {"HEAD"}}
+	if ref.Name != "HEAD" {
+		labels[ref.Sha] = []string{"HEAD -> " + ref.Name}
+	}
 
 	// Now recent
 	fetchconf := cfg.FetchPruneConfig()
-	if fetchconf.FetchRecentRefsDays > 0 {
-		pruneRefDays := fetchconf.FetchRecentRefsDays + fetchconf.PruneOffsetDays
-		tracerx.Printf("PRUNE: Retaining non-HEAD refs within %d (%d+%d) days", pruneRefDays, fetchconf.FetchRecentRefsDays, fetchconf.PruneOffsetDays)
+	pruneRefDays, pruneCommitDays := pruneRetainDays(fetchconf)
+	if pruneRefDays > 0 {
 		refsSince := time.Now().AddDate(0, 0, -pruneRefDays)
 		// Keep all recent refs including any recent remote branches
 		refs, err := git.RecentBranches(refsSince, fetchconf.FetchRecentRefsIncludeRemotes, "")
 		if err != nil {
 			Panic(err, "Could not scan for recent refs")
 		}
-		for _, ref := range refs {
-			if commits.Add(ref.Sha) {
+		for _, recent := range refs {
+			// The current branch is always retained
+			if recent.Name == ref.Name {
+				continue
+			}
+			if pruneRefExcluded(recent) {
+				tracerx.Printf("PRUNE: Excluding ref %v", recent.Name)
+				*outExcludedRefs = append(*outExcludedRefs, recent)
+				continue
+			}
+			if commits.Add(recent.Sha) {
 				// A new commit
-				waitg.Add(1)
-				go pruneTaskGetRetainedAtRef(ref.Sha, retainChan, errorChan, waitg)
+				order = append(order, recent.Sha)
 			}
+			labels[recent.Sha] = append(labels[recent.Sha], recent.Name)
 		}
 	}
 
+	for i, commit := range order {
+		// The objects at HEAD are also recorded for --cached-only
+		var headObjects *tools.StringSet
+		if i == 0 {
+			headObjects = outHeadObjects
+		}
+		waitg.Add(1)
+		go pruneTaskGetRetainedAtRef(commit, "at "+pruneRefLabel(commit, labels[commit]), headObjects, retainChan, errorChan, waitg)
+	}
+
 	// For every unique commit we've fetched, check recent commits too
 	// Only if we're fetching recent commits, otherwise only keep at refs
-	if fetchconf.FetchRecentCommitsDays > 0 {
-		pruneCommitDays := fetchconf.FetchRecentCommitsDays + fetchconf.PruneOffsetDays
-		for commit := range commits.Iter() {
+	if pruneCommitDays > 0 {
+		for _, commit := range order {
 			// We measure from the last commit at the ref
 			summ, err := git.GetCommitSummary(commit)
 			if err != nil {
@@ -396,12 +697,44 @@ func pruneTaskGetRetainedCurrentAndRecentRefs(retainChan chan string, errorChan
 				continue
 			}
 			commitsSince := summ.CommitDate.AddDate(0, 0, -pruneCommitDays)
+			reason := fmt.Sprintf("in the %d days of commits up to %s", pruneCommitDays, pruneRefLabel(commit, labels[commit]))
 			waitg.Add(1)
-			go pruneTaskGetPreviousVersionsOfRef(commit, commitsSince, retainChan, errorChan, waitg)
+			go pruneTaskGetPreviousVersionsOfRef(commit, reason, commitsSince, retainChan, errorChan, waitg)
 		}
 	}
 }
 
+// pruneRetainDays returns how many days back prune retains non-HEAD refs, and
+// previous versions behind each retained ref. --keep-days, or else
+// lfs.pruneretainunreachabledays, sets both; otherwise they are the fetch
+// recent windows plus lfs.pruneoffsetdays. Zero means nothing is retained on
+// that basis, which is always the case with --cached-only.
+func pruneRetainDays(fetchconf *config.FetchPruneConfig) (refDays, commitDays int) {
+	if pruneCachedOnlyArg {
+		tracerx.Printf("PRUNE: Retaining only HEAD, worktree HEADs and unpushed objects")
+		return 0, 0
+	}
+
+	keepDays := fetchconf.PruneRetainDays
+	if pruneKeepDaysArg >= 0 {
+		keepDays = pruneKeepDaysArg
+	}
+
+	if keepDays >= 0 {
+		tracerx.Printf("PRUNE: Retaining refs and commits within %d days", keepDays)
+		return keepDays, keepDays
+	}
+
+	if fetchconf.FetchRecentRefsDays > 0 {
+		refDays = fetchconf.FetchRecentRefsDays + fetchconf.PruneOffsetDays
+		tracerx.Printf("PRUNE: Retaining non-HEAD refs within %d (%d+%d) days", refDays, fetchconf.FetchRecentRefsDays, fetchconf.PruneOffsetDays)
+	}
+	if fetchconf.FetchRecentCommitsDays > 0 {
+		commitDays = fetchconf.FetchRecentCommitsDays + fetchconf.PruneOffsetDays
+	}
+	return refDays, commitDays
+}
+
 // Background task, must call waitg.Done() once at end
 func pruneTaskGetRetainedUnpushed(retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
@@ -415,6 +748,7 @@ func pruneTaskGetRetainedUnpushed(retainChan chan string, errorChan chan error,
 	}
 	for wp := range refchan.Results {
 		retainChan <- wp.Pointer.Oid
+		pruneExplained.Add(wp.Pointer.Oid, fmt.Sprintf("in commits not pushed to %q", remoteName))
 		tracerx.Printf("RETAIN: %v unpushed", wp.Pointer.Oid)
 	}
 	err = refchan.Wait()
@@ -423,6 +757,27 @@ func pruneTaskGetRetainedUnpushed(retainChan chan string, errorChan chan error,
 	}
 }
 
+// Background task, must call waitg.Done() once at end
+func pruneTaskGetUnpushedObjects(outObjectSet *tools.StringSet, errorChan chan error, waitg *sync.WaitGroup) {
+	defer waitg.Done()
+
+	remoteName := cfg.FetchPruneConfig().PruneRemoteName
+
+	refchan, err := lfs.ScanUnpushedToChan(remoteName)
+	if err != nil {
+		errorChan <- err
+		return
+	}
+	for wp := range refchan.Results {
+		outObjectSet.Add(wp.Pointer.Oid)
+		tracerx.Printf("UNPUSHED: %v", wp.Pointer.Oid)
+	}
+	err = refchan.Wait()
+	if err != nil {
+		errorChan <- err
+	}
+}
+
 // Background task, must call waitg.Done() once at end
 func pruneTaskGetRetainedWorktree(retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
@@ -449,7 +804,7 @@ func pruneTaskGetRetainedWorktree(retainChan chan string, errorChan chan error,
 			// Worktree is on a different commit
 			waitg.Add(1)
 			// Don't need to 'cd' to worktree since we share same repo
-			go pruneTaskGetRetainedAtRef(ref.Sha, retainChan, errorChan, waitg)
+			go pruneTaskGetRetainedAtRef(ref.Sha, "at worktree HEAD "+pruneRefLabel(ref.Sha, []string{ref.Name}), nil, retainChan, errorChan, waitg)
 		}
 	}
 
@@ -486,5 +841,12 @@ func init() {
 	pruneCmd.Flags().BoolVarP(&pruneVerboseArg, "verbose", "v", false, "Print full details of what is/would be deleted")
 	pruneCmd.Flags().BoolVarP(&pruneVerifyArg, "verify-remote", "c", false, "Verify that remote has LFS files before deleting")
 	pruneCmd.Flags().BoolVar(&pruneDoNotVerifyArg, "no-verify-remote", false, "Override lfs.pruneverifyremotealways and don't verify")
+	pruneCmd.Flags().BoolVar(&pruneUnpushedArg, "include-unpushed", false, "Also delete LFS files only referenced by unpushed commits")
+	pruneCmd.Flags().BoolVarP(&pruneYesArg, "yes", "y", false, "Don't ask for confirmation before deleting unpushed LFS files")
+	pruneCmd.Flags().BoolVar(&pruneCachedOnlyArg, "cached-only", false, "Delete all LFS files not needed by HEAD or unpushed commits, however recent")
+	pruneCmd.Flags().StringVar(&pruneExcludeRefsArg, "exclude-refs", "", "Don't retain LFS files for recent refs matching these comma separated globs")
+	pruneCmd.Flags().BoolVar(&pruneExplainArg, "explain", false, "With --dry-run, list why each LFS file is retained or would be pruned")
+	pruneCmd.Flags().IntVar(&pruneKeepDaysArg, "keep-days", -1, "Retain LFS files from the last N days of history, overriding lfs.pruneretainunreachabledays")
+	addQuietFlag(pruneCmd)
 	RootCmd.AddCommand(pruneCmd)
 }
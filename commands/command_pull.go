@@ -2,8 +2,13 @@ package commands
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 
 	"github.com/github/git-lfs/git"
+	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/subprocess"
 	"github.com/spf13/cobra"
 )
 
@@ -12,8 +17,12 @@ var (
 		Use: "pull",
 		Run: pullCommand,
 	}
-	pullIncludeArg string
-	pullExcludeArg string
+	pullIncludeArg   string
+	pullExcludeArg   string
+	pullPathsFromArg string
+	pullPathsNul     bool
+	pullResumeArg    bool
+	pullContinueArg  bool
 )
 
 func pullCommand(cmd *cobra.Command, args []string) {
@@ -25,7 +34,7 @@ func pullCommand(cmd *cobra.Command, args []string) {
 			Panic(err, fmt.Sprintf("Invalid remote name '%v'", args[0]))
 		}
 		cfg.CurrentRemote = args[0]
-	} else {
+	} else if !pullContinueArg {
 		// Actively find the default remote, don't just assume origin
 		defaultRemote, err := git.DefaultRemote()
 		if err != nil {
@@ -34,10 +43,154 @@ func pullCommand(cmd *cobra.Command, args []string) {
 		cfg.CurrentRemote = defaultRemote
 	}
 
+	if pullContinueArg {
+		if len(pullPathsFromArg) > 0 || len(pullIncludeArg) > 0 || len(pullExcludeArg) > 0 || pullPathsNul || pullResumeArg {
+			Exit("--continue cannot be combined with --include, --exclude, --paths-from or --resume")
+		}
+		pullContinue(len(args) > 0)
+		return
+	}
+
+	if len(pullPathsFromArg) > 0 {
+		if len(pullIncludeArg) > 0 || len(pullExcludeArg) > 0 {
+			Exit("--paths-from cannot be combined with --include or --exclude")
+		}
+		if pullResumeArg {
+			Exit("--paths-from cannot be combined with --resume")
+		}
+		pullPaths(pullPathsFromArg, pullPathsNul)
+		return
+	} else if pullPathsNul {
+		Exit("-z can only be used with --paths-from")
+	}
+
 	pull(determineIncludeExcludePaths(cfg, pullIncludeArg, pullExcludeArg))
 
 }
 
+// pullPaths fetches and checks out exactly the Git LFS files listed in the
+// given manifest, one repository-relative path per line, or separated by NUL
+// characters if nul is set. Paths which do not exist in the current ref, or
+// are not Git LFS files, are reported and skipped.
+func pullPaths(manifest string, nul bool) {
+	ref, err := git.CurrentRef()
+	if err != nil {
+		Panic(err, "Could not pull")
+	}
+
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		Exit("Could not read paths from %s: %s", manifest, err)
+	}
+
+	pointers, err := lfs.ScanTree(ref.Sha)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS files")
+	}
+
+	pointersByName := make(map[string]*lfs.WrappedPointer, len(pointers))
+	for _, p := range pointers {
+		pointersByName[p.Name] = p
+	}
+
+	sep := "\n"
+	if nul {
+		sep = "\x00"
+	}
+
+	// The manifest isn't part of the key, so this pull can be continued,
+	// but not resumed
+	pullJournal = newTransferJournal("", cfg.CurrentRemote, ref.Sha)
+
+	var selected []*lfs.WrappedPointer
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(string(data), sep) {
+		if !nul {
+			line = strings.TrimSuffix(line, "\r")
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		name := filepath.ToSlash(filepath.Clean(line))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if p, ok := pointersByName[name]; ok {
+			selected = append(selected, p)
+		} else if subprocess.ExecCommand("git", "cat-file", "-e", ref.Sha+":"+name).Run() == nil {
+			Error("%s:%d: %s is not a Git LFS file, skipping", manifest, i+1, line)
+		} else {
+			Error("%s:%d: %s does not exist in %s, skipping", manifest, i+1, line, ref.Name)
+		}
+	}
+
+	c := make(chan *lfs.WrappedPointer)
+	done := fetchAndReportToChanAsync(selected, nil, nil, c)
+	skipped := checkoutWithChan(c)
+
+	ok := <-done
+	enforceStorageCap()
+	reportSkippedCheckouts(skipped)
+	if !ok {
+		reportPullFailures()
+		Exit("Warning: errors occurred")
+	}
+}
+
+// pullContinue retries the objects the last failed pull couldn't download,
+// and checks out the files which use them, using the remote that pull used
+// unless one was given.
+func pullContinue(remoteGiven bool) {
+	journal, err := loadTransferJournal()
+	if err != nil {
+		Exit("Could not read %s: %s", transferJournalPath(), err)
+	}
+	if journal == nil || journal.Failed() == 0 {
+		Exit("No failed pull to continue")
+	}
+
+	ref, err := git.CurrentRef()
+	if err != nil {
+		Panic(err, "Could not pull")
+	}
+	if ref.Sha != journal.Ref {
+		Exit("The current commit has changed since the failed pull, run 'git lfs pull' instead")
+	}
+	if !remoteGiven {
+		cfg.CurrentRemote = journal.Remote
+	}
+
+	pointers := journal.Pointers(true)
+	Print("Retrying %d objects which failed to download", journal.Failed())
+	pullJournal = journal
+
+	c := make(chan *lfs.WrappedPointer)
+	done := fetchAndReportToChanAsync(pointers, nil, nil, c)
+	skipped := checkoutFromFetchChan(nil, nil, c)
+
+	ok := <-done
+	enforceStorageCap()
+	reportSkippedCheckouts(skipped)
+	if !ok {
+		reportPullFailures()
+		Exit("Warning: errors occurred")
+	}
+}
+
+// reportPullFailures suggests `git lfs pull --continue` if the pull's journal
+// recorded objects which failed to download.
+func reportPullFailures() {
+	if pullJournal == nil {
+		return
+	}
+	if failed := pullJournal.Failed(); failed > 0 {
+		Error("Run 'git lfs pull --continue' to retry the %d objects which failed to download", failed)
+	}
+}
+
 func pull(includePaths, excludePaths []string) {
 
 	ref, err := git.CurrentRef()
@@ -45,13 +198,50 @@ func pull(includePaths, excludePaths []string) {
 		Panic(err, "Could not pull")
 	}
 
-	c := fetchRefToChan(ref.Sha, includePaths, excludePaths)
-	checkoutFromFetchChan(includePaths, excludePaths, c)
+	if cfg.Offline() {
+		// Nothing can be downloaded, so refuse to check out anything
+		// unless the local store already has every object
+		pointers, err := pointersToFetchForRef(ref.Sha)
+		if err != nil {
+			Panic(err, "Could not scan for Git LFS files")
+		}
+		if !fetchPointers(pointers, includePaths, excludePaths) {
+			Exit("Could not pull, objects are missing from the local store")
+		}
+	}
+
+	key := transferJournalKey(cfg.CurrentRemote, ref.Sha, includePaths, excludePaths)
+	pullJournal = openTransferJournal(key, cfg.CurrentRemote, ref.Sha, pullResumeArg)
 
+	var c chan *lfs.WrappedPointer
+	var done <-chan bool
+	if len(pullJournal.Files) > 0 {
+		// Resuming, so the journal already has the ref's files
+		c = make(chan *lfs.WrappedPointer)
+		done = fetchAndReportToChanAsync(pullJournal.Pointers(false), nil, nil, c)
+	} else {
+		c, done = fetchRefToChan(ref.Sha, includePaths, excludePaths)
+	}
+	skipped := checkoutFromFetchChan(includePaths, excludePaths, c)
+
+	ok := <-done
+	enforceStorageCap()
+	reportSkippedCheckouts(skipped)
+	if !ok {
+		reportPullFailures()
+		Exit("Warning: errors occurred")
+	}
 }
 
 func init() {
 	pullCmd.Flags().StringVarP(&pullIncludeArg, "include", "I", "", "Include a list of paths")
 	pullCmd.Flags().StringVarP(&pullExcludeArg, "exclude", "X", "", "Exclude a list of paths")
+	pullCmd.Flags().StringVarP(&pullPathsFromArg, "paths-from", "", "", "Only pull the paths listed in a file")
+	pullCmd.Flags().BoolVarP(&pullPathsNul, "null", "z", false, "Paths in the --paths-from file are separated by NUL characters")
+	pullCmd.Flags().BoolVarP(&checkoutSkipErrorsArg, "skip-errors", "", false, "Leave files which can't be downloaded or checked out as pointers and continue")
+	pullCmd.Flags().BoolVarP(&pullResumeArg, "resume", "", false, "Resume an interrupted pull of the same ref and paths")
+	pullCmd.Flags().BoolVarP(&pullContinueArg, "continue", "", false, "Retry only the objects which failed to download in the last pull")
+	addTransferFailureFlags(pullCmd)
+	addQuietFlag(pullCmd)
 	RootCmd.AddCommand(pullCmd)
 }
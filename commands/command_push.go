@@ -2,8 +2,12 @@ package commands
 
 import (
 	"io/ioutil"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
 
+	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
 	"github.com/rubyist/tracerx"
@@ -15,10 +19,12 @@ var (
 		Use: "push",
 		Run: pushCommand,
 	}
-	pushDryRun    = false
-	pushObjectIDs = false
-	pushAll       = false
-	useStdin      = false
+	pushDryRun     = false
+	pushVerifyOnly = false
+	pushObjectIDs  = false
+	pushAll        = false
+	pushAllRemotes = false
+	useStdin       = false
 
 	// shares some global vars and functions with command_pre_push.go
 )
@@ -45,7 +51,7 @@ func uploadsBetweenRefAndRemote(ctx *uploadContext, refnames []string) {
 	scanOpt.ScanMode = lfs.ScanLeftToRemoteMode
 	scanOpt.RemoteName = cfg.CurrentRemote
 
-	if pushAll {
+	if pushAll || pushVerifyOnly {
 		scanOpt.ScanMode = lfs.ScanRefsMode
 	}
 
@@ -65,6 +71,97 @@ func uploadsBetweenRefAndRemote(ctx *uploadContext, refnames []string) {
 	}
 }
 
+// uploadsToAllRemotes pushes the objects referenced by the given refs, or the
+// current ref, to the Git LFS endpoint of every remote. The refs are scanned
+// once, and a failure to push to one remote does not stop the others.
+func uploadsToAllRemotes(refnames []string) {
+	if len(refnames) == 0 && !pushAll {
+		ref, err := git.CurrentRef()
+		if err != nil {
+			Exit("Could not find the current ref: %s", err)
+		}
+		refnames = []string{ref.Name}
+	}
+
+	refs, err := refsByNames(refnames)
+	if err != nil {
+		Error("%s", err)
+		Exit("Error getting local refs.")
+	}
+
+	scanOpt := lfs.NewScanRefsOptions()
+	scanOpt.ScanMode = lfs.ScanRefsMode
+
+	var pointers []*lfs.WrappedPointer
+	for _, ref := range refs {
+		refPointers, err := lfs.ScanRefs(ref.Name, "", scanOpt)
+		if err != nil {
+			Panic(err, "Error scanning for Git LFS files in the %q ref", ref.Name)
+		}
+		pointers = append(pointers, refPointers...)
+	}
+
+	remotes, err := git.RemoteList()
+	if err != nil {
+		Exit("Could not list remotes: %s", err)
+	}
+	sort.Strings(remotes)
+
+	pushedEndpoints := make(map[string]string, len(remotes))
+	var pushed, failed []string
+	for _, remote := range remotes {
+		endpoint := cfg.ResolvedRemoteEndpoint(remote, "upload")
+		if endpoint.Url == config.EndpointUrlUnknown {
+			Print("Skipping %s: no Git LFS endpoint configured", remote)
+			continue
+		}
+
+		if !canPushToEndpoint(endpoint) {
+			Print("Skipping %s: Git LFS can't push to %s", remote, endpoint.Url)
+			continue
+		}
+
+		if other, ok := pushedEndpoints[endpoint.Url]; ok {
+			Print("Skipping %s: same Git LFS endpoint as %s", remote, other)
+			continue
+		}
+		pushedEndpoints[endpoint.Url] = remote
+
+		Print("Pushing to %s (%s)", remote, endpoint.Url)
+		cfg.CurrentRemote = remote
+		if errs := uploadPointers(newUploadContext(pushDryRun), pointers); len(errs) > 0 {
+			Error("Failed to push to %s", remote)
+			failed = append(failed, remote)
+		} else {
+			pushed = append(pushed, remote)
+		}
+	}
+
+	if len(pushed) > 0 {
+		Print("Pushed to %s", strings.Join(pushed, ", "))
+	}
+
+	if len(failed) > 0 {
+		Exit("Failed to push to %s", strings.Join(failed, ", "))
+	}
+}
+
+// pushableSchemes are the URL schemes of the Git LFS endpoints which objects
+// can be pushed to: the Git LFS API, and sftp servers.
+var pushableSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"sftp":  true,
+}
+
+// canPushToEndpoint reports whether objects can be pushed to endpoint. Remotes
+// which are local paths or use other transports resolve to endpoints that
+// objects can't be pushed to.
+func canPushToEndpoint(endpoint config.Endpoint) bool {
+	u, err := url.Parse(endpoint.Url)
+	return err == nil && pushableSchemes[u.Scheme]
+}
+
 func uploadsWithObjectIDs(ctx *uploadContext, oids []string) {
 	pointers := make([]*lfs.WrappedPointer, len(oids))
 
@@ -112,8 +209,27 @@ func refsByNames(refnames []string) ([]*git.Ref, error) {
 // pushCommand calculates the git objects to send by looking comparing the range
 // of commits between the local and remote git servers.
 func pushCommand(cmd *cobra.Command, args []string) {
+	setupHttpStats()
+
+	if pushVerifyOnly && (pushDryRun || useStdin || pushAllRemotes) {
+		Exit("--verify-only cannot be combined with --dry-run, --stdin or --all-remotes")
+	}
+
+	if pushAllRemotes {
+		if useStdin || pushObjectIDs {
+			Exit("--all-remotes cannot be combined with --stdin or --object-id")
+		}
+
+		if cfg.Offline() && !pushDryRun {
+			Exit("lfs.offline is set, refusing to push")
+		}
+
+		uploadsToAllRemotes(args)
+		return
+	}
+
 	if len(args) == 0 {
-		Print("Specify a remote and a remote branch name (`git lfs push origin master`)")
+		Error("Specify a remote and a remote branch name (`git lfs push origin master`)")
 		os.Exit(1)
 	}
 
@@ -123,7 +239,12 @@ func pushCommand(cmd *cobra.Command, args []string) {
 	}
 
 	cfg.CurrentRemote = args[0]
+	if cfg.Offline() && !pushDryRun {
+		Exit("lfs.offline is set, refusing to push to %q", cfg.CurrentRemote)
+	}
+
 	ctx := newUploadContext(pushDryRun)
+	ctx.VerifyOnly = pushVerifyOnly
 
 	if useStdin {
 		requireStdin("Run this command from the Git pre-push hook, or leave the --stdin flag off.")
@@ -149,14 +270,14 @@ func pushCommand(cmd *cobra.Command, args []string) {
 		uploadsBetweenRefs(ctx, left, right)
 	} else if pushObjectIDs {
 		if len(args) < 2 {
-			Print("Usage: git lfs push --object-id <remote> <lfs-object-id> [lfs-object-id] ...")
+			Error("Usage: git lfs push --object-id <remote> <lfs-object-id> [lfs-object-id] ...")
 			return
 		}
 
 		uploadsWithObjectIDs(ctx, args[1:])
 	} else {
 		if len(args) < 1 {
-			Print("Usage: git lfs push --dry-run <remote> [ref]")
+			Error("Usage: git lfs push --dry-run <remote> [ref]")
 			return
 		}
 
@@ -166,9 +287,14 @@ func pushCommand(cmd *cobra.Command, args []string) {
 
 func init() {
 	pushCmd.Flags().BoolVarP(&pushDryRun, "dry-run", "d", false, "Do everything except actually send the updates")
+	pushCmd.Flags().BoolVarP(&pushVerifyOnly, "verify-only", "", false, "Report objects missing from the server, without uploading them")
 	pushCmd.Flags().BoolVarP(&useStdin, "stdin", "s", false, "Take refs on stdin (for pre-push hook)")
 	pushCmd.Flags().BoolVarP(&pushObjectIDs, "object-id", "o", false, "Push LFS object ID(s)")
 	pushCmd.Flags().BoolVarP(&pushAll, "all", "a", false, "Push all objects for the current ref to the remote.")
+	pushCmd.Flags().BoolVarP(&pushAllRemotes, "all-remotes", "", false, "Push to every remote with a Git LFS endpoint.")
+	addHttpStatsFlag(pushCmd)
+	addTransferFailureFlags(pushCmd)
 
+	addQuietFlag(pushCmd)
 	RootCmd.AddCommand(pushCmd)
 }
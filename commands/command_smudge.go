@@ -21,6 +21,7 @@ var (
 )
 
 func smudgeCommand(cmd *cobra.Command, args []string) {
+	warnLfsConfigErrors()
 	requireStdin("This command should be run by the Git 'smudge' filter")
 	lfs.InstallHooks(false)
 
@@ -38,14 +39,21 @@ func smudgeCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if !smudgeInfo && (smudgeSkip || cfg.GetenvBool("GIT_LFS_SKIP_SMUDGE", false)) {
+		// Leave the pointer exactly as Git gave it to us, even if the
+		// object is available locally, so `git lfs checkout` can
+		// materialize it later
+		mr := io.MultiReader(b, os.Stdin)
+		if _, err := io.Copy(os.Stdout, mr); err != nil {
+			Panic(err, "Error writing data to stdout:")
+		}
+		return
+	}
+
 	lfs.LinkOrCopyFromReference(ptr.Oid, ptr.Size)
 
 	if smudgeInfo {
-		localPath, err := lfs.LocalMediaPath(ptr.Oid)
-		if err != nil {
-			Exit(err.Error())
-		}
-
+		localPath := lfs.LocalMediaPathReadOnly(ptr.Oid)
 		stat, err := os.Stat(localPath)
 		if err != nil {
 			Print("%d --", ptr.Size)
@@ -63,10 +71,6 @@ func smudgeCommand(cmd *cobra.Command, args []string) {
 
 	download := lfs.FilenamePassesIncludeExcludeFilter(filename, cfg.FetchIncludePaths(), cfg.FetchExcludePaths())
 
-	if smudgeSkip || cfg.GetenvBool("GIT_LFS_SKIP_SMUDGE", false) {
-		download = false
-	}
-
 	err = ptr.Smudge(os.Stdout, filename, download, cb)
 	if file != nil {
 		file.Close()
@@ -74,9 +78,10 @@ func smudgeCommand(cmd *cobra.Command, args []string) {
 
 	if err != nil {
 		ptr.Encode(os.Stdout)
-		// Download declined error is ok to skip if we weren't requesting download
-		if !(errutil.IsDownloadDeclinedError(err) && !download) {
-			LoggedError(err, "Error downloading object: %s (%s)", filename, ptr.Oid)
+		// Download declined error is ok to skip, whether we weren't
+		// requesting download or lfs.download.filter refused it
+		if !errutil.IsDownloadDeclinedError(err) {
+			LoggedError(err, "Error downloading object: %s (%s): %s", filename, ptr.Oid, err)
 			if !cfg.SkipDownloadErrors() {
 				os.Exit(2)
 			}
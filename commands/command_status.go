@@ -1,10 +1,20 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -13,33 +23,124 @@ var (
 		Use: "status",
 		Run: statusCommand,
 	}
-	porcelain = false
+	porcelain          = false
+	statusJson         = false
+	statusNoUntracked  = false
+	statusIncludeArg   string
+	statusExcludeArg   string
+	statusIncludePaths []string
+	statusExcludePaths []string
+	statusShowLocks    = false
+	statusRelativeTo   string
+	statusRelative     = false
+	statusAheadArg     string
+	statusLimit        int
+	statusSortArg      string
+
+	// statusRelativeDir is the directory paths are shown relative to, or ""
+	// to show them relative to the root of the repository
+	statusRelativeDir string
+
+	// statusLocks holds the locks on the server by path for
+	// --show-lock-owner, or nil if they aren't being shown
+	statusLocks map[string]api.Lock
 )
 
 func statusCommand(cmd *cobra.Command, args []string) {
 	requireInRepo()
 
+	statusIncludePaths = tools.CleanPaths(statusIncludeArg, ",")
+	statusExcludePaths = tools.CleanPaths(statusExcludeArg, ",")
+	setStatusRelativeDir()
+
+	switch statusSortArg {
+	case "", "size", "path":
+	default:
+		Exit("Invalid --sort %q, expected size or path", statusSortArg)
+	}
+	if statusLimit < 0 {
+		Exit("Invalid --limit %d", statusLimit)
+	}
+	if statusLimit > 0 && (porcelain || statusJson) {
+		Exit("--limit cannot be combined with --porcelain or --json")
+	}
+
+	if len(statusAheadArg) > 0 {
+		if len(args) > 0 {
+			Exit("--ahead cannot be used with a <base>..<head> range")
+		}
+		if statusJson || statusShowLocks {
+			Exit("--ahead cannot be combined with --json or --show-lock-owner")
+		}
+		statusAheadCommand(statusAheadArg)
+		return
+	}
+
+	if len(args) > 0 {
+		if statusShowLocks {
+			Exit("--show-lock-owner cannot be used with a <base>..<head> range")
+		}
+		statusDiffCommand(args[0])
+		return
+	}
+
+	if statusJson {
+		Exit("--json requires a <base>..<head> range")
+	}
+
 	ref, err := git.CurrentRef()
 	if err != nil {
 		Panic(err, "Could not get the current ref")
 	}
 
-	stagedPointers, err := lfs.ScanIndex()
+	scannedPointers, err := lfs.ScanIndex()
 	if err != nil {
 		Panic(err, "Could not scan staging for Git LFS objects")
 	}
 
+	stagedPointers := make([]*lfs.WrappedPointer, 0, len(scannedPointers))
+	for _, p := range scannedPointers {
+		if statusPathIncluded(p.Name, p.SrcName) {
+			stagedPointers = append(stagedPointers, p)
+		}
+	}
+
+	var untracked []statusLine
+	if !statusNoUntracked {
+		untracked = statusUntracked()
+	}
+
+	if statusShowLocks {
+		if porcelain {
+			Exit("--show-lock-owner cannot be combined with --porcelain")
+		}
+		loadStatusLocks()
+	}
+
 	if porcelain {
+		sortStatus(len(stagedPointers),
+			func(i int) string { return stagedPointers[i].Name },
+			func(i int) int64 { return stagedPointers[i].Size },
+			func(i, j int) { stagedPointers[i], stagedPointers[j] = stagedPointers[j], stagedPointers[i] })
+
 		for _, p := range stagedPointers {
 			switch p.Status {
 			case "R", "C":
-				Print("%s  %s -> %s %d", p.Status, p.SrcName, p.Name, p.Size)
+				Print("%s  %s -> %s %d", p.Status, statusPath(p.SrcName), statusPath(p.Name), p.Size)
 			case "M":
-				Print(" %s %s %d", p.Status, p.Name, p.Size)
+				Print(" %s %s %d", p.Status, statusPath(p.Name), p.Size)
 			default:
-				Print("%s  %s %d", p.Status, p.Name, p.Size)
+				Print("%s  %s %d", p.Status, statusPath(p.Name), p.Size)
 			}
 		}
+
+		sortStatus(len(untracked),
+			func(i int) string { return untracked[i].name },
+			func(i int) int64 { return untracked[i].size },
+			func(i, j int) { untracked[i], untracked[j] = untracked[j], untracked[i] })
+		for _, u := range untracked {
+			Print("?? %s %d", statusPath(u.name), u.size)
+		}
 		return
 	}
 
@@ -54,32 +155,430 @@ func statusCommand(cmd *cobra.Command, args []string) {
 		}
 
 		Print("Git LFS objects to be pushed to %s:\n", remoteRef.Name)
+		var lines []statusLine
 		for _, p := range pointers {
-			Print("\t%s (%s)", p.Name, humanizeBytes(p.Size))
+			if statusPathIncluded(p.Name, "") {
+				lines = append(lines, statusLine{p.Name, p.Size, fmt.Sprintf("\t%s (%s)%s", statusPath(p.Name), humanizeBytes(p.Size), statusLockOwner(p.Name))})
+			}
 		}
+		printStatusLines(lines)
 	}
 
 	Print("\nGit LFS objects to be committed:\n")
+	var lines []statusLine
 	for _, p := range stagedPointers {
 		switch p.Status {
 		case "R", "C":
-			Print("\t%s -> %s (%s)", p.SrcName, p.Name, humanizeBytes(p.Size))
+			lines = append(lines, statusLine{p.Name, p.Size, fmt.Sprintf("\t%s -> %s (%s)%s", statusPath(p.SrcName), statusPath(p.Name), humanizeBytes(p.Size), statusLockOwner(p.Name))})
 		case "M":
 		default:
-			Print("\t%s (%s)", p.Name, humanizeBytes(p.Size))
+			lines = append(lines, statusLine{p.Name, p.Size, fmt.Sprintf("\t%s (%s)%s", statusPath(p.Name), humanizeBytes(p.Size), statusLockOwner(p.Name))})
 		}
 	}
+	printStatusLines(lines)
 
 	Print("\nGit LFS objects not staged for commit:\n")
+	var unstaged []statusLine
 	for _, p := range stagedPointers {
 		if p.Status == "M" {
-			Print("\t%s", p.Name)
+			unstaged = append(unstaged, statusLine{p.Name, p.Size, fmt.Sprintf("\t%s%s", statusPath(p.Name), statusLockOwner(p.Name))})
 		}
 	}
+	printStatusLines(unstaged)
+
+	if len(untracked) > 0 {
+		Print("\nUntracked files the Git LFS filter would clean:\n")
+		printStatusLines(untracked)
+	}
 
 	Print("")
 }
 
+// statusUntracked returns the untracked files which would be stored in Git LFS
+// if they were added, other than those --include and --exclude leave out.
+func statusUntracked() []statusLine {
+	paths, err := git.UntrackedLfsFiles()
+	if err != nil {
+		Panic(err, "Could not list untracked files")
+	}
+
+	var lines []statusLine
+	for _, path := range paths {
+		if !statusPathIncluded(path, "") {
+			continue
+		}
+
+		var size int64
+		if stat, err := os.Stat(filepath.Join(config.LocalWorkingDir, path)); err == nil {
+			size = stat.Size()
+		}
+		lines = append(lines, statusLine{path, size, fmt.Sprintf("\t%s", statusPath(path))})
+	}
+	return lines
+}
+
+// statusLine is a file listed in a section of the human readable status, with
+// the name and size it is sorted by.
+type statusLine struct {
+	name string
+	size int64
+	text string
+}
+
+// printStatusLines prints a section's lines in the order given by --sort, and
+// only as many as --limit allows, saying how many more there are.
+func printStatusLines(lines []statusLine) {
+	sortStatus(len(lines),
+		func(i int) string { return lines[i].name },
+		func(i int) int64 { return lines[i].size },
+		func(i, j int) { lines[i], lines[j] = lines[j], lines[i] })
+
+	shown := lines
+	if statusLimit > 0 && len(lines) > statusLimit {
+		shown = lines[:statusLimit]
+	}
+	for _, l := range shown {
+		Print("%s", l.text)
+	}
+	if hidden := len(lines) - len(shown); hidden > 0 {
+		Print("\t... and %d more not shown, see --limit", hidden)
+	}
+}
+
+// sortStatus sorts a list of n files by --sort: largest first for "size",
+// with files of the same size by path, or by path for "path". Without --sort,
+// the list is left in the order it was scanned in.
+func sortStatus(n int, name func(int) string, size func(int) int64, swap func(i, j int)) {
+	if len(statusSortArg) == 0 {
+		return
+	}
+	sort.Sort(&statusSorter{n: n, name: name, size: size, swap: swap})
+}
+
+type statusSorter struct {
+	n    int
+	name func(int) string
+	size func(int) int64
+	swap func(i, j int)
+}
+
+func (s *statusSorter) Len() int      { return s.n }
+func (s *statusSorter) Swap(i, j int) { s.swap(i, j) }
+func (s *statusSorter) Less(i, j int) bool {
+	if statusSortArg == "size" && s.size(i) != s.size(j) {
+		return s.size(i) > s.size(j)
+	}
+	return s.name(i) < s.name(j)
+}
+
+// setStatusRelativeDir sets the directory given by --relative-to, or the
+// current directory for --relative, for statusPath.
+func setStatusRelativeDir() {
+	if len(statusRelativeTo) > 0 && statusRelative {
+		Exit("--relative cannot be combined with --relative-to")
+	}
+
+	dir := statusRelativeTo
+	if statusRelative {
+		dir = "."
+	}
+	if len(dir) == 0 {
+		return
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		Exit("Invalid directory %q: %s", dir, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	statusRelativeDir = abs
+}
+
+// statusPath returns how a path relative to the root of the repository is
+// shown: relative to the --relative-to directory if one was given, with "../"
+// for paths outside it, or unchanged otherwise.
+func statusPath(name string) string {
+	if len(statusRelativeDir) == 0 || len(name) == 0 {
+		return name
+	}
+
+	root := config.LocalWorkingDir
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	rel, err := filepath.Rel(statusRelativeDir, filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return name
+	}
+	return filepath.ToSlash(rel)
+}
+
+// loadStatusLocks fetches every lock on the server for --show-lock-owner. If
+// the server can't be reached, status is shown without them.
+func loadStatusLocks() {
+	locks, err := findLocks(nil, 0)
+	if err != nil {
+		Error("Warning: could not get locks from the server, not showing lock owners: %s", err)
+		return
+	}
+
+	statusLocks = make(map[string]api.Lock, len(locks))
+	for _, lock := range locks {
+		statusLocks[filepath.ToSlash(lock.Path)] = lock
+	}
+}
+
+// statusLockOwner returns who holds the lock on the given file, to follow it
+// in the status output, or nothing if lock owners aren't being shown.
+func statusLockOwner(name string) string {
+	if statusLocks == nil {
+		return ""
+	}
+
+	lock, ok := statusLocks[name]
+	if !ok {
+		return "\t[unlocked]"
+	}
+
+	if isCommitterLock(lock, api.CurrentCommitter()) {
+		return "\t[locked by you]"
+	}
+	return fmt.Sprintf("\t[locked by %s <%s>]", lock.Committer.Name, lock.Committer.Email)
+}
+
+// statusPathIncluded returns whether a file passes --include and --exclude.
+// Renamed files are shown if either their old or new name passes.
+func statusPathIncluded(name, srcName string) bool {
+	if lfs.FilenamePassesIncludeExcludeFilter(name, statusIncludePaths, statusExcludePaths) {
+		return true
+	}
+	return len(srcName) > 0 && lfs.FilenamePassesIncludeExcludeFilter(srcName, statusIncludePaths, statusExcludePaths)
+}
+
+// statusAheadCommand reports how many Git LFS objects, and how many bytes, a
+// push to the given remote would upload: those referenced by commits which
+// aren't on any of its remote tracking branches, less those the server
+// already has, which are found with a batch API download check.
+func statusAheadCommand(remote string) {
+	if err := git.ValidateRemote(remote); err != nil {
+		Exit("Invalid remote name %q", remote)
+	}
+
+	pointers, err := lfs.ScanUnpushed(remote)
+	if err != nil {
+		Panic(err, "Could not scan for unpushed Git LFS objects")
+	}
+
+	unpushed := make([]*lfs.WrappedPointer, 0, len(pointers))
+	seen := tools.NewStringSetWithCapacity(len(pointers))
+	for _, p := range pointers {
+		if seen.Contains(p.Oid) || !statusPathIncluded(p.Name, "") {
+			continue
+		}
+		seen.Add(p.Oid)
+		unpushed = append(unpushed, p)
+	}
+
+	present := statusObjectsOnServer(remote, unpushed)
+
+	var count int
+	var size int64
+	for _, p := range unpushed {
+		if !present.Contains(p.Oid) {
+			count++
+			size += p.Size
+		}
+	}
+
+	if porcelain {
+		Print("%d %d", count, size)
+		return
+	}
+
+	Print("%d Git LFS objects (%s) to be pushed to %s", count, humanizeBytes(size), remote)
+	if already := len(unpushed) - count; already > 0 {
+		Print("%d more in unpushed commits are already on the server", already)
+	}
+}
+
+// statusObjectsOnServer returns which of the objects the remote's Git LFS
+// server already has. It exits if the server can't be asked.
+func statusObjectsOnServer(remote string, pointers []*lfs.WrappedPointer) tools.StringSet {
+	present := tools.NewStringSetWithCapacity(len(pointers))
+	if len(pointers) == 0 {
+		return present
+	}
+
+	cfg.CurrentRemote = remote
+	q := lfs.NewDownloadCheckQueue(0, 0)
+	watch := q.Watch()
+
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for oid := range watch {
+			present.Add(oid)
+		}
+	}()
+
+	for _, p := range pointers {
+		q.Add(lfs.NewDownloadable(p))
+	}
+	q.Wait()
+	wait.Wait()
+
+	for _, err := range q.Errors() {
+		// Objects the server doesn't have are reported individually
+		if _, ok := errutil.GetInnerError(err).(*api.ObjectError); ok {
+			continue
+		}
+		Exit("Could not check which objects are on %s: %s", remote, err)
+	}
+	return present
+}
+
+// statusDiffObject is the JSON representation of one side of a changed file
+type statusDiffObject struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// statusDiffFile is the JSON representation of a changed file
+type statusDiffFile struct {
+	Name    string            `json:"name"`
+	SrcName string            `json:"from,omitempty"`
+	Old     *statusDiffObject `json:"old,omitempty"`
+	New     *statusDiffObject `json:"new,omitempty"`
+}
+
+// statusDiff is the JSON output of `git lfs status <base>..<head> --json`
+type statusDiff struct {
+	Base     string            `json:"base"`
+	Head     string            `json:"head"`
+	Added    []*statusDiffFile `json:"added"`
+	Modified []*statusDiffFile `json:"modified"`
+	Removed  []*statusDiffFile `json:"removed"`
+	Renamed  []*statusDiffFile `json:"renamed"`
+}
+
+// statusDiffCommand reports the Git LFS objects that changed between the two
+// sides of a "<base>..<head>" range.
+func statusDiffCommand(rangeArg string) {
+	parts := strings.SplitN(rangeArg, "..", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		Exit("Invalid range %q, expected <base>..<head>", rangeArg)
+	}
+
+	base, err := git.ResolveRef(parts[0])
+	if err != nil {
+		Exit("Could not resolve %q: %s", parts[0], err)
+	}
+
+	head, err := git.ResolveRef(parts[1])
+	if err != nil {
+		Exit("Could not resolve %q: %s", parts[1], err)
+	}
+
+	diffs, err := lfs.ScanTreeDiff(base.Sha, head.Sha)
+	if err != nil {
+		Panic(err, "Could not scan for Git LFS objects")
+	}
+
+	out := &statusDiff{
+		Base:     base.Sha,
+		Head:     head.Sha,
+		Added:    make([]*statusDiffFile, 0),
+		Modified: make([]*statusDiffFile, 0),
+		Removed:  make([]*statusDiffFile, 0),
+		Renamed:  make([]*statusDiffFile, 0),
+	}
+
+	for _, d := range diffs {
+		if !statusPathIncluded(d.Name, d.SrcName) {
+			continue
+		}
+
+		f := &statusDiffFile{
+			Name:    statusPath(d.Name),
+			SrcName: statusPath(d.SrcName),
+			Old:     newStatusDiffObject(d.Old),
+			New:     newStatusDiffObject(d.New),
+		}
+
+		switch {
+		case d.Status == "R":
+			out.Renamed = append(out.Renamed, f)
+		case d.Old == nil:
+			out.Added = append(out.Added, f)
+		case d.New == nil:
+			out.Removed = append(out.Removed, f)
+		default:
+			out.Modified = append(out.Modified, f)
+		}
+	}
+
+	for _, files := range [][]*statusDiffFile{out.Added, out.Modified, out.Removed, out.Renamed} {
+		sortStatusDiffFiles(files)
+	}
+
+	if statusJson {
+		enc, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			Panic(err, "Could not encode Git LFS status")
+		}
+		os.Stdout.Write(append(enc, '\n'))
+		return
+	}
+
+	// The changes are one section, so that --sort orders them all
+	var lines []statusLine
+	for _, f := range out.Added {
+		lines = append(lines, statusLine{f.Name, f.size(), fmt.Sprintf("\tadded:    %s (%s)", f.Name, humanizeBytes(f.New.Size))})
+	}
+	for _, f := range out.Modified {
+		lines = append(lines, statusLine{f.Name, f.size(), fmt.Sprintf("\tmodified: %s (%s -> %s)", f.Name, humanizeBytes(f.Old.Size), humanizeBytes(f.New.Size))})
+	}
+	for _, f := range out.Removed {
+		lines = append(lines, statusLine{f.Name, f.size(), fmt.Sprintf("\tremoved:  %s (%s)", f.Name, humanizeBytes(f.Old.Size))})
+	}
+	for _, f := range out.Renamed {
+		lines = append(lines, statusLine{f.Name, f.size(), fmt.Sprintf("\trenamed:  %s -> %s", f.SrcName, f.Name)})
+	}
+
+	Print("Git LFS objects changed between %s and %s:\n", parts[0], parts[1])
+	printStatusLines(lines)
+}
+
+// size is the size of the file's new version, or of its old one if it was
+// removed, for --sort.
+func (f *statusDiffFile) size() int64 {
+	if f.New != nil {
+		return f.New.Size
+	}
+	if f.Old != nil {
+		return f.Old.Size
+	}
+	return 0
+}
+
+func sortStatusDiffFiles(files []*statusDiffFile) {
+	sortStatus(len(files),
+		func(i int) string { return files[i].Name },
+		func(i int) int64 { return files[i].size() },
+		func(i, j int) { files[i], files[j] = files[j], files[i] })
+}
+
+func newStatusDiffObject(p *lfs.WrappedPointer) *statusDiffObject {
+	if p == nil {
+		return nil
+	}
+	return &statusDiffObject{Oid: p.Oid, Size: p.Size}
+}
+
 var byteUnits = []string{"B", "KB", "MB", "GB", "TB"}
 
 func humanizeBytes(bytes int64) string {
@@ -102,5 +601,15 @@ func humanizeBytes(bytes int64) string {
 
 func init() {
 	statusCmd.Flags().BoolVarP(&porcelain, "porcelain", "p", false, "Give the output in an easy-to-parse format for scripts.")
+	statusCmd.Flags().BoolVarP(&statusJson, "json", "j", false, "Give the output of a <base>..<head> range as JSON.")
+	statusCmd.Flags().BoolVarP(&statusNoUntracked, "no-untracked", "", false, "Don't list untracked files which would be stored in Git LFS.")
+	statusCmd.Flags().StringVarP(&statusIncludeArg, "include", "I", "", "Only list paths matching these patterns.")
+	statusCmd.Flags().StringVarP(&statusExcludeArg, "exclude", "X", "", "Don't list paths matching these patterns.")
+	statusCmd.Flags().StringVarP(&statusRelativeTo, "relative-to", "", "", "Show paths relative to this directory.")
+	statusCmd.Flags().BoolVarP(&statusRelative, "relative", "", false, "Show paths relative to the current directory.")
+	statusCmd.Flags().StringVarP(&statusAheadArg, "ahead", "", "", "Count the Git LFS objects a push to this remote would upload.")
+	statusCmd.Flags().BoolVarP(&statusShowLocks, "show-lock-owner", "", false, "Show who has locked each file on the server.")
+	statusCmd.Flags().IntVarP(&statusLimit, "limit", "", 0, "Show at most this many files in each section.")
+	statusCmd.Flags().StringVarP(&statusSortArg, "sort", "", "", "Sort the files in each section by size (largest first) or path.")
 	RootCmd.AddCommand(statusCmd)
 }
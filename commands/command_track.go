@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -28,6 +30,12 @@ var (
 
 	trackVerboseLoggingFlag bool
 	trackDryRunFlag         bool
+	trackFilenameFlag       bool
+	trackPrintMatchFlag     bool
+
+	// printMatchAttributes are the attributes `git lfs track --print-match`
+	// reports, being those which decide how Git LFS treats a file.
+	printMatchAttributes = []string{"filter", "diff", "merge", "text", "lockable"}
 )
 
 func trackCommand(cmd *cobra.Command, args []string) {
@@ -41,10 +49,23 @@ func trackCommand(cmd *cobra.Command, args []string) {
 		os.Exit(128)
 	}
 
+	if trackPrintMatchFlag {
+		if len(args) == 0 {
+			Exit("Usage: git lfs track --print-match <path>...")
+		}
+		printAttributeMatches(args)
+		return
+	}
+
 	lfs.InstallHooks(false)
 	knownPaths := findPaths()
 
 	if len(args) == 0 {
+		if trackVerboseLoggingFlag {
+			listPatternsVerbose()
+			return
+		}
+
 		Print("Listing tracked paths")
 		for _, t := range knownPaths {
 			Print("    %s (%s)", t.Path, t.Source)
@@ -74,6 +95,10 @@ func trackCommand(cmd *cobra.Command, args []string) {
 
 ArgsLoop:
 	for _, pattern := range args {
+		if trackFilenameFlag {
+			pattern = escapeAttrPattern(pattern)
+		}
+
 		for _, known := range knownPaths {
 			if known.Path == filepath.Join(relpath, pattern) {
 				Print("%s already supported", pattern)
@@ -142,12 +167,54 @@ ArgsLoop:
 }
 
 type mediaPath struct {
-	Path   string
-	Source string
+	Path     string
+	Source   string
+	Line     int
+	Lockable bool
+
+	// Tracked is false for lines which unset the filter attribute, or set it
+	// to something other than "lfs".
+	Tracked bool
+
+	// InfoAttributes is true for lines in the repository's info/attributes,
+	// whose patterns are relative to the repository root.
+	InfoAttributes bool
+
+	// precedence orders lines the way Git resolves attributes: the
+	// repository's info/attributes wins over any .gitattributes, deeper
+	// .gitattributes win over shallower ones, and later lines in a file win
+	// over earlier ones.
+	precedence int64
+}
+
+// attrPrecedence returns the precedence of a line in an attributes file at the
+// given depth below the repository root.
+func attrPrecedence(depth, line int) int64 {
+	return int64(depth)<<32 + int64(line)
 }
 
+func (p mediaPath) location() string {
+	return fmt.Sprintf("%s:%d", p.Source, p.Line)
+}
+
+// findPaths returns the Git LFS patterns from every attributes file in the
+// repository.
 func findPaths() []mediaPath {
 	paths := make([]mediaPath, 0)
+	for _, p := range findFilterPatterns() {
+		if p.Tracked {
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
+// findFilterPatterns returns every attributes line which sets, unsets, or
+// changes the filter attribute, in the order they appear on disk.
+func findFilterPatterns() []mediaPath {
+	paths := make([]mediaPath, 0)
+	repoAttributes := filepath.Join(config.LocalGitDir, "info", "attributes")
 
 	for _, path := range findAttributeFiles() {
 		attributes, err := os.Open(path)
@@ -155,26 +222,255 @@ func findPaths() []mediaPath {
 			continue
 		}
 
+		relfile, _ := filepath.Rel(config.LocalWorkingDir, path)
+		reldir := filepath.Dir(relfile)
+
+		depth := 1 << 20
+		if path != repoAttributes {
+			depth = 0
+			if reldir != "." {
+				depth = strings.Count(reldir, string(filepath.Separator)) + 1
+			}
+		}
+
 		scanner := bufio.NewScanner(attributes)
+		lineno := 0
 
 		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.Contains(line, "filter=lfs") {
-				fields := strings.Fields(line)
-				relfile, _ := filepath.Rel(config.LocalWorkingDir, path)
-				pattern := fields[0]
-				if reldir := filepath.Dir(relfile); len(reldir) > 0 {
-					pattern = filepath.Join(reldir, pattern)
+			lineno++
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			mp := mediaPath{Source: relfile, Line: lineno, InfoAttributes: path == repoAttributes, precedence: attrPrecedence(depth, lineno)}
+			hasFilter := false
+
+			for _, attr := range fields[1:] {
+				switch {
+				case attr == "filter=lfs":
+					hasFilter = true
+					mp.Tracked = true
+				case attr == "-filter", attr == "!filter", strings.HasPrefix(attr, "filter="):
+					hasFilter = true
+					mp.Tracked = false
+				case attr == "lockable":
+					mp.Lockable = true
 				}
+			}
+
+			if !hasFilter {
+				continue
+			}
 
-				paths = append(paths, mediaPath{Path: pattern, Source: relfile})
+			mp.Path = fields[0]
+			if path != repoAttributes && reldir != "." {
+				mp.Path = filepath.Join(reldir, mp.Path)
 			}
+
+			paths = append(paths, mp)
 		}
+
+		attributes.Close()
 	}
 
 	return paths
 }
 
+// listPatternsVerbose prints every Git LFS pattern with the file and line it
+// comes from, highest precedence first, noting any pattern which a later or
+// deeper attributes line overrides.
+func listPatternsVerbose() {
+	patterns := findFilterPatterns()
+	sort.Stable(byPrecedence(patterns))
+
+	Print("Listing tracked patterns, highest precedence first")
+	for i, p := range patterns {
+		if !p.Tracked {
+			continue
+		}
+
+		details := []string{p.location()}
+		if p.Lockable {
+			details = append(details, "lockable")
+		}
+
+		for _, winner := range patterns[:i] {
+			if winner.Path != p.Path {
+				continue
+			}
+
+			if winner.Tracked {
+				details = append(details, "overridden by "+winner.location())
+			} else {
+				details = append(details, "untracked by "+winner.location())
+			}
+			break
+		}
+
+		Print("    %s (%s)", p.Path, strings.Join(details, ", "))
+	}
+}
+
+// printAttributeMatches prints the attributes Git resolves for each of the
+// given paths, with the attributes file and line each value comes from.
+func printAttributeMatches(paths []string) {
+	wd, _ := os.Getwd()
+
+	for _, p := range paths {
+		values, err := git.CheckAttributes(p, printMatchAttributes...)
+		if err != nil {
+			Exit("Error checking the attributes of %s: %s", p, err)
+		}
+
+		abs := p
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(wd, p)
+		}
+		relpath, err := filepath.Rel(config.LocalWorkingDir, abs)
+		if err != nil {
+			Exit("Path %q is outside of git working directory %q.", p, config.LocalWorkingDir)
+		}
+		sources := findAttributeSources(filepath.ToSlash(relpath))
+
+		Print("%s", p)
+		for _, attr := range printMatchAttributes {
+			value := values[attr]
+			if source, ok := sources[attr]; ok {
+				Print("    %s: %s (%s)", attr, value, source.location())
+			} else if value == "unspecified" {
+				Print("    %s: %s", attr, value)
+			} else {
+				Print("    %s: %s (from attributes outside the repository)", attr, value)
+			}
+		}
+	}
+}
+
+// findAttributeSources returns, for each attribute that lines in the
+// repository's attributes files give the path, the line which wins. The path
+// is relative to the repository root, with forward slashes.
+func findAttributeSources(path string) map[string]mediaPath {
+	sources := make(map[string]mediaPath)
+	repoAttributes := filepath.Join(config.LocalGitDir, "info", "attributes")
+
+	for _, file := range findAttributeFiles() {
+		attributes, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+
+		relfile, _ := filepath.Rel(config.LocalWorkingDir, file)
+		reldir := filepath.ToSlash(filepath.Dir(relfile))
+
+		depth := 1 << 20
+		if file == repoAttributes {
+			reldir = "."
+		} else {
+			depth = 0
+			if reldir != "." {
+				depth = strings.Count(reldir, "/") + 1
+			}
+		}
+
+		scanner := bufio.NewScanner(attributes)
+		lineno := 0
+
+		for scanner.Scan() {
+			lineno++
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			if !attrPatternMatches(fields[0], reldir, path) {
+				continue
+			}
+
+			mp := mediaPath{Path: fields[0], Source: relfile, Line: lineno, InfoAttributes: file == repoAttributes, precedence: attrPrecedence(depth, lineno)}
+			for _, attr := range fields[1:] {
+				names := []string{strings.TrimLeft(strings.SplitN(attr, "=", 2)[0], "-!")}
+				if attr == "binary" {
+					// the binary macro unsets diff, merge and text
+					names = []string{"binary", "diff", "merge", "text"}
+				}
+
+				for _, name := range names {
+					if winner, ok := sources[name]; !ok || mp.precedence >= winner.precedence {
+						sources[name] = mp
+					}
+				}
+			}
+		}
+
+		attributes.Close()
+	}
+
+	return sources
+}
+
+// attrPatternMatches reports whether a pattern from an attributes file in the
+// directory dir matches file, both relative to the repository root. As in Git,
+// a pattern without a slash matches the file's name at any depth below dir,
+// and one with a slash matches its path relative to dir, where "*" and "?"
+// don't match a slash. A leading "**/" matches in any directory, a trailing
+// "/**" matches everything inside a directory, and "/**/" matches zero or more
+// directories.
+func attrPatternMatches(pattern, dir, file string) bool {
+	pattern = strings.Replace(pattern, "[[:space:]]", " ", -1)
+
+	if dir != "." {
+		if !strings.HasPrefix(file, dir+"/") {
+			return false
+		}
+		file = strings.TrimPrefix(file, dir+"/")
+	}
+
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(file))
+		return matched
+	}
+
+	return attrSegmentsMatch(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(file, "/"))
+}
+
+// attrSegmentsMatch reports whether the slash separated segments of a pattern
+// match those of a file's path, where a "**" segment matches any number of
+// them.
+func attrSegmentsMatch(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(segments) > 0
+			}
+			for i := 0; i <= len(segments); i++ {
+				if attrSegmentsMatch(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
+
+type byPrecedence []mediaPath
+
+func (p byPrecedence) Len() int           { return len(p) }
+func (p byPrecedence) Less(i, j int) bool { return p[i].precedence > p[j].precedence }
+func (p byPrecedence) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
 func findAttributeFiles() []string {
 	paths := make([]string, 0)
 
@@ -219,6 +515,29 @@ func needsTrailingLinebreak(filename string) bool {
 	return !strings.HasSuffix(string(buf[0:bytesRead]), "\n")
 }
 
+// escapeAttrPattern escapes the glob characters in the given file-name, so that
+// it can be written to a .gitattributes file as a pattern matching only that
+// file. A leading "!" or "#" is escaped too, since it would otherwise make the
+// line a negative pattern or a comment.
+func escapeAttrPattern(name string) string {
+	name = filepath.ToSlash(name)
+
+	escaped := make([]rune, 0, len(name))
+	for i, c := range name {
+		switch c {
+		case '\\', '*', '?', '[', ']':
+			escaped = append(escaped, '\\')
+		case '!', '#':
+			if i == 0 {
+				escaped = append(escaped, '\\')
+			}
+		}
+		escaped = append(escaped, c)
+	}
+
+	return string(escaped)
+}
+
 // blocklistItem returns the name of the blocklist item preventing the given
 // file-name from being tracked, or an empty string, if there is none.
 func blocklistItem(name string) string {
@@ -234,8 +553,10 @@ func blocklistItem(name string) string {
 }
 
 func init() {
-	trackCmd.Flags().BoolVarP(&trackVerboseLoggingFlag, "verbose", "v", false, "log which files are being tracked and modified")
+	trackCmd.Flags().BoolVarP(&trackVerboseLoggingFlag, "verbose", "v", false, "log which files are being tracked and modified, or list patterns with their sources")
 	trackCmd.Flags().BoolVarP(&trackDryRunFlag, "dry-run", "d", false, "preview results of running `git lfs track`")
+	trackCmd.Flags().BoolVarP(&trackFilenameFlag, "filename", "", false, "treat the arguments as literal file names, not patterns")
+	trackCmd.Flags().BoolVarP(&trackPrintMatchFlag, "print-match", "", false, "print the attributes Git resolves for the given paths, and where they come from")
 
 	RootCmd.AddCommand(trackCmd)
 }
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttrPatternMatches(t *testing.T) {
+	tests := []struct {
+		Pattern string
+		Dir     string
+		Path    string
+		Matches bool
+	}{
+		{"*.psd", ".", "art/x.psd", true},
+		{"*.psd", "art", "art/raw/x.psd", true},
+		{"*.psd", "art", "x.psd", false},
+		{"/top.dat", ".", "top.dat", true},
+		{"/top.dat", ".", "q/top.dat", false},
+		{"sub/*.txt", ".", "sub/a.txt", true},
+		{"sub/*.txt", ".", "sub/deep/a.txt", false},
+		{"**/c.dat", ".", "c.dat", true},
+		{"**/c.dat", ".", "q/r/c.dat", true},
+		{"dir/**", ".", "dir/x", true},
+		{"dir/**", ".", "dir/y/z", true},
+		{"dir/**", ".", "dir", false},
+		{"a/**/b", ".", "a/b", true},
+		{"a/**/b", ".", "a/x/b", true},
+		{"a/**/b", ".", "a/x/y/b", true},
+		{"a/**/b", ".", "a/bb", false},
+		{"a/**/b", "art", "art/a/x/b", true},
+		{"my[[:space:]]file.dat", ".", "my file.dat", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.Matches, attrPatternMatches(test.Pattern, test.Dir, test.Path),
+			"%q in %q against %q", test.Pattern, test.Dir, test.Path)
+	}
+}
@@ -36,19 +36,24 @@ func unlockCommand(cmd *cobra.Command, args []string) {
 	setLockRemoteFor(cfg)
 
 	var id string
-	if len(args) != 0 {
+	if unlockCmdFlags.Id != "" {
+		if len(args) != 0 {
+			Exit("Usage: git lfs unlock (--id my-lock-id | <path>)")
+		}
+		// The lock ID is enough for the server, so the path it was
+		// taken on doesn't have to exist anymore
+		id = unlockCmdFlags.Id
+	} else if len(args) != 0 {
 		path, err := lockPath(args[0])
 		if err != nil {
-			Error(err.Error())
+			Exit("Unable to unlock %s: %s", args[0], err)
 		}
 
 		if id, err = lockIdFromPath(path); err != nil {
-			Error(err.Error())
+			Exit("Unable to unlock %s: %s", args[0], err)
 		}
-	} else if unlockCmdFlags.Id != "" {
-		id = unlockCmdFlags.Id
 	} else {
-		Error("Usage: git lfs unlock (--id my-lock-id | <path>)")
+		Exit("Usage: git lfs unlock (--id my-lock-id | <path>)")
 	}
 
 	s, resp := API.Locks.Unlock(id, unlockCmdFlags.Force)
@@ -63,7 +68,7 @@ func unlockCommand(cmd *cobra.Command, args []string) {
 		Exit("Server unable to unlock lock.")
 	}
 
-	Print("'%s' was unlocked (%s)", args[0], resp.Lock.Id)
+	Print("'%s' was unlocked (%s)", resp.Lock.Path, resp.Lock.Id)
 }
 
 // lockIdFromPath makes a call to the LFS API and resolves the ID for the locked
@@ -2,11 +2,14 @@ package commands
 
 import (
 	"bufio"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/lfs"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +19,8 @@ var (
 		Use: "untrack",
 		Run: untrackCommand,
 	}
+
+	untrackDryRunFlag bool
 )
 
 // untrackCommand takes a list of paths as an argument, and removes each path from the
@@ -42,6 +47,11 @@ func untrackCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if untrackDryRunFlag {
+		untrackDryRun(string(data), args)
+		return
+	}
+
 	attributes := strings.NewReader(string(data))
 
 	attributesFile, err := os.Create(".gitattributes")
@@ -81,6 +91,109 @@ func removePath(path string, args []string) bool {
 	return false
 }
 
+// untrackDryRun prints the lines of .gitattributes that untracking the given
+// paths would remove, and the files Git knows about which would stop being Git
+// LFS files, without changing anything. Files which another pattern still
+// tracks are listed separately, with the line that tracks them.
+func untrackDryRun(data string, args []string) {
+	wd, _ := os.Getwd()
+	source, _ := filepath.Rel(config.LocalWorkingDir, filepath.Join(wd, ".gitattributes"))
+
+	removed := make(map[int]bool)
+	var patterns []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if !strings.Contains(line, "filter=lfs") {
+			continue
+		}
+
+		path := strings.Fields(line)[0]
+		if removePath(path, args) {
+			Print("Would remove %s:%d: %s", source, lineno, line)
+			removed[lineno] = true
+			patterns = append(patterns, path)
+		}
+	}
+
+	if len(patterns) == 0 {
+		Print("Nothing to untrack")
+		return
+	}
+
+	remaining := make([]mediaPath, 0)
+	for _, p := range findFilterPatterns() {
+		if p.Source != source || !removed[p.Line] {
+			remaining = append(remaining, p)
+		}
+	}
+
+	var untracked, stillTracked []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		files, err := git.GetTrackedFiles(pattern)
+		if err != nil {
+			LoggedError(err, "Error getting git tracked files")
+			continue
+		}
+
+		for _, f := range files {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+
+			relpath, err := filepath.Rel(config.LocalWorkingDir, filepath.Join(wd, f))
+			if err != nil {
+				continue
+			}
+			if winner, ok := untrackWinningPattern(remaining, filepath.ToSlash(relpath)); ok && winner.Tracked {
+				stillTracked = append(stillTracked, fmt.Sprintf("%s (%s at %s)", f, winner.Path, winner.location()))
+			} else {
+				untracked = append(untracked, f)
+			}
+		}
+	}
+
+	if len(untracked) > 0 {
+		Print("Files which would no longer be Git LFS files:")
+		for _, f := range untracked {
+			Print("    %s", f)
+		}
+	}
+	if len(stillTracked) > 0 {
+		Print("Files which stay tracked by another pattern:")
+		for _, f := range stillTracked {
+			Print("    %s", f)
+		}
+	}
+}
+
+// untrackWinningPattern returns the filter line which Git would apply to the
+// path, relative to the repository root, out of the given lines, if any match.
+func untrackWinningPattern(patterns []mediaPath, path string) (mediaPath, bool) {
+	var winner mediaPath
+	found := false
+	for _, p := range patterns {
+		dir := "."
+		pattern := filepath.ToSlash(p.Path)
+		if !p.InfoAttributes {
+			// relative to its .gitattributes
+			dir = filepath.ToSlash(filepath.Dir(p.Source))
+			if dir != "." {
+				pattern = strings.TrimPrefix(pattern, dir+"/")
+			}
+		}
+
+		if attrPatternMatches(pattern, dir, path) && (!found || p.precedence >= winner.precedence) {
+			winner = p
+			found = true
+		}
+	}
+	return winner, found
+}
+
 func init() {
+	untrackCmd.Flags().BoolVarP(&untrackDryRunFlag, "dry-run", "d", false, "preview results of running `git lfs untrack`")
 	RootCmd.AddCommand(untrackCmd)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -17,7 +18,9 @@ import (
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/httputil"
 	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/localstorage"
 	"github.com/github/git-lfs/tools"
+	"github.com/github/git-lfs/transfer"
 	"github.com/spf13/cobra"
 )
 
@@ -42,6 +45,11 @@ var (
 	}
 	ManPages = make(map[string]string, 20)
 	cfg      = config.Config
+
+	transferFailFastArg  bool
+	transferKeepGoingArg bool
+	quietArg             bool
+	httpStatsArg         bool
 )
 
 // Error prints a formatted message to Stderr.  It also gets printed to the
@@ -106,8 +114,26 @@ func Panic(err error, format string, args ...interface{}) {
 }
 
 func Run() {
+	if err := localstorage.CheckTempDir(); err != nil {
+		Exit("%s", err)
+	}
+
+	cfg.OnLfsConfigError(func(err error) {
+		Exit("%s", err)
+	})
+
 	RootCmd.Execute()
 	httputil.LogHttpStats(cfg)
+	httputil.PrintStatsReport(os.Stderr)
+}
+
+// warnLfsConfigErrors only warns about .lfsconfig values which couldn't be
+// interpolated, instead of exiting, for the filters, which Git runs for each
+// file and which mostly don't need them.
+func warnLfsConfigErrors() {
+	cfg.OnLfsConfigError(func(err error) {
+		Error("WARNING: %s", err)
+	})
 }
 
 func Cleanup() {
@@ -163,7 +189,7 @@ func logPanic(loggedError error) string {
 	var fmtWriter io.Writer = os.Stderr
 
 	now := time.Now()
-	name := now.Format("20060102T150405.999999999")
+	name := now.Format(logTimeFormat)
 	full := filepath.Join(config.LocalLogDir, name+".log")
 
 	if err := os.MkdirAll(config.LocalLogDir, 0755); err != nil {
@@ -238,6 +264,84 @@ func determineIncludeExcludePaths(config *config.Configuration, includeArg, excl
 		tools.CleanPathsDefault(excludeArg, ",", config.FetchExcludePaths())
 }
 
+// addTransferFailureFlags adds --fail-fast and --keep-going to a command which
+// transfers objects.
+func addTransferFailureFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&transferFailFastArg, "fail-fast", "", false, "Stop transferring after the first error")
+	cmd.Flags().BoolVarP(&transferKeepGoingArg, "keep-going", "", false, "Attempt every transfer, and report all errors at the end")
+}
+
+// addHttpStatsFlag adds --stats to a command which transfers objects.
+func addHttpStatsFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&httpStatsArg, "stats", "", false, "Print how long HTTP requests spent in each phase at the end")
+}
+
+// setupHttpStats starts collecting HTTP timings for --stats, which Run prints
+// once the command is done.
+func setupHttpStats() {
+	if httpStatsArg {
+		httputil.EnableStatsReport()
+	}
+}
+
+// addQuietFlag adds --quiet to a command, which silences its progress and
+// informational output, leaving only errors. GIT_LFS_QUIET has the same
+// effect.
+func addQuietFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&quietArg, "quiet", "q", false, "Only print errors, without progress")
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		setupQuiet()
+	}
+}
+
+// setupQuiet discards everything printed with Print, including spinners, when
+// --quiet or GIT_LFS_QUIET is set, and sets GIT_LFS_QUIET so that progress
+// meters and any git-lfs subprocesses are quiet too.
+func setupQuiet() {
+	if quietArg {
+		cfg.Setenv("GIT_LFS_QUIET", "1")
+	}
+	if cfg.Quiet() {
+		OutputWriter = ioutil.Discard
+	}
+}
+
+// transferFailFast returns whether transfer queues should stop after their
+// first error, from --fail-fast or --keep-going, or else lfs.transfer.failfast.
+func transferFailFast() bool {
+	if transferFailFastArg && transferKeepGoingArg {
+		Exit("Cannot combine --fail-fast and --keep-going")
+	}
+
+	if transferFailFastArg || transferKeepGoingArg {
+		return transferFailFastArg
+	}
+
+	return cfg.TransferFailFast()
+}
+
+// reportTransferErrors prints every error from the finished queue, and how
+// many transfers it skipped after stopping at the first error, if any. It
+// returns the errors.
+func reportTransferErrors(q *lfs.TransferQueue) []error {
+	for _, err := range q.Errors() {
+		if Debugging || errutil.IsFatalError(err) {
+			LoggedError(err, "%s", err.Error())
+		} else {
+			if inner := errutil.GetInnerError(err); inner != nil {
+				Error("%s", inner.Error())
+			}
+			Error("%s", err.Error())
+		}
+	}
+
+	if n := q.Aborted(); n > 0 {
+		Error("Stopped after the first failed transfer, skipping %d more (use --keep-going to attempt every transfer)", n)
+	}
+
+	return q.Errors()
+}
+
 func printHelp(commandName string) {
 	if txt, ok := ManPages[commandName]; ok {
 		fmt.Fprintf(os.Stderr, "%s\n", strings.TrimSpace(txt))
@@ -279,4 +383,8 @@ func init() {
 	RootCmd.SetHelpFunc(help)
 	RootCmd.SetHelpTemplate("{{.UsageString}}")
 	RootCmd.SetUsageFunc(usage)
+
+	if isCommandEnabled(cfg, "chunked") {
+		transfer.EnableChunkedTransfers()
+	}
 }
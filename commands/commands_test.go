@@ -52,3 +52,15 @@ func TestCommandEnabledDisabledByDefault(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, isCommandEnabled(cfg, "locks"))
 }
+
+func TestEscapeAttrPattern(t *testing.T) {
+	assert.Equal(t, "a.dat", escapeAttrPattern("a.dat"))
+	assert.Equal(t, `foo\[1\].bin`, escapeAttrPattern("foo[1].bin"))
+	assert.Equal(t, `\*.bin`, escapeAttrPattern("*.bin"))
+	assert.Equal(t, `what\?.bin`, escapeAttrPattern("what?.bin"))
+	assert.Equal(t, `back\\slash.bin`, escapeAttrPattern(`back\slash.bin`))
+	assert.Equal(t, `\!bang.bin`, escapeAttrPattern("!bang.bin"))
+	assert.Equal(t, `\#hash.bin`, escapeAttrPattern("#hash.bin"))
+	assert.Equal(t, "mid!dle#.bin", escapeAttrPattern("mid!dle#.bin"))
+	assert.Equal(t, `dir/\[a\]/b\*.bin`, escapeAttrPattern("dir/[a]/b*.bin"))
+}
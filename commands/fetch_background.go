@@ -0,0 +1,266 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/subprocess"
+)
+
+// backgroundFetchEnv is set in the environment of the detached process which
+// `git lfs fetch --background` starts, telling it to supervise the fetch
+// rather than start another.
+const backgroundFetchEnv = "GIT_LFS_BACKGROUND_FETCH"
+
+// backgroundFetchStartTimeout is how long a lock without a pid is taken to
+// belong to a background fetch which is still starting.
+const backgroundFetchStartTimeout = time.Minute
+
+// backgroundFetchState is recorded in .git/lfs/fetch-background.json by the
+// process supervising a background fetch, when it starts and when it ends.
+type backgroundFetchState struct {
+	Pid      int       `json:"pid"`
+	Args     []string  `json:"args"`
+	State    string    `json:"state"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+func backgroundFetchPath(name string) string {
+	return filepath.Join(config.LocalGitStorageDir, "lfs", name)
+}
+
+func backgroundFetchLockPath() string {
+	return backgroundFetchPath("fetch-background.lock")
+}
+
+func backgroundFetchStatePath() string {
+	return backgroundFetchPath("fetch-background.json")
+}
+
+func backgroundFetchLogPath() string {
+	return backgroundFetchPath("fetch-background.log")
+}
+
+// startBackgroundFetch takes the background fetch lock, and starts a detached
+// copy of this command to supervise the fetch, with its output going to
+// .git/lfs/fetch-background.log. Only one background fetch runs at a time.
+func startBackgroundFetch() {
+	if err := createBackgroundFetchLock(); err != nil {
+		Exit("%s", err)
+	}
+
+	logFile, err := os.OpenFile(backgroundFetchLogPath(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		os.Remove(backgroundFetchLockPath())
+		Exit("Could not create the background fetch log: %s", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), backgroundFetchEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	subprocess.Detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		os.Remove(backgroundFetchLockPath())
+		Exit("Could not start a background fetch: %s", err)
+	}
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+
+	Print("Fetching in the background (pid %d), logging to %s", pid, backgroundFetchLogPath())
+	Print("Run 'git lfs fetch --status' to check on it.")
+}
+
+// createBackgroundFetchLock creates the background fetch lock file, replacing
+// one left behind by a background fetch which is no longer running.
+func createBackgroundFetchLock() error {
+	path := backgroundFetchLockPath()
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return f.Close()
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("Could not create %s: %s", path, err)
+		}
+
+		if pid, running := backgroundFetchRunning(); running {
+			if pid > 0 {
+				return fmt.Errorf("A background fetch is already running (pid %d), see 'git lfs fetch --status'", pid)
+			}
+			return fmt.Errorf("A background fetch is already starting, see 'git lfs fetch --status'")
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Could not remove stale %s: %s", path, err)
+		}
+	}
+}
+
+// backgroundFetchRunning returns the pid in the background fetch lock, and
+// whether that process is still running. A lock with no pid yet belongs to a
+// fetch which is starting, unless it's too old for that.
+func backgroundFetchRunning() (int, bool) {
+	path := backgroundFetchLockPath()
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+
+	by, _ := ioutil.ReadFile(path)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(by)))
+	if err != nil || pid <= 0 {
+		return 0, time.Since(stat.ModTime()) < backgroundFetchStartTimeout
+	}
+	return pid, subprocess.ProcessRunning(pid)
+}
+
+// superviseBackgroundFetch runs in the process detached by
+// startBackgroundFetch. It runs the fetch itself as a child, so that however
+// the fetch ends, the outcome is recorded for `git lfs fetch --status`, temp
+// objects are cleaned up, and the lock is removed.
+func superviseBackgroundFetch() {
+	args := backgroundFetchArgs()
+	state := &backgroundFetchState{
+		Pid:     os.Getpid(),
+		Args:    args,
+		State:   "running",
+		Started: time.Now(),
+	}
+
+	if err := ioutil.WriteFile(backgroundFetchLockPath(), []byte(strconv.Itoa(state.Pid)), 0644); err != nil {
+		Error("Could not write %s: %s", backgroundFetchLockPath(), err)
+	}
+	writeBackgroundFetchState(state)
+
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, backgroundFetchEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(env, "GIT_LFS_FORCE_PROGRESS=1")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+
+	if cerr := lfs.ClearTempObjects(); cerr != nil {
+		Error("Error clearing old temp files: %s", cerr)
+	}
+
+	state.Finished = time.Now()
+	if err != nil {
+		state.State = "failed"
+		state.Error = err.Error()
+	} else {
+		state.State = "succeeded"
+	}
+	writeBackgroundFetchState(state)
+	os.Remove(backgroundFetchLockPath())
+
+	if err != nil {
+		os.Exit(2)
+	}
+}
+
+// backgroundFetchArgs returns the arguments this command was run with, less
+// --background, for the fetch run by the background process.
+func backgroundFetchArgs() []string {
+	args := make([]string, 0, len(os.Args)-1)
+	for _, arg := range os.Args[1:] {
+		if arg == "--background" || strings.HasPrefix(arg, "--background=") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// writeBackgroundFetchState replaces the state file by renaming, so that
+// `git lfs fetch --status` never reads one which is only partly written.
+func writeBackgroundFetchState(state *backgroundFetchState) {
+	path := backgroundFetchStatePath()
+	by, err := json.Marshal(state)
+	if err == nil {
+		err = ioutil.WriteFile(path+".tmp", by, 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		Error("Could not record the background fetch state: %s", err)
+	}
+}
+
+func readBackgroundFetchState() (*backgroundFetchState, error) {
+	by, err := ioutil.ReadFile(backgroundFetchStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	state := &backgroundFetchState{}
+	if err := json.Unmarshal(by, state); err != nil {
+		return nil, fmt.Errorf("Invalid %s: %s", backgroundFetchStatePath(), err)
+	}
+	return state, nil
+}
+
+// backgroundFetchStatus reports on the latest background fetch. It exits
+// non-zero if that fetch failed, or stopped without recording how it ended.
+func backgroundFetchStatus() {
+	pid, running := backgroundFetchRunning()
+	state, err := readBackgroundFetchState()
+	if err != nil {
+		Exit("%s", err)
+	}
+
+	if running && pid == 0 {
+		Print("A background fetch is starting")
+		return
+	}
+
+	if state == nil {
+		Print("No background fetch has been run")
+		return
+	}
+
+	command := "git lfs " + strings.Join(state.Args, " ")
+	switch {
+	case running:
+		Print("Background fetch running (pid %d) since %s: %s", pid, state.Started.Format(time.RFC3339), command)
+	case state.State == "succeeded":
+		Print("Background fetch succeeded at %s: %s", state.Finished.Format(time.RFC3339), command)
+	case state.State == "failed":
+		Print("Background fetch failed at %s: %s", state.Finished.Format(time.RFC3339), command)
+	default:
+		Print("Background fetch (pid %d) stopped unexpectedly: %s", state.Pid, command)
+	}
+	Print("Log: %s", backgroundFetchLogPath())
+
+	switch {
+	case running, state.State == "succeeded":
+		return
+	case state.State == "failed":
+		Exit("Error: %s", state.Error)
+	default:
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/github/git-lfs/progress"
+)
+
+// fsckBufferSize is the size of the buffer each fsck worker streams objects
+// through while hashing them, so memory use stays flat however large the
+// objects are.
+const fsckBufferSize = 64 * 1024
+
+// fsckObject is a local object to be verified by fsck, and the result of
+// verifying it.
+type fsckObject struct {
+	Oid  string
+	Name string
+	Path string
+	Size int64
+	// Recalculated is the SHA-256 of the object's content, once verified.
+	Recalculated string
+	// Err is the error reading the object, if it couldn't be verified.
+	Err error
+}
+
+type fsckObjectsByName []*fsckObject
+
+func (o fsckObjectsByName) Len() int           { return len(o) }
+func (o fsckObjectsByName) Less(i, j int) bool { return o[i].Name < o[j].Name }
+func (o fsckObjectsByName) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+
+// fsckVerifyObjects hashes each of objects across a pool of up to concurrency
+// workers, setting their Recalculated and Err fields. Each worker reuses a
+// single buffer of fsckBufferSize bytes. meter may be nil.
+func fsckVerifyObjects(objects []*fsckObject, concurrency int, meter *fsckMeter) {
+	if concurrency > len(objects) {
+		concurrency = len(objects)
+	}
+
+	objc := make(chan *fsckObject, len(objects))
+	for _, obj := range objects {
+		objc <- obj
+	}
+	close(objc)
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+
+			buf := make([]byte, fsckBufferSize)
+			for obj := range objc {
+				obj.Recalculated, obj.Err = fsckHashObject(obj.Path, buf, meter)
+				meter.FinishObject()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// fsckHashObject returns the hex-encoded SHA-256 of the file at path, reading
+// it through buf.
+func fsckHashObject(path string, buf []byte, meter *fsckMeter) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// Read through buf by hand, rather than with io.CopyBuffer, which skips
+	// the buffer for readers implementing io.WriterTo.
+	oidHash := sha256.New()
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			oidHash.Write(buf[:n])
+			meter.AddBytes(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(oidHash.Sum(nil)), nil
+}
+
+// fsckMeter reports the number of objects and bytes verified so far on
+// standard error. A nil *fsckMeter reports nothing.
+type fsckMeter struct {
+	bytes        int64 // int64s must come first for struct alignment
+	totalBytes   int64
+	objects      int32
+	totalObjects int32
+	spinner      *progress.Spinner
+	finished     chan struct{}
+	wg           sync.WaitGroup
+}
+
+// newFsckMeter starts a meter for verifying totalObjects objects of totalBytes
+// bytes in all, returning nil if there is nothing to verify.
+func newFsckMeter(totalObjects int, totalBytes int64) *fsckMeter {
+	if totalObjects == 0 {
+		return nil
+	}
+
+	m := &fsckMeter{
+		totalBytes:   totalBytes,
+		totalObjects: int32(totalObjects),
+		spinner:      progress.NewSpinner(),
+		finished:     make(chan struct{}),
+	}
+
+	m.wg.Add(1)
+	go m.writer()
+	return m
+}
+
+func (m *fsckMeter) AddBytes(n int) {
+	if m != nil {
+		atomic.AddInt64(&m.bytes, int64(n))
+	}
+}
+
+func (m *fsckMeter) FinishObject() {
+	if m != nil {
+		atomic.AddInt32(&m.objects, 1)
+	}
+}
+
+// Finish stops the meter, and prints the totals verified.
+func (m *fsckMeter) Finish() {
+	if m == nil {
+		return
+	}
+
+	close(m.finished)
+	m.wg.Wait()
+	m.spinner.Finish(os.Stderr, fmt.Sprintf("Verified %d of %d objects (%s)",
+		atomic.LoadInt32(&m.objects), m.totalObjects, humanizeBytes(atomic.LoadInt64(&m.bytes))))
+}
+
+func (m *fsckMeter) writer() {
+	defer m.wg.Done()
+
+	for {
+		m.spinner.Print(os.Stderr, fmt.Sprintf("Verifying objects: %d of %d (%s of %s)",
+			atomic.LoadInt32(&m.objects), m.totalObjects,
+			humanizeBytes(atomic.LoadInt64(&m.bytes)), humanizeBytes(m.totalBytes)))
+
+		select {
+		case <-m.finished:
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
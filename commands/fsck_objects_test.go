@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFsckObject(t testing.TB, dir, name string, size int) *fsckObject {
+	content := make([]byte, size)
+	rand.Read(content)
+
+	sum := sha256.Sum256(content)
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return &fsckObject{
+		Oid:  hex.EncodeToString(sum[:]),
+		Name: name,
+		Path: path,
+		Size: int64(size),
+	}
+}
+
+func TestFsckVerifyObjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsck-objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := writeFsckObject(t, dir, "good", 3*fsckBufferSize+17)
+	empty := writeFsckObject(t, dir, "empty", 0)
+	corrupt := writeFsckObject(t, dir, "corrupt", 100)
+	ioutil.WriteFile(corrupt.Path, []byte("CORRUPTION"), 0644)
+	missing := &fsckObject{Oid: "missing", Name: "missing", Path: filepath.Join(dir, "missing")}
+
+	fsckVerifyObjects([]*fsckObject{good, empty, corrupt, missing}, 2, nil)
+
+	assert.Nil(t, good.Err)
+	assert.Equal(t, good.Oid, good.Recalculated)
+	assert.Nil(t, empty.Err)
+	assert.Equal(t, empty.Oid, empty.Recalculated)
+	assert.Nil(t, corrupt.Err)
+	assert.NotEqual(t, corrupt.Oid, corrupt.Recalculated)
+	assert.True(t, os.IsNotExist(missing.Err))
+}
+
+func TestFsckMeterCountsObjectsAndBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsck-meter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	objects := []*fsckObject{
+		writeFsckObject(t, dir, "a", 10),
+		writeFsckObject(t, dir, "b", 2*fsckBufferSize),
+	}
+
+	// without starting the writer, which prints to the terminal
+	meter := &fsckMeter{totalObjects: 2, totalBytes: 10 + 2*fsckBufferSize}
+	fsckVerifyObjects(objects, 4, meter)
+
+	assert.Equal(t, int32(2), meter.objects)
+	assert.Equal(t, meter.totalBytes, meter.bytes)
+}
+
+// The benchmarks below compare hashing large objects by reading each one into
+// memory first with streaming them through fsckHashObject, and verifying many
+// large objects one at a time with verifying them across a worker pool. Run
+// them with -benchmem to see the memory used per object.
+
+const fsckBenchObjectSize = 32 * 1024 * 1024
+
+func benchFsckObjects(b *testing.B, n int) []*fsckObject {
+	dir, err := ioutil.TempDir("", "fsck-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	objects := make([]*fsckObject, n)
+	for i := range objects {
+		objects[i] = writeFsckObject(b, dir, fmt.Sprintf("object%d", i), fsckBenchObjectSize)
+	}
+	return objects
+}
+
+func cleanupFsckObjects(objects []*fsckObject) {
+	os.RemoveAll(filepath.Dir(objects[0].Path))
+}
+
+func BenchmarkFsckHashReadAll(b *testing.B) {
+	objects := benchFsckObjects(b, 1)
+	defer cleanupFsckObjects(objects)
+
+	b.SetBytes(fsckBenchObjectSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		by, err := ioutil.ReadFile(objects[0].Path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sum := sha256.Sum256(by)
+		if hex.EncodeToString(sum[:]) != objects[0].Oid {
+			b.Fatal("oid mismatch")
+		}
+	}
+}
+
+func BenchmarkFsckHashStream(b *testing.B) {
+	objects := benchFsckObjects(b, 1)
+	defer cleanupFsckObjects(objects)
+
+	buf := make([]byte, fsckBufferSize)
+
+	b.SetBytes(fsckBenchObjectSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		oid, err := fsckHashObject(objects[0].Path, buf, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if oid != objects[0].Oid {
+			b.Fatal("oid mismatch")
+		}
+	}
+}
+
+func benchmarkFsckVerifyObjects(b *testing.B, concurrency int) {
+	objects := benchFsckObjects(b, 8)
+	defer cleanupFsckObjects(objects)
+
+	b.SetBytes(int64(len(objects)) * fsckBenchObjectSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fsckVerifyObjects(objects, concurrency, nil)
+	}
+}
+
+func BenchmarkFsckVerifyObjectsSerial(b *testing.B) {
+	benchmarkFsckVerifyObjects(b, 1)
+}
+
+func BenchmarkFsckVerifyObjectsConcurrent(b *testing.B) {
+	benchmarkFsckVerifyObjects(b, runtime.NumCPU())
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/httputil"
+	"github.com/github/git-lfs/transfer"
+)
+
+// fsckRemote asks the current remote about each object with batch download
+// requests, reporting those it doesn't have or has with the wrong size. With
+// --deep, each object the remote has is downloaded and hashed, without being
+// stored locally.
+func fsckRemote(pointerIndex map[string]string, sizeIndex map[string]int64) (bool, error) {
+	oids := make([]string, 0, len(pointerIndex))
+	for oid := range pointerIndex {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+
+	objects := make([]*api.ObjectResource, 0, len(oids))
+	for _, oid := range oids {
+		objects = append(objects, &api.ObjectResource{Oid: oid, Size: sizeIndex[oid]})
+	}
+
+	ok := true
+	// --deep downloads with a plain GET, so only basic links will do
+	adapters := []string{transfer.BasicAdapterName}
+	for start := 0; start < len(objects); start += existsBatchSize {
+		end := start + existsBatchSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+		batch := objects[start:end]
+
+		objs, _, err := api.Batch(batch, "download", adapters)
+		if err != nil {
+			if errutil.IsNotImplementedError(err) {
+				return false, fmt.Errorf("The server does not support the batch API, which --verify-remote needs")
+			}
+			return false, err
+		}
+
+		results := make(map[string]*api.ObjectResource, len(objs))
+		for _, o := range objs {
+			results[o.Oid] = o
+		}
+
+		for _, o := range batch {
+			if !fsckRemoteObject(pointerIndex[o.Oid], o, results[o.Oid]) {
+				ok = false
+			}
+		}
+	}
+
+	if ok {
+		Print("%d objects are on %s", len(objects), cfg.CurrentRemote)
+	}
+	return ok, nil
+}
+
+// fsckRemoteObject reports whether the server's response for an object shows
+// it has a valid copy, printing why not otherwise.
+func fsckRemoteObject(name string, o, res *api.ObjectResource) bool {
+	switch {
+	case res == nil:
+		Print("Object %s (%s) could not be checked on the remote: not in the server's response", name, o.Oid)
+		return false
+	case res.Error != nil && res.Error.Code == 404:
+		Print("Object %s (%s) is missing on the remote", name, o.Oid)
+		return false
+	case res.Error != nil:
+		Print("Object %s (%s) could not be checked on the remote: %s", name, o.Oid, res.Error)
+		return false
+	}
+
+	if _, ok := res.Rel("download"); !ok {
+		Print("Object %s (%s) is missing on the remote", name, o.Oid)
+		return false
+	}
+	if res.Size != o.Size {
+		Print("Object %s (%s) is %d bytes on the remote, expected %d", name, o.Oid, res.Size, o.Size)
+		return false
+	}
+	if !fsckDeep {
+		return true
+	}
+
+	oid, size, err := fsckRemoteHash(res)
+	switch {
+	case err != nil:
+		Print("Object %s (%s) could not be downloaded from the remote: %s", name, o.Oid, err)
+		return false
+	case size != o.Size:
+		Print("Object %s (%s) downloaded %d bytes from the remote, expected %d", name, o.Oid, size, o.Size)
+		return false
+	case oid != o.Oid:
+		Print("Object %s (%s) is corrupt on the remote", name, o.Oid)
+		return false
+	}
+	return true
+}
+
+// fsckRemoteHash downloads the object and returns its OID and size, without
+// keeping the content.
+func fsckRemoteHash(obj *api.ObjectResource) (string, int64, error) {
+	req, err := obj.NewRequest("download", "GET")
+	if err != nil {
+		return "", 0, err
+	}
+
+	rel, _ := obj.Rel("download")
+	res, err := api.DoRequest(req, !rel.IsSigned("download"))
+	if err != nil {
+		return "", 0, err
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.download", res)
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return "", 0, fmt.Errorf("Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
+	}
+
+	oidHash := sha256.New()
+	size, err := io.Copy(oidHash, res.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(oidHash.Sum(nil)), size, nil
+}
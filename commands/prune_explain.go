@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/github/git-lfs/localstorage"
+	"github.com/github/git-lfs/tools"
+)
+
+// pruneExplanation records why each object is retained by prune, for
+// `git lfs prune --dry-run --explain`.
+type pruneExplanation struct {
+	reasons map[string][]string
+	mu      sync.Mutex
+}
+
+// pruneExplained collects the reasons objects are retained, when --explain
+// is given. It is nil otherwise, and adding to it does nothing.
+var pruneExplained *pruneExplanation
+
+func newPruneExplanation() *pruneExplanation {
+	return &pruneExplanation{reasons: make(map[string][]string)}
+}
+
+// Add records a reason the object is retained, unless it was already given.
+func (e *pruneExplanation) Add(oid, reason string) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range e.reasons[oid] {
+		if r == reason {
+			return
+		}
+	}
+	e.reasons[oid] = append(e.reasons[oid], reason)
+}
+
+// Reasons returns the reasons the object is retained, in the order they were
+// found.
+func (e *pruneExplanation) Reasons(oid string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reasons[oid]
+}
+
+// pruneRefLabel describes a commit by the refs which point at it, for the
+// reasons given by --explain, e.g. "HEAD -> master, origin/master (1a2b3c4)".
+func pruneRefLabel(sha string, names []string) string {
+	short := sha
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if len(names) == 0 {
+		return short
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(names, ", "), short)
+}
+
+// pruneReportExplanation lists each local object with why it is retained, or
+// why nothing retains it so that it would be pruned.
+func pruneReportExplanation(localObjects []localstorage.Object, retainedObjects, reachableObjects, unpushedObjects tools.StringSet, shallow bool) {
+	var retained, prunable []string
+	for _, file := range localObjects {
+		entry := fmt.Sprintf(" * %v (%v)", file.Oid, humanizeBytes(file.Size))
+
+		switch {
+		case retainedObjects.Contains(file.Oid):
+			reasons := pruneExplained.Reasons(file.Oid)
+			retained = append(retained, entry)
+			for _, reason := range reasons {
+				retained = append(retained, "   - "+reason)
+			}
+		case shallow && !reachableObjects.Contains(file.Oid):
+			retained = append(retained, entry, "   - may be needed by commits beyond the shallow clone boundary")
+		case unpushedObjects != nil && unpushedObjects.Contains(file.Oid):
+			prunable = append(prunable, entry+": only referenced by unpushed commits, see --include-unpushed")
+		case reachableObjects.Contains(file.Oid):
+			prunable = append(prunable, entry+": only referenced by commits outside the retention windows")
+		default:
+			prunable = append(prunable, entry+": not referenced by any commit")
+		}
+	}
+
+	if len(retained) > 0 {
+		Print("Retained files:")
+		Print("%s", strings.Join(retained, "\n"))
+	}
+	if len(prunable) > 0 {
+		Print("Prunable files:")
+		Print("%s", strings.Join(prunable, "\n"))
+	}
+}
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/github/git-lfs/git"
+	"github.com/github/git-lfs/lfs"
+	"github.com/github/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// storageCapCandidate is a local object which may be evicted to bring the
+// store under lfs.storage.maxsize.
+type storageCapCandidate struct {
+	Oid      string
+	Size     int64
+	LastUsed time.Time
+}
+
+// enforceStorageCap evicts the least recently used objects from the local
+// store once it is over lfs.storage.maxsize, until it is under the cap again.
+// It is called after objects have been fetched. As with prune, objects needed
+// by the current checkout, the index, other worktrees' checkouts or unpushed
+// commits are never evicted, and with lfs.pruneverifyremotealways only
+// objects which the remote has are. An object's last use is its modification
+// time, which is updated whenever it is checked out.
+func enforceStorageCap() {
+	maxSize := cfg.StorageMaxSize()
+	if maxSize <= 0 {
+		return
+	}
+
+	var objects []storageCapCandidate
+	var totalSize int64
+	for obj := range lfs.ScanObjectsChan() {
+		objects = append(objects, storageCapCandidate{Oid: obj.Oid, Size: obj.Size})
+		totalSize += obj.Size
+	}
+
+	if totalSize <= maxSize {
+		tracerx.Printf("storage cap: local store is %d bytes, under lfs.storage.maxsize of %d", totalSize, maxSize)
+		return
+	}
+
+	retained, err := storageCapRetainedObjects()
+	if err != nil {
+		LoggedError(err, "Not evicting any files to keep the local store under lfs.storage.maxsize: %s", err)
+		return
+	}
+
+	candidates := make([]storageCapCandidate, 0, len(objects))
+	for _, obj := range objects {
+		if retained.Contains(obj.Oid) {
+			continue
+		}
+		path, err := lfs.LocalMediaPath(obj.Oid)
+		if err != nil {
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		obj.LastUsed = stat.ModTime()
+		candidates = append(candidates, obj)
+	}
+	sort.Sort(storageCapByLastUsed(candidates))
+
+	var evictable []storageCapCandidate
+	overSize := totalSize - maxSize
+	for _, c := range candidates {
+		if overSize <= 0 {
+			break
+		}
+		evictable = append(evictable, c)
+		overSize -= c.Size
+	}
+
+	if cfg.FetchPruneConfig().PruneVerifyRemoteAlways {
+		evictable = storageCapVerified(evictable)
+	}
+
+	var evicted int
+	var evictedSize int64
+	for _, c := range evictable {
+		if err := deleteLocalObject(c.Oid); err != nil {
+			LoggedError(err, "Unable to evict %s: %s", c.Oid, err)
+			continue
+		}
+		tracerx.Printf("storage cap: evicted %s, last used %v", c.Oid, c.LastUsed)
+		evicted++
+		evictedSize += c.Size
+	}
+	totalSize -= evictedSize
+
+	if evicted > 0 {
+		Print("Evicted %d least recently used files (%s) to keep the local store under lfs.storage.maxsize (%s)", evicted, humanizeBytes(evictedSize), humanizeBytes(maxSize))
+	}
+	if totalSize > maxSize {
+		Error("The local store is %s, over lfs.storage.maxsize (%s), because the remaining files are needed by the current checkout or unpushed commits", humanizeBytes(totalSize), humanizeBytes(maxSize))
+	}
+}
+
+// storageCapRetainedObjects returns the objects which must be kept in the
+// local store: those at HEAD, in the index, at other worktrees' HEADs and in
+// unpushed commits. These are found with the same tasks prune uses.
+func storageCapRetainedObjects() (tools.StringSet, error) {
+	retained := tools.NewStringSetWithCapacity(100)
+
+	ref, err := git.CurrentRef()
+	if err != nil {
+		return nil, err
+	}
+
+	indexPointers, err := lfs.ScanIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range indexPointers {
+		retained.Add(p.Oid)
+	}
+
+	retainChan := make(chan string, 100)
+	errorChan := make(chan error, 10)
+	var taskwait sync.WaitGroup
+	taskwait.Add(3)
+	go pruneTaskGetRetainedAtRef(ref.Sha, "at HEAD", nil, retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedUnpushed(retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedWorktree(retainChan, errorChan, &taskwait)
+
+	var retainwait sync.WaitGroup
+	retainwait.Add(1)
+	go func() {
+		defer retainwait.Done()
+		for oid := range retainChan {
+			retained.Add(oid)
+		}
+	}()
+
+	taskwait.Wait()
+	close(retainChan)
+	retainwait.Wait()
+
+	close(errorChan)
+	for err := range errorChan {
+		return nil, err
+	}
+	return retained, nil
+}
+
+// storageCapVerified returns those of the candidates which the prune remote
+// has, so that eviction never removes the only copy of an object.
+func storageCapVerified(candidates []storageCapCandidate) []storageCapCandidate {
+	if len(candidates) == 0 {
+		return candidates
+	}
+
+	defer func(remote string) {
+		cfg.CurrentRemote = remote
+	}(cfg.CurrentRemote)
+	cfg.CurrentRemote = cfg.FetchPruneConfig().PruneRemoteName
+
+	verifyQueue := lfs.NewDownloadCheckQueue(0, 0)
+	verifyc := verifyQueue.Watch()
+
+	verified := tools.NewStringSetWithCapacity(len(candidates))
+	var verifywait sync.WaitGroup
+	verifywait.Add(1)
+	go func() {
+		defer verifywait.Done()
+		for oid := range verifyc {
+			verified.Add(oid)
+		}
+	}()
+
+	for _, c := range candidates {
+		pointer := lfs.NewPointer(c.Oid, c.Size, nil)
+		verifyQueue.Add(lfs.NewDownloadable(&lfs.WrappedPointer{Pointer: pointer}))
+	}
+	verifyQueue.Wait()
+	verifywait.Wait()
+
+	kept := candidates[:0]
+	for _, c := range candidates {
+		if verified.Contains(c.Oid) {
+			kept = append(kept, c)
+		} else {
+			Error("Not evicting %s, it is missing on %q", c.Oid, cfg.CurrentRemote)
+		}
+	}
+	return kept
+}
+
+// deleteLocalObject removes an object, and its metadata sidecar if it has one,
+// from the local store.
+func deleteLocalObject(oid string) error {
+	mediaFile, err := lfs.LocalMediaPath(oid)
+	if err != nil {
+		return fmt.Errorf("Unable to find media path for %v: %v", oid, err)
+	}
+	if err := os.Remove(mediaFile); err != nil {
+		return fmt.Errorf("Failed to remove file %v: %v", mediaFile, err)
+	}
+	if metaFile, err := lfs.LocalMetadataPath(oid); err == nil {
+		os.Remove(metaFile)
+	}
+	return nil
+}
+
+type storageCapByLastUsed []storageCapCandidate
+
+func (s storageCapByLastUsed) Len() int           { return len(s) }
+func (s storageCapByLastUsed) Less(i, j int) bool { return s[i].LastUsed.Before(s[j].LastUsed) }
+func (s storageCapByLastUsed) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/lfs"
+	"github.com/rubyist/tracerx"
+)
+
+// The states of an object in a transfer journal. An object is in progress
+// from when it is queued for download until it is in the local store, and
+// failed if the pull ended without it.
+const (
+	journalInProgress = "in-progress"
+	journalDone       = "done"
+	journalFailed     = "failed"
+)
+
+// journalFlushInterval is how often objects finishing are written out to the
+// journal, rather than rewriting it for every one.
+const journalFlushInterval = time.Second
+
+// transferJournal records the files in a pull and the state of each of their
+// objects, in .git/lfs/pull-journal.json. `git lfs pull --resume` uses it to
+// carry on an interrupted pull of the same remote, ref and include/exclude
+// paths without scanning the ref again, and `git lfs pull --continue` to retry
+// just the objects which failed. It is replaced by renaming, so that an
+// interrupted write never leaves a partial journal behind, and removed once
+// every object is done.
+type transferJournal struct {
+	Key     string            `json:"key"`
+	Remote  string            `json:"remote"`
+	Ref     string            `json:"ref"`
+	Files   []journalFile     `json:"files"`
+	Objects map[string]string `json:"objects"`
+
+	mu      sync.Mutex
+	written time.Time
+}
+
+type journalFile struct {
+	Name string `json:"name"`
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// pullJournal is the journal for the current pull, if there is one. It is
+// updated by fetchAndReportToChan.
+var pullJournal *transferJournal
+
+func transferJournalPath() string {
+	return filepath.Join(config.LocalGitStorageDir, "lfs", "pull-journal.json")
+}
+
+// transferJournalKey identifies the parameters of a pull. A journal is only
+// resumed by a pull with the same key.
+func transferJournalKey(remote, ref string, include, exclude []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "remote %s\nref %s\n", remote, ref)
+	fmt.Fprintf(h, "include %s\nexclude %s\n", strings.Join(include, ","), strings.Join(exclude, ","))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newTransferJournal returns an empty journal for a pull. One with an empty key
+// can be continued, but not resumed.
+func newTransferJournal(key, remote, ref string) *transferJournal {
+	return &transferJournal{Key: key, Remote: remote, Ref: ref, Objects: make(map[string]string)}
+}
+
+// loadTransferJournal reads the journal left by the last pull which didn't
+// finish. It returns nil if there isn't one.
+func loadTransferJournal() (*transferJournal, error) {
+	by, err := ioutil.ReadFile(transferJournalPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	j := &transferJournal{}
+	if err := json.Unmarshal(by, j); err != nil {
+		return nil, err
+	}
+	if j.Objects == nil {
+		j.Objects = make(map[string]string)
+	}
+	return j, nil
+}
+
+// openTransferJournal returns the journal for a pull with the given key. With
+// resume, an existing journal for the same key is carried on, and one for a
+// different ref or include/exclude paths is discarded. Without it, any
+// existing journal is discarded.
+func openTransferJournal(key, remote, ref string, resume bool) *transferJournal {
+	j := newTransferJournal(key, remote, ref)
+	if !resume {
+		return j
+	}
+
+	existing, err := loadTransferJournal()
+	if err != nil {
+		Error("Could not read %s, starting a new pull: %s", transferJournalPath(), err)
+		return j
+	}
+	if existing == nil {
+		Print("No interrupted pull to resume, starting a new one")
+		return j
+	}
+	if existing.Key != key {
+		Print("The ref or include/exclude paths have changed since the interrupted pull, starting a new one")
+		return j
+	}
+
+	var done, partial int
+	for _, state := range existing.Objects {
+		if state == journalDone {
+			done++
+		} else {
+			partial++
+		}
+	}
+	Print("Resuming interrupted pull: %d of %d files already downloaded", done, len(existing.Objects))
+	if partial > 0 {
+		Print("%d files were part way through downloading, and resume from what was already downloaded where possible", partial)
+	}
+	return existing
+}
+
+// Pointers returns the files recorded in the journal. If failedOnly is set,
+// only those whose objects failed are returned.
+func (j *transferJournal) Pointers(failedOnly bool) []*lfs.WrappedPointer {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pointers := make([]*lfs.WrappedPointer, 0, len(j.Files))
+	for _, f := range j.Files {
+		if failedOnly && j.Objects[f.Oid] != journalFailed {
+			continue
+		}
+		pointers = append(pointers, &lfs.WrappedPointer{
+			Name:    f.Name,
+			Size:    f.Size,
+			Pointer: lfs.NewPointer(f.Oid, f.Size, nil),
+		})
+	}
+	return pointers
+}
+
+// Failed returns how many objects the pull ended without.
+func (j *transferJournal) Failed() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	failed := 0
+	for _, state := range j.Objects {
+		if state == journalFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Begin records the files about to be fetched, other than those the
+// include/exclude paths leave out, unless the journal already has its files,
+// and writes it out. Objects already in the local store are done, and the rest
+// in progress.
+func (j *transferJournal) Begin(pointers []*lfs.WrappedPointer, include, exclude []string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	record := len(j.Files) == 0
+	for _, p := range pointers {
+		if !lfs.FilenamePassesIncludeExcludeFilter(p.Name, include, exclude) {
+			continue
+		}
+		if record {
+			j.Files = append(j.Files, journalFile{Name: p.Name, Oid: p.Oid, Size: p.Size})
+		}
+
+		if lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+			j.Objects[p.Oid] = journalDone
+			continue
+		}
+		if j.Objects[p.Oid] == journalDone {
+			tracerx.Printf("%v [%v] was downloaded by the interrupted pull, but is missing", p.Name, p.Oid)
+		}
+		j.Objects[p.Oid] = journalInProgress
+	}
+
+	if record {
+		sort.Sort(journalFilesByName(j.Files))
+	}
+	j.write()
+}
+
+// Set records the state of an object. The journal is written out at most once
+// every journalFlushInterval; Finish writes out the rest.
+func (j *transferJournal) Set(oid, state string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.Objects[oid] == state {
+		return
+	}
+	j.Objects[oid] = state
+	if time.Since(j.written) >= journalFlushInterval {
+		j.write()
+	}
+}
+
+// Finish records each object still not in the local store as failed, apart
+// from those declined by lfs.download.filter, which are dropped. The journal is
+// then written out, or removed if every object is done.
+func (j *transferJournal) Finish(declined map[string]bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	complete := true
+	for _, f := range j.Files {
+		if declined[f.Oid] {
+			delete(j.Objects, f.Oid)
+			continue
+		}
+		if lfs.ObjectExistsOfSize(f.Oid, f.Size) {
+			j.Objects[f.Oid] = journalDone
+		} else {
+			j.Objects[f.Oid] = journalFailed
+			complete = false
+		}
+	}
+
+	if complete {
+		j.remove()
+		return
+	}
+	j.write()
+}
+
+func (j *transferJournal) write() {
+	j.written = time.Now()
+
+	path := transferJournalPath()
+	by, err := json.Marshal(j)
+	if err == nil {
+		err = ioutil.WriteFile(path+".tmp", by, 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		tracerx.Printf("pull journal: could not write %s: %s", path, err)
+	}
+}
+
+func (j *transferJournal) remove() {
+	if err := os.Remove(transferJournalPath()); err != nil && !os.IsNotExist(err) {
+		Error("Could not remove %s: %s", transferJournalPath(), err)
+	}
+}
+
+type journalFilesByName []journalFile
+
+func (f journalFilesByName) Len() int           { return len(f) }
+func (f journalFilesByName) Less(i, j int) bool { return f[i].Name < f[j].Name }
+func (f journalFilesByName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/github/git-lfs/errutil"
@@ -11,7 +12,10 @@ import (
 var uploadMissingErr = "%s does not exist in .git/lfs/objects. Tried %s, which matches %s."
 
 type uploadContext struct {
-	DryRun       bool
+	DryRun bool
+	// VerifyOnly checks which objects the server is missing, without
+	// uploading anything.
+	VerifyOnly   bool
 	uploadedOids tools.StringSet
 }
 
@@ -69,6 +73,7 @@ func (c *uploadContext) prepareUpload(unfiltered []*lfs.WrappedPointer) (*lfs.Tr
 	// build the TransferQueue, automatically skipping any missing objects that
 	// the server already has.
 	uploadQueue := lfs.NewUploadQueue(numObjects, totalSize, c.DryRun)
+	uploadQueue.SetFailFast(transferFailFast())
 	for _, p := range missingLocalObjects {
 		if c.HasUploaded(p.Oid) {
 			uploadQueue.Skip(p.Size)
@@ -112,6 +117,14 @@ func (c *uploadContext) checkMissing(missing []*lfs.WrappedPointer, missingSize
 }
 
 func upload(c *uploadContext, unfiltered []*lfs.WrappedPointer) {
+	if errs := uploadPointers(c, unfiltered); len(errs) > 0 {
+		os.Exit(2)
+	}
+}
+
+// uploadPointers uploads the objects for the given pointers to the current
+// remote, reporting and returning any transfer errors instead of exiting.
+func uploadPointers(c *uploadContext, unfiltered []*lfs.WrappedPointer) []error {
 	if c.DryRun {
 		for _, p := range unfiltered {
 			if c.HasUploaded(p.Oid) {
@@ -122,7 +135,11 @@ func upload(c *uploadContext, unfiltered []*lfs.WrappedPointer) {
 			c.SetUploaded(p.Oid)
 		}
 
-		return
+		return nil
+	}
+
+	if c.VerifyOnly {
+		return verifyPointers(c, unfiltered)
 	}
 
 	q, pointers := c.prepareUpload(unfiltered)
@@ -142,18 +159,57 @@ func upload(c *uploadContext, unfiltered []*lfs.WrappedPointer) {
 
 	q.Wait()
 
-	for _, err := range q.Errors() {
-		if Debugging || errutil.IsFatalError(err) {
-			LoggedError(err, err.Error())
-		} else {
-			if inner := errutil.GetInnerError(err); inner != nil {
-				Error(inner.Error())
-			}
-			Error(err.Error())
+	return reportTransferErrors(q)
+}
+
+// verifyPointers sends batch upload requests for the given pointers' objects
+// to the current remote, and reports those the server doesn't have, without
+// uploading them. An error is returned if any are missing, or couldn't be
+// checked.
+func verifyPointers(c *uploadContext, unfiltered []*lfs.WrappedPointer) []error {
+	pointers := make([]*lfs.WrappedPointer, 0, len(unfiltered))
+	var size int64
+	for _, p := range unfiltered {
+		if c.HasUploaded(p.Oid) {
+			continue
 		}
+		c.SetUploaded(p.Oid)
+		pointers = append(pointers, p)
+		size += p.Size
 	}
 
-	if len(q.Errors()) > 0 {
-		os.Exit(2)
+	q := lfs.NewUploadCheckQueue(len(pointers), size)
+	q.SetFailFast(transferFailFast())
+	missingc := q.Watch()
+
+	missing := tools.NewStringSet()
+	done := make(chan struct{})
+	go func() {
+		for oid := range missingc {
+			missing.Add(oid)
+		}
+		close(done)
+	}()
+
+	for _, p := range pointers {
+		q.Add(lfs.NewUploadableFromPointer(p))
 	}
+	q.Wait()
+	<-done
+
+	for _, p := range pointers {
+		if missing.Contains(p.Oid) {
+			Print("missing %s => %s", p.Oid, p.Name)
+		}
+	}
+
+	errs := reportTransferErrors(q)
+	if len(missing) > 0 {
+		Error("%d of %d objects are missing from %s", len(missing), len(pointers), cfg.CurrentRemote)
+		errs = append(errs, fmt.Errorf("%d objects are missing from %s", len(missing), cfg.CurrentRemote))
+	} else if len(errs) == 0 {
+		Print("All %d objects are present on %s", len(pointers), cfg.CurrentRemote)
+	}
+
+	return errs
 }
@@ -5,11 +5,16 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ThomsonReutersEikon/go-ntlm/ntlm"
 	"github.com/bgentry/go-netrc/netrc"
@@ -37,9 +42,15 @@ type FetchPruneConfig struct {
 	FetchRecentCommitsDays int
 	// Whether to always fetch recent even without --recent
 	FetchRecentAlways bool
+	// Whether to also fetch in initialized submodules even without
+	// --include-submodules
+	FetchSubmodulesAlways bool
 	// Number of days added to FetchRecent*; data outside combined window will be
 	// deleted when prune is run. (default 3)
 	PruneOffsetDays int
+	// Number of days of history prune retains objects from, replacing the
+	// FetchRecent* + PruneOffsetDays windows when set (default -1 = unset)
+	PruneRetainDays int
 	// Always verify with remote before pruning
 	PruneVerifyRemoteAlways bool
 	// Name of remote to check for unpushed and verify checks
@@ -57,14 +68,22 @@ type Configuration struct {
 
 	loading           sync.Mutex // guards initialization of gitConfig and remotes
 	gitConfig         map[string]string
+	credentialHelpers map[string]string // lfs.<url>.credentialhelper by <url> in its own case
 	origConfig        map[string]string
 	remotes           []string
 	extensions        map[string]Extension
 	fetchIncludePaths []string
 	fetchExcludePaths []string
+	lfsRoutes         map[string][]string
 	fetchPruneConfig  *FetchPruneConfig
 	manualEndpoint    *Endpoint
 	parsedNetrc       netrcfinder
+
+	// lfsConfigErrors are the problems with the .lfsconfig values which
+	// couldn't be interpolated, and so were left out, by lowercase key.
+	lfsConfigErrors       map[string][]string
+	lfsConfigErrorHandler func(error)
+	lfsConfigErrorsMutex  sync.Mutex
 }
 
 func New() *Configuration {
@@ -168,18 +187,26 @@ func (c *Configuration) GetenvBool(key string, def bool) bool {
 // GitRemoteUrl returns the git clone/push url for a given remote (blank if not found)
 // the forpush argument is to cater for separate remote.name.pushurl settings
 func (c *Configuration) GitRemoteUrl(remote string, forpush bool) string {
+	u, _ := c.gitRemoteUrlSource(remote, forpush)
+	return u
+}
+
+// gitRemoteUrlSource returns the url GitRemoteUrl would, and the config key it
+// comes from.
+func (c *Configuration) gitRemoteUrlSource(remote string, forpush bool) (string, string) {
 	if forpush {
-		if u, ok := c.GitConfig("remote." + remote + ".pushurl"); ok {
-			return u
+		key := "remote." + remote + ".pushurl"
+		if u, ok := c.GitConfig(key); ok {
+			return u, key
 		}
 	}
 
-	if u, ok := c.GitConfig("remote." + remote + ".url"); ok {
-		return u
+	key := "remote." + remote + ".url"
+	if u, ok := c.GitConfig(key); ok {
+		return u, key
 	}
 
-	return ""
-
+	return "", ""
 }
 
 // Manually set an Endpoint to use instead of deriving from Git config
@@ -187,28 +214,55 @@ func (c *Configuration) SetManualEndpoint(e Endpoint) {
 	c.manualEndpoint = &e
 }
 
+// Endpoint returns the Endpoint to use for the given operation against the
+// current remote. See ResolvedRemoteEndpoint for the order in which the
+// settings are consulted.
 func (c *Configuration) Endpoint(operation string) Endpoint {
 	if c.manualEndpoint != nil {
 		return *c.manualEndpoint
 	}
 
+	return c.ResolvedRemoteEndpoint(c.CurrentRemote, operation)
+}
+
+// ResolvedRemoteEndpoint returns the Endpoint used for the given operation when
+// operating on the named remote. Settings are consulted in this order:
+// 1. remote.<name>.lfspushurl (uploads only), then remote.<name>.lfsurl
+// 2. lfs.pushurl (uploads only), then lfs.url
+// 3. The git url of the remote, then the git url of the default remote
+func (c *Configuration) ResolvedRemoteEndpoint(remote, operation string) Endpoint {
+	endpoint, _ := c.ResolvedRemoteEndpointSource(remote, operation)
+	return endpoint
+}
+
+// ResolvedRemoteEndpointSource returns the Endpoint ResolvedRemoteEndpoint
+// would, and the config key it was found in, or "" if there is none.
+func (c *Configuration) ResolvedRemoteEndpointSource(remote, operation string) (Endpoint, string) {
+	if len(remote) == 0 {
+		remote = defaultRemote
+	}
+
+	if endpoint, key, ok := c.remoteLfsEndpoint(remote, operation); ok {
+		return endpoint, key
+	}
+
 	if operation == "upload" {
 		if url, ok := c.GitConfig("lfs.pushurl"); ok {
-			return NewEndpointWithConfig(url, c)
+			return NewEndpointWithConfig(url, c), "lfs.pushurl"
 		}
 	}
 
 	if url, ok := c.GitConfig("lfs.url"); ok {
-		return NewEndpointWithConfig(url, c)
+		return NewEndpointWithConfig(url, c), "lfs.url"
 	}
 
-	if len(c.CurrentRemote) > 0 && c.CurrentRemote != defaultRemote {
-		if endpoint := c.RemoteEndpoint(c.CurrentRemote, operation); len(endpoint.Url) > 0 {
-			return endpoint
+	if remote != defaultRemote {
+		if endpoint, key := c.remoteEndpointSource(remote, operation); len(endpoint.Url) > 0 {
+			return endpoint, key
 		}
 	}
 
-	return c.RemoteEndpoint(defaultRemote, operation)
+	return c.remoteEndpointSource(defaultRemote, operation)
 }
 
 func (c *Configuration) ConcurrentTransfers() int {
@@ -228,6 +282,58 @@ func (c *Configuration) ConcurrentTransfers() int {
 	return uploads
 }
 
+// RemoteConcurrentTransfers returns the number of concurrent transfers for
+// objects transferred with the remote's endpoint, such as those routed to it
+// by remote.<name>.lfsroute, from remote.<name>.lfsconcurrenttransfers.
+// Default is ConcurrentTransfers, including if the setting is invalid.
+func (c *Configuration) RemoteConcurrentTransfers(remote string) int {
+	if c.NtlmAccess("download") {
+		return 1
+	}
+	return c.GitConfigInt("remote."+remote+".lfsconcurrenttransfers", c.ConcurrentTransfers())
+}
+
+// RemoteMaxBandwidth returns the limit on the combined rate, in bytes per
+// second, of the transfers made with the remote's endpoint, from
+// remote.<name>.lfsmaxbandwidth. The value may have a k, m, g or t suffix, as
+// for lfs.storage.maxsize. It returns 0, meaning no limit, if the setting is
+// unset or invalid.
+func (c *Configuration) RemoteMaxBandwidth(remote string) int64 {
+	key := "remote." + remote + ".lfsmaxbandwidth"
+	v, ok := c.GitConfig(key)
+	if !ok {
+		return 0
+	}
+
+	rate, err := parseByteSize(v)
+	if err != nil || rate < 0 {
+		tracerx.Printf("Invalid %s %q, ignoring", key, v)
+		return 0
+	}
+	return rate
+}
+
+// FsckConcurrency returns the number of objects `git lfs fsck` verifies at
+// once. Default is the number of CPUs, including if lfs.fsck.concurrency is
+// invalid.
+func (c *Configuration) FsckConcurrency() int {
+	return c.GitConfigInt("lfs.fsck.concurrency", runtime.NumCPU())
+}
+
+// SshRetries returns how many times to retry the git-lfs-authenticate SSH
+// command after it fails with an SSH connection error. Authentication
+// rejections are never retried. Default is 0, including if lfs.ssh.retries is
+// invalid, and at most maxSshRetries.
+func (c *Configuration) SshRetries() int {
+	if retries := c.GitConfigInt("lfs.ssh.retries", 0); retries < maxSshRetries {
+		return retries
+	}
+	return maxSshRetries
+}
+
+// maxSshRetries bounds lfs.ssh.retries, since each retry waits longer.
+const maxSshRetries = 10
+
 // BasicTransfersOnly returns whether to only allow "basic" HTTP transfers.
 // Default is false, including if the lfs.basictransfersonly is invalid
 func (c *Configuration) BasicTransfersOnly() bool {
@@ -240,10 +346,209 @@ func (c *Configuration) TusTransfersAllowed() bool {
 	return c.GitConfigBool("lfs.tustransfers", false)
 }
 
+// GlobalIndexPath returns the path of the index of the objects stored by all
+// the repositories on this machine, from lfs.globalindex. A value of true
+// means ~/.git-lfs-index, and a path starting with ~/ is in the home
+// directory. It returns an empty string if there is no index, including if
+// lfs.globalindex is false.
+func (c *Configuration) GlobalIndexPath() string {
+	v, _ := c.GitConfig("lfs.globalindex")
+	switch strings.ToLower(v) {
+	case "", "false", "no", "off", "0":
+		return ""
+	case "true", "yes", "on", "1":
+		return filepath.Join(os.Getenv("HOME"), ".git-lfs-index")
+	}
+
+	if strings.HasPrefix(v, "~/") {
+		return filepath.Join(os.Getenv("HOME"), v[2:])
+	}
+	return v
+}
+
+// ReferenceRepo returns the path of a local repository to take Git LFS objects
+// from before downloading them, from lfs.referencerepo. Default is empty.
+func (c *Configuration) ReferenceRepo() string {
+	v, _ := c.GitConfig("lfs.referencerepo")
+	return v
+}
+
+// TempDir returns the directory that in-progress object temp files should be
+// written to instead of the repository's .git/lfs directory, from the
+// GIT_LFS_TMPDIR environment variable, or lfs.tempdir. It returns an empty
+// string if neither is set.
+func (c *Configuration) TempDir() string {
+	if dir := c.Getenv("GIT_LFS_TMPDIR"); len(dir) > 0 {
+		return dir
+	}
+
+	dir, _ := c.GitConfig("lfs.tempdir")
+	return dir
+}
+
+// ForceProgress returns whether transfer progress should be printed every
+// ProgressInterval when stdout is not a terminal, even if nothing has changed,
+// from GIT_LFS_FORCE_PROGRESS, or else lfs.forceprogress. Default is false.
+func (c *Configuration) ForceProgress() bool {
+	return c.GetenvBool("GIT_LFS_FORCE_PROGRESS", c.GitConfigBool("lfs.forceprogress", false))
+}
+
+// Quiet returns whether fetch, pull, push, checkout and prune should print
+// only errors, without the progress meter, from GIT_LFS_QUIET. Their --quiet
+// flags set it too. Default is false.
+func (c *Configuration) Quiet() bool {
+	return c.GetenvBool("GIT_LFS_QUIET", false)
+}
+
+// ProgressInterval returns how often transfer progress is printed when stdout
+// is not a terminal, from lfs.progressinterval in seconds. Default is 1 second,
+// including if the value is invalid.
+func (c *Configuration) ProgressInterval() time.Duration {
+	return time.Duration(c.GitConfigInt("lfs.progressinterval", 1)) * time.Second
+}
+
+// StorageBaseDir returns the read-only object store which objects are read
+// from when they are not in the repository's own store, from
+// lfs.storage.base. It is laid out like .git/lfs/objects. It returns an empty
+// string if there is no base store.
+func (c *Configuration) StorageBaseDir() string {
+	dir, _ := c.GitConfig("lfs.storage.base")
+	return dir
+}
+
+// StorageMaxSize returns the soft cap on the size of the local object store,
+// in bytes, from lfs.storage.maxsize. The value may have a k, m, g or t
+// suffix, as in "20g", for multiples of 1024. It returns 0, meaning no cap, if
+// the setting is unset or invalid.
+func (c *Configuration) StorageMaxSize() int64 {
+	v, ok := c.GitConfig("lfs.storage.maxsize")
+	if !ok {
+		return 0
+	}
+
+	size, err := parseByteSize(v)
+	if err != nil || size < 0 {
+		tracerx.Printf("Invalid lfs.storage.maxsize %q, ignoring", v)
+		return 0
+	}
+	return size
+}
+
+// parseByteSize parses a number of bytes with an optional k, m, g or t suffix,
+// as git does for integer config values.
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := int64(1)
+	if len(value) > 0 {
+		switch value[len(value)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		case 't':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * multiplier, nil
+}
+
+// AdaptiveTransfers returns whether the transfer queue should lower its
+// concurrency when the server responds with 429 or 5xx errors, and raise it
+// again as transfers succeed. Default is false, including if
+// lfs.transfer.adaptive is invalid
+func (c *Configuration) AdaptiveTransfers() bool {
+	return c.GitConfigBool("lfs.transfer.adaptive", false)
+}
+
+// TransferFailFast returns whether fetch, pull and push should stop starting
+// transfers after the first one fails, rather than attempting every transfer
+// and reporting all the errors at the end. Default is false, including if
+// lfs.transfer.failfast is invalid
+func (c *Configuration) TransferFailFast() bool {
+	return c.GitConfigBool("lfs.transfer.failfast", false)
+}
+
+// TransferExpect100 returns whether basic uploads should send
+// "Expect: 100-continue" and wait for the server to accept the request before
+// sending the object, so that one it rejects isn't sent for nothing. Default
+// is true, including if lfs.transfer.expect100 is invalid
+func (c *Configuration) TransferExpect100() bool {
+	return c.GitConfigBool("lfs.transfer.expect100", true)
+}
+
+// RedirectTrustedHosts returns the hosts from the comma separated
+// lfs.redirect.trustedhosts which the Authorization header is kept for when a
+// request is redirected to them from another host. Entries are lowercased, and
+// may include a port, or start with "*." to match any subdomain.
+func (c *Configuration) RedirectTrustedHosts() []string {
+	value, _ := c.GitConfig("lfs.redirect.trustedhosts")
+
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); len(host) > 0 {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// Offline returns whether git-lfs is restricted to the local object store.
+// When set, any attempt to contact the LFS server is an error.
+// Default is false, including if lfs.offline is invalid
+func (c *Configuration) Offline() bool {
+	return c.GitConfigBool("lfs.offline", false)
+}
+
+// MetadataEnabled returns whether the clean filter should record each
+// object's original path, mode and mtime in a sidecar file, and whether those
+// sidecars should be transferred and restored.
+func (c *Configuration) MetadataEnabled() bool {
+	return c.GitConfigBool("lfs.metadata", false)
+}
+
+// UserAgentSuffix returns the token to append to git-lfs's User-Agent, from
+// GIT_LFS_USER_AGENT_SUFFIX or else lfs.useragent.suffix. Default is empty.
+func (c *Configuration) UserAgentSuffix() string {
+	if v := c.Getenv("GIT_LFS_USER_AGENT_SUFFIX"); len(v) > 0 {
+		return v
+	}
+
+	v, _ := c.GitConfig("lfs.useragent.suffix")
+	return v
+}
+
 func (c *Configuration) BatchTransfer() bool {
 	return c.GitConfigBool("lfs.batch", true)
 }
 
+// BatchRequestCompress returns whether large batch request bodies should be
+// gzipped, because the server is known to accept them.
+func (c *Configuration) BatchRequestCompress() bool {
+	return c.GitConfigBool("lfs.batch.requestcompress", false)
+}
+
+// CapabilitiesTTL returns how long the capabilities discovered for a server are
+// cached before they are discovered again. 0 disables the cache.
+func (c *Configuration) CapabilitiesTTL() time.Duration {
+	// Not GitConfigInt, which would treat 0 as unset.
+	if v, ok := c.GitConfig("lfs.capabilitiesttl"); ok {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return time.Hour
+}
+
 func (c *Configuration) NtlmAccess(operation string) bool {
 	return c.Access(operation) == "ntlm"
 }
@@ -297,6 +602,51 @@ func (c *Configuration) EndpointAccess(e Endpoint) string {
 	return "none"
 }
 
+// CredentialHelper returns the Git credential helper to ask for credentials
+// for the given URL instead of the ones in credential.helper, from the
+// lfs.<url>.credentialhelper key whose URL is the longest prefix of it, e.g.
+// lfs.https://lfs.example.com.credentialhelper for every URL on that host. As
+// in Git, the scheme and host are matched without regard to case, but the path
+// isn't. It returns an empty string if there is no such key.
+func (c *Configuration) CredentialHelper(rawurl string) string {
+	c.loadGitConfig()
+	rawurl = lowerSchemeAndHost(rawurl)
+
+	var helper, matched string
+	for prefix, value := range c.credentialHelpers {
+		prefix = lowerSchemeAndHost(prefix)
+		if !urlHasPrefix(rawurl, prefix) || len(prefix) <= len(matched) {
+			continue
+		}
+
+		helper, matched = value, prefix
+	}
+
+	return helper
+}
+
+// lowerSchemeAndHost returns rawurl with its scheme and host lowercased.
+func lowerSchemeAndHost(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	return u.String()
+}
+
+// urlHasPrefix returns whether prefix is rawurl, or a leading part of it
+// ending at a path separator.
+func urlHasPrefix(rawurl, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if len(prefix) == 0 || !strings.HasPrefix(rawurl, prefix) {
+		return false
+	}
+	return len(rawurl) == len(prefix) || rawurl[len(prefix)] == '/'
+}
+
 func (c *Configuration) SetEndpointAccess(e Endpoint, authType string) {
 	tracerx.Printf("setting repository access to %s", authType)
 	key := fmt.Sprintf("lfs.%s.access", e.Url)
@@ -321,35 +671,68 @@ func (c *Configuration) SetEndpointAccess(e Endpoint, authType string) {
 
 func (c *Configuration) FetchIncludePaths() []string {
 	c.loadGitConfig()
+	c.checkLfsConfigKeys(func(key string) bool { return key == "lfs.fetchinclude" })
 	return c.fetchIncludePaths
 }
 
 func (c *Configuration) FetchExcludePaths() []string {
 	c.loadGitConfig()
+	c.checkLfsConfigKeys(func(key string) bool { return key == "lfs.fetchexclude" })
 	return c.fetchExcludePaths
 }
 
+// LfsRoutes returns the path patterns set with remote.<name>.lfsroute, keyed
+// by remote name. Objects for matching paths are stored on that remote's LFS
+// endpoint rather than the current remote's.
+func (c *Configuration) LfsRoutes() map[string][]string {
+	c.loadGitConfig()
+	c.checkLfsConfigKeys(func(key string) bool {
+		return strings.HasPrefix(key, "remote.") && strings.HasSuffix(key, ".lfsroute")
+	})
+	return c.lfsRoutes
+}
+
 func (c *Configuration) RemoteEndpoint(remote, operation string) Endpoint {
+	endpoint, _ := c.remoteEndpointSource(remote, operation)
+	return endpoint
+}
+
+// remoteEndpointSource returns the Endpoint RemoteEndpoint would, and the
+// config key it was found in, or "" if there is none.
+func (c *Configuration) remoteEndpointSource(remote, operation string) (Endpoint, string) {
 	if len(remote) == 0 {
 		remote = defaultRemote
 	}
 
+	if endpoint, key, ok := c.remoteLfsEndpoint(remote, operation); ok {
+		return endpoint, key
+	}
+
+	// finally fall back on git remote url (also supports pushurl)
+	if url, key := c.gitRemoteUrlSource(remote, operation == "upload"); url != "" {
+		return NewEndpointFromCloneURLWithConfig(url, c), key
+	}
+
+	return Endpoint{}, ""
+}
+
+// remoteLfsEndpoint returns the Endpoint configured with remote.<name>.lfsurl
+// (or remote.<name>.lfspushurl when uploading), the key it was found in, and
+// whether one was found.
+func (c *Configuration) remoteLfsEndpoint(remote, operation string) (Endpoint, string, bool) {
 	// Support separate push URL if specified and pushing
 	if operation == "upload" {
-		if url, ok := c.GitConfig("remote." + remote + ".lfspushurl"); ok {
-			return NewEndpointWithConfig(url, c)
+		key := "remote." + remote + ".lfspushurl"
+		if url, ok := c.GitConfig(key); ok {
+			return NewEndpointWithConfig(url, c), key, true
 		}
 	}
-	if url, ok := c.GitConfig("remote." + remote + ".lfsurl"); ok {
-		return NewEndpointWithConfig(url, c)
-	}
-
-	// finally fall back on git remote url (also supports pushurl)
-	if url := c.GitRemoteUrl(remote, operation == "upload"); url != "" {
-		return NewEndpointFromCloneURLWithConfig(url, c)
+	key := "remote." + remote + ".lfsurl"
+	if url, ok := c.GitConfig(key); ok {
+		return NewEndpointWithConfig(url, c), key, true
 	}
 
-	return Endpoint{}
+	return Endpoint{}, "", false
 }
 
 func (c *Configuration) Remotes() []string {
@@ -368,6 +751,9 @@ func (c *Configuration) GitProtocol() string {
 
 func (c *Configuration) Extensions() map[string]Extension {
 	c.loadGitConfig()
+	c.checkLfsConfigKeys(func(key string) bool {
+		return strings.HasPrefix(key, "lfs.extension.")
+	})
 	return c.extensions
 }
 
@@ -408,7 +794,11 @@ func (c *Configuration) GitConfigBool(key string, def bool) bool {
 
 func (c *Configuration) GitConfig(key string) (string, bool) {
 	c.loadGitConfig()
-	value, ok := c.gitConfig[strings.ToLower(key)]
+	key = strings.ToLower(key)
+	value, ok := c.gitConfig[key]
+	if !ok {
+		c.checkLfsConfigKeys(func(k string) bool { return k == key })
+	}
 	return value, ok
 }
 
@@ -424,6 +814,7 @@ func (c *Configuration) FetchPruneConfig() *FetchPruneConfig {
 			FetchRecentRefsIncludeRemotes: true,
 			FetchRecentCommitsDays:        0,
 			PruneOffsetDays:               3,
+			PruneRetainDays:               -1,
 			PruneVerifyRemoteAlways:       false,
 			PruneRemoteName:               "origin",
 		}
@@ -449,12 +840,23 @@ func (c *Configuration) FetchPruneConfig() *FetchPruneConfig {
 				c.fetchPruneConfig.FetchRecentAlways = b
 			}
 		}
+		if v, ok := c.GitConfig("lfs.fetchsubmodules"); ok {
+			if b, err := parseConfigBool(v); err == nil {
+				c.fetchPruneConfig.FetchSubmodulesAlways = b
+			}
+		}
 		if v, ok := c.GitConfig("lfs.pruneoffsetdays"); ok {
 			n, err := strconv.Atoi(v)
 			if err == nil && n >= 0 {
 				c.fetchPruneConfig.PruneOffsetDays = n
 			}
 		}
+		if v, ok := c.GitConfig("lfs.pruneretainunreachabledays"); ok {
+			n, err := strconv.Atoi(v)
+			if err == nil && n >= 0 {
+				c.fetchPruneConfig.PruneRetainDays = n
+			}
+		}
 		if v, ok := c.GitConfig("lfs.pruneverifyremotealways"); ok {
 			if b, err := parseConfigBool(v); err == nil {
 				c.fetchPruneConfig.PruneVerifyRemoteAlways = b
@@ -494,18 +896,18 @@ func (c *Configuration) loadGitConfig() bool {
 	c.extensions = make(map[string]Extension)
 	uniqRemotes := make(map[string]bool)
 
+	listOutput, err := git.Config.List()
+	if err != nil {
+		panic(fmt.Errorf("Error listing git config: %s", err))
+	}
+
 	configFiles := []string{
 		filepath.Join(LocalWorkingDir, ".lfsconfig"),
 
 		// TODO: remove .gitconfig support for Git LFS v2.0 https://github.com/github/git-lfs/issues/839
 		filepath.Join(LocalWorkingDir, ".gitconfig"),
 	}
-	c.readGitConfigFromFiles(configFiles, 0, uniqRemotes)
-
-	listOutput, err := git.Config.List()
-	if err != nil {
-		panic(fmt.Errorf("Error listing git config: %s", err))
-	}
+	c.readGitConfigFromFiles(configFiles, 0, uniqRemotes, lfsConfigInterpolation(listOutput))
 
 	c.readGitConfig(listOutput, uniqRemotes, false)
 
@@ -520,7 +922,7 @@ func (c *Configuration) loadGitConfig() bool {
 	return true
 }
 
-func (c *Configuration) readGitConfigFromFiles(filenames []string, filenameIndex int, uniqRemotes map[string]bool) {
+func (c *Configuration) readGitConfigFromFiles(filenames []string, filenameIndex int, uniqRemotes map[string]bool, interpolate bool) {
 	filename := filenames[filenameIndex]
 	_, err := os.Stat(filename)
 	if err == nil {
@@ -534,6 +936,9 @@ func (c *Configuration) readGitConfigFromFiles(filenames []string, filenameIndex
 		if err != nil {
 			panic(fmt.Errorf("Error listing git config from %s: %s", filename, err))
 		}
+		if interpolate {
+			fileOutput = c.interpolateConfig(fileOutput)
+		}
 		c.readGitConfig(fileOutput, uniqRemotes, true)
 		return
 	}
@@ -541,7 +946,7 @@ func (c *Configuration) readGitConfigFromFiles(filenames []string, filenameIndex
 	if os.IsNotExist(err) {
 		newIndex := filenameIndex + 1
 		if len(filenames) > newIndex {
-			c.readGitConfigFromFiles(filenames, newIndex, uniqRemotes)
+			c.readGitConfigFromFiles(filenames, newIndex, uniqRemotes, interpolate)
 		}
 		return
 	}
@@ -549,6 +954,109 @@ func (c *Configuration) readGitConfigFromFiles(filenames []string, filenameIndex
 	panic(fmt.Errorf("Error listing git config from %s: %s", filename, err))
 }
 
+var configVarRE = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// lfsConfigInterpolation returns whether lfs.lfsconfig.interpolate is true in
+// the `git config -l` output. It is only read from there so that a repository
+// can't have its .lfsconfig copy the user's environment into a URL.
+func lfsConfigInterpolation(listOutput string) bool {
+	var enabled bool
+	for _, line := range strings.Split(listOutput, "\n") {
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) == 2 && strings.ToLower(pieces[0]) == "lfs.lfsconfig.interpolate" {
+			enabled, _ = parseConfigBool(pieces[1])
+		}
+	}
+	return enabled
+}
+
+// interpolateConfig replaces each ${NAME} in the values of the config output
+// with the environment variable NAME. A value naming a variable which is unset
+// or empty is left out, and recorded for checkLfsConfigKeys.
+func (c *Configuration) interpolateConfig(output string) string {
+	lines := strings.Split(output, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) < 2 {
+			kept = append(kept, line)
+			continue
+		}
+
+		var missing []string
+		value := configVarRE.ReplaceAllStringFunc(pieces[1], func(ref string) string {
+			name := ref[2 : len(ref)-1]
+			v := c.Getenv(name)
+			if len(v) == 0 {
+				missing = append(missing, ref)
+			}
+			return v
+		})
+
+		if len(missing) > 0 {
+			if c.lfsConfigErrors == nil {
+				c.lfsConfigErrors = make(map[string][]string)
+			}
+			key := strings.ToLower(pieces[0])
+			for _, ref := range missing {
+				c.lfsConfigErrors[key] = append(c.lfsConfigErrors[key],
+					fmt.Sprintf("%s: %s is not set", pieces[0], ref))
+			}
+			continue
+		}
+		kept = append(kept, pieces[0]+"="+value)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// OnLfsConfigError sets the func called with an error the first time each
+// .lfsconfig value which couldn't be interpolated is read, because an
+// environment variable it refers to isn't set. Values which your own Git
+// config also sets aren't reported. With no func, such values are left out
+// without an error.
+func (c *Configuration) OnLfsConfigError(fn func(error)) {
+	c.lfsConfigErrorsMutex.Lock()
+	c.lfsConfigErrorHandler = fn
+	c.lfsConfigErrorsMutex.Unlock()
+}
+
+// checkLfsConfigKeys reports the .lfsconfig values which couldn't be
+// interpolated, with keys that match, to the func set with OnLfsConfigError.
+// Each is only reported once.
+func (c *Configuration) checkLfsConfigKeys(match func(key string) bool) {
+	c.lfsConfigErrorsMutex.Lock()
+	handler := c.lfsConfigErrorHandler
+	if handler == nil || len(c.lfsConfigErrors) == 0 {
+		c.lfsConfigErrorsMutex.Unlock()
+		return
+	}
+
+	var keys []string
+	for key := range c.lfsConfigErrors {
+		if !match(key) {
+			continue
+		}
+		if _, ok := c.gitConfig[key]; ok {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	var problems []string
+	for _, key := range keys {
+		problems = append(problems, c.lfsConfigErrors[key]...)
+		delete(c.lfsConfigErrors, key)
+	}
+	c.lfsConfigErrorsMutex.Unlock()
+
+	if len(problems) == 0 {
+		return
+	}
+	handler(fmt.Errorf("Error reading .lfsconfig:\n  %s",
+		strings.Join(problems, "\n  ")))
+}
+
 func (c *Configuration) readGitConfig(output string, uniqRemotes map[string]bool, onlySafe bool) {
 	lines := strings.Split(output, "\n")
 	uniqKeys := make(map[string]string)
@@ -599,13 +1107,20 @@ func (c *Configuration) readGitConfig(output string, uniqRemotes map[string]bool
 			ext.Name = name
 			c.extensions[name] = ext
 		} else if len(keyParts) > 1 && keyParts[0] == "remote" {
-			if onlySafe && (len(keyParts) == 3 && keyParts[2] != "lfsurl") {
+			if onlySafe && (len(keyParts) == 3 && !remoteSafeKeys[keyParts[2]]) {
 				continue
 			}
 
 			allowed = true
 			remote := keyParts[1]
 			uniqRemotes[remote] = remote == "origin"
+
+			if len(keyParts) == 3 && keyParts[2] == "lfsroute" {
+				if c.lfsRoutes == nil {
+					c.lfsRoutes = make(map[string][]string)
+				}
+				c.lfsRoutes[remote] = tools.CleanPaths(value, ",")
+			}
 		} else if len(keyParts) > 2 && keyParts[len(keyParts)-1] == "access" {
 			allowed = true
 		}
@@ -614,6 +1129,20 @@ func (c *Configuration) readGitConfig(output string, uniqRemotes map[string]bool
 			continue
 		}
 
+		if onlySafe && isSftpURL(key, value) {
+			if ShowConfigWarnings {
+				fmt.Fprintf(os.Stderr, "WARNING: Ignoring %s=%q from .lfsconfig: SFTP URLs can only be set in your Git config.\n", pieces[0], value)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(key, "lfs.") && strings.HasSuffix(key, ".credentialhelper") {
+			if c.credentialHelpers == nil {
+				c.credentialHelpers = make(map[string]string)
+			}
+			c.credentialHelpers[pieces[0][len("lfs."):len(pieces[0])-len(".credentialhelper")]] = value
+		}
+
 		c.gitConfig[key] = value
 
 		if len(keyParts) == 2 && keyParts[0] == "lfs" {
@@ -636,6 +1165,23 @@ func keyIsUnsafe(key string) bool {
 	return true
 }
 
+// isSftpURL reports whether key is an LFS URL key and value is an sftp://
+// URL. These run the local sftp client against the host in the URL, so a
+// cloned .lfsconfig may not set them.
+func isSftpURL(key, value string) bool {
+	keyParts := strings.Split(key, ".")
+	isURLKey := key == "lfs.url" ||
+		(len(keyParts) == 3 && keyParts[0] == "remote" && keyParts[2] == "lfsurl")
+
+	return isURLKey && strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), "sftp://")
+}
+
+// remoteSafeKeys are the remote.<name>.* keys which may be set in .lfsconfig.
+var remoteSafeKeys = map[string]bool{
+	"lfsurl":   true,
+	"lfsroute": true,
+}
+
 var safeKeys = []string{
 	"lfs.fetchexclude",
 	"lfs.fetchinclude",
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,6 +22,21 @@ func TestEndpointDefaultsToOrigin(t *testing.T) {
 }
 
 func TestEndpointOverridesOrigin(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.url":           "abc",
+			"remote.origin.url": "https://example.com/foo/bar.git",
+		},
+		remotes: []string{},
+	}
+
+	endpoint := config.Endpoint("download")
+	assert.Equal(t, "abc", endpoint.Url)
+	assert.Equal(t, "", endpoint.SshUserAndHost)
+	assert.Equal(t, "", endpoint.SshPath)
+}
+
+func TestRemoteLfsUrlOverridesLfsUrl(t *testing.T) {
 	config := &Configuration{
 		gitConfig: map[string]string{
 			"lfs.url":              "abc",
@@ -28,11 +46,86 @@ func TestEndpointOverridesOrigin(t *testing.T) {
 	}
 
 	endpoint := config.Endpoint("download")
-	assert.Equal(t, "abc", endpoint.Url)
+	assert.Equal(t, "def", endpoint.Url)
 	assert.Equal(t, "", endpoint.SshUserAndHost)
 	assert.Equal(t, "", endpoint.SshPath)
 }
 
+func TestResolvedRemoteEndpointPrecedence(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.url":                    "https://lfs.com/default",
+			"lfs.pushurl":                "https://lfs.com/default-push",
+			"remote.origin.url":          "https://example.com/origin.git",
+			"remote.dedicated.url":       "https://example.com/dedicated.git",
+			"remote.dedicated.lfsurl":    "https://dedicated.com/lfs",
+			"remote.pushonly.url":        "https://example.com/pushonly.git",
+			"remote.pushonly.lfspushurl": "https://pushonly.com/lfs",
+			"remote.plain.url":           "https://example.com/plain.git",
+		},
+		remotes: []string{},
+	}
+
+	// remote.<name>.lfsurl wins over lfs.url and lfs.pushurl
+	assert.Equal(t, "https://dedicated.com/lfs", config.ResolvedRemoteEndpoint("dedicated", "download").Url)
+	assert.Equal(t, "https://dedicated.com/lfs", config.ResolvedRemoteEndpoint("dedicated", "upload").Url)
+
+	// remote.<name>.lfspushurl only applies to uploads
+	assert.Equal(t, "https://lfs.com/default", config.ResolvedRemoteEndpoint("pushonly", "download").Url)
+	assert.Equal(t, "https://pushonly.com/lfs", config.ResolvedRemoteEndpoint("pushonly", "upload").Url)
+
+	// lfs.url and lfs.pushurl win over the git url of the remote
+	assert.Equal(t, "https://lfs.com/default", config.ResolvedRemoteEndpoint("plain", "download").Url)
+	assert.Equal(t, "https://lfs.com/default-push", config.ResolvedRemoteEndpoint("plain", "upload").Url)
+
+	config.CurrentRemote = "dedicated"
+	assert.Equal(t, "https://dedicated.com/lfs", config.Endpoint("download").Url)
+}
+
+func TestResolvedRemoteEndpointFallsBackToGitUrl(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"remote.origin.url": "https://example.com/origin.git",
+			"remote.other.url":  "https://example.com/other.git",
+		},
+		remotes: []string{},
+	}
+
+	assert.Equal(t, "https://example.com/other.git/info/lfs", config.ResolvedRemoteEndpoint("other", "download").Url)
+	assert.Equal(t, "https://example.com/origin.git/info/lfs", config.ResolvedRemoteEndpoint("missing", "download").Url)
+	assert.Equal(t, "https://example.com/origin.git/info/lfs", config.ResolvedRemoteEndpoint("", "download").Url)
+}
+
+func TestResolvedRemoteEndpointSource(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.pushurl":                "https://lfs.com/default-push",
+			"remote.origin.url":          "https://example.com/origin.git",
+			"remote.dedicated.lfsurl":    "https://dedicated.com/lfs",
+			"remote.pushonly.url":        "https://example.com/pushonly.git",
+			"remote.pushonly.pushurl":    "https://example.com/pushonly-push.git",
+			"remote.pushonly.lfspushurl": "https://pushonly.com/lfs",
+		},
+		remotes: []string{},
+	}
+
+	endpoint, source := config.ResolvedRemoteEndpointSource("dedicated", "download")
+	assert.Equal(t, "https://dedicated.com/lfs", endpoint.Url)
+	assert.Equal(t, "remote.dedicated.lfsurl", source)
+
+	endpoint, source = config.ResolvedRemoteEndpointSource("pushonly", "download")
+	assert.Equal(t, "https://example.com/pushonly.git/info/lfs", endpoint.Url)
+	assert.Equal(t, "remote.pushonly.url", source)
+
+	endpoint, source = config.ResolvedRemoteEndpointSource("pushonly", "upload")
+	assert.Equal(t, "https://pushonly.com/lfs", endpoint.Url)
+	assert.Equal(t, "remote.pushonly.lfspushurl", source)
+
+	endpoint, source = config.ResolvedRemoteEndpointSource("origin", "upload")
+	assert.Equal(t, "https://lfs.com/default-push", endpoint.Url)
+	assert.Equal(t, "lfs.pushurl", source)
+}
+
 func TestEndpointNoOverrideDefaultRemote(t *testing.T) {
 	config := &Configuration{
 		gitConfig: map[string]string{
@@ -314,6 +407,105 @@ func TestConcurrentTransfersZeroValue(t *testing.T) {
 	assert.Equal(t, 3, n)
 }
 
+func TestRemoteConcurrentTransfersSetValue(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.concurrenttransfers":              "5",
+			"remote.origin.lfsconcurrenttransfers": "2",
+		},
+	}
+
+	assert.Equal(t, 2, config.RemoteConcurrentTransfers("origin"))
+	assert.Equal(t, 5, config.RemoteConcurrentTransfers("other"))
+}
+
+func TestRemoteConcurrentTransfersInvalid(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"remote.origin.lfsconcurrenttransfers": "0",
+		},
+	}
+
+	assert.Equal(t, 3, config.RemoteConcurrentTransfers("origin"))
+}
+
+func TestRemoteMaxBandwidth(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"remote.origin.lfsmaxbandwidth":  "2m",
+			"remote.plain.lfsmaxbandwidth":   "1000",
+			"remote.invalid.lfsmaxbandwidth": "fast",
+		},
+	}
+
+	assert.Equal(t, int64(2*1024*1024), config.RemoteMaxBandwidth("origin"))
+	assert.Equal(t, int64(1000), config.RemoteMaxBandwidth("plain"))
+	assert.Equal(t, int64(0), config.RemoteMaxBandwidth("invalid"))
+	assert.Equal(t, int64(0), config.RemoteMaxBandwidth("other"))
+}
+
+func TestFsckConcurrencySetValue(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.fsck.concurrency": "6",
+		},
+	}
+
+	assert.Equal(t, 6, config.FsckConcurrency())
+}
+
+func TestFsckConcurrencyDefault(t *testing.T) {
+	config := &Configuration{}
+
+	assert.Equal(t, runtime.NumCPU(), config.FsckConcurrency())
+}
+
+func TestFsckConcurrencyInvalid(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.fsck.concurrency": "none",
+		},
+	}
+
+	assert.Equal(t, runtime.NumCPU(), config.FsckConcurrency())
+}
+
+func TestSshRetriesSetValue(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.ssh.retries": "3",
+		},
+	}
+
+	assert.Equal(t, 3, config.SshRetries())
+}
+
+func TestSshRetriesDefault(t *testing.T) {
+	config := &Configuration{}
+
+	assert.Equal(t, 0, config.SshRetries())
+}
+
+func TestSshRetriesInvalid(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.ssh.retries": "-1",
+		},
+	}
+
+	assert.Equal(t, 0, config.SshRetries())
+}
+
+func TestSshRetriesTooMany(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.ssh.retries": "1000",
+		},
+	}
+
+	assert.Equal(t, 10, config.SshRetries())
+}
+
 func TestConcurrentTransfersNonNumeric(t *testing.T) {
 	config := &Configuration{
 		gitConfig: map[string]string{
@@ -394,6 +586,133 @@ func TestTusTransfersAllowedInvalidValue(t *testing.T) {
 	assert.Equal(t, false, b)
 }
 
+func TestTransferFailFastSetValue(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.transfer.failfast": "true",
+		},
+	}
+
+	assert.Equal(t, true, config.TransferFailFast())
+}
+
+func TestTransferFailFastDefault(t *testing.T) {
+	config := &Configuration{}
+
+	assert.Equal(t, false, config.TransferFailFast())
+}
+
+func TestTransferFailFastInvalidValue(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.transfer.failfast": "wat",
+		},
+	}
+
+	assert.Equal(t, false, config.TransferFailFast())
+}
+
+func TestTransferExpect100Default(t *testing.T) {
+	config := &Configuration{}
+
+	assert.Equal(t, true, config.TransferExpect100())
+}
+
+func TestTransferExpect100SetValue(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.transfer.expect100": "false",
+		},
+	}
+
+	assert.Equal(t, false, config.TransferExpect100())
+}
+
+func TestForceProgress(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{"lfs.forceprogress": "true"},
+		envVars:   map[string]string{},
+	}
+	assert.True(t, config.ForceProgress())
+
+	config.envVars["GIT_LFS_FORCE_PROGRESS"] = "0"
+	assert.False(t, config.ForceProgress())
+
+	config = &Configuration{
+		envVars: map[string]string{"GIT_LFS_FORCE_PROGRESS": "1"},
+	}
+	assert.True(t, config.ForceProgress())
+}
+
+func TestForceProgressDefault(t *testing.T) {
+	config := &Configuration{
+		envVars: map[string]string{"GIT_LFS_FORCE_PROGRESS": ""},
+	}
+	assert.False(t, config.ForceProgress())
+}
+
+func TestQuiet(t *testing.T) {
+	config := &Configuration{
+		envVars: map[string]string{"GIT_LFS_QUIET": "1"},
+	}
+	assert.True(t, config.Quiet())
+
+	config.envVars["GIT_LFS_QUIET"] = "false"
+	assert.False(t, config.Quiet())
+
+	config.envVars["GIT_LFS_QUIET"] = ""
+	assert.False(t, config.Quiet())
+}
+
+func TestProgressInterval(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{"lfs.progressinterval": "30"},
+	}
+	assert.Equal(t, 30*time.Second, config.ProgressInterval())
+
+	for _, value := range []string{"", "0", "-1", "wat"} {
+		config := &Configuration{
+			gitConfig: map[string]string{"lfs.progressinterval": value},
+		}
+		assert.Equal(t, time.Second, config.ProgressInterval(), "lfs.progressinterval=%q", value)
+	}
+}
+
+func TestStorageBaseDir(t *testing.T) {
+	config := &Configuration{
+		gitConfig: map[string]string{
+			"lfs.storage.base": "/mnt/lfs/objects",
+		},
+	}
+
+	assert.Equal(t, "/mnt/lfs/objects", config.StorageBaseDir())
+	assert.Equal(t, "", (&Configuration{}).StorageBaseDir())
+}
+
+func TestStorageMaxSize(t *testing.T) {
+	tests := map[string]int64{
+		"1048576": 1048576,
+		"512k":    512 * 1024,
+		"20g":     20 * 1024 * 1024 * 1024,
+		"2M":      2 * 1024 * 1024,
+		"1t":      1024 * 1024 * 1024 * 1024,
+		"0":       0,
+		"-1g":     0,
+		"":        0,
+		"g":       0,
+		"20gb":    0,
+	}
+
+	for value, expected := range tests {
+		config := &Configuration{
+			gitConfig: map[string]string{"lfs.storage.maxsize": value},
+		}
+		assert.Equal(t, expected, config.StorageMaxSize(), "lfs.storage.maxsize=%q", value)
+	}
+
+	assert.Equal(t, int64(0), (&Configuration{}).StorageMaxSize())
+}
+
 func TestBatch(t *testing.T) {
 	tests := map[string]bool{
 		"":         true,
@@ -575,3 +894,102 @@ func TestFetchIncludeExcludesAreCleaned(t *testing.T) {
 	assert.Equal(t, []string{"/path/to/clean"}, config.FetchIncludePaths())
 	assert.Equal(t, []string{"/other/path/to/clean"}, config.FetchExcludePaths())
 }
+
+func TestLfsRoutes(t *testing.T) {
+	config := NewFromValues(map[string]string{
+		"remote.textures.lfsurl":   "https://textures.com/lfs",
+		"remote.textures.lfsroute": "*.png, art/textures",
+		"remote.Audio.lfsroute":    "*.wav",
+		"remote.origin.url":        "https://example.com/origin.git",
+	})
+
+	assert.Equal(t, map[string][]string{
+		"textures": []string{"*.png", "art/textures"},
+		"audio":    []string{"*.wav"},
+	}, config.LfsRoutes())
+}
+
+func TestLfsConfigIgnoresSftpURLs(t *testing.T) {
+	config := &Configuration{gitConfig: make(map[string]string)}
+	config.readGitConfig(strings.Join([]string{
+		"lfs.url=sftp://-oProxyCommand=touch${IFS}pwned/lfs",
+		"remote.origin.lfsurl=SFTP://host/lfs",
+		"remote.textures.lfsurl=https://textures.com/lfs",
+	}, "\n"), map[string]bool{}, true)
+
+	assert.Equal(t, map[string]string{
+		"remote.textures.lfsurl": "https://textures.com/lfs",
+	}, config.gitConfig)
+
+	config.readGitConfig("lfs.url=sftp://host/lfs\n", map[string]bool{}, false)
+	assert.Equal(t, "sftp://host/lfs", config.gitConfig["lfs.url"])
+}
+
+func TestLfsConfigInterpolation(t *testing.T) {
+	assert.False(t, lfsConfigInterpolation("lfs.url=https://example.com\n"))
+	assert.True(t, lfsConfigInterpolation("lfs.lfsconfig.interpolate=true\n"))
+	assert.False(t, lfsConfigInterpolation("lfs.lfsconfig.interpolate=true\nlfs.lfsconfig.interpolate=false\n"))
+}
+
+func TestInterpolateConfig(t *testing.T) {
+	config := &Configuration{
+		envVars: map[string]string{
+			"LFS_HOST": "lfs.example.com",
+			"LFS_REPO": "repo",
+			"EMPTY":    "",
+		},
+	}
+
+	output := config.interpolateConfig(strings.Join([]string{
+		"lfs.url=https://${LFS_HOST}/${LFS_REPO}.git/info/lfs",
+		"lfs.fetchinclude=$LFS_HOST,${not a var}",
+		"remote.other.lfsurl=https://${MISSING_HOST}/${EMPTY}",
+		"",
+	}, "\n"))
+
+	assert.Equal(t, strings.Join([]string{
+		"lfs.url=https://lfs.example.com/repo.git/info/lfs",
+		"lfs.fetchinclude=$LFS_HOST,${not a var}",
+		"",
+	}, "\n"), output)
+	assert.Equal(t, map[string][]string{
+		"remote.other.lfsurl": []string{
+			"remote.other.lfsurl: ${MISSING_HOST} is not set",
+			"remote.other.lfsurl: ${EMPTY} is not set",
+		},
+	}, config.lfsConfigErrors)
+}
+
+func TestLfsConfigErrorsOnlyForKeysRead(t *testing.T) {
+	config := NewFromValues(map[string]string{
+		"lfs.url":                "https://lfs.example.com",
+		"lfs.batch":              "true",
+		"remote.origin.url":      "https://example.com/origin.git",
+		"remote.origin.lfsroute": "*.png",
+	})
+	config.lfsConfigErrors = map[string][]string{
+		"lfs.url":                  []string{"lfs.url: ${LFS_HOST} is not set"},
+		"remote.other.lfsurl":      []string{"remote.other.lfsurl: ${OTHER} is not set"},
+		"remote.textures.lfsroute": []string{"remote.textures.lfsroute: ${ROUTE} is not set"},
+	}
+
+	var errs []string
+	config.OnLfsConfigError(func(err error) {
+		errs = append(errs, err.Error())
+	})
+
+	// unrelated keys, and keys set in Git config, aren't errors
+	config.GitConfigBool("lfs.batch", false)
+	config.GitConfig("lfs.url")
+	config.FetchIncludePaths()
+	assert.Equal(t, 0, len(errs))
+
+	config.GitConfig("remote.Other.lfsurl")
+	config.GitConfig("remote.other.lfsurl")
+	config.LfsRoutes()
+	config.LfsRoutes()
+	assert.Equal(t, []string{
+		"Error reading .lfsconfig:\n  remote.other.lfsurl: ${OTHER} is not set",
+		"Error reading .lfsconfig:\n  remote.textures.lfsroute: ${ROUTE} is not set",
+	}, errs)
+}
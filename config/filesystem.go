@@ -57,6 +57,27 @@ func resolveReferenceDir(gitStorageDir string) string {
 	return ""
 }
 
+// ReferenceRepoObjectsDir returns the Git LFS object store of the repository
+// at repo, which may be a working tree, its .git directory or a bare
+// repository.
+func ReferenceRepoObjectsDir(repo string) (string, error) {
+	abs, err := filepath.Abs(repo)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range []string{
+		filepath.Join(abs, ".git", "lfs", "objects"),
+		filepath.Join(abs, "lfs", "objects"),
+	} {
+		if tools.DirExists(dir) {
+			return tools.ResolveSymlinks(dir), nil
+		}
+	}
+
+	return "", fmt.Errorf("No Git LFS objects found in reference repository %s", repo)
+}
+
 // From a git dir, get the location that objects are to be stored (we will store lfs alongside)
 // Sometimes there is an additional level of redirect on the .git folder by way of a commondir file
 // before you find object storage, e.g. 'git worktree' uses this. It redirects to gitdir either by GIT_DIR
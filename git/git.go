@@ -466,6 +466,201 @@ func FormatGitDate(tm time.Time) string {
 	return tm.Format("Mon Jan 2 15:04:05 2006 -0700")
 }
 
+// ParseApproxDate converts a date as accepted by `git log --since`, either
+// absolute ("2016-05-01") or relative ("2.weeks", "3 days ago"), into a time.
+// Git does the parsing so the forms match exactly what users already know.
+func ParseApproxDate(str string) (time.Time, error) {
+	if len(strings.TrimSpace(str)) == 0 {
+		return time.Time{}, errors.New("Empty date")
+	}
+
+	out, err := subprocess.SimpleExec("git", "rev-parse", "--since="+str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to parse date %q: %v", str, err)
+	}
+
+	secs, err := strconv.ParseInt(strings.TrimPrefix(out, "--max-age="), 10, 64)
+	if err != nil || !strings.HasPrefix(out, "--max-age=") {
+		return time.Time{}, fmt.Errorf("Failed to parse date %q: unexpected output %q", str, out)
+	}
+
+	return time.Unix(secs, 0), nil
+}
+
+// IndexStages returns the blob SHA-1 of each entry for path in the index, keyed
+// by merge stage: 0 for a path which is not conflicted, otherwise 1 (base),
+// 2 (ours) and 3 (theirs), any of which may be absent. Paths are relative to
+// the current directory, as with other git commands.
+func IndexStages(path string) (map[int]string, error) {
+	cmd := subprocess.ExecCommand("git", "ls-files", "--stage", "-z", "--", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git ls-files: %v", err)
+	}
+
+	want := filepath.ToSlash(filepath.Clean(path))
+	stages := make(map[int]string)
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> SP <sha1> SP <stage> TAB <path>
+		tab := strings.Index(entry, "\t")
+		if tab < 0 || entry[tab+1:] != want {
+			continue
+		}
+
+		fields := strings.Fields(entry[:tab])
+		if len(fields) != 3 {
+			continue
+		}
+
+		stage, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		stages[stage] = fields[1]
+	}
+
+	return stages, nil
+}
+
+// IndexEntry is the stage 0 index entry for a path, along with the stat data
+// git recorded for the working tree file when the entry was last refreshed.
+// Size, Dev and Ino are truncated to 32 bits, as git stores them.
+type IndexEntry struct {
+	Sha1  string
+	Ctime time.Time
+	Mtime time.Time
+	Dev   uint32
+	Ino   uint32
+	Uid   uint32
+	Gid   uint32
+	Size  uint32
+	// IndexMtime is the modification time of the index file itself, against
+	// which git judges whether the stat data can be trusted.
+	IndexMtime time.Time
+}
+
+// GetIndexEntry returns the stage 0 index entry for path, or nil if the path
+// is not in the index or is conflicted. Paths are relative to the current
+// directory, as with other git commands.
+func GetIndexEntry(path string) (*IndexEntry, error) {
+	out, err := subprocess.ExecCommand("git", "ls-files", "--stage", "--debug", "--", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git ls-files: %v", err)
+	}
+
+	want := filepath.ToSlash(filepath.Clean(path))
+	var entry *IndexEntry
+	for _, line := range strings.Split(string(out), "\n") {
+		// <mode> SP <sha1> SP <stage> TAB <path>, followed by indented lines
+		// of stat data such as "  mtime: <sec>:<nsec>" and "  size: <n>".
+		if tab := strings.Index(line, "\t"); tab >= 0 && !strings.HasPrefix(line, " ") {
+			fields := strings.Fields(line[:tab])
+			if line[tab+1:] != want || len(fields) != 3 || fields[2] != "0" {
+				return nil, nil
+			}
+			entry = &IndexEntry{Sha1: fields[1]}
+			continue
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		for _, stat := range strings.Split(strings.TrimSpace(line), "\t") {
+			parts := strings.SplitN(stat, ": ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			var err error
+			switch parts[0] {
+			case "ctime":
+				entry.Ctime, err = parseIndexTime(parts[1])
+			case "mtime":
+				entry.Mtime, err = parseIndexTime(parts[1])
+			case "dev":
+				entry.Dev, err = parseIndexUint32(parts[1])
+			case "ino":
+				entry.Ino, err = parseIndexUint32(parts[1])
+			case "uid":
+				entry.Uid, err = parseIndexUint32(parts[1])
+			case "gid":
+				entry.Gid, err = parseIndexUint32(parts[1])
+			case "size":
+				entry.Size, err = parseIndexUint32(parts[1])
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Invalid index %s for %s: %q", parts[0], path, parts[1])
+			}
+		}
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	indexFile, err := subprocess.SimpleExec("git", "rev-parse", "--git-path", "index")
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	entry.IndexMtime = stat.ModTime()
+
+	return entry, nil
+}
+
+// parseIndexTime parses a "<sec>:<nsec>" time from `git ls-files --debug`.
+func parseIndexTime(s string) (time.Time, error) {
+	var sec, nsec int64
+	if _, err := fmt.Sscanf(s, "%d:%d", &sec, &nsec); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+func parseIndexUint32(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	return uint32(n), err
+}
+
+// ModifiedPaths returns the paths, relative to the root of the repository, of
+// files which differ from what has been pushed to remote: changes in the
+// working tree or index relative to HEAD, and changes made by commits on HEAD
+// which are not yet on any of remote's branches. remote can be left blank to
+// mean 'any remote'.
+func ModifiedPaths(remote string) ([]string, error) {
+	remotes := "--remotes"
+	if len(remote) > 0 {
+		remotes = "--remotes=" + remote
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, args := range [][]string{
+		{"diff", "--name-only", "-z", "HEAD"},
+		{"log", "--name-only", "-z", "--format=", "HEAD", "--not", remotes},
+	} {
+		out, err := subprocess.ExecCommand("git", args...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to call git %s: %v", args[0], err)
+		}
+
+		for _, path := range strings.Split(string(out), "\x00") {
+			path = strings.TrimSpace(path)
+			if len(path) > 0 && !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
 // Get summary information about a commit
 func GetCommitSummary(commit string) (*CommitSummary, error) {
 	cmd := subprocess.ExecCommand("git", "show", "-s",
@@ -552,6 +747,14 @@ func RootDir() (string, error) {
 
 }
 
+// IsShallow returns whether the repository whose objects are in gitDir is a
+// shallow clone, from the shallow file Git keeps there listing the commits its
+// history stops at.
+func IsShallow(gitDir string) bool {
+	stat, err := os.Stat(filepath.Join(gitDir, "shallow"))
+	return err == nil && stat.Size() > 0
+}
+
 func GitDir() (string, error) {
 	cmd := subprocess.ExecCommand("git", "rev-parse", "--git-dir")
 	out, err := cmd.Output()
@@ -565,6 +768,84 @@ func GitDir() (string, error) {
 	return "", nil
 }
 
+// SubmodulePaths returns the absolute paths of the initialized submodules of
+// the current repository. Nested submodules are not included.
+func SubmodulePaths() ([]string, error) {
+	root, err := RootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := subprocess.ExecCommand("git", "submodule", "--quiet", "foreach", "pwd")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git submodule foreach: %v %v", err, string(out))
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if path := strings.TrimSpace(line); len(path) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// UntrackedLfsFiles returns the paths, relative to the root of the repository,
+// of files in the working tree which Git neither tracks nor ignores, but which
+// the lfs filter would clean if they were added.
+func UntrackedLfsFiles() ([]string, error) {
+	root, err := RootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := subprocess.ExecCommand("git", "ls-files", "--others", "--exclude-standard", "-z")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git ls-files: %v", err)
+	}
+
+	var others []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if len(path) > 0 {
+			others = append(others, path)
+		}
+	}
+	if len(others) == 0 {
+		return nil, nil
+	}
+
+	cmd = subprocess.ExecCommand("git", "check-attr", "-z", "--stdin", "filter")
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(strings.Join(others, "\x00") + "\x00")
+	out, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git check-attr: %v", err)
+	}
+
+	// Each path is reported as <path> NUL filter NUL <value> NUL
+	var paths []string
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+2] == "lfs" {
+			paths = append(paths, fields[i])
+		}
+	}
+	return paths, nil
+}
+
+// UsesLfs returns whether any .gitattributes file at HEAD in the repository
+// at dir assigns the lfs filter to some path.
+func UsesLfs(dir string) bool {
+	cmd := subprocess.ExecCommand("git", "grep", "-q", "filter=lfs", "HEAD", "--",
+		".gitattributes", "*/.gitattributes")
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
 // GetAllWorkTreeHEADs returns the refs that all worktrees are using as HEADs
 // This returns all worktrees plus the master working copy, and works even if
 // working dir is actually in a worktree right now
@@ -942,6 +1223,30 @@ func GetTrackedFiles(pattern string) ([]string, error) {
 
 }
 
+// CheckAttributes returns the values Git resolves for the given attributes of
+// path, as `git check-attr` reports them: "set", "unset", "unspecified", or the
+// attribute's value. The path is relative to the current working directory.
+func CheckAttributes(path string, attrs ...string) (map[string]string, error) {
+	args := append([]string{"check-attr", "-z"}, attrs...)
+	args = append(args, "--", path)
+
+	output, err := subprocess.SimpleExec("git", args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git check-attr: %v", err)
+	}
+
+	// Each attribute is reported as <path> NUL <attribute> NUL <value> NUL
+	fields := strings.Split(output, "\x00")
+	values := make(map[string]string, len(attrs))
+	for i := 0; i+2 < len(fields); i += 3 {
+		values[fields[i+1]] = fields[i+2]
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("git check-attr reported no attributes for %q", path)
+	}
+	return values, nil
+}
+
 func sanitizePattern(pattern string) string {
 	if strings.HasPrefix(pattern, "/") {
 		return pattern[1:]
@@ -265,6 +265,26 @@ func TestWorkTrees(t *testing.T) {
 	assert.Equal(t, expectedRefs, refs, "Refs should be correct")
 }
 
+func TestParseApproxDate(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	abs, err := ParseApproxDate("2016-05-01 12:00:00 +0000")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC).Unix(), abs.Unix())
+
+	rel, err := ParseApproxDate("2.weeks")
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -14), rel, time.Minute)
+
+	_, err = ParseApproxDate("")
+	assert.NotNil(t, err)
+}
+
 func TestVersionCompare(t *testing.T) {
 	assert.True(t, IsVersionAtLeast("2.6.0", "2.6.0"))
 	assert.True(t, IsVersionAtLeast("2.6.0", "2.6"))
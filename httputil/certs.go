@@ -5,26 +5,56 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/github/git-lfs/config"
 	"github.com/rubyist/tracerx"
 )
 
 // isCertVerificationDisabledForHost returns whether SSL certificate verification
-// has been disabled for the given host, or globally
+// has been disabled for the given host, or globally. A setting for the host
+// takes precedence over http.sslverify, so verification can be turned off for
+// a single development server, or back on for one host when it is off
+// everywhere else.
 func isCertVerificationDisabledForHost(cfg *config.Configuration, host string) bool {
-	hostSslVerify, _ := cfg.GitConfig(fmt.Sprintf("http.https://%v/.sslverify", host))
-	if hostSslVerify == "false" {
+	if cfg.GetenvBool("GIT_SSL_NO_VERIFY", false) {
 		return true
 	}
 
-	globalSslVerify, _ := cfg.GitConfig("http.sslverify")
-	if globalSslVerify == "false" || cfg.GetenvBool("GIT_SSL_NO_VERIFY", false) {
-		return true
+	if hostSslVerify, ok := hostGitConfig(cfg, host, "sslverify"); ok {
+		return !parseSslVerify(hostSslVerify)
 	}
 
-	return false
+	globalSslVerify, _ := cfg.GitConfig("http.sslverify")
+	return !parseSslVerify(globalSslVerify)
+}
 
+// parseSslVerify returns whether an sslverify value leaves verification on,
+// which it does unless the value is one of git's false booleans, so that a
+// mistyped value doesn't turn verification off.
+func parseSslVerify(value string) bool {
+	switch strings.ToLower(value) {
+	case "false", "0", "off", "no", "f":
+		return false
+	}
+	return true
+}
+
+// hostGitConfig returns the value of the given http setting for the host
+// (which may be "host:port"), looking at lfs.https://<host>/.<key> and
+// lfs.https://<host>.<key> first, so that a setting can be made for Git LFS
+// alone, and then git's own http.https://<host>/.<key> and
+// http.https://<host>.<key>.
+func hostGitConfig(cfg *config.Configuration, host, key string) (string, bool) {
+	for _, section := range []string{"lfs", "http"} {
+		if value, ok := cfg.GitConfig(fmt.Sprintf("%s.https://%v/.%s", section, host, key)); ok {
+			return value, true
+		}
+		if value, ok := cfg.GitConfig(fmt.Sprintf("%s.https://%v.%s", section, host, key)); ok {
+			return value, true
+		}
+	}
+	return "", false
 }
 
 // getRootCAsForHost returns a certificate pool for that specific host (which may
@@ -51,14 +81,9 @@ func appendRootCAsForHostFromGitconfig(cfg *config.Configuration, pool *x509.Cer
 	if cafile := cfg.Getenv("GIT_SSL_CAINFO"); len(cafile) > 0 {
 		return appendCertsFromFile(pool, cafile)
 	}
-	// http.<url>/.sslcainfo or http.<url>.sslcainfo
-	// we know we have simply "host" or "host:port"
-	hostKeyWithSlash := fmt.Sprintf("http.https://%v/.sslcainfo", host)
-	if cafile, ok := cfg.GitConfig(hostKeyWithSlash); ok {
-		return appendCertsFromFile(pool, cafile)
-	}
-	hostKeyWithoutSlash := fmt.Sprintf("http.https://%v.sslcainfo", host)
-	if cafile, ok := cfg.GitConfig(hostKeyWithoutSlash); ok {
+	// lfs.<url>.sslcainfo or http.<url>.sslcainfo, with or without a
+	// trailing slash; we know we have simply "host" or "host:port"
+	if cafile, ok := hostGitConfig(cfg, host, "sslcainfo"); ok {
 		return appendCertsFromFile(pool, cafile)
 	}
 	// http.sslcainfo
@@ -175,3 +175,67 @@ func TestCertVerifyDisabledHostConfig(t *testing.T) {
 	assert.True(t, isCertVerificationDisabledForHost(cfg, "specifichost.com"))
 	assert.False(t, isCertVerificationDisabledForHost(cfg, "otherhost.com"))
 }
+
+func TestCertFromLfsSSLCAInfoConfig(t *testing.T) {
+	tempfile, err := ioutil.TempFile("", "testcert")
+	assert.Nil(t, err, "Error creating temp cert file")
+	defer os.Remove(tempfile.Name())
+
+	_, err = tempfile.WriteString(testCert)
+	assert.Nil(t, err, "Error writing temp cert file")
+	tempfile.Close()
+
+	for _, hostName := range sslCAInfoConfigHostNames {
+		cfg := config.New()
+		cfg.SetAllEnv(map[string]string{"GIT_SSL_CAINFO": ""})
+
+		hostKey := fmt.Sprintf("lfs.https://%v.sslcainfo", hostName)
+		cfg.SetConfig(hostKey, tempfile.Name())
+
+		for _, matchedHostTest := range sslCAInfoMatchedHostTests {
+			pool := getRootCAsForHost(cfg, matchedHostTest.hostName)
+			assert.Equal(t, matchedHostTest.shouldMatch, pool != nil,
+				"Cert lookup for %q with %q", matchedHostTest.hostName, hostKey)
+		}
+	}
+}
+
+func TestCertLfsSSLCAInfoTakesPrecedence(t *testing.T) {
+	tempfile, err := ioutil.TempFile("", "testcert")
+	assert.Nil(t, err, "Error creating temp cert file")
+	defer os.Remove(tempfile.Name())
+
+	_, err = tempfile.WriteString(testCert)
+	assert.Nil(t, err, "Error writing temp cert file")
+	tempfile.Close()
+
+	cfg := config.New()
+	cfg.SetAllEnv(map[string]string{"GIT_SSL_CAINFO": ""})
+	cfg.SetConfig("lfs.https://git-lfs.local/.sslcainfo", tempfile.Name())
+	cfg.SetConfig("http.https://git-lfs.local/.sslcainfo", filepath.Join(os.TempDir(), "missing-cert-file"))
+	cfg.SetConfig("http.sslcainfo", filepath.Join(os.TempDir(), "missing-cert-file"))
+
+	assert.NotNil(t, getRootCAsForHost(cfg, "git-lfs.local"))
+	assert.Nil(t, getRootCAsForHost(cfg, "otherhost.com"))
+}
+
+func TestCertVerifyDisabledLfsHostConfig(t *testing.T) {
+	cfg := config.New()
+	cfg.SetConfig("lfs.https://specifichost.com:8443.sslverify", "false")
+	assert.True(t, isCertVerificationDisabledForHost(cfg, "specifichost.com:8443"))
+	assert.False(t, isCertVerificationDisabledForHost(cfg, "specifichost.com"))
+}
+
+func TestCertVerifyHostConfigOverridesGlobal(t *testing.T) {
+	cfg := config.New()
+	cfg.SetConfig("http.sslverify", "false")
+	cfg.SetConfig("http.https://specifichost.com/.sslverify", "true")
+	assert.False(t, isCertVerificationDisabledForHost(cfg, "specifichost.com"))
+	assert.True(t, isCertVerificationDisabledForHost(cfg, "otherhost.com"))
+}
+
+func TestCertVerifyInvalidValueLeavesVerificationOn(t *testing.T) {
+	cfg := config.New()
+	cfg.SetConfig("http.sslverify", "flase")
+	assert.False(t, isCertVerificationDisabledForHost(cfg, "anyhost.com"))
+}
@@ -5,20 +5,26 @@ package httputil
 import (
 	"bufio"
 	"bytes"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/http/httputil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
 	"github.com/rubyist/tracerx"
 )
 
@@ -32,8 +38,13 @@ type httpTransferStats struct {
 type httpTransfer struct {
 	requestStats  *httpTransferStats
 	responseStats *httpTransferStats
+	timings       *httpTimings
 }
 
+// expectContinueTimeout is how long a request with "Expect: 100-continue"
+// waits for the server's response before sending its body regardless.
+const expectContinueTimeout = time.Second
+
 var (
 	// TODO should use some locks
 	httpTransfers           = make(map[*http.Response]*httpTransfer)
@@ -43,10 +54,36 @@ var (
 	httpClients             map[string]*HttpClient
 	httpClientsMutex        sync.Mutex
 	UserAgent               string
+	requestId               string
+	requestIdOnce           sync.Once
 )
 
+// RequestId returns the random ID sent as X-Git-Lfs-Request-Id with every
+// request made by this process, so that servers can correlate all the requests
+// belonging to one push, pull or fetch.
+func RequestId() string {
+	requestIdOnce.Do(func() {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			requestId = fmt.Sprintf("%x", time.Now().UnixNano())
+			return
+		}
+		requestId = hex.EncodeToString(b)
+	})
+
+	return requestId
+}
+
+// userAgent returns UserAgent with any configured suffix appended.
+func userAgent(cfg *config.Configuration) string {
+	if suffix := cfg.UserAgentSuffix(); len(suffix) > 0 {
+		return UserAgent + " " + suffix
+	}
+	return UserAgent
+}
+
 func LogTransfer(cfg *config.Configuration, key string, res *http.Response) {
-	if cfg.IsLoggingStats {
+	if collectingStats(cfg) {
 		httpTransferBucketsLock.Lock()
 		httpTransferBuckets[key] = append(httpTransferBuckets[key], res)
 		httpTransferBucketsLock.Unlock()
@@ -59,6 +96,13 @@ type HttpClient struct {
 }
 
 func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
+	if err := checkOffline(c.Config, req); err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", userAgent(c.Config))
+	req.Header.Set("X-Git-Lfs-Request-Id", RequestId())
+
 	traceHttpRequest(c.Config, req)
 
 	crc := countingRequest(c.Config, req)
@@ -68,6 +112,13 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 		req.Body = crc
 	}
 
+	var timings *httpTimings
+	if collectingStats(c.Config) {
+		var trace *httptrace.ClientTrace
+		timings, trace = newHttpTimings()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	}
+
 	start := time.Now()
 	res, err := c.Client.Do(req)
 	if err != nil {
@@ -79,7 +130,7 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 	cresp := countingResponse(c.Config, res)
 	res.Body = cresp
 
-	if c.Config.IsLoggingStats {
+	if collectingStats(c.Config) {
 		reqHeaderSize := 0
 		resHeaderSize := 0
 
@@ -96,7 +147,7 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 		// Response body size cannot be figured until it is read. Do not rely on a Content-Length
 		// header because it may not exist or be -1 in the case of chunked responses.
 		resstats := &httpTransferStats{HeaderSize: resHeaderSize, Start: start}
-		t := &httpTransfer{requestStats: reqstats, responseStats: resstats}
+		t := &httpTransfer{requestStats: reqstats, responseStats: resstats, timings: timings}
 		httpTransfersLock.Lock()
 		httpTransfers[res] = t
 		httpTransfersLock.Unlock()
@@ -105,6 +156,16 @@ func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
 	return res, err
 }
 
+// checkOffline returns a fatal error if lfs.offline is set, since no request
+// may leave the machine in that mode.
+func checkOffline(cfg *config.Configuration, req *http.Request) error {
+	if !cfg.Offline() {
+		return nil
+	}
+
+	return errutil.NewFatalError(fmt.Errorf("lfs.offline is set, refusing to contact %s", req.URL.Host))
+}
+
 // NewHttpClient returns a new HttpClient for the given host (which may be "host:port")
 func NewHttpClient(c *config.Configuration, host string) *HttpClient {
 	httpClientsMutex.Lock()
@@ -120,19 +181,33 @@ func NewHttpClient(c *config.Configuration, host string) *HttpClient {
 	dialtime := c.GitConfigInt("lfs.dialtimeout", 30)
 	keepalivetime := c.GitConfigInt("lfs.keepalive", 1800) // 30 minutes
 	tlstime := c.GitConfigInt("lfs.tlstimeout", 30)
+	maxidleconns := c.GitConfigInt("lfs.http.maxidleconns", c.ConcurrentTransfers())
+	idleconntime := 90
+	// GitConfigInt treats 0 as unset, but here it means "no timeout".
+	if v, ok := c.GitConfig("lfs.http.idleconntimeout"); ok {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			idleconntime = secs
+		}
+	}
 
 	tr := &http.Transport{
 		Proxy: ProxyFromGitConfigOrEnvironment(c),
-		Dial: (&net.Dialer{
+		// DialContext rather than Dial, so that the connection is traced
+		// for the HTTP stats
+		DialContext: (&net.Dialer{
 			Timeout:   time.Duration(dialtime) * time.Second,
 			KeepAlive: time.Duration(keepalivetime) * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout: time.Duration(tlstime) * time.Second,
-		MaxIdleConnsPerHost: c.ConcurrentTransfers(),
+		}).DialContext,
+		TLSHandshakeTimeout:   time.Duration(tlstime) * time.Second,
+		MaxIdleConnsPerHost:   maxidleconns,
+		IdleConnTimeout:       time.Duration(idleconntime) * time.Second,
+		ExpectContinueTimeout: expectContinueTimeout,
 	}
 
 	tr.TLSClientConfig = &tls.Config{}
 	if isCertVerificationDisabledForHost(c, host) {
+		// Clients are cached per host, so this is only printed once.
+		fmt.Fprintf(os.Stderr, "WARNING: SSL certificate verification is disabled for %s. Its identity is not being checked, and objects and credentials sent to it can be intercepted.\n", host)
 		tr.TLSClientConfig.InsecureSkipVerify = true
 	} else {
 		tr.TLSClientConfig.RootCAs = getRootCAsForHost(c, host)
@@ -140,23 +215,35 @@ func NewHttpClient(c *config.Configuration, host string) *HttpClient {
 
 	client := &HttpClient{
 		Config: c,
-		Client: &http.Client{Transport: tr, CheckRedirect: CheckRedirect},
+		Client: &http.Client{Transport: tr, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return CheckRedirect(c, req, via)
+		}},
 	}
 	httpClients[host] = client
 
 	return client
 }
 
-func CheckRedirect(req *http.Request, via []*http.Request) error {
+// CheckRedirect copies the headers of the original request to req, which it
+// was redirected to. The Authorization header is only kept if req is to the
+// same scheme and host, or to a host in lfs.redirect.trustedhosts without
+// going from https to http.
+func CheckRedirect(cfg *config.Configuration, req *http.Request, via []*http.Request) error {
 	if len(via) >= 3 {
 		return errors.New("stopped after 3 redirects")
 	}
 
 	oldest := via[0]
+	authNote := ""
 	for key, _ := range oldest.Header {
 		if key == "Authorization" {
 			if req.URL.Scheme != oldest.URL.Scheme || req.URL.Host != oldest.URL.Host {
-				continue
+				if !isRedirectTrusted(cfg, oldest.URL, req.URL) {
+					req.Header.Del(key)
+					authNote = ", dropping the Authorization header"
+					continue
+				}
+				authNote = ", keeping the Authorization header for a trusted host"
 			}
 		}
 		req.Header.Set(key, oldest.Header.Get(key))
@@ -164,11 +251,35 @@ func CheckRedirect(req *http.Request, via []*http.Request) error {
 
 	oldestUrl := strings.SplitN(oldest.URL.String(), "?", 2)[0]
 	newUrl := strings.SplitN(req.URL.String(), "?", 2)[0]
-	tracerx.Printf("api: redirect %s %s to %s", oldest.Method, oldestUrl, newUrl)
+	tracerx.Printf("api: redirect %s %s to %s%s", oldest.Method, oldestUrl, newUrl, authNote)
 
 	return nil
 }
 
+// isRedirectTrusted returns whether credentials for from may be sent to to,
+// because its host is in lfs.redirect.trustedhosts.
+func isRedirectTrusted(cfg *config.Configuration, from, to *url.URL) bool {
+	if from.Scheme == "https" && to.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(to.Host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	for _, trusted := range cfg.RedirectTrustedHosts() {
+		if trusted == host || trusted == hostname {
+			return true
+		}
+		if strings.HasPrefix(trusted, "*.") && strings.HasSuffix(hostname, trusted[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
 var tracedTypes = []string{"json", "text", "xml", "html"}
 
 func traceHttpRequest(cfg *config.Configuration, req *http.Request) {
@@ -283,7 +394,7 @@ func (c *CountingReadCloser) Read(b []byte) (int, error) {
 		}
 	}
 
-	if err == io.EOF && c.cfg.IsLoggingStats {
+	if err == io.EOF && collectingStats(c.cfg) {
 		// This httpTransfer is done, we're checking it this way so we can also
 		// catch httpTransfers where the caller forgets to Close() the Body.
 		if c.response != nil {
@@ -291,6 +402,9 @@ func (c *CountingReadCloser) Read(b []byte) (int, error) {
 			if httpTransfer, ok := httpTransfers[c.response]; ok {
 				httpTransfer.responseStats.BodySize = c.Count
 				httpTransfer.responseStats.Stop = time.Now()
+				if httpTransfer.timings != nil {
+					httpTransfer.timings.finish()
+				}
 			}
 			httpTransfersLock.Unlock()
 		}
@@ -312,7 +426,7 @@ func LogHttpStats(cfg *config.Configuration) {
 		return
 	}
 
-	fmt.Fprintf(file, "concurrent=%d batch=%v time=%d version=%s\n", cfg.ConcurrentTransfers(), cfg.BatchTransfer(), time.Now().Unix(), config.Version)
+	fmt.Fprintf(file, "concurrent=%d batch=%v time=%d version=%s requestid=%s useragent=%q\n", cfg.ConcurrentTransfers(), cfg.BatchTransfer(), time.Now().Unix(), config.Version, RequestId(), userAgent(cfg))
 
 	for key, responses := range httpTransferBuckets {
 		for _, response := range responses {
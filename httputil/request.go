@@ -86,8 +86,28 @@ func doHttpRequest(cfg *config.Configuration, req *http.Request, creds auth.Cred
 	return res, err
 }
 
+// doHttpRequestWithCredsRefresh runs doHttpRequest, and if the server rejects
+// credentials from the credential helper with a 401, makes the error
+// retriable. By then handleResponse has told the helper to reject (erase)
+// them, so when the transfer queue retries the request, it asks the helper
+// for fresh ones. This covers short lived tokens that expire in the middle of
+// a long push or fetch, with the queue's usual limit of one retry. A 403 means
+// the credentials aren't allowed to do this, so isn't retried.
+func doHttpRequestWithCredsRefresh(cfg *config.Configuration, req *http.Request, creds auth.Creds) (*http.Response, error) {
+	res, err := doHttpRequest(cfg, req, creds)
+	if err != nil && creds != nil && res != nil && res.StatusCode == 401 {
+		tracerx.Printf("api: credentials rejected with HTTP 401, asking the credential helper for fresh ones on retry")
+		return res, errutil.NewRetriableError(err)
+	}
+	return res, err
+}
+
 // DoHttpRequest performs a single HTTP request
 func DoHttpRequest(cfg *config.Configuration, req *http.Request, useCreds bool) (*http.Response, error) {
+	if err := checkOffline(cfg, req); err != nil {
+		return nil, err
+	}
+
 	var creds auth.Creds
 	if useCreds {
 		c, err := auth.GetCreds(cfg, req)
@@ -97,11 +117,15 @@ func DoHttpRequest(cfg *config.Configuration, req *http.Request, useCreds bool)
 		creds = c
 	}
 
-	return doHttpRequest(cfg, req, creds)
+	return doHttpRequestWithCredsRefresh(cfg, req, creds)
 }
 
 // DoHttpRequestWithRedirects runs a HTTP request and responds to redirects
 func DoHttpRequestWithRedirects(cfg *config.Configuration, req *http.Request, via []*http.Request, useCreds bool) (*http.Response, error) {
+	if err := checkOffline(cfg, req); err != nil {
+		return nil, err
+	}
+
 	var creds auth.Creds
 	if useCreds {
 		c, err := auth.GetCreds(cfg, req)
@@ -111,12 +135,17 @@ func DoHttpRequestWithRedirects(cfg *config.Configuration, req *http.Request, vi
 		creds = c
 	}
 
-	res, err := doHttpRequest(cfg, req, creds)
+	res, err := doHttpRequestWithCredsRefresh(cfg, req, creds)
 	if err != nil {
 		return res, err
 	}
 
 	if res.StatusCode == 307 {
+		// Nothing else reads the redirect's body, drain it so the connection
+		// can be reused for the redirected request
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+
 		redirectTo := res.Header.Get("Location")
 		locurl, err := url.Parse(redirectTo)
 		if err == nil && !locurl.IsAbs() {
@@ -148,7 +177,7 @@ func DoHttpRequestWithRedirects(cfg *config.Configuration, req *http.Request, vi
 		redirectedReq.Body = realBody
 		redirectedReq.ContentLength = req.ContentLength
 
-		if err = CheckRedirect(redirectedReq, via); err != nil {
+		if err = CheckRedirect(cfg, redirectedReq, via); err != nil {
 			return res, errutil.Errorf(err, err.Error())
 		}
 
@@ -1,9 +1,19 @@
 package httputil
 
 import (
+	"bytes"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
 
+	"github.com/github/git-lfs/auth"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/tools"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,3 +53,282 @@ func TestGetAuthType(t *testing.T) {
 		c.Assert(t)
 	}
 }
+
+func TestDoHttpRequestOffline(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.offline": "true",
+	})
+
+	req, err := http.NewRequest("GET", srv.URL+"/objects/batch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := DoHttpRequest(cfg, req, false)
+	assert.Nil(t, res)
+	assert.True(t, errutil.IsFatalError(err))
+	assert.False(t, called)
+}
+
+func TestDoHttpRequestUserAgentAndRequestId(t *testing.T) {
+	var agents, ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		ids = append(ids, r.Header.Get("X-Git-Lfs-Request-Id"))
+	}))
+	defer srv.Close()
+
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.useragent.suffix": "ci-job-1",
+	})
+
+	for i := 0; i < 2; i++ {
+		req, err := NewHttpRequest("GET", srv.URL+"/objects/batch", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = DoHttpRequest(cfg, req, false)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, []string{UserAgent + " ci-job-1", UserAgent + " ci-job-1"}, agents)
+	assert.Len(t, ids[0], 32)
+	assert.Equal(t, ids[0], ids[1])
+	assert.Equal(t, RequestId(), ids[0])
+}
+
+func TestUserAgentSuffixFromEnv(t *testing.T) {
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.useragent.suffix": "from-config",
+	})
+	assert.Equal(t, UserAgent+" from-config", userAgent(cfg))
+
+	cfg.SetAllEnv(map[string]string{
+		"GIT_LFS_USER_AGENT_SUFFIX": "from-env",
+	})
+	assert.Equal(t, UserAgent+" from-env", userAgent(cfg))
+}
+
+func TestDoHttpRequestRefreshesRejectedCreds(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		by, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(by))
+
+		if _, pass, _ := r.BasicAuth(); pass != "fresh" {
+			w.WriteHeader(401)
+		}
+	}))
+	defer srv.Close()
+
+	var calls []string
+	password := "stale"
+	oldCredsFunc := auth.SetCredentialsFunc(func(cfg *config.Configuration, input auth.Creds, subCommand string) (auth.Creds, error) {
+		calls = append(calls, subCommand+" "+input["password"])
+		if subCommand == "reject" {
+			password = "fresh"
+		}
+		return auth.Creds{"username": "user", "password": password}, nil
+	})
+	defer auth.SetCredentialsFunc(oldCredsFunc)
+
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.url": srv.URL,
+	})
+
+	newReq := func() *http.Request {
+		req, err := NewHttpRequest("POST", srv.URL+"/objects/batch", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Body = tools.NewReadSeekCloserWrapper(bytes.NewReader([]byte("body")))
+		return req
+	}
+
+	// the rejected credentials are erased, and the request is left to be
+	// retried
+	res, err := DoHttpRequest(cfg, newReq(), true)
+	assert.True(t, errutil.IsRetriableError(err))
+	assert.Equal(t, 401, res.StatusCode)
+	assert.Equal(t, []string{"body"}, bodies)
+	assert.Equal(t, []string{"fill ", "reject stale"}, calls)
+
+	// the retry gets fresh credentials from the helper
+	res, err = DoHttpRequest(cfg, newReq(), true)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, []string{"body", "body"}, bodies)
+	assert.Equal(t, []string{"fill ", "reject stale", "fill ", "approve fresh"}, calls)
+}
+
+func TestDoHttpRequestDoesNotRetryForbidden(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(403)
+	}))
+	defer srv.Close()
+
+	oldCredsFunc := auth.SetCredentialsFunc(func(cfg *config.Configuration, input auth.Creds, subCommand string) (auth.Creds, error) {
+		return auth.Creds{"username": "user", "password": "pass"}, nil
+	})
+	defer auth.SetCredentialsFunc(oldCredsFunc)
+
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.url": srv.URL,
+	})
+
+	req, err := NewHttpRequest("GET", srv.URL+"/objects/batch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := DoHttpRequest(cfg, req, true)
+	assert.NotNil(t, err)
+	assert.False(t, errutil.IsRetriableError(err))
+	assert.Equal(t, 403, res.StatusCode)
+	assert.Equal(t, 1, requests)
+}
+
+func TestDoHttpRequestReusesConnections(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+
+		switch r.URL.Path {
+		case "/redirect":
+			w.Header().Set("Location", "/json")
+			w.WriteHeader(307)
+			w.Write([]byte("moved"))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"message":"hi"}` + "\n"))
+		case "/missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(404)
+			w.Write([]byte(`{"message":"not found"}`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("some text that nobody reads"))
+		}
+	}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	cfg := config.NewFromValues(map[string]string{})
+
+	for i := 0; i < 3; i++ {
+		for _, path := range []string{"/json", "/text", "/missing"} {
+			req, err := NewHttpRequest("GET", srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := DoHttpRequest(cfg, req, false)
+			if path == "/missing" {
+				assert.NotNil(t, err)
+				continue
+			}
+			assert.Nil(t, err)
+
+			var obj ClientError
+			assert.Nil(t, DecodeResponse(res, &obj))
+		}
+
+		req, err := NewHttpRequest("POST", srv.URL+"/redirect", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Body = tools.NewReadSeekCloserWrapper(bytes.NewReader([]byte("body")))
+
+		res, err := DoHttpRequestWithRedirects(cfg, req, nil, false)
+		assert.Nil(t, err)
+
+		var obj ClientError
+		assert.Nil(t, DecodeResponse(res, &obj))
+		assert.Equal(t, "hi", obj.Message)
+	}
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&conns))
+}
+
+// redirectAuthHeader redirects a request from one test server to another,
+// which is on a different port and so a different host, and returns the
+// Authorization header the second server received.
+func redirectAuthHeader(t *testing.T, trustedHosts func(target *url.URL) string) string {
+	var auth string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+"/object", 302)
+	}))
+	defer srv.Close()
+
+	targetUrl, _ := url.Parse(target.URL)
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.redirect.trustedhosts": trustedHosts(targetUrl),
+	})
+
+	req, err := NewHttpRequest("GET", srv.URL+"/object", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := DoHttpRequest(cfg, req, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	return auth
+}
+
+func TestDoHttpRequestRedirectDropsAuthForOtherHosts(t *testing.T) {
+	auth := redirectAuthHeader(t, func(target *url.URL) string { return "" })
+	assert.Equal(t, "", auth)
+
+	auth = redirectAuthHeader(t, func(target *url.URL) string { return "lfs.example.com, 127.0.0.2" })
+	assert.Equal(t, "", auth)
+}
+
+func TestDoHttpRequestRedirectKeepsAuthForTrustedHosts(t *testing.T) {
+	auth := redirectAuthHeader(t, func(target *url.URL) string { return "lfs.example.com," + target.Host })
+	assert.Equal(t, "Basic dXNlcjpwYXNz", auth)
+
+	// a bare hostname trusts every port
+	auth = redirectAuthHeader(t, func(target *url.URL) string { return target.Hostname() })
+	assert.Equal(t, "Basic dXNlcjpwYXNz", auth)
+}
+
+func TestIsRedirectTrusted(t *testing.T) {
+	cfg := config.NewFromValues(map[string]string{
+		"lfs.redirect.trustedhosts": "Storage.example.com, *.cdn.example.com, other.example.com:8443",
+	})
+	from, _ := url.Parse("https://lfs.example.com/objects")
+
+	for rawurl, trusted := range map[string]bool{
+		"https://storage.example.com/a":      true,
+		"https://storage.example.com:8080/a": true,
+		"https://eu.cdn.example.com/a":       true,
+		"https://cdn.example.com/a":          false,
+		"https://other.example.com:8443/a":   true,
+		"https://other.example.com/a":        false,
+		"https://evil.example.org/a":         false,
+		"http://storage.example.com/a":       false,
+	} {
+		to, _ := url.Parse(rawurl)
+		assert.Equal(t, trusted, isRedirectTrusted(cfg, from, to), rawurl)
+	}
+}
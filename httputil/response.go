@@ -33,6 +33,9 @@ var (
 func DecodeResponse(res *http.Response, obj interface{}) error {
 	ctype := res.Header.Get("Content-Type")
 	if !(lfsMediaTypeRE.MatchString(ctype) || jsonMediaTypeRE.MatchString(ctype)) {
+		// Drain the body anyway, so the connection can be reused
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
 		return nil
 	}
 
@@ -0,0 +1,229 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/git-lfs/config"
+)
+
+var (
+	// reportingStats is set by EnableStatsReport, for PrintStatsReport.
+	reportingStats bool
+)
+
+// EnableStatsReport makes HTTP stats be collected, as they are when
+// GIT_LOG_STATS is set, so that PrintStatsReport can summarise them once the
+// command is done.
+func EnableStatsReport() {
+	reportingStats = true
+}
+
+// collectingStats returns whether the stats of each HTTP transfer are to be
+// recorded, either to be logged or reported.
+func collectingStats(cfg *config.Configuration) bool {
+	return cfg.IsLoggingStats || reportingStats
+}
+
+// httpTimings records how long each phase of an HTTP request took. Phases
+// which didn't happen, such as connecting when a kept-alive connection is
+// reused, take no time.
+type httpTimings struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Transfer time.Duration
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	mu           sync.Mutex
+}
+
+// newHttpTimings returns timings for a request starting now, and the trace
+// which fills them in as the request is made.
+func newHttpTimings() (*httpTimings, *httptrace.ClientTrace) {
+	t := &httpTimings{start: time.Now()}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.DNS = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			t.Connect = time.Since(t.connectStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.TLS = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			t.wroteRequest = time.Now()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			since := t.wroteRequest
+			if since.IsZero() {
+				since = t.start
+			}
+			t.TTFB = t.firstByte.Sub(since)
+			t.mu.Unlock()
+		},
+	}
+
+	return t, trace
+}
+
+// finish records the end of the transfer of the response body.
+func (t *httpTimings) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.firstByte.IsZero() {
+		t.Transfer = time.Since(t.firstByte)
+	}
+}
+
+// httpStatsClass is the total of the timings of a class of requests.
+type httpStatsClass struct {
+	Name     string
+	Requests int
+	Bytes    int64
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Transfer time.Duration
+}
+
+func (c *httpStatsClass) add(t *httpTimings, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c.Requests++
+	c.Bytes += size
+	c.DNS += t.DNS
+	c.Connect += t.Connect
+	c.TLS += t.TLS
+	c.TTFB += t.TTFB
+	c.Transfer += t.Transfer
+}
+
+// httpStatsClassName returns the class of request a LogTransfer key belongs
+// to: batch API requests, object transfers, or other API requests.
+func httpStatsClassName(key string) string {
+	switch {
+	case key == "lfs.batch":
+		return "batch"
+	case strings.Contains(key, "download"), strings.Contains(key, "upload"):
+		return "object"
+	default:
+		return "other"
+	}
+}
+
+// PrintStatsReport writes the average time spent in each phase of the HTTP
+// requests made, for each class of request, after EnableStatsReport is called.
+func PrintStatsReport(w io.Writer) {
+	if !reportingStats {
+		return
+	}
+
+	classes := make(map[string]*httpStatsClass)
+
+	httpTransferBucketsLock.Lock()
+	httpTransfersLock.Lock()
+	for key, responses := range httpTransferBuckets {
+		name := httpStatsClassName(key)
+		class, ok := classes[name]
+		if !ok {
+			class = &httpStatsClass{Name: name}
+			classes[name] = class
+		}
+
+		for _, response := range responses {
+			transfer, ok := httpTransfers[response]
+			if !ok || transfer.timings == nil {
+				continue
+			}
+			size := int64(transfer.requestStats.BodySize + transfer.responseStats.BodySize)
+			class.add(transfer.timings, size)
+		}
+	}
+	httpTransfersLock.Unlock()
+	httpTransferBucketsLock.Unlock()
+
+	fmt.Fprint(w, formatStatsReport(classes))
+}
+
+func formatStatsReport(classes map[string]*httpStatsClass) string {
+	names := make([]string, 0, len(classes))
+	for name, class := range classes {
+		if class.Requests > 0 {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "HTTP timings: no requests were made\n"
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP timings, averaged per request:\n")
+	fmt.Fprintf(&b, "  %-7s %8s %10s %8s %8s %8s %8s %9s\n", "class", "requests", "bytes", "dns", "connect", "tls", "ttfb", "transfer")
+	for _, name := range names {
+		c := classes[name]
+		n := time.Duration(c.Requests)
+		fmt.Fprintf(&b, "  %-7s %8d %10d %8s %8s %8s %8s %9s\n", c.Name, c.Requests, c.Bytes,
+			formatStatsDuration(c.DNS/n),
+			formatStatsDuration(c.Connect/n),
+			formatStatsDuration(c.TLS/n),
+			formatStatsDuration(c.TTFB/n),
+			formatStatsDuration(c.Transfer/n))
+	}
+	return b.String()
+}
+
+// formatStatsDuration gives a duration in whole milliseconds, or seconds to two
+// decimal places once it is a second or more.
+func formatStatsDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d/time.Millisecond)
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
@@ -0,0 +1,42 @@
+package httputil
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpStatsClassName(t *testing.T) {
+	assert.Equal(t, "batch", httpStatsClassName("lfs.batch"))
+	assert.Equal(t, "object", httpStatsClassName("lfs.data.download"))
+	assert.Equal(t, "object", httpStatsClassName("lfs.data.upload.part"))
+	assert.Equal(t, "object", httpStatsClassName("lfs.download"))
+	assert.Equal(t, "other", httpStatsClassName("lfs.data.verify"))
+}
+
+func TestFormatStatsReport(t *testing.T) {
+	batch := &httpStatsClass{Name: "batch"}
+	batch.add(&httpTimings{DNS: 2 * time.Millisecond, TTFB: 30 * time.Millisecond}, 100)
+	batch.add(&httpTimings{TTFB: 10 * time.Millisecond}, 50)
+	object := &httpStatsClass{Name: "object"}
+	object.add(&httpTimings{Connect: 4 * time.Millisecond, Transfer: 1500 * time.Millisecond}, 1024)
+
+	report := formatStatsReport(map[string]*httpStatsClass{
+		"batch":  batch,
+		"object": object,
+		"other":  &httpStatsClass{Name: "other"},
+	})
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "HTTP timings, averaged per request:", lines[0])
+	assert.Equal(t, []string{"class", "requests", "bytes", "dns", "connect", "tls", "ttfb", "transfer"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"batch", "2", "150", "1ms", "0ms", "0ms", "20ms", "0ms"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"object", "1", "1024", "0ms", "4ms", "0ms", "0ms", "1.50s"}, strings.Fields(lines[3]))
+}
+
+func TestFormatStatsReportWithoutRequests(t *testing.T) {
+	assert.Equal(t, "HTTP timings: no requests were made\n", formatStatsReport(map[string]*httpStatsClass{}))
+}
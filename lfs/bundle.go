@@ -0,0 +1,226 @@
+package lfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-lfs/tools"
+)
+
+// A bundle is a tar archive holding Git LFS objects, for moving them between
+// machines without a server. Its first entry is an index, named bundleIndexName,
+// with a bundleHeader line followed by an "<oid> <size>" line per object. Each
+// object follows as an entry named bundleObjectPrefix + oid.
+const (
+	bundleHeader       = "git-lfs bundle v1"
+	bundleIndexName    = "index"
+	bundleObjectPrefix = "objects/"
+)
+
+var bundleOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+// BundleRejection describes an object in a bundle which was not imported.
+type BundleRejection struct {
+	Oid    string
+	Reason string
+}
+
+// UnbundleResult summarises the import of a bundle into the local store.
+type UnbundleResult struct {
+	Imported int
+	Existing int
+	Rejected []*BundleRejection
+}
+
+func (r *UnbundleResult) reject(oid, format string, args ...interface{}) {
+	r.Rejected = append(r.Rejected, &BundleRejection{oid, fmt.Sprintf(format, args...)})
+}
+
+// WriteBundle writes a bundle of the given pointers' objects, all of which must
+// be in the local store, to w.
+func WriteBundle(w io.Writer, pointers []*Pointer) error {
+	tw := tar.NewWriter(w)
+	mtime := time.Unix(0, 0)
+
+	var index bytes.Buffer
+	index.WriteString(bundleHeader + "\n")
+	for _, p := range pointers {
+		fmt.Fprintf(&index, "%s %d\n", p.Oid, p.Size)
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    bundleIndexName,
+		Mode:    0644,
+		Size:    int64(index.Len()),
+		ModTime: mtime,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(index.Bytes()); err != nil {
+		return err
+	}
+
+	for _, p := range pointers {
+		if err := writeBundleObject(tw, p, mtime); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeBundleObject(tw *tar.Writer, p *Pointer, mtime time.Time) error {
+	f, err := os.Open(LocalMediaPathReadOnly(p.Oid))
+	if err != nil {
+		return fmt.Errorf("Unable to open object %s: %v", p.Oid, err)
+	}
+	defer f.Close()
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    bundleObjectPrefix + p.Oid,
+		Mode:    0644,
+		Size:    p.Size,
+		ModTime: mtime,
+	}); err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tw, f, p.Size)
+	return err
+}
+
+// Unbundle imports the objects in the bundle read from r into the local store.
+// Each object's content is hashed and only imported if it matches both the
+// OID and the size listed in the bundle's index; anything else is rejected.
+// An error is returned if r is not a bundle, or cannot be read to the end.
+func Unbundle(r io.Reader) (*UnbundleResult, error) {
+	tr := tar.NewReader(r)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != bundleIndexName {
+		return nil, fmt.Errorf("Not a Git LFS bundle")
+	}
+
+	index, err := readBundleIndex(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UnbundleResult{}
+	seen := make(map[string]bool, len(index))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return result, fmt.Errorf("Unable to read bundle: %v", err)
+		}
+
+		oid := strings.TrimPrefix(hdr.Name, bundleObjectPrefix)
+		size, listed := index[oid]
+
+		switch {
+		case oid == hdr.Name || !bundleOidRE.MatchString(oid):
+			result.reject(hdr.Name, "not a Git LFS object")
+			continue
+		case !listed:
+			result.reject(oid, "not listed in the bundle index")
+			continue
+		case seen[oid]:
+			continue
+		}
+		seen[oid] = true
+
+		if hdr.Size != size {
+			result.reject(oid, "size %d does not match %d in the bundle index", hdr.Size, size)
+			continue
+		}
+
+		if ObjectExistsOfSize(oid, size) {
+			result.Existing++
+			continue
+		}
+
+		if reason, err := unbundleObject(tr, oid, size); err != nil {
+			return result, err
+		} else if len(reason) > 0 {
+			result.reject(oid, "%s", reason)
+			continue
+		}
+		result.Imported++
+	}
+
+	for oid := range index {
+		if !seen[oid] {
+			result.reject(oid, "missing from the bundle")
+		}
+	}
+
+	return result, nil
+}
+
+func readBundleIndex(r io.Reader) (map[string]int64, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || scanner.Text() != bundleHeader {
+		return nil, fmt.Errorf("Unsupported Git LFS bundle version")
+	}
+
+	index := make(map[string]int64)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !bundleOidRE.MatchString(fields[0]) {
+			return nil, fmt.Errorf("Invalid bundle index entry: %q", scanner.Text())
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("Invalid bundle index entry: %q", scanner.Text())
+		}
+		index[fields[0]] = size
+	}
+
+	return index, scanner.Err()
+}
+
+// unbundleObject copies one object out of the bundle into the local store,
+// returning the reason it was rejected if its content does not match oid, or
+// an error if the bundle or local store could not be accessed.
+func unbundleObject(r io.Reader, oid string, size int64) (string, error) {
+	tmp, err := TempFile("bundle")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	tmp.Close()
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s from bundle: %v", oid, err)
+	}
+
+	if written != size {
+		return fmt.Sprintf("truncated, read %d of %d bytes", written, size), nil
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != oid {
+		return fmt.Sprintf("content hashes to %s", actual), nil
+	}
+
+	mediafile, err := LocalMediaPath(oid)
+	if err != nil {
+		return "", err
+	}
+
+	return "", tools.RenameFileCopyPermissions(tmp.Name(), mediafile)
+}
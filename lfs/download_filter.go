@@ -0,0 +1,36 @@
+package lfs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/subprocess"
+	"github.com/rubyist/tracerx"
+)
+
+// DownloadFilterAllows runs the command configured in lfs.download.filter, if
+// any, with the OID and size of an object about to be downloaded. The object
+// may only be downloaded if the command exits successfully; a command which
+// cannot be run declines every object.
+func DownloadFilterAllows(oid string, size int64) bool {
+	filter, ok := config.Config.GitConfig("lfs.download.filter")
+	if !ok || len(strings.TrimSpace(filter)) == 0 {
+		return true
+	}
+
+	// Run through the shell, as Git runs filter commands, so that the
+	// command and its arguments can be quoted
+	tracerx.Printf("download filter: %s %s %d", filter, oid, size)
+	cmd := subprocess.ExecCommand("sh", "-c", filter+` "$@"`, filter, oid, strconv.FormatInt(size, 10))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		tracerx.Printf("download filter declined %s: %s", oid, err)
+		return false
+	}
+
+	return true
+}
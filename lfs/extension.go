@@ -27,120 +27,187 @@ type pipeResponse struct {
 }
 
 type pipeExtResult struct {
-	name   string
-	oidIn  string
-	oidOut string
+	name    string
+	oidIn   string
+	oidOut  string
+	sizeIn  int64
+	sizeOut int64
 }
 
 type extCommand struct {
-	cmd    *exec.Cmd
-	out    io.WriteCloser
-	err    *bytes.Buffer
-	hasher hash.Hash
-	result *pipeExtResult
+	cmd     *exec.Cmd
+	in      *io.PipeReader
+	out     io.WriteCloser
+	err     *bytes.Buffer
+	hasher  hash.Hash
+	counter *countingWriter
+	result  *pipeExtResult
 }
 
+type extExit struct {
+	ec  *extCommand
+	err error
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// pipeExtensions pipes the request's content through the clean or smudge
+// command of each extension in turn, in the order given, writing the output
+// of the last to a temp file. The OID and size of the content going into and
+// coming out of each extension are returned in results, for the caller to
+// check. Each command runs with its own stdin and stdout pipes, so an error
+// names the extension which failed, with whatever it wrote to stderr.
 func pipeExtensions(request *pipeRequest) (response pipeResponse, err error) {
+	if request.action != "clean" && request.action != "smudge" {
+		err = fmt.Errorf("Invalid action: " + request.action)
+		return
+	}
+	if len(request.extensions) == 0 {
+		err = fmt.Errorf("No extensions to %s with", request.action)
+		return
+	}
+
 	var extcmds []*extCommand
 	for _, e := range request.extensions {
-		var pieces []string
-		switch request.action {
-		case "clean":
-			pieces = strings.Split(e.Clean, " ")
-		case "smudge":
-			pieces = strings.Split(e.Smudge, " ")
-		default:
-			err = fmt.Errorf("Invalid action: " + request.action)
+		command := e.Clean
+		if request.action == "smudge" {
+			command = e.Smudge
+		}
+
+		pieces := strings.Fields(command)
+		if len(pieces) == 0 {
+			err = fmt.Errorf("Extension '%s' has no %s command configured (lfs.extension.%s.%s)", e.Name, request.action, e.Name, request.action)
 			return
 		}
-		name := strings.Trim(pieces[0], " ")
+
 		var args []string
 		for _, value := range pieces[1:] {
-			arg := strings.Replace(value, "%f", request.fileName, -1)
-			args = append(args, arg)
+			args = append(args, strings.Replace(value, "%f", request.fileName, -1))
 		}
-		cmd := exec.Command(name, args...)
-		ec := &extCommand{cmd: cmd, result: &pipeExtResult{name: e.Name}}
-		extcmds = append(extcmds, ec)
-	}
 
-	hasher := sha256.New()
-	pipeReader, pipeWriter := io.Pipe()
-	multiWriter := io.MultiWriter(hasher, pipeWriter)
+		var errBuff bytes.Buffer
+		cmd := exec.Command(pieces[0], args...)
+		cmd.Stderr = &errBuff
+		extcmds = append(extcmds, &extCommand{
+			cmd:     cmd,
+			err:     &errBuff,
+			hasher:  sha256.New(),
+			counter: &countingWriter{},
+			result:  &pipeExtResult{name: e.Name},
+		})
+	}
 
-	var input io.Reader
-	var output io.WriteCloser
-	input = pipeReader
-	extcmds[0].cmd.Stdin = input
 	if response.file, err = TempFile(""); err != nil {
 		return
 	}
-	defer response.file.Close()
-	output = response.file
+	defer func() {
+		response.file.Close()
+		if err != nil {
+			os.Remove(response.file.Name())
+			response.file = nil
+			response.results = nil
+		}
+	}()
 
-	last := len(extcmds) - 1
+	// Connect each extension's stdout to the next one's stdin, and the last
+	// one's to the temp file.
+	inputReader, inputWriter := io.Pipe()
+	input := inputReader
 	for i, ec := range extcmds {
-		ec.hasher = sha256.New()
+		ec.in = input
+		ec.cmd.Stdin = input
 
-		if i == last {
-			ec.cmd.Stdout = io.MultiWriter(ec.hasher, output)
+		if i == len(extcmds)-1 {
+			ec.out = response.file
+		} else {
+			nextInput, output := io.Pipe()
 			ec.out = output
-			continue
+			input = nextInput
 		}
+		ec.cmd.Stdout = io.MultiWriter(ec.hasher, ec.counter, ec.out)
+	}
 
-		nextec := extcmds[i+1]
-		var nextStdin io.WriteCloser
-		var stdout io.ReadCloser
-		if nextStdin, err = nextec.cmd.StdinPipe(); err != nil {
-			return
-		}
-		if stdout, err = ec.cmd.StdoutPipe(); err != nil {
+	for i, ec := range extcmds {
+		if err = ec.cmd.Start(); err != nil {
+			err = fmt.Errorf("Extension '%s' could not be started: %s", ec.result.name, err)
+			inputWriter.CloseWithError(err)
+			for _, ec := range extcmds {
+				ec.in.CloseWithError(err)
+				if ec.out != response.file {
+					ec.out.Close()
+				}
+			}
+			for _, started := range extcmds[:i] {
+				started.cmd.Process.Kill()
+				started.cmd.Wait()
+			}
 			return
 		}
-
-		ec.cmd.Stdin = input
-		ec.cmd.Stdout = io.MultiWriter(ec.hasher, nextStdin)
-		ec.out = nextStdin
-
-		input = stdout
-
-		var errBuff bytes.Buffer
-		ec.err = &errBuff
-		ec.cmd.Stderr = ec.err
 	}
 
+	// Once an extension exits, its stdin is closed so that the extension
+	// before it can't block writing to it, and its stdout is closed so that
+	// the next one reads to the end.
+	exits := make(chan extExit, len(extcmds))
 	for _, ec := range extcmds {
-		if err = ec.cmd.Start(); err != nil {
-			return
-		}
+		go func(ec *extCommand) {
+			err := ec.cmd.Wait()
+			ec.in.CloseWithError(fmt.Errorf("extension '%s' exited", ec.result.name))
+			if ec.out != response.file {
+				ec.out.Close()
+			}
+			exits <- extExit{ec, err}
+		}(ec)
 	}
 
-	if _, err = io.Copy(multiWriter, request.reader); err != nil {
-		return
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	_, copyErr := io.Copy(io.MultiWriter(hasher, counter, inputWriter), request.reader)
+	inputWriter.Close()
+
+	// When one extension fails, those around it are likely to fail as a
+	// result, so the first to exit with an error is reported.
+	for range extcmds {
+		exit := <-exits
+		if exit.err != nil && err == nil {
+			err = extensionError(exit.ec, exit.err)
+		}
 	}
-	if err = pipeWriter.Close(); err != nil {
+	if err != nil {
 		return
 	}
 
-	for _, ec := range extcmds {
-		if err = ec.cmd.Wait(); err != nil {
-			if ec.err != nil {
-				errStr := ec.err.String()
-				err = fmt.Errorf("Extension '%s' failed with: %s", ec.result.name, errStr)
-			}
-			return
-		}
-		if err = ec.out.Close(); err != nil {
-			return
-		}
+	if copyErr != nil {
+		err = fmt.Errorf("Extension '%s' did not read all of its input: %s", extcmds[0].result.name, copyErr)
+		return
 	}
 
 	oid := hex.EncodeToString(hasher.Sum(nil))
+	size := counter.n
 	for _, ec := range extcmds {
 		ec.result.oidIn = oid
+		ec.result.sizeIn = size
 		oid = hex.EncodeToString(ec.hasher.Sum(nil))
+		size = ec.counter.n
 		ec.result.oidOut = oid
+		ec.result.sizeOut = size
 		response.results = append(response.results, ec.result)
 	}
 	return
 }
+
+func extensionError(ec *extCommand, err error) error {
+	msg := strings.TrimSpace(ec.err.String())
+	if len(msg) == 0 {
+		return fmt.Errorf("Extension '%s' failed: %s", ec.result.name, err)
+	}
+	return fmt.Errorf("Extension '%s' failed: %s: %s", ec.result.name, err, msg)
+}
@@ -0,0 +1,296 @@
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/git-lfs/localstorage"
+	"github.com/github/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	globalIndexHeader = "# git-lfs global object index v1"
+
+	// globalIndexLockTimeout is how long to wait for another process to
+	// finish updating the index.
+	globalIndexLockTimeout = 10 * time.Second
+
+	// globalIndexStaleLock is how old a lock must be to be taken to belong
+	// to a process which died while holding it.
+	globalIndexStaleLock = time.Minute
+)
+
+var globalIndexOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+// GlobalIndex maps the OIDs of objects to where a copy of each is stored on
+// this machine, in the store of any repository, so that a fetch in one
+// repository can link objects which another already has rather than download
+// them again. It is set with lfs.globalindex.
+//
+// The index is a text file with a line for each object, "<oid> <path>", where
+// path is the absolute path of the object in some repository's store. New
+// entries are appended, and later lines for an OID replace earlier ones.
+// Paths are only hints: an object is checked against its OID before it is
+// linked, and entries for objects which have gone are ignored.
+type GlobalIndex struct {
+	path    string
+	entries map[string]string
+	added   map[string]string
+	mu      sync.Mutex
+}
+
+// OpenGlobalIndex reads the index at path. An index which doesn't exist yet is
+// empty.
+func OpenGlobalIndex(path string) (*GlobalIndex, error) {
+	idx := &GlobalIndex{
+		path:    path,
+		entries: make(map[string]string),
+		added:   make(map[string]string),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, _, err := parseGlobalIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", path, err)
+	}
+	idx.entries = entries
+	return idx, nil
+}
+
+// parseGlobalIndex returns the entries in an index, and how many lines it has
+// for them, including those replaced by later lines. Malformed lines are
+// skipped.
+func parseGlobalIndex(r io.Reader) (map[string]string, int, error) {
+	entries := make(map[string]string)
+	var lines int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || !globalIndexOidRE.MatchString(fields[0]) || !filepath.IsAbs(fields[1]) {
+			continue
+		}
+		entries[fields[0]] = fields[1]
+		lines++
+	}
+	return entries, lines, scanner.Err()
+}
+
+// LinkOrCopy links the object into the local store from the path the index
+// has for it, or copies it if a link can't be made, so long as it matches its
+// OID. It returns whether the object was taken from the index.
+func (i *GlobalIndex) LinkOrCopy(oid string, size int64) bool {
+	if i == nil {
+		return false
+	}
+
+	i.mu.Lock()
+	src, ok := i.entries[oid]
+	i.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	mediafile, err := LocalMediaPath(oid)
+	if err != nil || src == mediafile {
+		return false
+	}
+
+	if !tools.FileExistsOfSize(src, size) || !referenceObjectMatches(src, oid) {
+		tracerx.Printf("lfs: global index entry %s for %s is stale, ignoring it", src, oid)
+		return false
+	}
+
+	if err := LinkOrCopy(src, mediafile); err != nil {
+		tracerx.Printf("lfs: could not link %s from %s: %s", oid, src, err)
+		return false
+	}
+
+	tracerx.Printf("lfs: linked %s from %s", oid, src)
+	return true
+}
+
+// Add records the object's path in the local store, to be written out by
+// Save, if it is there. Objects only in the lfs.storage.base store, and those
+// the index already has in the local store, are left alone.
+func (i *GlobalIndex) Add(oid string, size int64) {
+	if i == nil {
+		return
+	}
+
+	path := localstorage.Objects().ObjectPath(oid)
+	if !tools.FileExistsOfSize(path, size) {
+		return
+	}
+	if !filepath.IsAbs(path) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return
+		}
+		path = abs
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.entries[oid] != path {
+		i.added[oid] = path
+	}
+}
+
+// Save appends the objects which were added to the index file, holding its
+// lock so that processes in other repositories don't update it at the same
+// time. The file is compacted once most of its lines have been replaced.
+func (i *GlobalIndex) Save() error {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.added) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := lockGlobalIndex(i.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Other processes may have written to it since it was opened
+	entries := make(map[string]string)
+	var lines int
+	if f, err := os.Open(i.path); err == nil {
+		entries, lines, err = parseGlobalIndex(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("Error reading %s: %s", i.path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for oid, path := range i.added {
+		entries[oid] = path
+	}
+
+	if lines+len(i.added) > 2*len(entries) {
+		err = writeGlobalIndex(i.path, entries)
+	} else {
+		err = appendGlobalIndex(i.path, i.added, lines == 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	i.entries = entries
+	i.added = make(map[string]string)
+	return nil
+}
+
+func appendGlobalIndex(path string, added map[string]string, header bool) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if header {
+		if stat, err := f.Stat(); err == nil && stat.Size() == 0 {
+			fmt.Fprintln(&buf, globalIndexHeader)
+		}
+	}
+	writeGlobalIndexEntries(&buf, added)
+
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeGlobalIndex replaces the index by renaming, so that it is never read
+// part way through being written.
+func writeGlobalIndex(path string, entries map[string]string) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, globalIndexHeader)
+	writeGlobalIndexEntries(&buf, entries)
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeGlobalIndexEntries(w io.Writer, entries map[string]string) {
+	oids := make([]string, 0, len(entries))
+	for oid, path := range entries {
+		// A path with a newline can't be recorded
+		if !strings.ContainsAny(path, "\r\n") {
+			oids = append(oids, oid)
+		}
+	}
+	sort.Strings(oids)
+
+	for _, oid := range oids {
+		fmt.Fprintf(w, "%s %s\n", oid, entries[oid])
+	}
+}
+
+// lockGlobalIndex takes the index's lock file, waiting for another process
+// holding it, and returns a func to release it. A lock left behind by a
+// process which died is removed once it is old enough.
+func lockGlobalIndex(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(globalIndexLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("Could not lock %s: %s", path, err)
+		}
+
+		if stat, err := os.Stat(lockPath); err == nil && time.Since(stat.ModTime()) > globalIndexStaleLock {
+			tracerx.Printf("lfs: removing stale lock %s", lockPath)
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Could not lock %s: %s is held by another process", path, lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,104 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	globalIndexTestOid1 = "1111111111111111111111111111111111111111111111111111111111111111"
+	globalIndexTestOid2 = "2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestGlobalIndexParse(t *testing.T) {
+	index := strings.Join([]string{
+		globalIndexHeader,
+		globalIndexTestOid1 + " /repo1/.git/lfs/objects/11/11/" + globalIndexTestOid1,
+		"not an entry",
+		"abc /repo/.git/lfs/objects/ab/c0/abc",
+		globalIndexTestOid2 + " relative/path",
+		"",
+		globalIndexTestOid1 + " /repo 2/.git/lfs/objects/11/11/" + globalIndexTestOid1,
+	}, "\n")
+
+	entries, lines, err := parseGlobalIndex(strings.NewReader(index))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, lines)
+	assert.Equal(t, map[string]string{
+		globalIndexTestOid1: "/repo 2/.git/lfs/objects/11/11/" + globalIndexTestOid1,
+	}, entries)
+}
+
+func TestGlobalIndexSaveAppendsAndCompacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfs-global-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index")
+
+	idx, err := OpenGlobalIndex(path)
+	assert.Nil(t, err)
+	idx.added[globalIndexTestOid1] = "/repo1/a"
+	idx.added[globalIndexTestOid2] = "/repo1/b"
+	assert.Nil(t, idx.Save())
+
+	by, _ := ioutil.ReadFile(path)
+	assert.Equal(t, globalIndexHeader+"\n"+
+		globalIndexTestOid1+" /repo1/a\n"+
+		globalIndexTestOid2+" /repo1/b\n", string(by))
+
+	// another repository's entry is appended
+	other, err := OpenGlobalIndex(path)
+	assert.Nil(t, err)
+	other.added[globalIndexTestOid1] = "/repo2/a"
+	assert.Nil(t, other.Save())
+
+	by, _ = ioutil.ReadFile(path)
+	assert.True(t, strings.HasSuffix(string(by), globalIndexTestOid2+" /repo1/b\n"+globalIndexTestOid1+" /repo2/a\n"))
+
+	// once most lines are replaced, the index is rewritten
+	other.added[globalIndexTestOid1] = "/repo3/a"
+	other.added[globalIndexTestOid2] = "/repo3/b"
+	assert.Nil(t, other.Save())
+
+	by, _ = ioutil.ReadFile(path)
+	assert.Equal(t, globalIndexHeader+"\n"+
+		globalIndexTestOid1+" /repo3/a\n"+
+		globalIndexTestOid2+" /repo3/b\n", string(by))
+
+	reopened, err := OpenGlobalIndex(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "/repo3/a", reopened.entries[globalIndexTestOid1])
+	assert.False(t, fileExists(path+".lock"))
+}
+
+func TestGlobalIndexLockRemovesStaleLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfs-global-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index")
+
+	assert.Nil(t, ioutil.WriteFile(path+".lock", nil, 0644))
+	old := time.Now().Add(-2 * globalIndexStaleLock)
+	assert.Nil(t, os.Chtimes(path+".lock", old, old))
+
+	unlock, err := lockGlobalIndex(path)
+	assert.Nil(t, err)
+	assert.True(t, fileExists(path+".lock"))
+	unlock()
+	assert.False(t, fileExists(path+".lock"))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
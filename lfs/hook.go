@@ -12,32 +12,70 @@ import (
 	"github.com/github/git-lfs/errutil"
 )
 
+// hookBlockBegin and hookBlockEnd delimit the lines Git LFS adds to a hook it
+// shares with other scripts in a core.hooksPath directory.
+const (
+	hookBlockBegin = "# BEGIN git-lfs: added by `git lfs install`, removed by `git lfs uninstall`"
+	hookBlockEnd   = "# END git-lfs"
+)
+
 // A Hook represents a githook as described in http://git-scm.com/docs/githooks.
 // Hooks have a type, which is the type of hook that they are, and a body, which
 // represents the thing they will execute when invoked by Git.
+//
+// Chained is what is added to an existing hook in a core.hooksPath directory,
+// which is usually shared, so must not be replaced, between hookBlockBegin and
+// hookBlockEnd. It runs Git LFS, then lets the rest of the hook carry on.
 type Hook struct {
 	Type         string
 	Contents     string
+	Chained      string
 	Upgradeables []string
 }
 
+// HooksDir returns the directory Git runs hooks from. That is core.hooksPath if
+// it is set, which is relative to the top of the working tree, or the Git
+// directory in a bare repository. Otherwise it is the hooks directory in the
+// local Git directory.
+func HooksDir() string {
+	hooksPath, ok := hooksPathConfig()
+	if !ok {
+		return filepath.Join(config.LocalGitDir, "hooks")
+	}
+
+	if strings.HasPrefix(hooksPath, "~/") {
+		hooksPath = filepath.Join(os.Getenv("HOME"), hooksPath[2:])
+	}
+	if filepath.IsAbs(hooksPath) {
+		return hooksPath
+	}
+	if len(config.LocalWorkingDir) > 0 {
+		return filepath.Join(config.LocalWorkingDir, hooksPath)
+	}
+	return filepath.Join(config.LocalGitDir, hooksPath)
+}
+
+func hooksPathConfig() (string, bool) {
+	hooksPath, _ := config.Config.GitConfig("core.hooksPath")
+	return hooksPath, len(hooksPath) > 0
+}
+
 func (h *Hook) Exists() bool {
 	_, err := os.Stat(h.Path())
 	return err == nil
 }
 
 // Path returns the desired (or actual, if installed) location where this hook
-// should be installed, relative to the local Git directory.
+// should be installed, in HooksDir.
 func (h *Hook) Path() string {
-	return filepath.Join(config.LocalGitDir, "hooks", string(h.Type))
+	return filepath.Join(HooksDir(), string(h.Type))
 }
 
 // Install installs this Git hook on disk, or upgrades it if it does exist, and
-// is upgradeable. It will create a hooks directory relative to the local Git
-// directory. It returns and halts at any errors, and returns nil if the
-// operation was a success.
+// is upgradeable. It will create the hooks directory if need be. It returns
+// and halts at any errors, and returns nil if the operation was a success.
 func (h *Hook) Install(force bool) error {
-	if err := os.MkdirAll(filepath.Join(config.LocalGitDir, "hooks"), 0755); err != nil {
+	if err := os.MkdirAll(HooksDir(), 0755); err != nil {
 		return err
 	}
 
@@ -57,11 +95,24 @@ func (h *Hook) write() error {
 
 // Upgrade upgrades the (assumed to be) existing git hook to the current
 // contents. A hook is considered "upgrade-able" if its contents are matched in
-// the member variable `Upgradeables`. It halts and returns any errors as they
-// arise.
+// the member variable `Upgradeables`. In a core.hooksPath directory, any other
+// hook has the Chained lines added to it, or updated if it already has them.
+// It halts and returns any errors as they arise.
 func (h *Hook) Upgrade() error {
+	by, err := ioutil.ReadFile(h.Path())
+	if err != nil {
+		return err
+	}
+
+	if rest, ok := removeHookBlock(string(by)); ok {
+		return h.writeChained(rest)
+	}
+
 	match, err := h.matchesCurrent()
 	if err != nil {
+		if _, ok := hooksPathConfig(); ok && len(h.Chained) > 0 {
+			return h.writeChained(string(by))
+		}
 		return err
 	}
 
@@ -72,13 +123,90 @@ func (h *Hook) Upgrade() error {
 	return h.write()
 }
 
+// writeChained adds the Chained lines to the given hook contents, after the
+// #! line if there is one, and writes them to disk. The Chained lines are shell
+// commands, so it refuses to add them to a hook run by anything else.
+func (h *Hook) writeChained(contents string) error {
+	if !isShellHook(contents) {
+		return fmt.Errorf("Hook already exists: %s\n\n%s is not a sh or bash script, so Git LFS can't add itself to it. Add `git lfs %s \"$@\"` to it by hand, or rename it and run it from a new %s hook which runs Git LFS too.", h.Type, h.Path(), h.Type, h.Type)
+	}
+
+	block := hookBlockBegin + "\n" + h.Chained + "\n" + hookBlockEnd + "\n"
+
+	if strings.HasPrefix(contents, "#!") {
+		if i := strings.Index(contents, "\n"); i >= 0 {
+			contents = contents[:i+1] + block + contents[i+1:]
+		} else {
+			contents = contents + "\n" + block
+		}
+	} else {
+		contents = block + contents
+	}
+
+	// Anyone who can run the hook can still do so, as WriteFile keeps the
+	// mode of an existing file
+	return ioutil.WriteFile(h.Path(), []byte(contents), 0755)
+}
+
+// isShellHook returns whether the hook contents are run by sh or bash. Git runs
+// a hook without a #! line with sh.
+func isShellHook(contents string) bool {
+	if !strings.HasPrefix(contents, "#!") {
+		return true
+	}
+
+	line := strings.SplitN(contents[2:], "\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	return interpreter == "sh" || interpreter == "bash"
+}
+
+// removeHookBlock returns the hook contents without the lines between
+// hookBlockBegin and hookBlockEnd, and whether there were any.
+func removeHookBlock(contents string) (string, bool) {
+	lines := strings.SplitAfter(contents, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case hookBlockBegin:
+			begin = i
+		case hookBlockEnd:
+			if begin >= 0 {
+				end = i
+			}
+		}
+		if end >= 0 {
+			break
+		}
+	}
+
+	if begin < 0 || end < 0 {
+		return contents, false
+	}
+	return strings.Join(lines[:begin], "") + strings.Join(lines[end+1:], ""), true
+}
+
 // Uninstall removes the hook on disk so long as it matches the current version,
-// or any of the past versions of this hook.
+// or any of the past versions of this hook. From a hook which Git LFS was
+// chained to, only the lines Git LFS added are removed.
 func (h *Hook) Uninstall() error {
 	if !InRepo() {
 		return errutil.NewInvalidRepoError(nil)
 	}
 
+	if by, err := ioutil.ReadFile(h.Path()); err == nil {
+		if rest, ok := removeHookBlock(string(by)); ok {
+			return ioutil.WriteFile(h.Path(), []byte(rest), 0755)
+		}
+	}
+
 	match, err := h.matchesCurrent()
 	if err != nil {
 		return err
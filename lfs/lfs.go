@@ -3,11 +3,14 @@
 package lfs
 
 import (
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/localstorage"
@@ -51,8 +54,28 @@ func LocalMediaPath(oid string) (string, error) {
 	return localstorage.Objects().BuildObjectPath(oid)
 }
 
+// LocalMediaPathReadOnly returns the path to read the given object from,
+// without creating any directories. That is the local store, unless only the
+// lfs.storage.base store has the object.
 func LocalMediaPathReadOnly(oid string) string {
-	return localstorage.Objects().ObjectPath(oid)
+	path := localstorage.Objects().ObjectPath(oid)
+	if !tools.FileExists(path) {
+		if base := LocalBaseMediaPath(oid); base != "" && tools.FileExists(base) {
+			return base
+		}
+	}
+	return path
+}
+
+// LocalBaseMediaPath returns the path of the given object in the read-only
+// store set by lfs.storage.base, or an empty string if there isn't one. New
+// objects are never written there.
+func LocalBaseMediaPath(oid string) string {
+	dir := config.Config.StorageBaseDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, oid[0:2], oid[2:4], oid)
 }
 
 func LocalReferencePath(sha string) string {
@@ -62,9 +85,29 @@ func LocalReferencePath(sha string) string {
 	return filepath.Join(config.LocalReferenceDir, sha[0:2], sha[2:4], sha)
 }
 
+// ObjectExistsOfSize returns whether the local store, or else the
+// lfs.storage.base store, has the given object with the given size.
 func ObjectExistsOfSize(oid string, size int64) bool {
 	path := localstorage.Objects().ObjectPath(oid)
-	return tools.FileExistsOfSize(path, size)
+	if tools.FileExistsOfSize(path, size) {
+		return true
+	}
+
+	base := LocalBaseMediaPath(oid)
+	return base != "" && tools.FileExistsOfSize(base, size)
+}
+
+// TouchLocalObject records that the given object in the local store has just
+// been used by setting its modification time to now, so that the least
+// recently used objects are the first to be evicted to keep the store under
+// lfs.storage.maxsize. Objects only in the lfs.storage.base store are left
+// alone.
+func TouchLocalObject(oid string) {
+	now := time.Now()
+	path := localstorage.Objects().ObjectPath(oid)
+	if err := os.Chtimes(path, now, now); err != nil && !os.IsNotExist(err) {
+		tracerx.Printf("Unable to record use of %s: %v", oid, err)
+	}
 }
 
 func Environ() []string {
@@ -127,6 +170,7 @@ func ClearTempObjects() error {
 	if localstorage.Objects() == nil {
 		return nil
 	}
+	clearSmudgeTempFiles()
 	return localstorage.Objects().ClearTempObjects()
 }
 
@@ -161,7 +205,30 @@ func LinkOrCopyFromReference(oid string, size int64) error {
 		return err
 	}
 	if altMediafile != "" && tools.FileExistsOfSize(altMediafile, size) {
+		if !referenceObjectMatches(altMediafile, oid) {
+			tracerx.Printf("lfs: %s in reference %s does not match its OID, ignoring it", oid, config.LocalReferenceDir)
+			return nil
+		}
+
+		tracerx.Printf("lfs: linking %s from reference %s", oid, config.LocalReferenceDir)
 		return LinkOrCopy(altMediafile, mediafile)
 	}
 	return nil
 }
+
+// referenceObjectMatches returns whether the content of the file at path
+// hashes to oid, so a damaged object in a reference repository is never
+// linked into this one.
+func referenceObjectMatches(path, oid string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	hash := tools.NewLfsContentHash()
+	if _, err := io.Copy(hash, f); err != nil {
+		return false
+	}
+	return hex.EncodeToString(hash.Sum(nil)) == oid
+}
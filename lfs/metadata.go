@@ -0,0 +1,147 @@
+package lfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/tools"
+	"github.com/github/git-lfs/transfer"
+	"github.com/rubyist/tracerx"
+)
+
+// ObjectMetadata describes the working tree file an object was cleaned from.
+// When lfs.metadata is enabled it is stored as a JSON sidecar next to the
+// object in the local store. It never affects the pointer or the OID.
+type ObjectMetadata struct {
+	Oid   string      `json:"oid"`
+	Path  string      `json:"path"`
+	Mode  os.FileMode `json:"mode"`
+	Mtime time.Time   `json:"mtime"`
+}
+
+// LocalMetadataPath returns the path of the metadata sidecar for the given
+// object, alongside the object itself.
+func LocalMetadataPath(oid string) (string, error) {
+	mediafile, err := LocalMediaPath(oid)
+	if err != nil {
+		return "", err
+	}
+	return mediafile + ".json", nil
+}
+
+// WriteObjectMetadata records the path, mode and mtime of the working tree
+// file that produced oid.
+func WriteObjectMetadata(oid, filename string) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	by, err := json.Marshal(&ObjectMetadata{
+		Oid:   oid,
+		Path:  filename,
+		Mode:  stat.Mode().Perm(),
+		Mtime: stat.ModTime().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeMetadataSidecar(oid, by)
+}
+
+// ReadObjectMetadata returns the metadata sidecar for oid, or nil if it has
+// none.
+func ReadObjectMetadata(oid string) (*ObjectMetadata, error) {
+	by, err := readMetadataSidecar(oid)
+	if err != nil || by == nil {
+		return nil, err
+	}
+
+	meta := &ObjectMetadata{}
+	if err := json.Unmarshal(by, meta); err != nil {
+		return nil, fmt.Errorf("Invalid metadata for %s: %v", oid, err)
+	}
+	return meta, nil
+}
+
+// RestoreObjectMetadata sets the mtime of filename to the one recorded for
+// oid, if any. The file mode is left to Git, which records it in the tree.
+func RestoreObjectMetadata(oid, filename string) error {
+	meta, err := ReadObjectMetadata(oid)
+	if err != nil || meta == nil || meta.Mtime.IsZero() {
+		return err
+	}
+
+	tracerx.Printf("metadata: restoring mtime of %s to %s", filename, meta.Mtime)
+	return os.Chtimes(filename, meta.Mtime, meta.Mtime)
+}
+
+// transferMetadata sends or fetches the metadata sidecar for a transferred
+// object using the optional "metadata" batch action. Metadata is best effort:
+// failures are reported, but do not fail the transfer of the object itself.
+func transferMetadata(obj *api.ObjectResource, dir transfer.Direction) {
+	if !config.Config.MetadataEnabled() {
+		return
+	}
+
+	if _, ok := obj.Rel("metadata"); !ok {
+		return
+	}
+
+	var err error
+	if dir == transfer.Upload {
+		var by []byte
+		if by, err = readMetadataSidecar(obj.Oid); err == nil && by != nil {
+			err = api.UploadMetadata(obj, by)
+		}
+	} else {
+		var by []byte
+		if by, err = api.DownloadMetadata(obj); err == nil && len(by) > 0 {
+			err = writeMetadataSidecar(obj.Oid, by)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to transfer metadata for %s: %v\n", obj.Oid, err)
+	}
+}
+
+func readMetadataSidecar(oid string) ([]byte, error) {
+	path, err := LocalMetadataPath(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	by, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return by, err
+}
+
+func writeMetadataSidecar(oid string, by []byte) error {
+	path, err := LocalMetadataPath(oid)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := TempFile("metadata")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(by)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return tools.RenameFileCopyPermissions(tmp.Name(), path)
+}
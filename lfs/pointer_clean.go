@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/progress"
+	"github.com/github/git-lfs/subprocess"
 	"github.com/github/git-lfs/tools"
+	"github.com/rubyist/tracerx"
 )
 
 type cleanedAsset struct {
@@ -36,13 +42,18 @@ func PointerClean(reader io.Reader, fileName string, fileSize int64, cb progress
 			return nil, err
 		}
 
-		oid = response.results[len(response.results)-1].oidOut
+		last := response.results[len(response.results)-1]
+		oid = last.oidOut
 		tmp = response.file
 		var stat os.FileInfo
 		if stat, err = os.Stat(tmp.Name()); err != nil {
 			return nil, err
 		}
-		size = stat.Size()
+		if stat.Size() != last.sizeOut {
+			os.Remove(tmp.Name())
+			return nil, fmt.Errorf("Extension '%s' wrote %d bytes, but %d reached %s", last.name, last.sizeOut, stat.Size(), tmp.Name())
+		}
+		size = last.sizeOut
 
 		for _, result := range response.results {
 			if result.oidIn != result.oidOut {
@@ -61,6 +72,31 @@ func PointerClean(reader io.Reader, fileName string, fileSize int64, cb progress
 	return &cleanedAsset{tmp.Name(), pointer}, err
 }
 
+// PointerHash returns the pointer PointerClean would for the content, without
+// keeping the content anywhere, for `git lfs clean --no-store`. Extensions
+// still write their output to a temp file, which is removed.
+func PointerHash(reader io.Reader, fileName string, fileSize int64, cb progress.CopyCallback) (*Pointer, error) {
+	extensions, err := config.Config.SortedExtensions()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(extensions) > 0 {
+		cleaned, err := PointerClean(reader, fileName, fileSize, cb)
+		if err != nil {
+			return nil, err
+		}
+		cleaned.Teardown()
+		return cleaned.Pointer, nil
+	}
+
+	oid, size, err := hashContent(ioutil.Discard, reader, fileSize, cb)
+	if err != nil {
+		return nil, err
+	}
+	return NewPointer(oid, size, nil), nil
+}
+
 func copyToTemp(reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid string, size int64, tmp *os.File, err error) {
 	tmp, err = TempFile("")
 	if err != nil {
@@ -69,8 +105,15 @@ func copyToTemp(reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid
 
 	defer tmp.Close()
 
+	oid, size, err = hashContent(tmp, reader, fileSize, cb)
+	return
+}
+
+// hashContent copies the content to w, returning its OID and size. Content
+// which is already a pointer returns a clean pointer error instead.
+func hashContent(w io.Writer, reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid string, size int64, err error) {
 	oidHash := sha256.New()
-	writer := io.MultiWriter(oidHash, tmp)
+	writer := io.MultiWriter(oidHash, w)
 
 	if fileSize == 0 {
 		cb = nil
@@ -93,6 +136,67 @@ func copyToTemp(reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid
 	return
 }
 
+// UnchangedPointer returns the pointer staged for fileName when the index
+// shows the file is unchanged since it was cleaned, so that the clean filter
+// can emit it again without re-hashing the content. It returns nil whenever
+// that cannot be established with certainty, and the file must then be
+// cleaned in full.
+func UnchangedPointer(fileName string, stat os.FileInfo) *Pointer {
+	if len(fileName) == 0 || stat == nil || !stat.Mode().IsRegular() {
+		return nil
+	}
+
+	if len(config.Config.Extensions()) > 0 {
+		return nil
+	}
+
+	entry, err := git.GetIndexEntry(fileName)
+	if err != nil || entry == nil {
+		return nil
+	}
+
+	// A file modified within the same timestamp as the index was written
+	// is "racily clean": its stat data can match despite a change.
+	if !indexStatMatches(entry, stat) || !entry.Mtime.Before(entry.IndexMtime) {
+		return nil
+	}
+
+	blob, err := subprocess.ExecCommand("git", "cat-file", "blob", entry.Sha1).Output()
+	if err != nil {
+		return nil
+	}
+
+	p, err := DecodePointer(bytes.NewReader(blob))
+	if err != nil || p.Size != stat.Size() || !ObjectExistsOfSize(p.Oid, p.Size) {
+		return nil
+	}
+
+	tracerx.Printf("clean: %s is unchanged, reusing its staged pointer", fileName)
+	return p
+}
+
+// fileStat is the stat data, besides size and modification time, which git
+// keeps in the index for a working tree file.
+type fileStat struct {
+	Ctime              time.Time
+	Dev, Ino, Uid, Gid uint32
+}
+
+// indexStatMatches reports whether stat matches all of the stat data the index
+// records for the file, as git checks it: a file rewritten with the same size
+// and mtime still gets a new ctime, and a replaced one a new inode. It is false
+// where that data isn't available.
+func indexStatMatches(entry *git.IndexEntry, stat os.FileInfo) bool {
+	st := statData(stat)
+	if st == nil {
+		return false
+	}
+
+	return entry.Size == uint32(stat.Size()) && entry.Mtime.Equal(stat.ModTime()) &&
+		entry.Ctime.Equal(st.Ctime) && entry.Dev == st.Dev && entry.Ino == st.Ino &&
+		entry.Uid == st.Uid && entry.Gid == st.Gid
+}
+
 func (a *cleanedAsset) Teardown() error {
 	return os.Remove(a.Filename)
 }
@@ -1,10 +1,13 @@
 package lfs
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cheggaaa/pb"
 	"github.com/github/git-lfs/tools"
@@ -17,26 +20,112 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
+// PointerSmudgeToFile writes the content for ptr to filename. The content is
+// written to a temp file, checked against the pointer's OID, and then renamed
+// into place keeping any existing file's permissions, so that a smudge which
+// fails or is interrupted leaves the old file untouched rather than partly
+// written.
 func PointerSmudgeToFile(filename string, ptr *Pointer, download bool, cb progress.CopyCallback) error {
 	os.MkdirAll(filepath.Dir(filename), 0755)
-	file, err := os.Create(filename)
+	file, err := createSmudgeFile(filename)
 	if err != nil {
 		return fmt.Errorf("Could not create working directory file: %v", err)
 	}
+	defer os.Remove(file.Name())
 	defer file.Close()
-	if err := PointerSmudge(file, ptr, filename, download, cb); err != nil {
+
+	hasher := tools.NewLfsContentHash()
+	if err := PointerSmudge(io.MultiWriter(file, hasher), ptr, filename, download, cb); err != nil {
 		if errutil.IsDownloadDeclinedError(err) {
 			// write placeholder data instead
 			file.Seek(0, os.SEEK_SET)
+			file.Truncate(0)
 			ptr.Encode(file)
+			if renameErr := replaceWithSmudgeFile(file, filename); renameErr != nil {
+				return fmt.Errorf("Could not write working directory file: %v", renameErr)
+			}
 			return err
 		} else {
 			return fmt.Errorf("Could not write working directory file: %v", err)
 		}
 	}
+
+	// With extensions, the working file is not the content the OID is for, and
+	// the extensions' own OIDs are checked instead
+	if len(ptr.Extensions) == 0 {
+		if oid := hex.EncodeToString(hasher.Sum(nil)); oid != ptr.Oid {
+			return fmt.Errorf("Could not write working directory file: expected OID %s, got %s", ptr.Oid, oid)
+		}
+	}
+
+	if err := replaceWithSmudgeFile(file, filename); err != nil {
+		return fmt.Errorf("Could not write working directory file: %v", err)
+	}
+
+	if config.Config.MetadataEnabled() {
+		if err := RestoreObjectMetadata(ptr.Oid, filename); err != nil {
+			tracerx.Printf("metadata: unable to restore %s: %s", filename, err)
+		}
+	}
 	return nil
 }
 
+// createSmudgeFile creates the temp file PointerSmudgeToFile writes the content
+// for filename to. It goes in the smudge temp dir under .git/lfs/tmp, so that
+// a smudge which is killed part way through leaves nothing in the working
+// tree, or beside filename outside of a repository. It is created with the
+// permissions a new working file would get.
+func createSmudgeFile(filename string) (*os.File, error) {
+	dir, base := filepath.Split(filename)
+	prefix := "." + base + ".lfs-smudge"
+	if InRepo() {
+		dir = smudgeTempDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		prefix = base
+	}
+
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("%s-%d-%d", prefix, os.Getpid(), i))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && i < 1000 {
+			continue
+		}
+		return file, err
+	}
+}
+
+func smudgeTempDir() string {
+	return filepath.Join(TempDir(), "smudge")
+}
+
+// clearSmudgeTempFiles removes smudge temp files which haven't been written to
+// for an hour, which were left by killed smudges.
+func clearSmudgeTempFiles() {
+	dir := smudgeTempDir()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() && time.Since(info.ModTime()) > time.Hour {
+			tracerx.Printf("Removing old smudge temp file: %s", info.Name())
+			os.Remove(filepath.Join(dir, info.Name()))
+		}
+	}
+}
+
+// replaceWithSmudgeFile closes the completed smudge file and renames it over
+// filename, keeping filename's permissions if it already exists.
+func replaceWithSmudgeFile(file *os.File, filename string) error {
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return tools.RenameFileCopyPermissions(file.Name(), filename)
+}
+
 func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download bool, cb progress.CopyCallback) error {
 	mediafile, err := LocalMediaPath(ptr.Oid)
 	if err != nil {
@@ -45,6 +134,11 @@ func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download
 
 	LinkOrCopyFromReference(ptr.Oid, ptr.Size)
 
+	if !tools.FileExists(mediafile) && ObjectExistsOfSize(ptr.Oid, ptr.Size) {
+		// only the base store has it, which is read but never changed
+		mediafile = LocalMediaPathReadOnly(ptr.Oid)
+	}
+
 	stat, statErr := os.Stat(mediafile)
 
 	if statErr == nil && stat != nil {
@@ -57,7 +151,10 @@ func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download
 	}
 
 	if statErr != nil || stat == nil {
-		if download {
+		if download && !DownloadFilterAllows(ptr.Oid, ptr.Size) {
+			fmt.Fprintf(os.Stderr, "Skipping %s (%s), declined by lfs.download.filter\n", workingfile, ptr.Oid)
+			return errutil.NewDownloadDeclinedError(nil)
+		} else if download {
 			err = downloadFile(writer, ptr, workingfile, mediafile, cb)
 		} else {
 			return errutil.NewDownloadDeclinedError(nil)
@@ -70,12 +167,22 @@ func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download
 		return errutil.NewSmudgeError(err, ptr.Oid, mediafile)
 	}
 
+	TouchLocalObject(ptr.Oid)
 	return nil
 }
 
 func downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string, cb progress.CopyCallback) error {
 	fmt.Fprintf(os.Stderr, "Downloading %s (%s)\n", workingfile, pb.FormatBytes(ptr.Size))
 
+	// The API client and adapter use the current remote's endpoint
+	if remote, ok := RouteRemote(workingfile); ok && remote != config.Config.CurrentRemote {
+		tracerx.Printf("smudge: %s is routed to remote %q", workingfile, remote)
+		defer func(current string) {
+			config.Config.CurrentRemote = current
+		}(config.Config.CurrentRemote)
+		config.Config.CurrentRemote = remote
+	}
+
 	xfers := transfer.GetDownloadAdapterNames()
 	obj, adapterName, err := api.BatchOrLegacySingle(&api.ObjectResource{Oid: ptr.Oid, Size: ptr.Size}, "download", xfers)
 	if err != nil {
@@ -123,6 +230,7 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 		}
 	}
 
+	size := ptr.Size
 	if len(ptr.Extensions) > 0 {
 		registeredExts := config.Config.Extensions()
 		extensions := make(map[string]config.Extension)
@@ -154,39 +262,45 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 			return errutil.Error(err)
 		}
 
-		actualExts := make(map[string]*pipeExtResult)
-		for _, result := range response.results {
-			actualExts[result.name] = result
+		// verify oids and sizes, in the order the extensions ran
+		first := response.results[0]
+		if ptr.Oid != first.oidIn {
+			os.Remove(response.file.Name())
+			err = fmt.Errorf("Actual oid %s during smudge does not match expected %s", first.oidIn, ptr.Oid)
+			return errutil.Error(err)
 		}
-
-		// verify name, order, and oids
-		oid := response.results[0].oidIn
-		if ptr.Oid != oid {
-			err = fmt.Errorf("Actual oid %s during smudge does not match expected %s", oid, ptr.Oid)
+		if ptr.Size != first.sizeIn {
+			os.Remove(response.file.Name())
+			err = fmt.Errorf("Actual size %d during smudge does not match expected %d", first.sizeIn, ptr.Size)
 			return errutil.Error(err)
 		}
 
-		for _, expected := range ptr.Extensions {
-			actual := actualExts[expected.Name]
-			if actual.name != expected.Name {
-				err = fmt.Errorf("Actual extension name '%s' does not match expected '%s'", actual.name, expected.Name)
-				return errutil.Error(err)
-			}
+		expectedExts := make(map[string]*PointerExtension)
+		for _, ptrExt := range ptr.Extensions {
+			expectedExts[ptrExt.Name] = ptrExt
+		}
+
+		for _, actual := range response.results {
+			expected := expectedExts[actual.name]
 			if actual.oidOut != expected.Oid {
+				os.Remove(response.file.Name())
 				err = fmt.Errorf("Actual oid %s for extension '%s' does not match expected %s", actual.oidOut, expected.Name, expected.Oid)
 				return errutil.Error(err)
 			}
 		}
 
 		// setup reader
-		reader, err = os.Open(response.file.Name())
+		smudged := response.file.Name()
+		defer os.Remove(smudged)
+		reader, err = os.Open(smudged)
 		if err != nil {
 			return errutil.Errorf(err, "Error opening smudged file: %s", err)
 		}
 		defer reader.Close()
+		size = response.results[len(response.results)-1].sizeOut
 	}
 
-	_, err = tools.CopyWithCallback(writer, reader, ptr.Size, cb)
+	_, err = tools.CopyWithCallback(writer, reader, size, cb)
 	if err != nil {
 		return errutil.Errorf(err, "Error reading from media file: %s", err)
 	}
@@ -461,6 +461,148 @@ func revListIndex(cache bool, indexMap *indexFileMap) (*StringChannelWrapper, er
 	return NewStringChannelWrapper(revs, errchan), nil
 }
 
+// PointerDiff describes a change to a Git LFS pointer between two trees. Old
+// is nil for added files and New is nil for removed ones. SrcName is only set
+// for renames, and holds the name of the file in the base tree.
+type PointerDiff struct {
+	Status  string
+	Name    string
+	SrcName string
+	Old     *WrappedPointer
+	New     *WrappedPointer
+}
+
+// ScanTreeDiff returns a slice of PointerDiff objects for all Git LFS pointers
+// that were added, modified, removed or renamed between the trees of base and
+// head. Files which are not LFS pointers on either side are not reported.
+func ScanTreeDiff(base, head string) ([]*PointerDiff, error) {
+	start := time.Now()
+	defer func() {
+		tracerx.PerformanceSince("scan-diff", start)
+	}()
+
+	diffs, err := diffTreeEntries(base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	revsChan := make(chan string, chanBufSize)
+	go func() {
+		for _, d := range diffs {
+			for _, sha1 := range []string{d.oldSha1, d.newSha1} {
+				if !z40.MatchString(sha1) {
+					revsChan <- sha1
+				}
+			}
+		}
+		close(revsChan)
+	}()
+
+	errchan := make(chan error)
+	close(errchan)
+
+	smallShas, err := catFileBatchCheck(NewStringChannelWrapper(revsChan, errchan))
+	if err != nil {
+		return nil, err
+	}
+
+	pointerc, err := catFileBatch(smallShas)
+	if err != nil {
+		return nil, err
+	}
+
+	pointers := make(map[string]*Pointer)
+	for p := range pointerc.Results {
+		pointers[p.Sha1] = p.Pointer
+	}
+	if err := pointerc.Wait(); err != nil {
+		return nil, err
+	}
+
+	wrap := func(sha1, name string) *WrappedPointer {
+		p, ok := pointers[sha1]
+		if !ok {
+			return nil
+		}
+		return &WrappedPointer{Sha1: sha1, Name: name, Size: p.Size, Pointer: p}
+	}
+
+	results := make([]*PointerDiff, 0, len(diffs))
+	for _, d := range diffs {
+		pd := &PointerDiff{
+			Status: d.status,
+			Name:   d.name,
+			Old:    wrap(d.oldSha1, d.srcName),
+			New:    wrap(d.newSha1, d.name),
+		}
+		if pd.Old == nil && pd.New == nil {
+			continue
+		}
+
+		if d.status == "R" {
+			pd.SrcName = d.srcName
+		}
+		results = append(results, pd)
+	}
+
+	return results, nil
+}
+
+// diffTreeEntry is a single line of output from git diff-tree
+type diffTreeEntry struct {
+	oldSha1 string
+	newSha1 string
+	status  string
+	name    string
+	srcName string
+}
+
+// diffTreeEntries uses git diff-tree to return the list of blobs which differ
+// between base and head, with renames detected.
+func diffTreeEntries(base, head string) ([]*diffTreeEntry, error) {
+	cmd, err := startCommand("git", "diff-tree", "-r", "-M", "-z", base, head, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stdin.Close()
+
+	entries := make([]*diffTreeEntry, 0)
+	scanner := bufio.NewScanner(cmd.Stdout)
+	scanner.Split(scanNullLines)
+	for scanner.Scan() {
+		// Format is:
+		// :<old mode> <new mode> <old sha1> <new sha1> <status>\0<file name>\0[<file name>\0]
+		description := strings.Split(strings.TrimPrefix(scanner.Text(), ":"), " ")
+		if len(description) < 5 || !scanner.Scan() {
+			continue
+		}
+
+		e := &diffTreeEntry{
+			oldSha1: description[2],
+			newSha1: description[3],
+			status:  description[4][0:1],
+			name:    scanner.Text(),
+		}
+		e.srcName = e.name
+
+		if e.status == "R" || e.status == "C" {
+			if !scanner.Scan() {
+				break
+			}
+			e.name = scanner.Text()
+		}
+		entries = append(entries, e)
+	}
+
+	stderr, _ := ioutil.ReadAll(cmd.Stderr)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("Error in git diff-tree: %v %v", err, string(stderr))
+	}
+
+	return entries, nil
+}
+
 // catFileBatchCheck uses git cat-file --batch-check to get the type
 // and size of a git object. Any object that isn't of type blob and
 // under the blobSizeCutoff will be ignored. revs is a channel over
@@ -810,6 +952,126 @@ func parseLsTree(reader io.Reader, output chan TreeBlob) {
 	}
 }
 
+// ScanIndexTree returns the Git LFS pointers at every path in the index, as
+// ScanTree does for a commit. Conflicted paths have no stage 0 entry, and are
+// returned separately, once each, with the pointer at the first of their merge
+// stages which has one.
+func ScanIndexTree() ([]*WrappedPointer, []*WrappedPointer, error) {
+	start := time.Now()
+	defer func() {
+		tracerx.PerformanceSince("scan-index-tree", start)
+	}()
+
+	entries, conflicted, err := lsFilesStage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The entries are already all listed, so there are no errors to come
+	// along with them
+	noErrors := make(chan error)
+	close(noErrors)
+
+	// ls-files doesn't report sizes, so weed out blobs too big to be
+	// pointers before reading any contents
+	shas := make(chan string, chanBufSize)
+	go func() {
+		for _, e := range entries {
+			shas <- e.Sha1
+		}
+		close(shas)
+	}()
+	smallc, err := catFileBatchCheck(NewStringChannelWrapper(shas, noErrors))
+	if err != nil {
+		return nil, nil, err
+	}
+	small := make(map[string]bool)
+	for sha := range smallc.Results {
+		small[sha] = true
+	}
+	if err := smallc.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	blobs := make(chan TreeBlob, chanBufSize)
+	go func() {
+		for _, e := range entries {
+			if small[e.Sha1] {
+				blobs <- e
+			}
+		}
+		close(blobs)
+	}()
+	pointerc, err := catFileBatchTree(NewTreeBlobChannelWrapper(blobs, noErrors))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pointers := make([]*WrappedPointer, 0)
+	conflictedPointers := make([]*WrappedPointer, 0)
+	for p := range pointerc.Results {
+		if !conflicted[p.Name] {
+			pointers = append(pointers, p)
+			continue
+		}
+		if n := len(conflictedPointers); n == 0 || conflictedPointers[n-1].Name != p.Name {
+			conflictedPointers = append(conflictedPointers, p)
+		}
+	}
+	err = pointerc.Wait()
+
+	return pointers, conflictedPointers, err
+}
+
+// lsFilesStage uses git ls-files --stage to list every entry in the index, at
+// each merge stage, and the paths which are conflicted.
+func lsFilesStage() ([]TreeBlob, map[string]bool, error) {
+	cmd, err := startCommand("git", "ls-files",
+		"--stage",     // report blob sha1s and merge stages
+		"-z",          // null line termination
+		"--full-name", // paths relative to the root
+		":/")          // the whole index regardless of where we are in it
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd.Stdin.Close()
+
+	entries, conflicted := parseLsFilesStage(cmd.Stdout)
+	stderr, _ := ioutil.ReadAll(cmd.Stderr)
+	if err := cmd.Wait(); err != nil {
+		return nil, nil, fmt.Errorf("Error in git ls-files: %v %v", err, string(stderr))
+	}
+	return entries, conflicted, nil
+}
+
+func parseLsFilesStage(reader io.Reader) ([]TreeBlob, map[string]bool) {
+	var entries []TreeBlob
+	conflicted := make(map[string]bool)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanNullLines)
+	for scanner.Scan() {
+		// <mode> SP <sha1> SP <stage> TAB <path>
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		attrs := strings.Fields(parts[0])
+		if len(attrs) != 3 {
+			continue
+		}
+
+		if attrs[2] != "0" {
+			conflicted[parts[1]] = true
+		}
+		entries = append(entries, TreeBlob{attrs[1], parts[1]})
+	}
+
+	return entries, conflicted
+}
+
 func scanNullLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
 		return 0, nil, nil
@@ -883,15 +1145,7 @@ func ScanPreviousVersionsToChan(ref string, since time.Time) (*PointerChannelWra
 // not pushed to the named remote. remoteName can be left blank to mean 'any remote'
 // return progressively in a channel
 func ScanUnpushedToChan(remoteName string) (*PointerChannelWrapper, error) {
-	logArgs := []string{"log",
-		"--branches", "--tags", // include all locally referenced commits
-		"--not"} // but exclude everything that comes after
-
-	if len(remoteName) == 0 {
-		logArgs = append(logArgs, "--remotes")
-	} else {
-		logArgs = append(logArgs, fmt.Sprintf("--remotes=%v", remoteName))
-	}
+	logArgs := unpushedLogArgs(remoteName)
 	// Add standard search args to find lfs references
 	logArgs = append(logArgs, logLfsSearchArgs...)
 
@@ -920,6 +1174,70 @@ func ScanUnpushedToChan(remoteName string) (*PointerChannelWrapper, error) {
 
 }
 
+// ScanUnpushedCommits returns summaries of the commits which have not been
+// pushed to the named remote and add a pointer to one of the given objects,
+// i.e. those which would lose their Git LFS content if the objects were
+// deleted. remoteName can be left blank to mean 'any remote'. Only Sha,
+// ShortSha and Subject are populated.
+func ScanUnpushedCommits(remoteName string, oids tools.StringSet) ([]*git.CommitSummary, error) {
+	logArgs := unpushedLogArgs(remoteName)
+	logArgs = append(logArgs, "-G", "oid sha256:", "-p", "--format=lfs-commit: %H|%h|%s")
+
+	cmd, err := startCommand("git", logArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stdin.Close()
+
+	commits := make([]*git.CommitSummary, 0)
+	var current *git.CommitSummary
+	var added bool
+	scanner := bufio.NewScanner(cmd.Stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "lfs-commit: ") {
+			fields := strings.SplitN(strings.TrimPrefix(line, "lfs-commit: "), "|", 3)
+			current, added = nil, false
+			if len(fields) == 3 {
+				current = &git.CommitSummary{Sha: fields[0], ShortSha: fields[1], Subject: fields[2]}
+			}
+			continue
+		}
+
+		if current == nil || added || !strings.HasPrefix(line, "+oid sha256:") {
+			continue
+		}
+		if oids.Contains(strings.TrimSpace(strings.TrimPrefix(line, "+oid sha256:"))) {
+			commits = append(commits, current)
+			added = true
+		}
+	}
+
+	stderr, _ := ioutil.ReadAll(cmd.Stderr)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("Error in git log: %v %v", err, string(stderr))
+	}
+
+	return commits, nil
+}
+
+// unpushedLogArgs returns the git log arguments which limit the output to
+// commits that have not been pushed to the named remote (or any remote if
+// remoteName is blank).
+func unpushedLogArgs(remoteName string) []string {
+	logArgs := []string{"log",
+		"--branches", "--tags", // include all locally referenced commits
+		"--not"} // but exclude everything that comes after
+
+	if len(remoteName) == 0 {
+		logArgs = append(logArgs, "--remotes")
+	} else {
+		logArgs = append(logArgs, fmt.Sprintf("--remotes=%v", remoteName))
+	}
+	return logArgs
+}
+
 // logPreviousVersions scans history for all previous versions of LFS pointers
 // from 'since' up to (but not including) the final state at ref
 func logPreviousSHAs(ref string, since time.Time) (*PointerChannelWrapper, error) {
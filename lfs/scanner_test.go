@@ -265,6 +265,24 @@ func TestLsTreeParser(t *testing.T) {
 	}
 }
 
+func TestLsFilesStageParser(t *testing.T) {
+	stdout := "100644 d899f6551a51cf19763c5955c7a06a2726f018e9 0	.gitattributes\000" +
+		"100644 4d343e022e11a8618db494dc3c501e80c7e18197 2	a dir/a.dat\000" +
+		"100644 6c2a8e2b6f3ce064e0d2e8c2ee8ba0fd3a6e61ec 3	a dir/a.dat\000"
+
+	entries, conflicted := parseLsFilesStage(strings.NewReader(stdout))
+
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "d899f6551a51cf19763c5955c7a06a2726f018e9", entries[0].Sha1)
+	assert.Equal(t, ".gitattributes", entries[0].Filename)
+	assert.Equal(t, "4d343e022e11a8618db494dc3c501e80c7e18197", entries[1].Sha1)
+	assert.Equal(t, "a dir/a.dat", entries[1].Filename)
+	assert.Equal(t, "6c2a8e2b6f3ce064e0d2e8c2ee8ba0fd3a6e61ec", entries[2].Sha1)
+
+	assert.Equal(t, 1, len(conflicted))
+	assert.Equal(t, true, conflicted["a dir/a.dat"])
+}
+
 func BenchmarkLsTreeParser(b *testing.B) {
 	stdout := "100644 blob d899f6551a51cf19763c5955c7a06a2726f018e9      42	.gitattributes\000100644 blob 4d343e022e11a8618db494dc3c501e80c7e18197     126	PB SCN 16 Odhrán.wav"
 	blobs := make(chan TreeBlob, b.N*2)
@@ -3,6 +3,13 @@ package lfs
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/github/git-lfs/config"
 )
 
 var (
@@ -10,6 +17,11 @@ var (
 	prePushHook = &Hook{
 		Type:     "pre-push",
 		Contents: "#!/bin/sh\ncommand -v git-lfs >/dev/null 2>&1 || { echo >&2 \"\\nThis repository is configured for Git LFS but 'git-lfs' was not found on your path. If you no longer wish to use Git LFS, remove this hook by deleting .git/hooks/pre-push.\\n\"; exit 2; }\ngit lfs pre-push \"$@\"",
+		// The refs on stdin are kept and given again to the rest of the hook
+		Chained: "command -v git-lfs >/dev/null 2>&1 || { echo >&2 \"\\nThis repository is configured for Git LFS but 'git-lfs' was not found on your path. If you no longer wish to use Git LFS, run 'git lfs uninstall' to remove it from this hook.\\n\"; exit 2; }\n" +
+			"git_lfs_stdin=\"$(cat)\"\n" +
+			"printf '%s\\n' \"$git_lfs_stdin\" | git lfs pre-push \"$@\" || exit $?\n" +
+			"exec <<GIT_LFS_STDIN\n$git_lfs_stdin\nGIT_LFS_STDIN",
 		Upgradeables: []string{
 			"#!/bin/sh\ngit lfs push --stdin $*",
 			"#!/bin/sh\ngit lfs push --stdin \"$@\"",
@@ -45,9 +57,14 @@ var (
 // Get user-readable manual install steps for hooks
 func GetHookInstallSteps() string {
 
+	dir := ".git/hooks"
+	if hooksPath, ok := hooksPathConfig(); ok {
+		dir = hooksPath
+	}
+
 	var buf bytes.Buffer
 	for _, h := range hooks {
-		buf.WriteString(fmt.Sprintf("Add the following to .git/hooks/%s :\n\n", h.Type))
+		buf.WriteString(fmt.Sprintf("Add the following to %s/%s :\n\n", dir, h.Type))
 		buf.WriteString(h.Contents)
 		buf.WriteString("\n")
 	}
@@ -96,3 +113,99 @@ func UninstallFilters() error {
 	filters.Uninstall()
 	return nil
 }
+
+// The statuses of an InstallCheck.
+const (
+	InstallPresent = "present"
+	InstallMissing = "missing"
+	InstallStale   = "stale"
+)
+
+// InstallCheck is the result of checking that one of the filters or hooks
+// `git lfs install` sets up is in place, for `git lfs env --installed`.
+type InstallCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// CheckFilters checks each filter.lfs setting in the active Git config. A
+// value is stale if it isn't the one `git lfs install` would set, with or
+// without --skip-smudge.
+func CheckFilters() []InstallCheck {
+	keys := make([]string, 0, len(filters.Properties))
+	for k := range filters.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	checks := make([]InstallCheck, 0, len(keys))
+	for _, k := range keys {
+		key := filters.normalizeKey(k)
+		value, _ := config.Config.GitConfig(key)
+
+		check := InstallCheck{Name: key, Status: InstallPresent, Detail: value}
+		switch {
+		case len(value) == 0:
+			check.Status = InstallMissing
+			check.Detail = "not set"
+		case value == filters.Properties[k], value == passFilters.Properties[k]:
+		default:
+			check.Status = InstallStale
+			check.Detail = fmt.Sprintf("%q, expected %q", value, filters.Properties[k])
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// CheckHooks checks each hook Git LFS installs, in HooksDir.
+func CheckHooks() []InstallCheck {
+	checks := make([]InstallCheck, 0, len(hooks))
+	for _, h := range hooks {
+		status, detail := h.check()
+		checks = append(checks, InstallCheck{
+			Name:   h.Type + " hook",
+			Status: status,
+			Detail: detail,
+		})
+	}
+	return checks
+}
+
+// check returns whether the hook is installed and runs Git LFS as this version
+// would have it. A hook with other contents which still runs Git LFS, such as
+// one merged by hand, is taken to be present.
+func (h *Hook) check() (string, string) {
+	path := h.Path()
+	stat, err := os.Stat(path)
+	if err != nil {
+		return InstallMissing, fmt.Sprintf("%s not found", path)
+	}
+
+	by, err := ioutil.ReadFile(path)
+	if err != nil {
+		return InstallStale, fmt.Sprintf("%s can't be read: %s", path, err)
+	}
+
+	contents := strings.TrimSpace(string(by))
+	if _, chained := removeHookBlock(contents); chained {
+		if !strings.Contains(contents, hookBlockBegin+"\n"+h.Chained+"\n"+hookBlockEnd) {
+			return InstallStale, fmt.Sprintf("%s has lines from an older version of Git LFS", path)
+		}
+	} else if contents != h.Contents {
+		for _, u := range h.Upgradeables {
+			if contents == u {
+				return InstallStale, fmt.Sprintf("%s is from an older version of Git LFS", path)
+			}
+		}
+		if !strings.Contains(contents, "git lfs") && !strings.Contains(contents, "git-lfs") {
+			return InstallMissing, fmt.Sprintf("%s does not run Git LFS", path)
+		}
+	}
+
+	if runtime.GOOS != "windows" && stat.Mode()&0111 == 0 {
+		return InstallStale, fmt.Sprintf("%s is not executable", path)
+	}
+	return InstallPresent, path
+}
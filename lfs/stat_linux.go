@@ -0,0 +1,24 @@
+// +build linux
+
+package lfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func statData(stat os.FileInfo) *fileStat {
+	st, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	return &fileStat{
+		Ctime: time.Unix(st.Ctim.Unix()),
+		Dev:   uint32(st.Dev),
+		Ino:   uint32(st.Ino),
+		Uid:   st.Uid,
+		Gid:   st.Gid,
+	}
+}
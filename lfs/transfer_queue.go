@@ -1,14 +1,17 @@
 package lfs
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/github/git-lfs/api"
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/git"
 	"github.com/github/git-lfs/progress"
+	"github.com/github/git-lfs/sftp"
 	"github.com/github/git-lfs/transfer"
 	"github.com/rubyist/tracerx"
 )
@@ -41,7 +44,18 @@ type TransferQueue struct {
 	retrying          uint32
 	meter             *progress.ProgressMeter
 	errors            []error
+	declined          []Transferable // Downloads refused by lfs.download.filter
+	modifiedSince     time.Time      // Downloads not modified on the server since are skipped
+	unchanged         []Transferable // Downloads skipped because of modifiedSince
+	unknownModified   int            // Downloads the server gave no modification time for
+	failFast          bool           // Stop starting transfers after the first error
+	aborted           uint32         // Set to 1 once failFast has stopped the queue
+	abortedCount      int32          // Transfers skipped because the queue was stopped
 	transferables     map[string]Transferable
+	remote            string                     // The remote whose endpoint this queue uses
+	routed            map[string][]Transferable  // Transfers routed to other remotes by remote.<name>.lfsroute
+	parent            *TransferQueue             // The queue this one transfers routed objects for
+	limiter           *transfer.BandwidthLimiter // Limits the rate of transfers with the remote's endpoint, if set
 	retries           []Transferable
 	batcher           *Batcher
 	apic              chan Transferable // Channel for processing individual API requests
@@ -57,16 +71,30 @@ type TransferQueue struct {
 
 // newTransferQueue builds a TransferQueue, direction and underlying mechanism determined by adapter
 func newTransferQueue(files int, size int64, dryRun bool, dir transfer.Direction) *TransferQueue {
+	meter := progress.NewProgressMeter(files, size, dryRun, config.Config.Getenv("GIT_LFS_PROGRESS"))
+	meter.SetPlainProgress(config.Config.ForceProgress(), config.Config.ProgressInterval())
+	meter.SetQuiet(config.Config.Quiet())
+
+	return startTransferQueue(meter, dryRun, dir)
+}
+
+// startTransferQueue builds a TransferQueue for the current remote, reporting
+// progress to meter, and starts processing it.
+func startTransferQueue(meter *progress.ProgressMeter, dryRun bool, dir transfer.Direction) *TransferQueue {
 	q := &TransferQueue{
 		direction:     dir,
 		dryRun:        dryRun,
-		meter:         progress.NewProgressMeter(files, size, dryRun, config.Config.Getenv("GIT_LFS_PROGRESS")),
+		meter:         meter,
 		apic:          make(chan Transferable, batchSize),
 		retriesc:      make(chan Transferable, batchSize),
 		errorc:        make(chan error),
-		oldApiWorkers: config.Config.ConcurrentTransfers(),
+		oldApiWorkers: config.Config.RemoteConcurrentTransfers(config.Config.CurrentRemote),
 		transferables: make(map[string]Transferable),
+		remote:        config.Config.CurrentRemote,
+		limiter:       transfer.NewBandwidthLimiter(config.Config.RemoteMaxBandwidth(config.Config.CurrentRemote)),
+		routed:        make(map[string][]Transferable),
 		trMutex:       &sync.Mutex{},
+		failFast:      config.Config.TransferFailFast(),
 	}
 
 	q.errorwait.Add(1)
@@ -77,11 +105,41 @@ func newTransferQueue(files int, size int64, dryRun bool, dir transfer.Direction
 	return q
 }
 
-// Add adds a Transferable to the transfer queue.
+// Add adds a Transferable to the transfer queue. Downloads declined by
+// lfs.download.filter are skipped without contacting the server. A
+// Transferable with the same OID as one already added is skipped, since the
+// object is only transferred once however many paths refer to it. One whose
+// path is routed to another remote by remote.<name>.lfsroute is held back
+// until Wait, and transferred with that remote's endpoint.
 func (q *TransferQueue) Add(t Transferable) {
-	q.wait.Add(1)
+	if q.isAborted() {
+		q.skipAborted(t.Size())
+		return
+	}
+
+	if q.direction == transfer.Download && !q.dryRun && atomic.LoadUint32(&q.retrying) == 0 &&
+		!DownloadFilterAllows(t.Oid(), t.Size()) {
+		q.trMutex.Lock()
+		q.declined = append(q.declined, t)
+		q.trMutex.Unlock()
+		q.Skip(t.Size())
+		return
+	}
+
 	q.trMutex.Lock()
+	if _, ok := q.transferables[t.Oid()]; ok && atomic.LoadUint32(&q.retrying) == 0 {
+		q.trMutex.Unlock()
+		tracerx.Printf("tq: %s [%s] is already queued", t.Name(), t.Oid())
+		q.Skip(t.Size())
+		return
+	}
 	q.transferables[t.Oid()] = t
+	if remote, ok := RouteRemote(t.Name()); ok && remote != q.remote {
+		q.routed[remote] = append(q.routed[remote], t)
+		q.trMutex.Unlock()
+		return
+	}
+	q.wait.Add(1)
 	q.trMutex.Unlock()
 
 	if q.batcher != nil {
@@ -128,9 +186,14 @@ func (q *TransferQueue) addToAdapter(t Transferable) {
 		q.handleTransferResult(res)
 		return
 	}
+	if q.isAborted() {
+		q.skipAborted(t.Size())
+		q.wait.Done()
+		return
+	}
 	err := q.ensureAdapterBegun()
 	if err != nil {
-		q.errorc <- err
+		q.fail(err)
 		q.Skip(t.Size())
 		q.wait.Done()
 		return
@@ -161,13 +224,18 @@ func (q *TransferQueue) ensureAdapterBegun() error {
 	adapterResultChan := make(chan transfer.TransferResult, 20)
 
 	// Progress callback - receives byte updates
+	adapterName := q.adapter.Name()
 	cb := func(name string, total, read int64, current int) error {
 		q.meter.TransferBytes(q.transferKind(), name, read, total, current)
+		q.meter.AdapterBytes(adapterName, int64(current))
 		return nil
 	}
 
+	concurrency := config.Config.RemoteConcurrentTransfers(q.remote)
 	tracerx.Printf("tq: starting transfer adapter %q", q.adapter.Name())
-	err := q.adapter.Begin(config.Config.ConcurrentTransfers(), cb, adapterResultChan)
+	tracerx.Printf("tq: %d concurrent transfers with remote %q, at most %d bytes/s (0 is unlimited)", concurrency, q.remote, config.Config.RemoteMaxBandwidth(q.remote))
+	q.adapter.SetBandwidthLimiter(q.limiter)
+	err := q.adapter.Begin(concurrency, cb, adapterResultChan)
 	if err != nil {
 		return err
 	}
@@ -185,7 +253,9 @@ func (q *TransferQueue) ensureAdapterBegun() error {
 }
 
 func (q *TransferQueue) handleTransferResult(res transfer.TransferResult) {
-	if res.Error != nil {
+	if res.Error == transfer.ErrAborted {
+		q.skipAborted(res.Transfer.Object.Size)
+	} else if res.Error != nil {
 		if q.canRetry(res.Error) {
 			tracerx.Printf("tq: retrying object %s", res.Transfer.Object.Oid)
 			q.trMutex.Lock()
@@ -194,12 +264,16 @@ func (q *TransferQueue) handleTransferResult(res transfer.TransferResult) {
 			if ok {
 				q.retry(t)
 			} else {
-				q.errorc <- res.Error
+				q.fail(res.Error)
 			}
 		} else {
-			q.errorc <- res.Error
+			q.fail(res.Error)
 		}
 	} else {
+		if !q.dryRun {
+			transferMetadata(res.Transfer.Object, q.direction)
+		}
+
 		oid := res.Transfer.Object.Oid
 		for _, c := range q.watchers {
 			c <- oid
@@ -242,16 +316,74 @@ func (q *TransferQueue) Wait() {
 
 	close(q.apic)
 	q.finishAdapter()
+
+	q.transferRouted()
 	close(q.errorc)
 
-	for _, watcher := range q.watchers {
-		close(watcher)
-	}
+	if q.parent == nil {
+		for _, watcher := range q.watchers {
+			close(watcher)
+		}
 
-	q.meter.Finish()
+		q.meter.Finish()
+	}
 	q.errorwait.Wait()
 }
 
+// transferRouted transfers the objects held back by Add for other remotes,
+// one remote at a time once the rest of the queue is done. The API client
+// and transfer adapters use the current remote's endpoint, so it is switched
+// to each remote in turn, and restored afterwards.
+func (q *TransferQueue) transferRouted() {
+	if len(q.routed) == 0 {
+		return
+	}
+
+	remotes := make([]string, 0, len(q.routed))
+	for remote := range q.routed {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+
+	defer func(current string) {
+		config.Config.CurrentRemote = current
+	}(config.Config.CurrentRemote)
+
+	for _, remote := range remotes {
+		routed := q.routed[remote]
+		if q.isAborted() {
+			for _, t := range routed {
+				q.skipAborted(t.Size())
+			}
+			continue
+		}
+
+		tracerx.Printf("tq: transferring %d objects routed to remote %q", len(routed), remote)
+		config.Config.CurrentRemote = remote
+
+		rq := startTransferQueue(q.meter, q.dryRun, q.direction)
+		rq.parent = q
+		rq.watchers = q.watchers
+		rq.failFast = q.failFast
+		rq.modifiedSince = q.modifiedSince
+		for _, t := range routed {
+			rq.Add(t)
+		}
+		rq.Wait()
+
+		for _, err := range rq.errors {
+			q.errorc <- err
+		}
+		q.declined = append(q.declined, rq.declined...)
+		q.unchanged = append(q.unchanged, rq.unchanged...)
+		q.unknownModified += rq.unknownModified
+		atomic.AddInt32(&q.abortedCount, rq.abortedCount)
+		if rq.isAborted() {
+			q.abort()
+		}
+	}
+}
+
 // Watch returns a channel where the queue will write the OID of each transfer
 // as it completes. The channel will be closed when the queue finishes processing.
 func (q *TransferQueue) Watch() chan string {
@@ -267,12 +399,18 @@ func (q *TransferQueue) Watch() chan string {
 // TODO LEGACY API: remove when legacy API removed
 func (q *TransferQueue) individualApiRoutine(apiWaiter chan interface{}) {
 	for t := range q.apic {
+		if q.isAborted() {
+			q.skipAborted(t.Size())
+			q.wait.Done()
+			continue
+		}
+
 		obj, err := t.LegacyCheck()
 		if err != nil {
 			if q.canRetry(err) {
 				q.retry(t)
 			} else {
-				q.errorc <- err
+				q.fail(err)
 			}
 			q.wait.Done()
 			continue
@@ -338,6 +476,14 @@ func (q *TransferQueue) batchApiRoutine() {
 			break
 		}
 
+		if q.isAborted() {
+			for _, t := range batch {
+				q.skipAborted(t.(Transferable).Size())
+			}
+			q.wait.Add(-len(batch))
+			continue
+		}
+
 		tracerx.Printf("tq: sending batch of size %d", len(batch))
 
 		transfers := make([]*api.ObjectResource, 0, len(batch))
@@ -364,7 +510,7 @@ func (q *TransferQueue) batchApiRoutine() {
 					q.retry(t.(Transferable))
 				}
 			} else {
-				q.errorc <- err
+				q.fail(err)
 			}
 
 			q.wait.Add(-len(transfers))
@@ -375,8 +521,14 @@ func (q *TransferQueue) batchApiRoutine() {
 		startProgress.Do(q.meter.Start)
 
 		for _, o := range objs {
+			if q.isAborted() {
+				q.skipAborted(o.Size)
+				q.wait.Done()
+				continue
+			}
+
 			if o.Error != nil {
-				q.errorc <- errutil.Errorf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+				q.fail(errutil.Errorf(o.Error, "[%v] %v", o.Oid, o.Error.Message))
 				q.Skip(o.Size)
 				q.wait.Done()
 				continue
@@ -388,7 +540,10 @@ func (q *TransferQueue) batchApiRoutine() {
 				transfer, ok := q.transferables[o.Oid]
 				q.trMutex.Unlock()
 
-				if ok {
+				if ok && q.skipUnchanged(transfer, o) {
+					q.Skip(transfer.Size())
+					q.wait.Done()
+				} else if ok {
 					transfer.SetObject(o)
 					q.meter.Add(transfer.Name())
 					q.addToAdapter(transfer)
@@ -412,6 +567,14 @@ func (q *TransferQueue) errorCollector() {
 	q.errorwait.Done()
 }
 
+// fail records err, stopping the queue first if it is failing fast
+func (q *TransferQueue) fail(err error) {
+	if q.failFast {
+		q.abort()
+	}
+	q.errorc <- err
+}
+
 func (q *TransferQueue) retryCollector() {
 	for t := range q.retriesc {
 		q.retries = append(q.retries, t)
@@ -443,7 +606,8 @@ func (q *TransferQueue) run() {
 	go q.errorCollector()
 	go q.retryCollector()
 
-	if config.Config.BatchTransfer() {
+	// An SFTP remote is always batched, since there's no legacy API for it
+	if config.Config.BatchTransfer() || sftp.IsSftpUrl(config.Config.Endpoint(q.transferKind()).Url) {
 		tracerx.Printf("tq: running as batched queue, batch size of %d", batchSize)
 		q.batcher = NewBatcher(batchSize)
 		go q.batchApiRoutine()
@@ -465,6 +629,99 @@ func (q *TransferQueue) canRetry(err error) bool {
 	return true
 }
 
+// Declined returns the downloads that lfs.download.filter refused.
+func (q *TransferQueue) Declined() []Transferable {
+	return q.declined
+}
+
+// SetModifiedSince makes the queue skip downloads which the server reports
+// were last modified before since. Servers which do not report modification
+// times are unaffected; see UnknownModified. It must be called before anything
+// is added to the queue.
+func (q *TransferQueue) SetModifiedSince(since time.Time) {
+	q.modifiedSince = since
+}
+
+// Unchanged returns the downloads skipped because the server reported they
+// were not modified since the time given to SetModifiedSince.
+func (q *TransferQueue) Unchanged() []Transferable {
+	return q.unchanged
+}
+
+// UnknownModified returns how many downloads were transferred regardless of
+// SetModifiedSince, because the server did not say when they were modified.
+func (q *TransferQueue) UnknownModified() int {
+	return q.unknownModified
+}
+
+// skipUnchanged reports whether t should be skipped because the server says o
+// has not been modified since q.modifiedSince, recording why.
+func (q *TransferQueue) skipUnchanged(t Transferable, o *api.ObjectResource) bool {
+	if q.modifiedSince.IsZero() || q.direction != transfer.Download {
+		return false
+	}
+
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	if o.ModifiedAt == nil {
+		q.unknownModified++
+		return false
+	}
+
+	if !o.ModifiedAt.Before(q.modifiedSince) {
+		return false
+	}
+
+	tracerx.Printf("tq: skipping %s, not modified since %s", t.Oid(), q.modifiedSince)
+	q.unchanged = append(q.unchanged, t)
+	return true
+}
+
+// SetFailFast sets whether the queue stops starting transfers after the first
+// one fails, instead of attempting every transfer. It overrides
+// lfs.transfer.failfast, and must be called before anything is added to the
+// queue.
+func (q *TransferQueue) SetFailFast(failFast bool) {
+	q.failFast = failFast
+}
+
+// Aborted returns how many transfers were skipped because an earlier one
+// failed while SetFailFast was on.
+func (q *TransferQueue) Aborted() int {
+	return int(atomic.LoadInt32(&q.abortedCount))
+}
+
+func (q *TransferQueue) isAborted() bool {
+	return atomic.LoadUint32(&q.aborted) == 1
+}
+
+// abort stops the queue from starting any more transfers, and tells the
+// adapter to drop the ones it has been given but not started.
+func (q *TransferQueue) abort() {
+	if !atomic.CompareAndSwapUint32(&q.aborted, 0, 1) {
+		return
+	}
+
+	tracerx.Printf("tq: aborting remaining transfers after an error")
+
+	// The adapter lock can be held by finishAdapter while it waits for the
+	// adapter's results to be handled, and abort may be called while handling
+	// one, so the adapter is told without waiting for the lock.
+	go func() {
+		q.adapterInitMutex.Lock()
+		defer q.adapterInitMutex.Unlock()
+		if q.adapterInProgress {
+			q.adapter.Abort()
+		}
+	}()
+}
+
+func (q *TransferQueue) skipAborted(size int64) {
+	atomic.AddInt32(&q.abortedCount, 1)
+	q.Skip(size)
+}
+
 // Errors returns any errors encountered during transfer.
 func (q *TransferQueue) Errors() []error {
 	return q.errors
@@ -8,6 +8,7 @@ import (
 	"github.com/github/git-lfs/api"
 	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/tools"
 	"github.com/github/git-lfs/transfer"
 )
 
@@ -56,6 +57,9 @@ func NewUploadable(oid, filename string) (*Uploadable, error) {
 	if err != nil {
 		return nil, errutil.Errorf(err, "Error uploading file %s (%s)", filename, oid)
 	}
+	if !tools.FileExists(localMediaPath) {
+		localMediaPath = LocalMediaPathReadOnly(oid)
+	}
 
 	if len(filename) > 0 {
 		if err := ensureFile(filename, localMediaPath); err != nil {
@@ -71,6 +75,20 @@ func NewUploadable(oid, filename string) (*Uploadable, error) {
 	return &Uploadable{oid: oid, OidPath: localMediaPath, Filename: filename, size: fi.Size()}, nil
 }
 
+// NewUploadableFromPointer builds an Uploadable for checking whether the
+// server has the pointer's object, which doesn't need the object locally.
+func NewUploadableFromPointer(p *WrappedPointer) *Uploadable {
+	return &Uploadable{oid: p.Oid, Filename: p.Name, size: p.Size}
+}
+
+// NewUploadCheckQueue builds a checking queue, which sends batch upload
+// requests to find which objects the server is missing, but doesn't upload
+// them. The OIDs the server asks to be uploaded are sent to watchers.
+func NewUploadCheckQueue(files int, size int64) *TransferQueue {
+	// Always dry run
+	return newTransferQueue(files, size, true, transfer.Upload)
+}
+
 // NewUploadQueue builds an UploadQueue, allowing `workers` concurrent uploads.
 func NewUploadQueue(files int, size int64, dryRun bool) *TransferQueue {
 	return newTransferQueue(files, size, dryRun, transfer.Upload)
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/github/git-lfs/config"
@@ -137,6 +138,30 @@ func FilenamePassesIncludeExcludeFilter(filename string, includePaths, excludePa
 	return true
 }
 
+// RouteRemote returns the remote whose remote.<name>.lfsroute patterns match
+// filename, and whether any remote's do. The patterns match as those in
+// lfs.fetchinclude do. If several remotes match, the first by name is used.
+func RouteRemote(filename string) (string, bool) {
+	routes := config.Config.LfsRoutes()
+	if len(routes) == 0 {
+		return "", false
+	}
+
+	remotes := make([]string, 0, len(routes))
+	for remote := range routes {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+
+	for _, remote := range remotes {
+		patterns := routes[remote]
+		if len(patterns) > 0 && FilenamePassesIncludeExcludeFilter(filename, patterns, nil) {
+			return remote, true
+		}
+	}
+	return "", false
+}
+
 func GetPlatform() Platform {
 	if currentPlatform == PlatformUndetermined {
 		switch runtime.GOOS {
@@ -266,7 +291,7 @@ func CopyFileContents(src string, dst string) error {
 	if err != nil {
 		return err
 	}
-	return os.Rename(tmp.Name(), dst)
+	return tools.RenameFileCopyPermissions(tmp.Name(), dst)
 }
 
 func LinkOrCopy(src string, dst string) error {
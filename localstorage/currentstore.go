@@ -1,6 +1,7 @@
 package localstorage
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -19,6 +20,14 @@ var (
 	objects        *LocalStorage
 	TempDir        = filepath.Join(os.TempDir(), "git-lfs")
 	checkedTempDir string
+	// IncompleteDir holds partial downloads, which are kept between
+	// invocations so that they can be resumed.
+	IncompleteDir string
+	// ChunkDir holds the chunks of objects sent or received by the chunked
+	// transfer adapter, by their own OIDs. It is only a cache, and can be
+	// deleted at any time.
+	ChunkDir   string
+	tempDirErr error
 )
 
 func Objects() *LocalStorage {
@@ -29,6 +38,15 @@ func ResolveDirs() {
 
 	config.ResolveGitBasicDirs()
 	TempDir = filepath.Join(config.LocalGitDir, "lfs", "tmp") // temp files per worktree
+	tempDirErr = nil
+	customTemp := false
+	if dir := config.Config.TempDir(); len(dir) > 0 {
+		custom := customTempDir(dir)
+		if tempDirErr = checkTempDir(custom); tempDirErr == nil {
+			TempDir = custom
+			customTemp = true
+		}
+	}
 
 	objs, err := NewStorage(
 		filepath.Join(config.LocalGitStorageDir, "lfs", "objects"),
@@ -40,12 +58,48 @@ func ResolveDirs() {
 	}
 
 	objects = objs
+	IncompleteDir = filepath.Join(objs.RootDir, "incomplete")
+	if customTemp {
+		IncompleteDir = filepath.Join(TempDir, "incomplete")
+	}
+	ChunkDir = filepath.Join(config.LocalGitStorageDir, "lfs", "chunks")
+
 	config.LocalLogDir = filepath.Join(objs.RootDir, "logs")
 	if err := os.MkdirAll(config.LocalLogDir, localLogDirPerms); err != nil {
 		panic(fmt.Errorf("Error trying to create log directory in '%s': %s", config.LocalLogDir, err))
 	}
 }
 
+// customTempDir returns the directory within the configured temp dir that this
+// repository's temp files are written to. Each repository gets its own, since
+// several may share the configured temp dir, and each clears its own temp files.
+func customTempDir(dir string) string {
+	sum := sha256.Sum256([]byte(config.LocalGitDir))
+	return filepath.Join(dir, fmt.Sprintf("git-lfs-%x", sum[:8]))
+}
+
+// CheckTempDir returns an error if the temp dir configured with lfs.tempdir or
+// GIT_LFS_TMPDIR can't be written to, in which case commands exit rather than
+// write temp files anywhere else.
+func CheckTempDir() error {
+	return tempDirErr
+}
+
+func checkTempDir(dir string) error {
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), tempDirPerms); err != nil {
+		return fmt.Errorf("Unable to create the Git LFS temp directory %q: %s", dir, err)
+	}
+
+	f, err := ioutil.TempFile(dir, "check")
+	if err != nil {
+		return fmt.Errorf("Unable to write to the Git LFS temp directory %q: %s", dir, err)
+	}
+
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
 func TempFile(prefix string) (*os.File, error) {
 	if checkedTempDir != TempDir {
 		if err := os.MkdirAll(TempDir, tempDirPerms); err != nil {
@@ -14,7 +14,7 @@ const (
 )
 
 var (
-	oidRE                = regexp.MustCompile(`\A[[:alnum:]]{64}`)
+	oidRE                = regexp.MustCompile(`\A[[:alnum:]]{64}\z`)
 	dirPerms os.FileMode = 0755
 )
 
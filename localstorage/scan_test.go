@@ -0,0 +1,42 @@
+package localstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllObjectsSkipsFilesNamedAfterObjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-lfs-localstorage-scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewStorage(filepath.Join(dir, "objects"), filepath.Join(dir, "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	path, err := storage.BuildObjectPath(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		path:              "test",
+		path + ".json":    "{}",
+		path + "-partial": "te",
+		filepath.Join(filepath.Dir(path), ".DS_Store"): "",
+	} {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, []Object{{Oid: oid, Size: 4}}, storage.AllObjects())
+}
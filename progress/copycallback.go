@@ -18,8 +18,11 @@ func (w *CallbackReader) Read(p []byte) (int, error) {
 		w.ReadSize += int64(n)
 	}
 
-	if err == nil && w.C != nil {
-		err = w.C(w.TotalSize, w.ReadSize, n)
+	// The last bytes may come with io.EOF, and must still be counted
+	if (err == nil || (err == io.EOF && n > 0)) && w.C != nil {
+		if cberr := w.C(w.TotalSize, w.ReadSize, n); cberr != nil {
+			err = cberr
+		}
 	}
 
 	return n, err
@@ -3,14 +3,17 @@ package progress
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/olekukonko/ts"
 )
 
+// defaultPlainInterval is how often progress is printed when stdout is not a
+// terminal, since each update then takes a whole line.
+const defaultPlainInterval = time.Second
+
 // ProgressMeter provides a progress bar type output for the TransferQueue. It
 // is given an estimated file count and size up front and tracks the number of
 // files and bytes transferred as well as the number of files and bytes that
@@ -25,11 +28,21 @@ type ProgressMeter struct {
 	started           int32
 	estimatedFiles    int32
 	startTime         time.Time
+	transferStart     time.Time        // When Start was first called
+	adapterBytes      map[string]int64 // Bytes moved by each transfer adapter
+	adapterMutex      sync.Mutex
 	finished          chan interface{}
 	logger            *progressLogger
 	fileIndex         map[string]int64 // Maps a file name to its transfer number
 	fileIndexMutex    *sync.Mutex
 	dryRun            bool
+	tty               bool // Overwrite one line with \r, rather than printing lines
+	updateMutex       sync.Mutex
+	lastLine          string        // Last progress printed when not a terminal
+	lastPrinted       time.Time     // When lastLine was printed
+	plainInterval     time.Duration // How often to print when not a terminal
+	plainForced       bool          // Print every plainInterval, even if unchanged
+	quiet             bool          // Print nothing, only log to GIT_LFS_PROGRESS
 }
 
 // NewProgressMeter creates a new ProgressMeter for the number and size of
@@ -49,11 +62,31 @@ func NewProgressMeter(estFiles int, estBytes int64, dryRun bool, logPath string)
 		estimatedFiles: int32(estFiles),
 		estimatedBytes: estBytes,
 		dryRun:         dryRun,
+		tty:            isTerminal(os.Stdout),
+		plainInterval:  defaultPlainInterval,
+	}
+}
+
+// SetPlainProgress sets how progress is printed when stdout is not a terminal,
+// such as in CI logs: a line at most every interval, and if force is set, at
+// least every interval too, so that a transfer which is taking a long time
+// doesn't look like it has hung. It has no effect on a terminal.
+func (p *ProgressMeter) SetPlainProgress(force bool, interval time.Duration) {
+	p.plainForced = force
+	if interval > 0 {
+		p.plainInterval = interval
 	}
 }
 
+// SetQuiet stops the meter printing anything, including the summary from
+// Finish. Progress is still logged to GIT_LFS_PROGRESS.
+func (p *ProgressMeter) SetQuiet(quiet bool) {
+	p.quiet = quiet
+}
+
 func (p *ProgressMeter) Start() {
 	if atomic.SwapInt32(&p.started, 1) == 0 {
+		p.transferStart = time.Now()
 		go p.writer()
 	}
 }
@@ -84,6 +117,18 @@ func (p *ProgressMeter) TransferBytes(direction, name string, read, total int64,
 	p.logBytes(direction, name, read, total)
 }
 
+// AdapterBytes records that n of the bytes passed to TransferBytes were moved
+// by the named transfer adapter. Once any are recorded, Finish prints the
+// throughput achieved, broken down by adapter if more than one was used.
+func (p *ProgressMeter) AdapterBytes(adapter string, n int64) {
+	p.adapterMutex.Lock()
+	if p.adapterBytes == nil {
+		p.adapterBytes = make(map[string]int64)
+	}
+	p.adapterBytes[adapter] += n
+	p.adapterMutex.Unlock()
+}
+
 // FinishTransfer increments the finished transfer count
 func (p *ProgressMeter) FinishTransfer(name string) {
 	atomic.AddInt64(&p.finishedFiles, 1)
@@ -95,11 +140,53 @@ func (p *ProgressMeter) FinishTransfer(name string) {
 // Finish shuts down the ProgressMeter
 func (p *ProgressMeter) Finish() {
 	close(p.finished)
-	p.update()
+	p.updateMutex.Lock()
+	p.render(true)
+	p.updateMutex.Unlock()
 	p.logger.Close()
-	if !p.dryRun && p.estimatedBytes > 0 {
+	if p.quiet {
+		return
+	}
+	if p.tty && !p.dryRun && p.estimatedBytes > 0 {
 		fmt.Fprintf(os.Stdout, "\n")
 	}
+	if summary := p.summary(time.Now()); len(summary) > 0 {
+		fmt.Fprintln(os.Stdout, summary)
+	}
+}
+
+// summary describes the bytes transferred, how long the transfers took until
+// now and the throughput achieved, or returns "" if no adapter moved any bytes.
+// The throughput is left out if no time has passed.
+func (p *ProgressMeter) summary(now time.Time) string {
+	p.adapterMutex.Lock()
+	defer p.adapterMutex.Unlock()
+
+	var total int64
+	adapters := make([]string, 0, len(p.adapterBytes))
+	for name, n := range p.adapterBytes {
+		total += n
+		adapters = append(adapters, name)
+	}
+	if p.dryRun || total == 0 || p.transferStart.IsZero() {
+		return ""
+	}
+
+	elapsed := now.Sub(p.transferStart)
+	out := fmt.Sprintf("Git LFS: transferred %s in %s", formatBytes(total), elapsed-elapsed%(10*time.Millisecond))
+	if elapsed > 0 {
+		out += fmt.Sprintf(" (%s/s)", formatBytes(int64(float64(total)/elapsed.Seconds())))
+	}
+
+	if len(adapters) > 1 {
+		sort.Strings(adapters)
+		parts := make([]string, 0, len(adapters))
+		for _, name := range adapters {
+			parts = append(parts, fmt.Sprintf("%s %s", name, formatBytes(p.adapterBytes[name])))
+		}
+		out += ": " + strings.Join(parts, ", ")
+	}
+	return out
 }
 
 func (p *ProgressMeter) logBytes(direction, name string, read, total int64) {
@@ -125,20 +212,24 @@ func (p *ProgressMeter) writer() {
 }
 
 func (p *ProgressMeter) update() {
-	if p.dryRun || (p.estimatedFiles == 0 && p.skippedFiles == 0) {
-		return
-	}
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
 
-	width := 80 // default to 80 chars wide if ts.GetSize() fails
-	size, err := ts.GetSize()
-	if err == nil {
-		width = size.Col()
+	p.render(false)
+}
+
+// render writes the current progress. On a terminal the line is redrawn in
+// place, fitted to the terminal width; otherwise a new line is printed at most
+// every plainInterval when the progress changes, or whenever final is set.
+func (p *ProgressMeter) render(final bool) {
+	if p.quiet || p.dryRun || (p.estimatedFiles == 0 && p.skippedFiles == 0) {
+		return
 	}
 
 	// (%d of %d files, %d skipped) %f B / %f B, %f B skipped
 	// skipped counts only show when > 0
 
-	out := fmt.Sprintf("\rGit LFS: (%d of %d files", p.finishedFiles, p.estimatedFiles)
+	out := fmt.Sprintf("Git LFS: (%d of %d files", p.finishedFiles, p.estimatedFiles)
 	if p.skippedFiles > 0 {
 		out += fmt.Sprintf(", %d skipped", p.skippedFiles)
 	}
@@ -147,12 +238,24 @@ func (p *ProgressMeter) update() {
 		out += fmt.Sprintf(", %s skipped", formatBytes(p.skippedBytes))
 	}
 
-	padlen := width - len(out)
-	if 0 < padlen {
-		out += strings.Repeat(" ", padlen)
+	if !p.tty {
+		due := time.Since(p.lastPrinted) >= p.plainInterval
+		if (out == p.lastLine && !(p.plainForced && due)) || (!final && !due) {
+			return
+		}
+		p.lastLine = out
+		p.lastPrinted = time.Now()
+		fmt.Fprintln(os.Stdout, out)
+		return
+	}
+
+	// After a resize the old line may have been reflowed across several
+	// rows, so start a fresh one instead of overwriting it
+	if terminalResized() {
+		fmt.Fprint(os.Stdout, "\n")
 	}
 
-	fmt.Fprintf(os.Stdout, out)
+	fmt.Fprint(os.Stdout, "\r"+fitToWidth(out, terminalWidth()))
 }
 
 func formatBytes(i int64) string {
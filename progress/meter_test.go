@@ -0,0 +1,47 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeterSummarySingleAdapter(t *testing.T) {
+	start := time.Now()
+	p := &ProgressMeter{
+		transferStart: start,
+		adapterBytes:  map[string]int64{"basic": 2048},
+	}
+
+	assert.Equal(t, "Git LFS: transferred 2.00 KB in 2s (1024 B/s)", p.summary(start.Add(2*time.Second)))
+}
+
+func TestMeterSummaryMultipleAdapters(t *testing.T) {
+	start := time.Now()
+	p := &ProgressMeter{
+		transferStart: start,
+		adapterBytes:  map[string]int64{"tus": 3072, "basic": 1024},
+	}
+
+	assert.Equal(t, "Git LFS: transferred 4.00 KB in 2s (2.00 KB/s): basic 1024 B, tus 3.00 KB", p.summary(start.Add(2*time.Second)))
+}
+
+func TestMeterSummaryNoTimeElapsed(t *testing.T) {
+	start := time.Now()
+	p := &ProgressMeter{
+		transferStart: start,
+		adapterBytes:  map[string]int64{"basic": 1024},
+	}
+
+	assert.Equal(t, "Git LFS: transferred 1024 B in 0s", p.summary(start))
+}
+
+func TestMeterSummaryNothingTransferred(t *testing.T) {
+	p := &ProgressMeter{transferStart: time.Now()}
+	assert.Equal(t, "", p.summary(time.Now()))
+
+	p.adapterBytes = map[string]int64{"basic": 1024}
+	p.dryRun = true
+	assert.Equal(t, "", p.summary(time.Now()))
+}
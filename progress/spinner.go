@@ -4,9 +4,6 @@ import (
 	"fmt"
 	"io"
 	"runtime"
-	"strings"
-
-	"github.com/olekukonko/ts"
 )
 
 // Indeterminate progress indicator 'spinner'
@@ -49,14 +46,7 @@ func (s *Spinner) update(out io.Writer, prefix, msg string) {
 
 	str := fmt.Sprintf("%v %v", prefix, msg)
 
-	width := 80 // default to 80 chars wide if ts.GetSize() fails
-	size, err := ts.GetSize()
-	if err == nil {
-		width = size.Col()
-	}
-	padding := strings.Repeat(" ", width-len(str))
-
-	fmt.Fprintf(out, "\r%v%v", str, padding)
+	fmt.Fprintf(out, "\r%v", fitToWidth(str, terminalWidth()))
 
 }
 
@@ -0,0 +1,75 @@
+package progress
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/olekukonko/ts"
+)
+
+const (
+	defaultWidth = 80 // used when the terminal size can't be determined
+	ellipsis     = "..."
+)
+
+var (
+	termWidth    int32
+	termResized  int32
+	termInitOnce sync.Once
+)
+
+// isTerminal returns whether f is attached to a terminal, rather than being
+// redirected to a file or pipe.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && (stat.Mode()&os.ModeCharDevice) != 0
+}
+
+// terminalWidth returns the current number of columns of the terminal on
+// stdout. The value is cached and refreshed when the terminal is resized.
+func terminalWidth() int {
+	termInitOnce.Do(func() {
+		refreshTerminalWidth()
+		notifyResize(func() {
+			refreshTerminalWidth()
+			atomic.StoreInt32(&termResized, 1)
+		})
+	})
+
+	return int(atomic.LoadInt32(&termWidth))
+}
+
+// terminalResized reports whether the terminal has been resized since it was
+// last asked, so that callers can redraw from a clean line.
+func terminalResized() bool {
+	return atomic.SwapInt32(&termResized, 0) == 1
+}
+
+func refreshTerminalWidth() {
+	width := defaultWidth
+	if size, err := ts.GetSize(); err == nil && size.Col() > 0 {
+		width = size.Col()
+	}
+	atomic.StoreInt32(&termWidth, int32(width))
+}
+
+// fitToWidth truncates s with an ellipsis, or pads it with spaces, so that it
+// exactly fills one line of a terminal with the given width. The last column
+// is left empty so that terminals which wrap eagerly don't start a new line.
+func fitToWidth(s string, width int) string {
+	width--
+	if width <= 0 {
+		return ""
+	}
+
+	if len(s) > width {
+		if width <= len(ellipsis) {
+			return s[:width]
+		}
+		return s[:width-len(ellipsis)] + ellipsis
+	}
+
+	return s + strings.Repeat(" ", width-len(s))
+}
@@ -0,0 +1,21 @@
+// +build !windows
+
+package progress
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize calls fn each time the terminal sends SIGWINCH.
+func notifyResize(fn func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGWINCH)
+
+	go func() {
+		for range c {
+			fn()
+		}
+	}()
+}
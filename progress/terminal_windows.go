@@ -0,0 +1,7 @@
+// +build windows
+
+package progress
+
+// notifyResize does nothing on Windows, which has no resize signal; the width
+// is read once when first needed.
+func notifyResize(fn func()) {}
@@ -0,0 +1,250 @@
+// Package sftp stores Git LFS objects on an SFTP server, for remotes whose
+// lfs.url has an sftp:// scheme, by running the OpenSSH sftp client in batch
+// mode. Authentication is left to the client, so SSH keys and agents work as
+// they do for ssh.
+// NOTE: Subject to change, do not rely on this package from outside git-lfs source
+package sftp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/rubyist/tracerx"
+)
+
+// AdapterName is the name of the transfer adapter which moves objects to and
+// from an SFTP remote.
+const AdapterName = "sftp"
+
+// Remote is a Git LFS object store on an SFTP server. Objects are kept under
+// Root as objects/<oid[0:2]>/<oid[2:4]>/<oid>, the same layout as the local
+// store in .git/lfs, so no API server is needed to find them.
+type Remote struct {
+	UserAndHost string
+	Port        string
+	// Root is the path of the store on the server. It is relative to the
+	// login directory if the URL's path starts with /~/.
+	Root string
+	url  string
+}
+
+// IsSftpUrl returns whether rawurl is an sftp:// URL.
+func IsSftpUrl(rawurl string) bool {
+	return strings.HasPrefix(strings.ToLower(rawurl), "sftp://")
+}
+
+// NewRemote parses an sftp://[user@]host[:port]/path URL.
+func NewRemote(rawurl string) (*Remote, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, errutil.Errorf(err, "Invalid SFTP URL %q", rawurl)
+	}
+
+	if !strings.EqualFold(u.Scheme, "sftp") || len(u.Host) == 0 {
+		return nil, errutil.Errorf(nil, "Invalid SFTP URL %q, expected sftp://[user@]host[:port]/path", rawurl)
+	}
+
+	r := &Remote{UserAndHost: u.Host, url: strings.TrimSuffix(rawurl, "/")}
+	if host, port, ok := splitPort(u.Host); ok {
+		r.UserAndHost = host
+		r.Port = port
+	}
+	if strings.HasPrefix(r.UserAndHost, "-") {
+		return nil, errutil.Errorf(nil, "Invalid SFTP URL %q, the host can't start with '-'", rawurl)
+	}
+	if u.User != nil && len(u.User.Username()) > 0 {
+		if strings.HasPrefix(u.User.Username(), "-") {
+			return nil, errutil.Errorf(nil, "Invalid SFTP URL %q, the user can't start with '-'", rawurl)
+		}
+		r.UserAndHost = u.User.Username() + "@" + r.UserAndHost
+	}
+
+	r.Root = strings.TrimSuffix(u.Path, "/")
+	if r.Root == "/~" || strings.HasPrefix(r.Root, "/~/") {
+		r.Root = strings.TrimPrefix(strings.TrimPrefix(r.Root, "/~"), "/")
+	}
+	if len(r.Root) == 0 {
+		r.Root = "."
+	}
+
+	return r, nil
+}
+
+func splitPort(host string) (string, string, bool) {
+	i := strings.LastIndex(host, ":")
+	if i < 0 {
+		return host, "", false
+	}
+	if _, err := strconv.Atoi(host[i+1:]); err != nil {
+		return host, "", false
+	}
+	return host[:i], host[i+1:], true
+}
+
+// ObjectsDir returns the path on the server of the directory objects are
+// stored in.
+func (r *Remote) ObjectsDir() string {
+	return path.Join(r.Root, "objects")
+}
+
+// ObjectPath returns the path on the server of the object with the given OID.
+func (r *Remote) ObjectPath(oid string) string {
+	return path.Join(r.Root, objectPath(oid))
+}
+
+// ObjectUrl returns the sftp:// URL of the object with the given OID.
+func (r *Remote) ObjectUrl(oid string) string {
+	return r.url + "/" + objectPath(oid)
+}
+
+func objectPath(oid string) string {
+	if len(oid) < 5 {
+		return path.Join("objects", oid)
+	}
+	return path.Join("objects", oid[0:2], oid[2:4], oid)
+}
+
+// String returns the remote's host and store path, for messages.
+func (r *Remote) String() string {
+	return fmt.Sprintf("%s:%s", r.UserAndHost, r.Root)
+}
+
+// Stat returns the sizes of those of the given objects which are on the
+// server. It returns an error if the store has no objects directory, so that
+// a mistyped lfs.url isn't mistaken for a store without the objects.
+func (r *Remote) Stat(oids []string) (map[string]int64, error) {
+	commands := make([]string, 0, len(oids)+1)
+	commands = append(commands, "-ls -l "+quote(r.Root))
+	for _, oid := range oids {
+		commands = append(commands, "-ls -l "+quote(r.ObjectPath(oid)))
+	}
+
+	out, err := r.run(commands)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := parseListing(out)
+	if e, ok := entries[r.ObjectsDir()]; !ok || !e.dir {
+		return nil, errutil.Errorf(nil, "SFTP remote %s has no Git LFS objects directory; create %s on the server to store objects there", r, r.ObjectsDir())
+	}
+
+	sizes := make(map[string]int64, len(oids))
+	for _, oid := range oids {
+		if e, ok := entries[r.ObjectPath(oid)]; ok && !e.dir {
+			sizes[oid] = e.size
+		}
+	}
+	return sizes, nil
+}
+
+// Get downloads the object at remotePath to localPath.
+func (r *Remote) Get(remotePath, localPath string) error {
+	_, err := r.run([]string{fmt.Sprintf("get %s %s", quote(remotePath), quote(localPath))})
+	return err
+}
+
+// Put uploads localPath to the object path for oid, creating its parent
+// directories under the objects directory if needed.
+func (r *Remote) Put(localPath, oid string) error {
+	remotePath := r.ObjectPath(oid)
+	parent := path.Dir(remotePath)
+	_, err := r.run([]string{
+		"-mkdir " + quote(path.Dir(parent)),
+		"-mkdir " + quote(parent),
+		fmt.Sprintf("put %s %s", quote(localPath), quote(remotePath)),
+	})
+	return err
+}
+
+// run runs the given commands in a single sftp batch session, returning its
+// standard output. Commands prefixed with "-" may fail without ending the
+// session.
+func (r *Remote) run(commands []string) (string, error) {
+	exe, args := r.command()
+	tracerx.Printf("sftp: %s %s (%d commands)", exe, strings.Join(args, " "), len(commands))
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(commands, "\n") + "\n")
+	var outbuf, errbuf bytes.Buffer
+	cmd.Stdout = &outbuf
+	cmd.Stderr = &errbuf
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(errbuf.String())
+		if len(msg) == 0 {
+			msg = err.Error()
+		}
+		return "", errutil.Errorf(err, "sftp %s: %s", r, msg)
+	}
+	return outbuf.String(), nil
+}
+
+// command returns the sftp client and its arguments. The client is `sftp`
+// unless lfs.sftpcommand is set, which may include arguments of its own.
+func (r *Remote) command() (string, []string) {
+	exe := "sftp"
+	var args []string
+	if command, ok := config.Config.GitConfig("lfs.sftpcommand"); ok {
+		if fields := strings.Fields(command); len(fields) > 0 {
+			exe = fields[0]
+			args = append(args, fields[1:]...)
+		}
+	}
+
+	args = append(args, "-b", "-", "-o", "BatchMode=yes")
+	if len(r.Port) > 0 {
+		args = append(args, "-P", r.Port)
+	}
+	// "--" ends the options, so the destination is never read as one.
+	return exe, append(args, "--", r.UserAndHost)
+}
+
+type listEntry struct {
+	dir  bool
+	size int64
+}
+
+// parseListing reads the output of `ls -l` commands, keyed by each entry's
+// path as listed. Batch mode echoes each command prefixed with "sftp>", and
+// those lines are skipped.
+func parseListing(out string) map[string]listEntry {
+	entries := make(map[string]listEntry)
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "sftp>") {
+			continue
+		}
+
+		// -rw-r--r--    1 user  group  1024 Oct 14 10:00 /path/to/file
+		fields := strings.Fields(line)
+		if len(fields) < 9 || len(fields[0]) != 10 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		name := strings.Join(fields[8:], " ")
+		entries[path.Clean(name)] = listEntry{dir: fields[0][0] == 'd', size: size}
+	}
+
+	return entries
+}
+
+// quote quotes s as an argument to an sftp batch command.
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
@@ -0,0 +1,88 @@
+package sftp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRemote(t *testing.T) {
+	r, err := NewRemote("sftp://lfs@example.com:2222/srv/lfs/")
+	assert.Nil(t, err)
+	assert.Equal(t, "lfs@example.com", r.UserAndHost)
+	assert.Equal(t, "2222", r.Port)
+	assert.Equal(t, "/srv/lfs", r.Root)
+
+	r, err = NewRemote("sftp://example.com/~/lfs")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", r.UserAndHost)
+	assert.Equal(t, "", r.Port)
+	assert.Equal(t, "lfs", r.Root)
+
+	r, err = NewRemote("sftp://example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, ".", r.Root)
+
+	_, err = NewRemote("https://example.com/lfs")
+	assert.NotNil(t, err)
+	_, err = NewRemote("sftp:///lfs")
+	assert.NotNil(t, err)
+}
+
+func TestNewRemoteRejectsOptions(t *testing.T) {
+	for _, rawurl := range []string{
+		"sftp://-oProxyCommand=x:2222/lfs",
+		"sftp://-oProxyCommand=x@example.com/lfs",
+	} {
+		r, err := NewRemote(rawurl)
+		assert.Nil(t, r, rawurl)
+		if assert.NotNil(t, err, rawurl) {
+			assert.Contains(t, err.Error(), "can't start with '-'")
+		}
+	}
+}
+
+func TestRemoteCommandEndsOptions(t *testing.T) {
+	r, err := NewRemote("sftp://lfs@example.com:2222/srv/lfs")
+	assert.Nil(t, err)
+
+	_, args := r.command()
+	assert.True(t, len(args) >= 2)
+	assert.Equal(t, []string{"--", "lfs@example.com"}, args[len(args)-2:])
+	for _, arg := range args[:len(args)-2] {
+		assert.NotEqual(t, "lfs@example.com", arg)
+	}
+}
+
+func TestRemoteObjectPaths(t *testing.T) {
+	oid := "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+	r, _ := NewRemote("sftp://example.com/srv/lfs")
+	assert.Equal(t, "/srv/lfs/objects", r.ObjectsDir())
+	assert.Equal(t, "/srv/lfs/objects/4d/7a/"+oid, r.ObjectPath(oid))
+	assert.Equal(t, "sftp://example.com/srv/lfs/objects/4d/7a/"+oid, r.ObjectUrl(oid))
+
+	r, _ = NewRemote("sftp://example.com/~/")
+	assert.Equal(t, "objects", r.ObjectsDir())
+	assert.Equal(t, "objects/4d/7a/"+oid, r.ObjectPath(oid))
+}
+
+func TestParseListing(t *testing.T) {
+	entries := parseListing(`sftp> -ls -l "/srv/lfs"
+drwxr-xr-x    2 lfs      lfs          4096 Oct 14 10:00 /srv/lfs/objects
+-rw-r--r--    1 lfs      lfs            12 Oct 14 10:00 /srv/lfs/a file
+sftp> -ls -l "/srv/lfs/objects/4d/7a/4d7a"
+-rw-r--r--    ? 1000     1000         1024 Oct 14  2016 /srv/lfs/objects/4d/7a/4d7a
+sftp> -ls -l "/srv/lfs/objects/00/00/0000"
+`)
+
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, listEntry{dir: true, size: 4096}, entries["/srv/lfs/objects"])
+	assert.Equal(t, listEntry{size: 12}, entries["/srv/lfs/a file"])
+	assert.Equal(t, listEntry{size: 1024}, entries["/srv/lfs/objects/4d/7a/4d7a"])
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"/srv/lfs"`, quote("/srv/lfs"))
+	assert.Equal(t, `"C:\\lfs \"tmp\""`, quote(`C:\lfs "tmp"`))
+}
@@ -3,7 +3,9 @@
 package subprocess
 
 import (
+	"os"
 	"os/exec"
+	"syscall"
 )
 
 // ExecCommand is a small platform specific wrapper around os/exec.Command
@@ -12,3 +14,21 @@ func ExecCommand(name string, arg ...string) *exec.Cmd {
 	cmd.Env = env
 	return cmd
 }
+
+// Detach makes cmd start in its own session, so that it keeps running after
+// the process which started it, and its terminal, have gone.
+func Detach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+}
+
+// ProcessRunning returns whether a process with the given pid is running.
+func ProcessRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
@@ -7,6 +7,10 @@ import (
 	"syscall"
 )
 
+// detachedProcess is the DETACHED_PROCESS process creation flag, which the
+// syscall package doesn't define.
+const detachedProcess = 0x00000008
+
 // ExecCommand is a small platform specific wrapper around os/exec.Command
 func ExecCommand(name string, arg ...string) *exec.Cmd {
 	cmd := exec.Command(name, arg...)
@@ -14,3 +18,31 @@ func ExecCommand(name string, arg ...string) *exec.Cmd {
 	cmd.Env = env
 	return cmd
 }
+
+// Detach makes cmd start in its own process group without a console, so that
+// it keeps running after the process which started it, and its console, have
+// gone.
+func Detach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.HideWindow = true
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess
+}
+
+// ProcessRunning returns whether a process with the given pid is running.
+func ProcessRunning(pid int) bool {
+	const stillActive = 259
+
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
@@ -21,6 +21,7 @@ import (
 	"net/textproto"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -32,6 +33,10 @@ import (
 var (
 	repoDir      string
 	largeObjects = newLfsStorage()
+	metadata     = newLfsStorage()
+	uploadParts  = newLfsStorage()
+	chunks       = newLfsStorage()
+	chunkLists   = newLfsStorage()
 	server       *httptest.Server
 	serverTLS    *httptest.Server
 
@@ -67,6 +72,9 @@ func main() {
 	})
 
 	mux.HandleFunc("/storage/", storageHandler)
+	mux.HandleFunc("/metadata/", metadataHandler)
+	mux.HandleFunc("/multipart/", multipartHandler)
+	mux.HandleFunc("/chunked/", chunkedHandler)
 	mux.HandleFunc("/redirect307/", redirect307Handler)
 	mux.HandleFunc("/locks", locksHandler)
 	mux.HandleFunc("/locks/", locksHandler)
@@ -126,16 +134,18 @@ func writeTestStateFile(contents []byte, envVar, defaultFilename string) string
 }
 
 type lfsObject struct {
-	Oid     string             `json:"oid,omitempty"`
-	Size    int64              `json:"size,omitempty"`
-	Actions map[string]lfsLink `json:"actions,omitempty"`
-	Err     *lfsError          `json:"error,omitempty"`
+	Oid        string             `json:"oid,omitempty"`
+	Size       int64              `json:"size,omitempty"`
+	ModifiedAt *time.Time         `json:"modified_at,omitempty"`
+	Actions    map[string]lfsLink `json:"actions,omitempty"`
+	Err        *lfsError          `json:"error,omitempty"`
 }
 
 type lfsLink struct {
-	Href      string            `json:"href"`
-	Header    map[string]string `json:"header,omitempty"`
-	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+	Href      string                 `json:"href"`
+	Header    map[string]string      `json:"header,omitempty"`
+	ExpiresAt time.Time              `json:"expires_at,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
 }
 
 type lfsError struct {
@@ -332,6 +342,8 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 		searchForTransfer = "tus"
 	} else if testingCustomTransfer {
 		searchForTransfer = "testcustom"
+	} else if strings.HasPrefix(repo, "chunked") {
+		searchForTransfer = "chunked"
 	}
 	if len(searchForTransfer) > 0 {
 		for _, t := range objs.Transfers {
@@ -356,6 +368,10 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 			if !exists {
 				o.Err = &lfsError{Code: 404, Message: fmt.Sprintf("Object %v does not exist", obj.Oid)}
 				addAction = false
+			} else if strings.HasPrefix(repo, "stored-size") {
+				// report the size of the stored object, not the one requested
+				by, _ := largeObjects.Get(repo, obj.Oid)
+				o.Size = int64(len(by))
 			}
 		} else {
 			if exists {
@@ -394,6 +410,26 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 				}
 
 				o.Actions = map[string]lfsLink{action: a}
+
+				if action == "upload" && strings.HasPrefix(repo, "multipart") {
+					o.Actions[action] = multipartLink(repo, obj.Oid, obj.Size)
+				}
+
+				if transferChoice == "chunked" {
+					o.Actions[action] = chunkedLink(repo, obj.Oid)
+				}
+
+				if strings.HasPrefix(repo, "modified-at") {
+					if modified, ok := largeObjects.ModifiedAt(repo, obj.Oid); ok {
+						o.ModifiedAt = &modified
+					}
+				}
+
+				if repoConfigBool(repo, "lfstest.metadata") {
+					o.Actions["metadata"] = lfsLink{
+						Href: server.URL + "/metadata/" + obj.Oid + "?r=" + repo,
+					}
+				}
 			}
 		}
 
@@ -447,6 +483,354 @@ func serveExpired(repo string) {
 	expiredRepos[repo] = true
 }
 
+// handles any /metadata/{oid} requests, storing and serving the object
+// metadata sidecars that clients send when lfs.metadata is enabled.
+func metadataHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := reqId(w)
+	if !ok {
+		return
+	}
+
+	repo := r.URL.Query().Get("r")
+	parts := strings.Split(r.URL.Path, "/")
+	oid := parts[len(parts)-1]
+
+	debug(id, "metadata %s %s repo: %s", r.Method, oid, repo)
+	switch r.Method {
+	case "POST":
+		by, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		metadata.Set(repo, oid, by)
+		w.WriteHeader(200)
+	case "GET":
+		by, ok := metadata.Get(repo, oid)
+		if !ok {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(by)
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+// multipartPartSize is the part size the test server asks for, small enough
+// that the integration tests can upload several parts.
+const multipartPartSize = 4
+
+// multipartLink returns an upload action asking for a multipart upload. Repos
+// named "multipart-init*" hand the part URLs out from an init URL, the others
+// list them in the action.
+func multipartLink(repo, oid string, size int64) lfsLink {
+	base := server.URL + "/multipart/" + oid
+	a := lfsLink{
+		Href:   base + "/complete?r=" + repo,
+		Header: map[string]string{},
+		Args:   map[string]interface{}{"part_size": multipartPartSize},
+	}
+
+	if strings.HasPrefix(repo, "multipart-init") {
+		a.Args["init_url"] = base + "/init?r=" + repo
+	} else {
+		a.Args["part_urls"] = multipartPartUrls(repo, oid, size)
+	}
+	return a
+}
+
+func multipartPartUrls(repo, oid string, size int64) []string {
+	n := int((size + multipartPartSize - 1) / multipartPartSize)
+	if n == 0 {
+		n = 1
+	}
+
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/multipart/%s/%d?r=%s", server.URL, oid, i+1, repo)
+	}
+	return urls
+}
+
+// multipartFailures counts the parts that "multipart-flaky*" repos have
+// failed on purpose, so each part only fails once.
+var multipartFailures = map[string]bool{}
+var multipartMu sync.Mutex
+
+// handles /multipart/{oid}/{init,complete,part number} requests
+func multipartHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := reqId(w)
+	if !ok {
+		return
+	}
+
+	repo := r.URL.Query().Get("r")
+	parts := strings.Split(r.URL.Path, "/")
+	oid, action := parts[len(parts)-2], parts[len(parts)-1]
+
+	debug(id, "multipart %s %s %s repo: %s", r.Method, oid, action, repo)
+	switch {
+	case r.Method == "POST" && action == "init":
+		var req struct {
+			Size     int64 `json:"size"`
+			PartSize int64 `json:"part_size"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PartSize != multipartPartSize {
+			w.WriteHeader(400)
+			return
+		}
+
+		by, _ := json.Marshal(map[string][]string{
+			"part_urls": multipartPartUrls(repo, oid, req.Size),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(by)
+	case r.Method == "POST" && action == "complete":
+		var req struct {
+			Parts []struct {
+				PartNumber int    `json:"part_number"`
+				ETag       string `json:"etag"`
+			} `json:"parts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		hash := sha256.New()
+		buf := &bytes.Buffer{}
+		for i, part := range req.Parts {
+			by, ok := uploadParts.Get(repo, fmt.Sprintf("%s/%d", oid, i+1))
+			if !ok || part.PartNumber != i+1 || part.ETag != partETag(by) {
+				debug(id, "multipart %s: bad part %d", oid, i+1)
+				w.WriteHeader(400)
+				return
+			}
+			io.MultiWriter(hash, buf).Write(by)
+		}
+
+		if hex.EncodeToString(hash.Sum(nil)) != oid {
+			w.WriteHeader(422)
+			return
+		}
+
+		largeObjects.Set(repo, oid, buf.Bytes())
+		w.WriteHeader(200)
+	case r.Method == "PUT":
+		key := oid + "/" + action
+		if strings.HasPrefix(repo, "multipart-flaky") && action == "2" {
+			multipartMu.Lock()
+			failed := multipartFailures[repo+"/"+key]
+			multipartFailures[repo+"/"+key] = true
+			multipartMu.Unlock()
+
+			if !failed {
+				io.Copy(ioutil.Discard, r.Body)
+				w.WriteHeader(500)
+				return
+			}
+		}
+
+		if len(r.Header.Get("Authorization")) > 0 {
+			debug(id, "multipart %s: credentials sent to a part URL", oid)
+			w.WriteHeader(400)
+			return
+		}
+
+		by, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+
+		uploadParts.Set(repo, key, by)
+		w.Header().Set("ETag", partETag(by))
+		w.WriteHeader(200)
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+func partETag(by []byte) string {
+	sum := sha256.Sum256(by)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// chunkedSizes are the chunk sizes the test server asks for, small enough
+// that the integration tests' objects are split into many chunks.
+var chunkedSizes = map[string]interface{}{
+	"chunk_min_size": 64,
+	"chunk_avg_size": 256,
+	"chunk_max_size": 1024,
+}
+
+type chunkedChunk struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+	Href string `json:"href,omitempty"`
+}
+
+type chunkedObject struct {
+	Oid    string          `json:"oid"`
+	Size   int64           `json:"size"`
+	Chunks []*chunkedChunk `json:"chunks"`
+}
+
+// chunkedLink returns an action for the "chunked" transfer adapter.
+func chunkedLink(repo, oid string) lfsLink {
+	return lfsLink{
+		Href:   server.URL + "/chunked/" + oid + "?r=" + repo,
+		Header: map[string]string{},
+		Args:   chunkedSizes,
+	}
+}
+
+func chunkUrl(repo, oid string) string {
+	return server.URL + "/chunked/chunks/" + oid + "?r=" + repo
+}
+
+// chunkedObjectChunks returns the chunk list of an object. Objects which
+// weren't uploaded in chunks are split into fixed size ones.
+func chunkedObjectChunks(repo, oid string) (*chunkedObject, bool) {
+	if by, ok := chunkLists.Get(repo, oid); ok {
+		obj := &chunkedObject{}
+		if err := json.Unmarshal(by, obj); err != nil {
+			return nil, false
+		}
+		return obj, true
+	}
+
+	by, ok := largeObjects.Get(repo, oid)
+	if !ok {
+		return nil, false
+	}
+
+	obj := &chunkedObject{Oid: oid, Size: int64(len(by))}
+	for len(by) > 0 {
+		n := 256
+		if n > len(by) {
+			n = len(by)
+		}
+		sum := sha256.Sum256(by[:n])
+		coid := hex.EncodeToString(sum[:])
+		chunks.Set(repo, coid, by[:n])
+		obj.Chunks = append(obj.Chunks, &chunkedChunk{Oid: coid, Size: int64(n)})
+		by = by[n:]
+	}
+	return obj, true
+}
+
+// handles /chunked/{oid}, /chunked/{oid}/commit and /chunked/chunks/{oid}
+// requests
+func chunkedHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := reqId(w)
+	if !ok {
+		return
+	}
+
+	repo := r.URL.Query().Get("r")
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/chunked/"), "/")
+
+	debug(id, "chunked %s %s repo: %s", r.Method, r.URL.Path, repo)
+	switch {
+	case len(parts) == 2 && parts[0] == "chunks" && r.Method == "PUT":
+		by, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+
+		sum := sha256.Sum256(by)
+		if hex.EncodeToString(sum[:]) != parts[1] {
+			w.WriteHeader(422)
+			return
+		}
+		chunks.Set(repo, parts[1], by)
+		w.WriteHeader(200)
+	case len(parts) == 2 && parts[0] == "chunks" && r.Method == "GET":
+		by, ok := chunks.Get(repo, parts[1])
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(200)
+		w.Write(by)
+	case len(parts) == 1 && r.Method == "GET":
+		obj, ok := chunkedObjectChunks(repo, parts[0])
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		for _, c := range obj.Chunks {
+			c.Href = chunkUrl(repo, c.Oid)
+		}
+
+		by, _ := json.Marshal(obj)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(by)
+	case len(parts) == 1 && r.Method == "POST":
+		obj := &chunkedObject{}
+		if err := json.NewDecoder(r.Body).Decode(obj); err != nil || obj.Oid != parts[0] {
+			w.WriteHeader(400)
+			return
+		}
+
+		missing := []*chunkedChunk{}
+		for _, c := range obj.Chunks {
+			if !chunks.Has(repo, c.Oid) {
+				missing = append(missing, &chunkedChunk{Oid: c.Oid, Href: chunkUrl(repo, c.Oid)})
+			}
+		}
+		debug(id, "chunked %s: missing %d of %d chunks", obj.Oid, len(missing), len(obj.Chunks))
+
+		by, _ := json.Marshal(map[string]interface{}{
+			"missing": missing,
+			"commit": lfsLink{
+				Href: server.URL + "/chunked/" + obj.Oid + "/commit?r=" + repo,
+			},
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(by)
+	case len(parts) == 2 && parts[1] == "commit" && r.Method == "POST":
+		obj := &chunkedObject{}
+		if err := json.NewDecoder(r.Body).Decode(obj); err != nil || obj.Oid != parts[0] {
+			w.WriteHeader(400)
+			return
+		}
+
+		hash := sha256.New()
+		buf := &bytes.Buffer{}
+		for _, c := range obj.Chunks {
+			by, ok := chunks.Get(repo, c.Oid)
+			if !ok {
+				debug(id, "chunked %s: missing chunk %s", obj.Oid, c.Oid)
+				w.WriteHeader(400)
+				return
+			}
+			io.MultiWriter(hash, buf).Write(by)
+		}
+
+		if hex.EncodeToString(hash.Sum(nil)) != obj.Oid || int64(buf.Len()) != obj.Size {
+			w.WriteHeader(422)
+			return
+		}
+
+		list, _ := json.Marshal(obj)
+		chunkLists.Set(repo, obj.Oid, list)
+		largeObjects.Set(repo, obj.Oid, buf.Bytes())
+		w.WriteHeader(200)
+	default:
+		w.WriteHeader(405)
+	}
+}
+
 // Persistent state across requests
 var batchResumeFailFallbackStorageAttempts = 0
 var tusStorageAttempts = 0
@@ -468,6 +852,12 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 	debug(id, "storage %s %s repo: %s", r.Method, oid, repo)
 	switch r.Method {
 	case "PUT":
+		if strings.HasPrefix(repo, "expect100") && r.Header.Get("Expect") != "100-continue" {
+			debug(id, "Expect: 100-continue expected")
+			w.WriteHeader(417)
+			return
+		}
+
 		switch oidHandlers[oid] {
 		case "status-storage-403":
 			w.WriteHeader(403)
@@ -545,6 +935,12 @@ func storageHandler(w http.ResponseWriter, r *http.Request) {
 					byteLimit = 8
 					batchResumeFailFallbackStorageAttempts++
 				}
+			} else if bytes.HasPrefix(by, []byte("slow-download")) {
+				// Stall before responding, so the client has to wait
+				time.Sleep(3 * time.Second)
+			} else if bytes.HasPrefix(by, []byte("download-500")) {
+				// Stored fine, but can't be downloaded
+				statusCode = 500
 			}
 			w.WriteHeader(statusCode)
 			if byteLimit > 0 {
@@ -741,12 +1137,15 @@ type Lock struct {
 	CommitSHA  string    `json:"commit_sha"`
 	LockedAt   time.Time `json:"locked_at"`
 	UnlockedAt time.Time `json:"unlocked_at,omitempty"`
+	// LeaseExpiresAt is only sent for locks with a lease
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
 }
 
 type LockRequest struct {
 	Path               string    `json:"path"`
 	LatestRemoteCommit string    `json:"latest_remote_commit"`
 	Committer          Committer `json:"committer"`
+	LeaseSeconds       int64     `json:"lease_seconds,omitempty"`
 }
 
 type LockResponse struct {
@@ -765,6 +1164,16 @@ type UnlockResponse struct {
 	Err  string `json:"error,omitempty"`
 }
 
+type RenewRequest struct {
+	Id           string `json:"id"`
+	LeaseSeconds int64  `json:"lease_seconds,omitempty"`
+}
+
+type RenewResponse struct {
+	Lock *Lock  `json:"lock"`
+	Err  string `json:"error,omitempty"`
+}
+
 type LockList struct {
 	Locks      []Lock `json:"locks"`
 	NextCursor string `json:"next_cursor,omitempty"`
@@ -774,6 +1183,8 @@ type LockList struct {
 var (
 	lmu   sync.RWMutex
 	locks = []Lock{}
+	// leases holds the lease each leased lock was created with, by lock ID
+	leases = make(map[string]time.Duration)
 )
 
 func addLocks(l ...Lock) {
@@ -786,12 +1197,61 @@ func addLocks(l ...Lock) {
 }
 
 func getLocks() []Lock {
+	expireLocks()
+
 	lmu.RLock()
 	defer lmu.RUnlock()
 
 	return locks
 }
 
+// expireLocks releases the locks whose leases have expired.
+func expireLocks() {
+	lmu.Lock()
+	defer lmu.Unlock()
+
+	now := time.Now()
+	active := locks[:0]
+	for _, l := range locks {
+		if l.LeaseExpiresAt != nil && now.After(*l.LeaseExpiresAt) {
+			delete(leases, l.Id)
+			continue
+		}
+		active = append(active, l)
+	}
+	locks = active
+}
+
+// renewLock extends the lease on the lock with the given id by lease, or by its
+// original lease if lease is 0.
+func renewLock(id string, lease time.Duration) (*Lock, string) {
+	expireLocks()
+
+	lmu.Lock()
+	defer lmu.Unlock()
+
+	for i, l := range locks {
+		if l.Id != id {
+			continue
+		}
+
+		original, ok := leases[id]
+		if !ok {
+			return nil, "lock has no lease"
+		}
+		if lease == 0 {
+			lease = original
+		}
+
+		expires := time.Now().Add(lease)
+		locks[i].LeaseExpiresAt = &expires
+		renewed := locks[i]
+		return &renewed, ""
+	}
+
+	return nil, "unable to find lock"
+}
+
 type LocksByCreatedAt []Lock
 
 func (c LocksByCreatedAt) Len() int           { return len(c) }
@@ -871,7 +1331,21 @@ func locksHandler(w http.ResponseWriter, r *http.Request) {
 			enc.Encode(ll)
 		}
 	case "POST":
-		if strings.HasSuffix(r.URL.Path, "unlock") {
+		if strings.HasSuffix(r.URL.Path, "renew") {
+			var renewRequest RenewRequest
+			if err := dec.Decode(&renewRequest); err != nil {
+				enc.Encode(&RenewResponse{
+					Err: err.Error(),
+				})
+				return
+			}
+
+			lock, err := renewLock(renewRequest.Id, time.Duration(renewRequest.LeaseSeconds)*time.Second)
+			enc.Encode(&RenewResponse{
+				Lock: lock,
+				Err:  err,
+			})
+		} else if strings.HasSuffix(r.URL.Path, "unlock") {
 			var unlockRequest UnlockRequest
 			if err := dec.Decode(&unlockRequest); err != nil {
 				enc.Encode(&UnlockResponse{
@@ -926,6 +1400,18 @@ func locksHandler(w http.ResponseWriter, r *http.Request) {
 				LockedAt:  time.Now(),
 			}
 
+			// Paths starting with "nolease" act like a server which doesn't
+			// support leases, and ignores the requested one.
+			if lockRequest.LeaseSeconds > 0 && !strings.HasPrefix(lockRequest.Path, "nolease") {
+				lease := time.Duration(lockRequest.LeaseSeconds) * time.Second
+				expires := lock.LockedAt.Add(lease)
+				lock.LeaseExpiresAt = &expires
+
+				lmu.Lock()
+				leases[lock.Id] = lease
+				lmu.Unlock()
+			}
+
 			addLocks(*lock)
 
 			// TODO(taylor): commit_needed case
@@ -992,8 +1478,17 @@ func repoFromLfsUrl(urlpath string) (string, error) {
 	return repo, nil
 }
 
+// repoConfigBool reports whether a test turned on the given boolean setting in
+// the config of the repo's bare repository, with git config.
+func repoConfigBool(repo, key string) bool {
+	cmd := exec.Command("git", "config", "--bool", "--file", filepath.Join(repoDir, repo+".git", "config"), key)
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
 type lfsStorage struct {
 	objects    map[string]map[string][]byte
+	modified   map[string]map[string]time.Time
 	incomplete map[string]map[string][]byte
 	mutex      *sync.Mutex
 }
@@ -1031,6 +1526,20 @@ func (s *lfsStorage) Set(repo, oid string, by []byte) {
 		s.objects[repo] = repoObjects
 	}
 	repoObjects[oid] = by
+
+	repoModified, ok := s.modified[repo]
+	if !ok {
+		repoModified = make(map[string]time.Time)
+		s.modified[repo] = repoModified
+	}
+	repoModified[oid] = time.Now()
+}
+
+func (s *lfsStorage) ModifiedAt(repo, oid string) (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	modified, ok := s.modified[repo][oid]
+	return modified, ok
 }
 
 func (s *lfsStorage) Delete(repo, oid string) {
@@ -1077,6 +1586,7 @@ func (s *lfsStorage) DeleteIncomplete(repo, oid string) {
 func newLfsStorage() *lfsStorage {
 	return &lfsStorage{
 		objects:    make(map[string]map[string][]byte),
+		modified:   make(map[string]map[string]time.Time),
 		incomplete: make(map[string]map[string][]byte),
 		mutex:      &sync.Mutex{},
 	}
@@ -0,0 +1,166 @@
+// +build testtools
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// This test sftp client stands in for OpenSSH's sftp in batch mode
+// (`sftp -b - [options] host`), so that the SFTP transfer adapter can be tested
+// without an SSH server. Paths on the "server" are found under the directory
+// in LFSTEST_SFTP_ROOT, with relative paths under its "home" directory. Each
+// invocation's arguments are appended to LFSTEST_SFTP_LOG, if it is set.
+func main() {
+	root := os.Getenv("LFSTEST_SFTP_ROOT")
+	if len(root) == 0 {
+		fail("LFSTEST_SFTP_ROOT is not set")
+	}
+
+	if logfile := os.Getenv("LFSTEST_SFTP_LOG"); len(logfile) > 0 {
+		f, err := os.OpenFile(logfile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			fail(err.Error())
+		}
+		fmt.Fprintln(f, strings.Join(os.Args[1:], " "))
+		f.Close()
+	}
+
+	if len(os.Args) < 3 || os.Args[1] != "-b" || os.Args[2] != "-" {
+		fail("usage: lfstest-sftp -b - [options] host")
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Printf("sftp> %s\n", line)
+
+		ignoreErr := strings.HasPrefix(line, "-")
+		args := splitArgs(strings.TrimPrefix(line, "-"))
+		if len(args) == 0 {
+			continue
+		}
+
+		if err := run(root, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			if !ignoreErr {
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+func run(root string, args []string) error {
+	serverPath := func(p string) string {
+		if path.IsAbs(p) {
+			return filepath.Join(root, filepath.FromSlash(p))
+		}
+		return filepath.Join(root, "home", filepath.FromSlash(p))
+	}
+
+	switch {
+	case args[0] == "ls" && len(args) == 3 && args[1] == "-l":
+		p := args[2]
+		fi, err := os.Stat(serverPath(p))
+		if err != nil {
+			return fmt.Errorf("Can't ls: %q not found", p)
+		}
+		if !fi.IsDir() {
+			printEntry(fi, p)
+			return nil
+		}
+		infos, err := ioutil.ReadDir(serverPath(p))
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			printEntry(info, path.Join(p, info.Name()))
+		}
+		return nil
+
+	case args[0] == "mkdir" && len(args) == 2:
+		if err := os.Mkdir(serverPath(args[1]), 0755); err != nil {
+			return fmt.Errorf("Couldn't create directory: %v", err)
+		}
+		return nil
+
+	case args[0] == "get" && len(args) == 3:
+		return copyFile(serverPath(args[1]), args[2])
+
+	case args[0] == "put" && len(args) == 3:
+		return copyFile(args[1], serverPath(args[2]))
+	}
+
+	return fmt.Errorf("Invalid command: %s", strings.Join(args, " "))
+}
+
+func printEntry(fi os.FileInfo, name string) {
+	perms := "-rw-r--r--"
+	if fi.IsDir() {
+		perms = "drwxr-xr-x"
+	}
+	fmt.Printf("%s    1 lfs      lfs      %8d %s %s\n", perms, fi.Size(), fi.ModTime().Format("Jan _2 15:04"), name)
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return fmt.Errorf("File %q not found.", from)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return fmt.Errorf("Couldn't open %q for writing: %v", to, err)
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// splitArgs splits a batch command into its arguments, which may be quoted
+// with backslash escapes.
+func splitArgs(line string) []string {
+	var args []string
+	var arg []rune
+	inArg, quoted, escaped := false, false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg = append(arg, r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case r == ' ' && !quoted:
+			if inArg {
+				args = append(args, string(arg))
+				arg = arg[:0]
+				inArg = false
+			}
+		default:
+			arg = append(arg, r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
@@ -0,0 +1,123 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "bundle create and unbundle"
+(
+  set -e
+
+  reponame="bundle-roundtrip"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents1="bundle v1"
+  contents1_oid=$(calc_oid "$contents1")
+  contents2="bundle v2"
+  contents2_oid=$(calc_oid "$contents2")
+
+  printf "$contents1" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  printf "$contents2" > a.dat
+  git add a.dat
+  git commit -m "update a.dat"
+
+  git lfs bundle create ../assets.lfsbundle master 2>&1 | tee bundle.log
+  grep "Bundled 2 object(s) reachable from master into ../assets.lfsbundle" bundle.log
+
+  rm -rf .git/lfs/objects
+  refute_local_object "$contents1_oid"
+  refute_local_object "$contents2_oid"
+
+  git lfs bundle unbundle ../assets.lfsbundle 2>&1 | tee unbundle.log
+  grep "Imported 2 object(s), 0 already present" unbundle.log
+  assert_local_object "$contents1_oid" 9
+  assert_local_object "$contents2_oid" 9
+
+  git lfs bundle unbundle ../assets.lfsbundle 2>&1 | tee unbundle.log
+  grep "Imported 0 object(s), 2 already present" unbundle.log
+
+  rm a.dat
+  git lfs checkout
+  [ "$contents2" = "$(cat a.dat)" ]
+)
+end_test
+
+begin_test "bundle create with missing objects"
+(
+  set -e
+
+  reponame="bundle-missing"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "missing" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  rm -rf .git/lfs/objects
+
+  set +e
+  git lfs bundle create ../missing.lfsbundle master 2>&1 | tee bundle.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Missing object: a.dat ($(calc_oid "missing"))" bundle.log
+  grep "1 object(s) reachable from master are not in the local store" bundle.log
+  [ ! -e ../missing.lfsbundle ]
+)
+end_test
+
+begin_test "bundle unbundle rejects corrupt objects"
+(
+  set -e
+
+  reponame="bundle-corrupt"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  good="good object"
+  good_oid=$(calc_oid "$good")
+  bad="bad object"
+  bad_oid=$(calc_oid "$bad")
+
+  printf "$good" > good.dat
+  printf "$bad" > bad.dat
+  git add .gitattributes good.dat bad.dat
+  git commit -m "add objects"
+
+  git lfs bundle create ../corrupt.lfsbundle master
+  rm -rf .git/lfs/objects
+
+  mkdir ../unpacked
+  tar -xf ../corrupt.lfsbundle -C ../unpacked
+  printf "BAD object" > "../unpacked/objects/$bad_oid"
+  (cd ../unpacked && tar -cf ../corrupt.lfsbundle index "objects/$good_oid" "objects/$bad_oid")
+
+  set +e
+  git lfs bundle unbundle ../corrupt.lfsbundle 2>&1 | tee unbundle.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Rejected $bad_oid: content hashes to $(calc_oid "BAD object")" unbundle.log
+  grep "Imported 1 object(s), 0 already present" unbundle.log
+  grep "1 object(s) in the bundle were rejected" unbundle.log
+  assert_local_object "$good_oid" 11
+  refute_local_object "$bad_oid"
+
+  printf "not a bundle" > ../garbage.lfsbundle
+  set +e
+  git lfs bundle unbundle ../garbage.lfsbundle 2>&1 | tee unbundle.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Not a Git LFS bundle" unbundle.log
+)
+end_test
@@ -119,3 +119,322 @@ begin_test "checkout: outside git repository"
   grep "Not in a git repository" checkout.log
 )
 end_test
+
+begin_test "checkout --stage"
+(
+  set -e
+
+  mkdir checkout-stage
+  cd checkout-stage
+  git init
+
+  git lfs track "*.dat"
+  printf "base" > a.dat
+  printf "clean" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "base"
+
+  git checkout -b theirs
+  printf "theirs" > a.dat
+  printf "added by theirs" > c.dat
+  git add a.dat c.dat
+  git commit -m "theirs"
+
+  git checkout master
+  printf "ours" > a.dat
+  printf "added by ours" > c.dat
+  git add a.dat c.dat
+  git commit -m "ours"
+
+  set +e
+  git merge theirs
+  set -e
+
+  git lfs checkout --stage=theirs a.dat 2>&1 | tee checkout.log
+  grep "Checked out stage 3 (theirs) of a.dat" checkout.log
+  [ "theirs" = "$(cat a.dat)" ]
+
+  git lfs checkout --stage=2 a.dat
+  [ "ours" = "$(cat a.dat)" ]
+
+  git lfs checkout --stage=base a.dat
+  [ "base" = "$(cat a.dat)" ]
+
+  # the conflict is left for the user to resolve
+  [ "UU a.dat" = "$(git status --porcelain a.dat)" ]
+
+  set +e
+  git lfs checkout --stage=1 c.dat 2>&1 | tee checkout.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "c.dat has no stage 1 (base) in the index" checkout.log
+
+  set +e
+  git lfs checkout --stage=3 b.dat 2>&1 | tee checkout.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "b.dat is not in a conflicted state" checkout.log
+
+  set +e
+  git lfs checkout --stage=4 a.dat 2>&1 | tee checkout.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Invalid --stage" checkout.log
+)
+end_test
+
+begin_test "checkout --dry-run"
+(
+  set -e
+
+  reponame="checkout-dry-run"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  for name in present missing done; do
+    printf "$name" > "$name.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "add files"
+
+  missing_oid="$(calc_oid "missing")"
+  rm ".git/lfs/objects/${missing_oid:0:2}/${missing_oid:2:2}/$missing_oid"
+  rm present.dat missing.dat
+
+  git lfs checkout --dry-run 2>&1 | tee checkout.log
+  grep "checkout: present.dat (7 B)" checkout.log
+  grep "missing: missing.dat ($missing_oid)" checkout.log
+  grep "up to date: done.dat" checkout.log
+  grep "1 files would be checked out (7 B), 1 up to date, 1 missing locally" checkout.log
+  grep "Run git lfs fetch" checkout.log
+
+  # nothing was written
+  [ ! -e present.dat ]
+  [ ! -e missing.dat ]
+
+  git lfs checkout --dry-run present.dat 2>&1 | tee checkout.log
+  grep "1 files would be checked out (7 B), 0 up to date, 0 missing locally" checkout.log
+
+  git lfs checkout --dry-run --stage=ours present.dat 2>&1 | tee checkout.log
+  grep "Cannot combine --dry-run with --stage" checkout.log
+)
+end_test
+
+begin_test "checkout --index"
+(
+  set -e
+
+  mkdir checkout-index
+  cd checkout-index
+  git init
+
+  git lfs track "*.dat"
+  printf "committed" > a.dat
+  printf "conflict base" > c.dat
+  git add .gitattributes a.dat c.dat
+  git commit -m "base"
+
+  git checkout -b theirs
+  printf "conflict theirs" > c.dat
+  git add c.dat
+  git commit -m "theirs"
+  git checkout master
+
+  # stage new versions, leaving only their pointers in the working copy
+  printf "staged" > a.dat
+  printf "staged missing" > b.dat
+  git add a.dat b.dat
+  git show :a.dat > a.dat
+  git show :b.dat > b.dat
+  missing_oid="$(calc_oid "staged missing")"
+  rm ".git/lfs/objects/${missing_oid:0:2}/${missing_oid:2:2}/$missing_oid"
+
+  # HEAD has another version of a.dat, so it's left alone
+  git lfs checkout
+  [ "staged" != "$(cat a.dat)" ]
+
+  set +e
+  git lfs checkout --index 2>&1 | tee checkout.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  [ "staged" = "$(cat a.dat)" ]
+  grep "Could not check out 1 files in the index, their objects are not local:" checkout.log
+  grep "b.dat ($missing_oid)" checkout.log
+  [ "$(git show :b.dat)" = "$(cat b.dat)" ]
+
+  # only the missing file makes it fail
+  git lfs checkout --index a.dat
+  git commit -m "staged"
+
+  printf "conflict ours" > c.dat
+  git add c.dat
+  git commit -m "ours"
+  set +e
+  git merge theirs
+  set -e
+
+  git lfs checkout --index 2>&1 | tee checkout.log
+  grep "Skipped c.dat, it is conflicted. Use --stage to check out one side." checkout.log
+  [ "UU c.dat" = "$(git status --porcelain c.dat)" ]
+
+  git lfs checkout --index --stage=ours c.dat 2>&1 | tee checkout.log
+  grep "Cannot combine --index with --stage" checkout.log
+  git lfs checkout --index --dry-run 2>&1 | tee checkout.log
+  grep "Cannot combine --dry-run with --index" checkout.log
+)
+end_test
+
+begin_test "checkout: failed smudge leaves no partial file"
+(
+  set -e
+
+  reponame="checkout-atomic"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="atomic contents"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+  git lfs fetch
+  chmod +x a.dat
+  pointer="$(cat a.dat)"
+
+  # an object of the right size but the wrong content fails part way through
+  # writing the working file
+  object=".git/lfs/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid"
+  chmod u+w "$object"
+  printf "corrupt content" > "$object"
+  git lfs checkout a.dat 2>&1 | tee checkout.log
+  grep "Could not checkout file" checkout.log
+  git lfs logs last | grep "expected OID $contents_oid"
+
+  [ "$pointer" = "$(cat a.dat)" ]
+  [ -z "$(ls -A | grep "lfs-smudge")" ]
+
+  # once it can be checked out, the file keeps its permissions
+  rm "$object"
+  git lfs fetch
+  git lfs checkout a.dat
+  [ "$contents" = "$(cat a.dat)" ]
+  [ -x a.dat ]
+  [ -z "$(ls -A | grep "lfs-smudge")" ]
+)
+end_test
+
+begin_test "checkout: killed smudge leaves no temp file in the working tree"
+(
+  set -e
+
+  reponame="checkout-killed"
+  git init "$reponame"
+  cd "$reponame"
+
+  # the smudge extension kills git-lfs once the smudge temp file is open
+  printf '#!/bin/sh\nkill -9 $PPID\n' > "$TRASHDIR/kill-smudge"
+  chmod +x "$TRASHDIR/kill-smudge"
+  git config lfs.extension.kill.clean "tr a-z A-Z"
+  git config lfs.extension.kill.smudge "tr A-Z a-z"
+  git config lfs.extension.kill.priority 0
+
+  git lfs track "*.dat"
+  contents="killed contents"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git cat-file -p :a.dat | grep "ext-0-kill"
+
+  rm a.dat
+  git config lfs.extension.kill.smudge "$TRASHDIR/kill-smudge"
+  set +e
+  git lfs checkout a.dat
+  res=$?
+  set -e
+  [ "$res" -ne 0 ]
+
+  [ -n "$(ls -A .git/lfs/tmp/smudge)" ]
+  [ ! -e a.dat ]
+  [ -z "$(git ls-files --others)" ]
+
+  git config lfs.extension.kill.smudge "tr A-Z a-z"
+  git lfs checkout a.dat
+  [ "$contents" = "$(cat a.dat)" ]
+  [ -z "$(git status --porcelain --untracked-files=all)" ]
+)
+end_test
+
+begin_test "checkout --skip-errors"
+(
+  set -e
+
+  reponame="checkout-skip-errors"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "skip a" > a.dat
+  printf "skip b" > b.dat
+  printf "skip c" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+  git push origin master
+
+  oida="$(calc_oid "skip a")"
+  oidc="$(calc_oid "skip c")"
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+  git lfs fetch
+  pointera="$(cat a.dat)"
+  pointerc="$(cat c.dat)"
+
+  # a.dat can't be checked out, and c.dat's object isn't local
+  objecta=".git/lfs/objects/${oida:0:2}/${oida:2:2}/$oida"
+  chmod u+w "$objecta"
+  printf "skip x" > "$objecta"
+  rm ".git/lfs/objects/${oidc:0:2}/${oidc:2:2}/$oidc"
+
+  # by default, checkout reports a.dat and carries on
+  git lfs checkout 2>&1 | tee checkout.log
+  grep "Could not checkout file" checkout.log
+  [ "0" = "$(grep -c "Skipped checking out" checkout.log)" ]
+  [ "$pointera" = "$(cat a.dat)" ]
+  [ "skip b" = "$(cat b.dat)" ]
+  [ "$pointerc" = "$(cat c.dat)" ]
+
+  set +e
+  git lfs checkout --skip-errors 2>&1 | tee checkout.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Skipped checking out 2 files, which were left as pointers:" checkout.log
+  grep "^  a.dat" checkout.log
+  grep "^  c.dat" checkout.log
+  [ "$pointera" = "$(cat a.dat)" ]
+  [ "skip b" = "$(cat b.dat)" ]
+  [ "$pointerc" = "$(cat c.dat)" ]
+
+  # a missing file is restored as a pointer
+  rm a.dat
+  set +e
+  git -c lfs.skipdownloaderrors=true lfs checkout 2>&1 | tee checkout.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Skipped checking out 2 files" checkout.log
+  [ "$pointera" = "$(cat a.dat)" ]
+)
+end_test
@@ -0,0 +1,105 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "chunked transfer uploads only the chunks the server lacks"
+(
+  set -e
+
+  # repos named "chunked*" tell the server to use the chunked adapter
+  reponame="chunked-upload"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  seq 1 2000 > a.dat
+  oid1=$(shasum -a 256 a.dat | cut -f 1 -d " ")
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  GITLFSCHUNKEDENABLED=1 GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "xfer: chunked upload of \"$oid1\" sent \([0-9]*\) of \1 chunks" push.log
+  assert_server_object "$reponame" "$oid1"
+
+  seq 1 2000 | sed "s/^1000$/changed/" > a.dat
+  oid2=$(shasum -a 256 a.dat | cut -f 1 -d " ")
+  git add a.dat
+  git commit -m "change a.dat"
+
+  GITLFSCHUNKEDENABLED=1 GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "xfer: chunked upload of \"$oid2\" sent [12] of [0-9]* chunks" push.log
+  assert_server_object "$reponame" "$oid2"
+)
+end_test
+
+begin_test "chunked transfer downloads only the chunks not held locally"
+(
+  set -e
+
+  reponame="chunked-download"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  seq 1 2000 > a.dat
+  oid1=$(shasum -a 256 a.dat | cut -f 1 -d " ")
+  size1=$(wc -c < a.dat | tr -d '[[:space:]]')
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  seq 1 2000 | sed "s/^1000$/changed/" > a.dat
+  oid2=$(shasum -a 256 a.dat | cut -f 1 -d " ")
+  size2=$(wc -c < a.dat | tr -d '[[:space:]]')
+  git add a.dat
+  git commit -m "change a.dat"
+
+  GITLFSCHUNKEDENABLED=1 git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+
+  GITLFSCHUNKEDENABLED=1 GIT_TRACE=1 git lfs fetch origin HEAD~1 2>&1 | tee fetch.log
+  grep "xfer: chunked download of \"$oid1\" fetched \([0-9]*\) of \1 chunks" fetch.log
+  assert_local_object "$oid1" "$size1"
+
+  GITLFSCHUNKEDENABLED=1 GIT_TRACE=1 git lfs fetch origin master 2>&1 | tee fetch.log
+  grep "xfer: chunked download of \"$oid2\" fetched [12] of [0-9]* chunks" fetch.log
+  assert_local_object "$oid2" "$size2"
+
+  # chunks of objects which are no longer in the local store are pruned
+  rm -rf .git/lfs/objects
+  git lfs prune 2>&1 | tee prune.log
+  grep "Pruned [0-9]* chunks" prune.log
+  [ "0" = "$(find .git/lfs/chunks -type f | wc -l | tr -d '[[:space:]]')" ]
+
+  # the local chunk store is only a cache
+  rm -rf .git/lfs/chunks .git/lfs/objects
+  GITLFSCHUNKEDENABLED=1 git lfs fetch origin master
+  assert_local_object "$oid2" "$size2"
+)
+end_test
+
+begin_test "chunked transfer is not used unless enabled"
+(
+  set -e
+
+  reponame="chunked-disabled"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  seq 1 2000 > a.dat
+  oid=$(shasum -a 256 a.dat | cut -f 1 -d " ")
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "tq: starting transfer adapter \"basic\"" push.log
+  [ "0" -eq "$(grep -c "chunked upload" push.log)" ]
+  assert_server_object "$reponame" "$oid"
+  [ ! -d .git/lfs/chunks ]
+)
+end_test
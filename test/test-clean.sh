@@ -56,3 +56,96 @@ This is my test pointer.  There are many like it, but this one is mine.\n" | git
   [ "$(pointer c2f909f6961bf85a92e2942ef3ed80c938a3d0ebaee6e72940692581052333be 586)" = "$(cat clean.log)" ]
 )
 end_test
+
+begin_test "clean unchanged file reuses staged pointer"
+(
+  set -e
+  clean_setup "unchanged"
+
+  git lfs track "*.dat"
+  printf "unchanged" > a.dat
+  touch -t 201601010000 a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  sleep 1
+  git update-index --refresh
+
+  oid=$(calc_oid "unchanged")
+  GIT_TRACE=1 git lfs clean a.dat < a.dat > clean.log 2> trace.log
+  [ "$(pointer $oid 9)" = "$(cat clean.log)" ]
+  grep "clean: a.dat is unchanged, reusing its staged pointer" trace.log
+
+  # same size, but a newer mtime than the index records
+  printf "different" > a.dat
+  GIT_TRACE=1 git lfs clean a.dat < a.dat > clean.log 2> trace.log
+  [ "$(pointer $(calc_oid "different") 9)" = "$(cat clean.log)" ]
+  grep "reusing its staged pointer" trace.log && exit 1
+
+  # same size and mtime as the index records, but rewritten since, so only
+  # the ctime tells them apart
+  printf "unchanged" > a.dat
+  touch -t 201601010000 a.dat
+  git update-index --refresh
+  sleep 1
+  printf "rewritten" > a.dat
+  touch -t 201601010000 a.dat
+  GIT_TRACE=1 git lfs clean a.dat < a.dat > clean.log 2> trace.log
+  [ "$(pointer $(calc_oid "rewritten") 9)" = "$(cat clean.log)" ]
+  grep "reusing its staged pointer" trace.log && exit 1
+
+  # racily clean: the file was modified after the index was written
+  printf "unchanged" > a.dat
+  touch -t 203001010000 a.dat
+  git update-index --refresh
+  printf "modified!" > a.dat
+  touch -t 203001010000 a.dat
+  GIT_TRACE=1 git lfs clean a.dat < a.dat > clean.log 2> trace.log
+  [ "$(pointer $(calc_oid "modified!") 9)" = "$(cat clean.log)" ]
+  grep "reusing its staged pointer" trace.log && exit 1
+
+  true
+)
+end_test
+
+begin_test "clean --path resolves from the repository root"
+(
+  set -e
+  clean_setup "clean-path"
+  git config lfs.metadata true
+
+  mkdir -p dir
+  printf "abc" > dir/a.dat
+  cd dir
+
+  oid="ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+  cat a.dat | git lfs clean --path=dir/a.dat | tee clean.log
+  [ "$(pointer $oid 3)" = "$(cat clean.log)" ]
+
+  cd ..
+  grep '"path":"dir/a.dat"' ".git/lfs/objects/ba/78/$oid.json"
+)
+end_test
+
+begin_test "clean --no-store"
+(
+  set -e
+  clean_setup "no-store"
+
+  printf "whatever" > a.dat
+  oid="$(calc_oid "whatever")"
+
+  git lfs clean a.dat < a.dat | tee stored.log
+  git lfs clean --no-store < a.dat | tee nostore.log
+  [ "$(cat stored.log)" = "$(cat nostore.log)" ]
+  [ "$(pointer "$oid" 8)" = "$(cat nostore.log)" ]
+
+  printf "not stored" | git lfs clean --no-store | tee clean.log
+  [ "$(pointer "$(calc_oid "not stored")" 10)" = "$(cat clean.log)" ]
+  refute_local_object "$(calc_oid "not stored")"
+  [ -z "$(find .git/lfs/tmp -type f)" ]
+
+  # pointers are passed through as usual
+  pointer "$oid" 8 | git lfs clean --no-store | tee clean.log
+  [ "$(pointer "$oid" 8)" = "$(cat clean.log)" ]
+)
+end_test
@@ -149,3 +149,46 @@ begin_test "extension config (with gitconfig)"
   [ "$expected2" = "$(git lfs ext)" ]
 )
 end_test
+
+begin_test "lfsconfig interpolation"
+(
+  set -e
+  reponame="lfsconfig-interpolation"
+  mkdir $reponame
+  cd $reponame
+  git init
+  git remote add origin "$GITSERVER/$reponame"
+
+  git config --file=.lfsconfig lfs.url 'https://${LFS_HOST}/repo.git/info/lfs'
+
+  # off unless the user enables it, and a literal ${...} isn't a valid URL
+  LFS_HOST=lfs.example.com git lfs env | tee env.log
+  grep "Endpoint=<unknown>" env.log
+
+  # .lfsconfig can't enable it itself
+  git config --file=.lfsconfig lfs.lfsconfig.interpolate true
+  LFS_HOST=lfs.example.com git lfs env | tee env.log
+  grep "Endpoint=<unknown>" env.log
+
+  git config lfs.lfsconfig.interpolate true
+  LFS_HOST=lfs.example.com git lfs env | tee env.log
+  grep "Endpoint=https://lfs.example.com/repo.git/info/lfs (auth=none)" env.log
+
+  set +e
+  env -u LFS_HOST git lfs env > env.log 2>&1
+  res=$?
+  set -e
+  cat env.log
+  [ "$res" -ne 0 ]
+  grep "Error reading .lfsconfig:" env.log
+  grep 'lfs.url: ${LFS_HOST} is not set' env.log
+
+  # commands which don't need lfs.url, and the filters, still work
+  env -u LFS_HOST git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+  printf "interpolation" > a.dat
+  env -u LFS_HOST git add .gitattributes a.dat 2>&1 | tee add.log
+  [ "0" -eq "$(grep -c "Error reading .lfsconfig" add.log)" ]
+  git cat-file -p :a.dat | grep "oid sha256:$(calc_oid "interpolation")"
+)
+end_test
@@ -292,3 +292,41 @@ begin_test "credentials from remote.origin.url"
   grep "(1 of 1 files)" fetch.log
 )
 end_test
+
+begin_test "credentials from lfs.<url>.credentialhelper"
+(
+  set -e
+
+  reponame="credentials-host-helper"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "host helper" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  helperlog="$TRASHDIR/host-helper.log"
+  git config "lfs.$GITSERVER.credentialhelper" \
+    "!f() { echo \"\$1\" >> \"$helperlog\"; git credential-lfstest \"\$@\"; }; f"
+
+  GIT_TRACE=1 git lfs push origin master 2>&1 | tee push.log
+  grep "creds: using credential helper" push.log
+  grep "(1 of 1 files)" push.log
+  assert_server_object "$reponame" "$(calc_oid "host helper")"
+  grep "get" "$helperlog"
+
+  # a helper for another host isn't used
+  rm "$helperlog"
+  git config --unset "lfs.$GITSERVER.credentialhelper"
+  git config "lfs.http://other.example.com.credentialhelper" \
+    "!f() { echo \"\$1\" >> \"$helperlog\"; exit 1; }; f"
+
+  printf "default helper" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+  git lfs push origin master
+  assert_server_object "$reponame" "$(calc_oid "default helper")"
+  [ ! -e "$helperlog" ]
+)
+end_test
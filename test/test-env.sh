@@ -234,7 +234,7 @@ begin_test "env with multiple remotes and lfs.url config"
   git remote add other "$GITSERVER/env-other-remote"
   git config lfs.url "http://foo/bar"
 
-  endpoint="$GITSERVER/env-other-remote.git/info/lfs (auth=none)"
+  endpoint="http://foo/bar (auth=none)"
   localwd=$(native_path "$TRASHDIR/$reponame")
   localgit=$(native_path "$TRASHDIR/$reponame/.git")
   localgitstore=$(native_path "$TRASHDIR/$reponame/.git")
@@ -303,7 +303,7 @@ begin_test "env with multiple remotes and lfs configs"
   expected=$(printf '%s
 %s
 
-Endpoint=http://foo/bar (auth=none)
+Endpoint=http://custom/origin (auth=none)
 Endpoint (other)=http://custom/other (auth=none)
 LocalWorkingDir=%s
 LocalGitDir=%s
@@ -364,7 +364,7 @@ begin_test "env with multiple remotes and lfs url and batch configs"
   expected=$(printf '%s
 %s
 
-Endpoint=http://foo/bar (auth=none)
+Endpoint=http://custom/origin (auth=none)
 Endpoint (other)=http://custom/other (auth=none)
 LocalWorkingDir=%s
 LocalGitDir=%s
@@ -906,3 +906,198 @@ UploadTransfers=basic,supertransfer,tus
 
 )
 end_test
+
+begin_test "env --check-endpoint"
+(
+  set -e
+
+  reponame="env-check-endpoint"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs env --check-endpoint 2>&1 | tee env.log
+  grep "Checking endpoint for origin: $GITSERVER/$reponame.git/info/lfs" env.log
+  grep "  POST $GITSERVER/$reponame.git/info/lfs/objects/batch" env.log
+  grep "  > Accept: application/vnd.git-lfs+json; charset=utf-8" env.log
+  grep "  Status: 200 OK" env.log
+  grep "  Transfer adapter: basic" env.log
+
+  set +e
+  git lfs env --check-endpoint not-a-remote 2>&1 | tee env.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Invalid remote name \"not-a-remote\"" env.log
+)
+end_test
+
+begin_test "env --check-endpoint with credentials"
+(
+  set -e
+
+  reponame="requirecreds"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" env-check-endpoint-creds
+
+  gitserverhost=$(echo "$GITSERVER" | cut -d'/' -f3)
+  git config lfs.url http://requirecreds:pass@$gitserverhost/$reponame.git/info/lfs
+
+  git lfs env --check-endpoint 2>&1 | tee env.log
+  grep "Checking endpoint for origin: http://requirecreds:\* \* \* \* \*@$gitserverhost/$reponame.git/info/lfs" env.log
+  grep "  Status: 200 OK" env.log
+  grep "  Authentication: ok" env.log
+  grep "pass@" env.log && exit 1
+
+  git config lfs.url http://$gitserverhost/$reponame.git/info/lfs
+  git config lfs.http://$gitserverhost/$reponame.git/info/lfs.access basic
+
+  set +e
+  git lfs env --check-endpoint 2>&1 | tee env.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "  > Authorization: Basic \* \* \* \* \*" env.log
+  grep "  Status: 403 Forbidden" env.log
+  grep "  Authentication: failed" env.log
+  grep "Endpoint check failed with HTTP 403" env.log
+)
+end_test
+
+begin_test "env shows cached capabilities"
+(
+  set -e
+
+  reponame="env-capabilities"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  endpoint="$GITSERVER/$reponame.git/info/lfs"
+  git lfs env | tee env.log
+  grep "Capabilities" env.log && exit 1
+
+  # nothing is learned about the server from an uncompressed batch request
+  git lfs track "*.dat"
+  printf "capabilities" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+  [ ! -f .git/lfs/capabilities.json ]
+
+  checked_at=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+  printf '{"%s":{"checked_at":"%s","batch_gzip":false}}' "$endpoint" "$checked_at" > .git/lfs/capabilities.json
+  git lfs env | tee env.log
+  grep "Capabilities ($endpoint) discovered .* ago" env.log
+  grep "  batch.gzip=false" env.log
+
+  git lfs env --check-endpoint
+  git lfs env | tee env.log
+  grep "Capabilities" env.log && exit 1
+
+  printf '{"%s":{"checked_at":"%s","batch_gzip":false}}' "$endpoint" "$checked_at" > .git/lfs/capabilities.json
+  git config lfs.capabilitiesttl 0
+  sleep 1
+  git lfs env | tee env.log
+  grep "Capabilities ($endpoint) discovered .* ago, expired" env.log
+)
+end_test
+
+begin_test "env --remote"
+(
+  set -e
+  reponame="env-remote"
+  mkdir $reponame
+  cd $reponame
+  git init
+  git remote add origin "https://example.com/origin.git"
+  git remote add other "ssh://git@example.com/foo/other.git"
+  git config remote.store.lfsurl "https://lfs.example.com/store"
+  git config lfs.concurrenttransfers 5
+
+  git lfs env --remote origin | tee env.log
+  grep "Remote=origin" env.log
+  grep "  Endpoint (download)=https://example.com/origin.git/info/lfs (from remote.origin.url)" env.log
+  grep "    auth=none (default)" env.log
+  grep "  ConcurrentTransfers=5 (from lfs.concurrenttransfers)" env.log
+
+  [ "0" = "$(grep -c "MaxBandwidth" env.log)" ]
+
+  git config remote.store.lfsconcurrenttransfers 2
+  git config remote.store.lfsmaxbandwidth 2m
+  git lfs env --remote store | tee env.log
+  grep "  Endpoint (upload)=https://lfs.example.com/store (from remote.store.lfsurl)" env.log
+  grep "  ConcurrentTransfers=2 (from remote.store.lfsconcurrenttransfers)" env.log
+  grep "  MaxBandwidth=2.0 MB/s (from remote.store.lfsmaxbandwidth)" env.log
+
+  git lfs env --remote other | tee env.log
+  grep "  Endpoint (download)=https://example.com/foo/other.git/info/lfs (from remote.other.url)" env.log
+  grep "    SSH=git@example.com:foo/other.git" env.log
+  grep "    authenticate=.*git@example.com git-lfs-authenticate foo/other.git download" env.log
+  grep "    authenticate=.*git-lfs-authenticate foo/other.git upload" env.log
+
+  git lfs env --remote missing 2>&1 | tee env.log
+  grep "Invalid remote name \"missing\"" env.log
+)
+end_test
+
+begin_test "env --installed"
+(
+  set -e
+  reponame="env-installed"
+  mkdir $reponame
+  cd $reponame
+  git init
+
+  set +e
+  git lfs env --installed 2>&1 | tee env.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" -ne 0 ]
+  grep "filter.lfs.clean: present (git-lfs clean -- %f)" env.log
+  grep "filter.lfs.smudge: present (git-lfs smudge -- %f)" env.log
+  grep "filter.lfs.required: present (true)" env.log
+  grep "pre-push hook: missing (.*/.git/hooks/pre-push not found)" env.log
+  grep "Git LFS is not fully installed. Run \`git lfs install\` to fix this." env.log
+
+  git lfs install
+  git lfs env --installed 2>&1 | tee env.log
+  grep "pre-push hook: present (.*/.git/hooks/pre-push)" env.log
+
+  printf "#!/bin/sh\ngit lfs push --stdin \$*\n" > .git/hooks/pre-push
+  git lfs env --installed > env.log 2>&1 && exit 1
+  cat env.log
+  grep "pre-push hook: stale (.*/.git/hooks/pre-push is from an older version of Git LFS)" env.log
+
+  printf "#!/bin/sh\necho hello\n" > .git/hooks/pre-push
+  git lfs env --installed > env.log 2>&1 && exit 1
+  cat env.log
+  grep "pre-push hook: missing (.*/.git/hooks/pre-push does not run Git LFS)" env.log
+
+  git config filter.lfs.clean "cat"
+  git lfs env --installed > env.log 2>&1 && exit 1
+  cat env.log
+  grep "filter.lfs.clean: stale (\"cat\", expected \"git-lfs clean -- %f\")" env.log
+  git config --unset filter.lfs.clean
+
+  # hooks are checked in core.hooksPath
+  git config core.hooksPath hooks
+  git lfs env --installed > env.log 2>&1 && exit 1
+  cat env.log
+  grep "pre-push hook: missing ($(pwd)/hooks/pre-push not found)" env.log
+  git lfs install
+  git lfs env --installed 2>&1 | tee env.log
+  grep "pre-push hook: present ($(pwd)/hooks/pre-push)" env.log
+)
+end_test
+
+begin_test "env --installed outside a repository"
+(
+  set -e
+  cd "$TRASHDIR"
+
+  GIT_DIR=/nonexistent git lfs env --installed 2>&1 | tee env.log
+  grep "Not in a git repository, so hooks were not checked" env.log
+  grep "filter.lfs.clean: present" env.log
+)
+end_test
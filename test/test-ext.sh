@@ -60,3 +60,84 @@ begin_test "ext"
   [ "$actual" = "$expected" ]
 )
 end_test
+
+begin_test "ext: clean and smudge through a chain of extensions"
+(
+  set -e
+
+  reponame="ext-chain"
+  mkdir "$reponame"
+  cd "$reponame"
+  git init
+
+  git config lfs.extension.foo.clean "tr a-z b-za"
+  git config lfs.extension.foo.smudge "tr b-za a-z"
+  git config lfs.extension.foo.priority 0
+
+  git config lfs.extension.bar.clean "rev"
+  git config lfs.extension.bar.smudge "rev"
+  git config lfs.extension.bar.priority 1
+
+  git config lfs.extension.baz.clean "base64"
+  git config lfs.extension.baz.smudge "base64 -d"
+  git config lfs.extension.baz.priority 2
+
+  git lfs track "*.dat"
+  printf "hello world\n" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git cat-file -p :a.dat | tee pointer.txt
+  grep "ext-0-foo sha256:$(printf "hello world\n" | shasum -a 256 | cut -f1 -d" ")" pointer.txt
+  grep "ext-1-bar sha256:$(printf "hello world\n" | tr a-z b-za | shasum -a 256 | cut -f1 -d" ")" pointer.txt
+  grep "ext-2-baz sha256:$(printf "hello world\n" | tr a-z b-za | rev | shasum -a 256 | cut -f1 -d" ")" pointer.txt
+  stored=$(printf "hello world\n" | tr a-z b-za | rev | base64)
+  grep "oid sha256:$(printf "%s\n" "$stored" | shasum -a 256 | cut -f1 -d" ")" pointer.txt
+  grep "size $(printf "%s\n" "$stored" | wc -c | tr -d " ")" pointer.txt
+
+  rm a.dat
+  git checkout -- a.dat
+  [ "hello world" = "$(cat a.dat)" ]
+)
+end_test
+
+begin_test "ext: failing extension is named"
+(
+  set -e
+
+  reponame="ext-failure"
+  mkdir "$reponame"
+  cd "$reponame"
+  git init
+
+  git config lfs.extension.foo.clean "tr a-z b-za"
+  git config lfs.extension.foo.smudge "tr b-za a-z"
+  git config lfs.extension.foo.priority 0
+
+  git config lfs.extension.bad.clean "ls /does/not/exist"
+  git config lfs.extension.bad.smudge "cat"
+  git config lfs.extension.bad.priority 1
+
+  git lfs track "*.dat"
+  printf "hello world\n" > a.dat
+  git add a.dat 2>&1 | tee add.log
+  grep "Extension 'bad' failed: exit status" add.log
+  grep "/does/not/exist" add.log
+  [ -z "$(git ls-files a.dat)" ]
+
+  git config lfs.extension.bad.clean "rev"
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # a smudge which changes the content fails the OID check for that extension
+  git config lfs.extension.bad.smudge "tr a-z A-Z"
+  rm a.dat
+  git lfs checkout a.dat 2>&1 | tee checkout.log
+  git lfs logs last | grep "Actual oid .* for extension 'bad' does not match expected"
+  [ ! -e a.dat ]
+
+  git config lfs.extension.bad.smudge "rev"
+  git lfs checkout a.dat
+  [ "hello world" = "$(cat a.dat)" ]
+)
+end_test
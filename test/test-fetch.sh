@@ -304,6 +304,86 @@ begin_test "fetch-recent"
 )
 end_test
 
+begin_test "fetch --since"
+(
+  set -e
+
+  reponame="fetch-since"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content0="sincecontent0"
+  content1="sincecontent1"
+  content2="sincecontent2"
+  content3="sincecontent3"
+  oid0=$(calc_oid "$content0")
+  oid1=$(calc_oid "$content1")
+  oid2=$(calc_oid "$content2")
+  oid3=$(calc_oid "$content3")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -30d)\",
+    \"Files\":[
+      {\"Filename\":\"file1.dat\",\"Size\":${#content0}, \"Data\":\"$content0\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -20d)\",
+    \"Files\":[
+      {\"Filename\":\"file1.dat\",\"Size\":${#content1}, \"Data\":\"$content1\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -10d)\",
+    \"Files\":[
+      {\"Filename\":\"file1.dat\",\"Size\":${#content2}, \"Data\":\"$content2\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -1d)\",
+    \"Files\":[
+      {\"Filename\":\"file2.dat\",\"Size\":${#content3}, \"Data\":\"$content3\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git push origin master
+  assert_server_object "$reponame" "$oid0"
+  assert_server_object "$reponame" "$oid3"
+
+  rm -rf .git/lfs/objects
+
+  # the current state, plus the version replaced 10 days ago
+  git lfs fetch --since=15.days origin master 2>&1 | tee fetch.log
+  grep "Fetching master for commits since" fetch.log
+  assert_local_object "$oid3" "${#content3}"
+  assert_local_object "$oid2" "${#content2}"
+  assert_local_object "$oid1" "${#content1}"
+  refute_local_object "$oid0"
+
+  rm -rf .git/lfs/objects
+
+  git lfs fetch --since="$(get_date -25d)" origin
+  assert_local_object "$oid1" "${#content1}"
+  assert_local_object "$oid0" "${#content0}"
+
+  rm -rf .git/lfs/objects
+
+  # nothing has been committed in the last hour
+  git lfs fetch --since="1 hour ago" origin master 2>&1 | tee fetch.log
+  grep "Skipping master, no commits since" fetch.log
+  refute_local_object "$oid3"
+
+  set +e
+  git lfs fetch --all --since=2.weeks origin 2>&1 | tee fetch.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Cannot combine --all with --since" fetch.log
+)
+end_test
+
 begin_test "fetch-all"
 (
   set -e
@@ -633,3 +713,608 @@ begin_test "fetch with invalid remote"
   grep "Invalid remote name" fetch.log
 )
 end_test
+
+begin_test "fetch: with lfs.download.filter"
+(
+  set -e
+
+  reponame="fetch-download-filter"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "small" > small.dat
+  printf "much larger" > large.dat
+  git add .gitattributes small.dat large.dat
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+
+  # decline anything over the size given, from a path with a space in it
+  mkdir "filter dir"
+  cat > "filter dir/filter.sh" <<-'FILTER'
+#!/bin/sh
+echo "$2 $3" >> "$(dirname "$0")/../filter.log"
+[ "$3" -le "$1" ]
+FILTER
+  chmod +x "filter dir/filter.sh"
+  git config lfs.download.filter "'$(pwd)/filter dir/filter.sh' 8"
+
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Skipped 1 objects declined by lfs.download.filter:" fetch.log
+  grep "large.dat ($(calc_oid "much larger"))" fetch.log
+  assert_local_object "$(calc_oid "small")" 5
+  refute_local_object "$(calc_oid "much larger")"
+  grep "$(calc_oid "much larger") 11" filter.log
+
+  rm small.dat large.dat
+  git checkout -- small.dat large.dat 2>&1 | tee checkout.log
+  grep "Skipping large.dat ($(calc_oid "much larger")), declined by lfs.download.filter" checkout.log
+  [ "small" = "$(cat small.dat)" ]
+  [ "version https://git-lfs.github.com/spec/v1" = "$(head -n 1 large.dat)" ]
+)
+end_test
+
+begin_test "fetch --include-submodules"
+(
+  set -e
+
+  reponame="fetch-include-submodules"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$reponame-lfs-sub"
+  setup_remote_repo "$reponame-plain-sub"
+
+  clone_repo "$reponame-lfs-sub" lfs-sub
+  git lfs track "*.dat"
+  printf "sub" > sub.dat
+  git add .gitattributes sub.dat
+  git commit -m "add sub.dat"
+  git push origin master
+  cd ..
+
+  clone_repo "$reponame-plain-sub" plain-sub
+  echo "plain" > README
+  git add README
+  git commit -m "add README"
+  git push origin master
+  cd ..
+
+  clone_repo "$reponame" "$reponame"
+  git lfs track "*.dat"
+  printf "super" > super.dat
+  git add .gitattributes super.dat
+  git commit -m "add super.dat"
+  git submodule add "$GITSERVER/$reponame-lfs-sub" lfs-sub
+  git submodule add "$GITSERVER/$reponame-plain-sub" plain-sub
+  git commit -m "add submodules"
+  git push origin master
+  cd ..
+
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  GIT_LFS_SKIP_SMUDGE=1 git submodule update --init
+  (cd lfs-sub && git config credential.helper lfstest)
+
+  git lfs fetch 2>&1 | tee fetch.log
+  assert_local_object "$(calc_oid "super")" 5
+  (cd lfs-sub && refute_local_object "$(calc_oid "sub")")
+
+  git lfs fetch --include-submodules 2>&1 | tee fetch.log
+  grep "Fetching submodule lfs-sub" fetch.log
+  grep "lfs-sub: ok" fetch.log
+  grep "plain-sub: skipped, does not use Git LFS" fetch.log
+
+  cd lfs-sub
+  assert_local_object "$(calc_oid "sub")" 3
+)
+end_test
+
+begin_test "fetch --changed-since"
+(
+  set -e
+
+  reponame="modified-at-fetch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "changed old" > old.dat
+  git add .gitattributes old.dat
+  git commit -m "add old.dat"
+  git push origin master
+
+  sleep 2
+  cutoff=$(date +%s)
+  sleep 2
+
+  printf "changed new" > new.dat
+  git add new.dat
+  git commit -m "add new.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --changed-since "$cutoff" 2>&1 | tee fetch.log
+  grep "Skipped 1 object(s) not modified on the server since" fetch.log
+  refute_local_object "$(calc_oid "changed old")"
+  assert_local_object "$(calc_oid "changed new")" 11
+
+  # without --changed-since, everything missing is fetched as before
+  git lfs fetch 2>&1 | tee fetch.log
+  assert_local_object "$(calc_oid "changed old")" 11
+)
+end_test
+
+begin_test "fetch --changed-since without server modification times"
+(
+  set -e
+
+  reponame="fetch-changed-since-unsupported"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "no modified_at" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+  git lfs fetch --changed-since "1 day ago" 2>&1 | tee fetch.log
+  grep "The server did not say when 1 object(s) were modified, so they were fetched regardless of --changed-since" fetch.log
+  assert_local_object "$(calc_oid "no modified_at")" 14
+
+)
+end_test
+
+begin_test "fetch --fail-fast and --keep-going"
+(
+  set -e
+
+  reponame="fetch-fail-fast"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "fail fast a" > a.dat
+  printf "fail fast b" > b.dat
+  printf "fail fast c" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+  git push origin master
+
+  delete_server_object "$reponame" "$(calc_oid "fail fast b")"
+  delete_server_object "$reponame" "$(calc_oid "fail fast c")"
+  rm -rf .git/lfs/objects
+
+  git lfs fetch --fail-fast 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs fetch --fail-fast' to fail"
+    exit 1
+  fi
+  [ "1" -eq "$(grep -o "Object [0-9a-f]* does not exist" fetch.log | sort -u | wc -l)" ]
+  grep "Stopped after the first failed transfer, skipping" fetch.log
+
+  rm -rf .git/lfs/objects
+  git config lfs.transfer.failfast true
+  git lfs fetch --keep-going 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected 'git lfs fetch --keep-going' to fail"
+    exit 1
+  fi
+  [ "2" -eq "$(grep -o "Object [0-9a-f]* does not exist" fetch.log | sort -u | wc -l)" ]
+  [ "0" -eq "$(grep -c "Stopped after the first failed transfer" fetch.log)" ]
+  assert_local_object "$(calc_oid "fail fast a")" 11
+
+  # lfs.transfer.failfast is the default without either flag
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Stopped after the first failed transfer, skipping" fetch.log
+
+  git lfs fetch --fail-fast --keep-going 2>&1 | tee fetch.log
+  grep "Cannot combine --fail-fast and --keep-going" fetch.log
+)
+end_test
+
+begin_test "fetch: GIT_LFS_FORCE_PROGRESS"
+(
+  set -e
+
+  reponame="fetch-force-progress"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "slow-download" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  # without it, unchanged progress isn't printed again while the server stalls
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetch.log
+  [ "1" -eq "$(grep -c "Git LFS: (0 of 1 files) 0 B / 13 B" fetch.log)" ]
+
+  rm -rf .git/lfs/objects
+  GIT_LFS_FORCE_PROGRESS=1 git lfs fetch 2>&1 | tee fetch.log
+  [ "2" -le "$(grep -c "Git LFS: (0 of 1 files) 0 B / 13 B" fetch.log)" ]
+  grep "Git LFS: (1 of 1 files)" fetch.log
+
+  # a longer interval than the stall prints it once again
+  rm -rf .git/lfs/objects
+  git config lfs.progressinterval 10
+  GIT_LFS_FORCE_PROGRESS=1 git lfs fetch 2>&1 | tee fetch.log
+  [ "1" -eq "$(grep -c "Git LFS: (0 of 1 files) 0 B / 13 B" fetch.log)" ]
+)
+end_test
+
+begin_test "fetch: same object at many paths"
+(
+  set -e
+
+  reponame="fetch-same-object-many-paths"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="same"
+  contents_oid=$(calc_oid "$contents")
+  for i in 1 2 3 4 5 6 7 8; do
+    mkdir -p "dir$i"
+    printf "$contents" > "dir$i/a.dat"
+  done
+  git add .gitattributes dir*
+  git commit -m "add the same object at many paths"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  refute_local_object "$contents_oid"
+
+  # --paths-from queues a download for every path, not just every object
+  ls dir*/a.dat > paths
+  GIT_TRACE=1 git lfs pull --paths-from paths 2>&1 | tee pull.log
+  assert_local_object "$contents_oid" 4
+  [ "1" -eq "$(grep -c "worker [0-9]* processing job for \"$contents_oid\"" pull.log)" ]
+  for i in 1 2 3 4 5 6 7 8; do
+    [ "$contents" = "$(cat "dir$i/a.dat")" ]
+  done
+)
+end_test
+
+begin_test "fetch --verify"
+(
+  set -e
+
+  reponame="fetch-verify"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  for name in a b c; do
+    printf "$name" > "$name.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "add objects"
+  git push origin master
+
+  git lfs fetch --verify 2>&1 | tee verify.log
+  grep "Verifying master" verify.log
+  grep "3 object(s) checked for master: 0 missing, 0 corrupt" verify.log
+
+  a_oid="$(calc_oid "a")"
+  b_oid="$(calc_oid "b")"
+  c_oid="$(calc_oid "c")"
+  rm ".git/lfs/objects/${a_oid:0:2}/${a_oid:2:2}/$a_oid"
+  printf "x" > ".git/lfs/objects/${b_oid:0:2}/${b_oid:2:2}/$b_oid"
+  rm c.dat
+
+  git lfs fetch --verify 2>&1 | tee verify.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fetch --verify to fail"
+    exit 1
+  fi
+  grep "Object a.dat ($a_oid) is missing" verify.log
+  grep "Object b.dat ($b_oid) is corrupt" verify.log
+  grep "3 object(s) checked for master: 1 missing, 1 corrupt" verify.log
+
+  # nothing was downloaded, repaired or checked out
+  refute_local_object "$a_oid"
+  [ "x" = "$(cat ".git/lfs/objects/${b_oid:0:2}/${b_oid:2:2}/$b_oid")" ]
+  [ ! -e c.dat ]
+
+  git lfs fetch --verify -X "a.dat,b.dat" 2>&1 | tee verify.log
+  grep "1 object(s) checked for master: 0 missing, 0 corrupt" verify.log
+
+  git lfs fetch --verify --all 2>&1 | tee verify.log
+  grep "Cannot combine --verify with" verify.log
+)
+end_test
+
+begin_test "fetch --all-tags"
+(
+  set -e
+
+  reponame="fetch-all-tags"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "tag1-a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "v1"
+  git tag v1
+
+  printf "tag2-a" > a.dat
+  printf "tag2-b" > b.dat
+  git add a.dat b.dat
+  git commit -m "v2"
+  git tag -a -m "release 2" v2
+
+  printf "head-c" > c.dat
+  git add c.dat
+  git commit -m "untagged"
+
+  git push origin master --tags
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+  rm -rf .git/lfs/objects
+
+  git lfs fetch --all-tags 2>&1 | tee fetch.log
+  grep "Fetching 3 objects for 2 tags" fetch.log
+  grep "2 tags scanned, 3 objects fetched, 0 already present" fetch.log
+  assert_local_object "$(calc_oid "tag1-a")" 6
+  assert_local_object "$(calc_oid "tag2-a")" 6
+  assert_local_object "$(calc_oid "tag2-b")" 6
+  refute_local_object "$(calc_oid "head-c")"
+
+  # filters apply, and objects already present are counted
+  rm -rf .git/lfs/objects/$(calc_oid "tag2-a" | cut -c 1-2)
+  rm -rf .git/lfs/objects/$(calc_oid "tag2-b" | cut -c 1-2)
+  git lfs fetch --all-tags -X "b.dat" 2>&1 | tee fetch.log
+  grep "2 tags scanned, 1 objects fetched, 1 already present" fetch.log
+  refute_local_object "$(calc_oid "tag2-b")"
+
+  git lfs fetch --all-tags --all 2>&1 | tee fetch.log
+  grep "Cannot combine --all-tags with" fetch.log
+)
+end_test
+
+begin_test "fetch --exists-only"
+(
+  set -e
+
+  reponame="fetch-exists-only"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "exists a" > a.dat
+  printf "exists b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  oida="$(calc_oid "exists a")"
+  oidb="$(calc_oid "exists b")"
+  oidmissing="$(calc_oid "not pushed")"
+  oiderror="$(calc_oid "status-batch-500")"
+
+  rm -rf .git/lfs/objects
+
+  printf "%s 8\n\n%s\n%s 10\n%s 8\n" "$oida" "$oidmissing" "$oidb" "$oida" > manifest.txt
+  git lfs fetch --exists-only --manifest manifest.txt | tee exists.log
+  [ "$(cat exists.log)" = "$oida present 8
+$oidmissing missing
+$oidb present 10" ]
+
+  # nothing is downloaded
+  refute_local_object "$oida"
+  refute_local_object "$oidb"
+
+  set +e
+  printf "%s 16\nnot-an-oid\n" "$oiderror" | git lfs fetch origin --exists-only --manifest - > exists.log 2> stderr.log
+  res=$?
+  set -e
+  cat exists.log stderr.log
+  [ "$res" = "2" ]
+  grep "^$oiderror error \[500\]" exists.log
+  grep -- "-:2: expected <oid> \[<size>\], skipping: not-an-oid" stderr.log
+
+  git lfs fetch --exists-only 2>&1 | tee exists.log
+  grep "must be given together" exists.log
+  git lfs fetch --exists-only --manifest manifest.txt --all 2>&1 | tee exists.log
+  grep "Cannot combine --exists-only" exists.log
+)
+end_test
+
+# wait_for_background_fetch polls `git lfs fetch --status` until the background
+# fetch is no longer running, leaving its output in status.log.
+wait_for_background_fetch() {
+  local n=0
+  while [ "$n" -lt 100 ]; do
+    git lfs fetch --status > status.log 2>&1 || true
+    grep -q "running\|starting" status.log || return 0
+    n=$((n+1))
+    sleep 0.1
+  done
+  echo "background fetch did not finish"
+  return 1
+}
+
+begin_test "fetch --background"
+(
+  set -e
+
+  reponame="fetch-background"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="background"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+  rm -rf .git/lfs/objects
+
+  git lfs fetch --status 2>&1 | tee status.log
+  grep "No background fetch has been run" status.log
+
+  git lfs fetch --background 2>&1 | tee fetch.log
+  grep "Fetching in the background (pid [0-9]*), logging to .*fetch-background.log" fetch.log
+
+  wait_for_background_fetch
+  grep "Background fetch succeeded at .*: git lfs fetch" status.log
+  grep "Log: .*fetch-background.log" status.log
+  assert_local_object "$contents_oid" "${#contents}"
+  grep "(1 of 1 files)" .git/lfs/fetch-background.log
+  [ ! -e .git/lfs/fetch-background.lock ]
+
+  # only one background fetch runs at a time, but a stale lock is replaced
+  echo "$$" > .git/lfs/fetch-background.lock
+  git lfs fetch --background 2>&1 | tee fetch.log
+  grep "A background fetch is already running (pid $$)" fetch.log
+  sh -c "exit 0" &
+  dead_pid=$!
+  wait "$dead_pid"
+  echo "$dead_pid" > .git/lfs/fetch-background.lock
+  git lfs fetch --background 2>&1 | tee fetch.log
+  grep "Fetching in the background" fetch.log
+  wait_for_background_fetch
+  grep "Background fetch succeeded" status.log
+
+  # a failed fetch is reported, and still removes the lock
+  rm -rf .git/lfs/objects
+  git config lfs.url "http://127.0.0.1:1/no-server"
+  git lfs fetch --background 2>&1 | tee fetch.log
+  wait_for_background_fetch
+  grep "Background fetch failed at" status.log
+  set +e
+  git lfs fetch --status > status.log 2>&1
+  res=$?
+  set -e
+  [ "$res" -ne 0 ]
+  [ ! -e .git/lfs/fetch-background.lock ]
+  refute_local_object "$contents_oid"
+
+  git lfs fetch --background --verify 2>&1 | tee fetch.log
+  grep "Cannot combine --background with --exists-only or --verify" fetch.log
+  git lfs fetch --background --status 2>&1 | tee fetch.log
+  grep "Cannot combine --status with --background" fetch.log
+)
+end_test
+
+begin_test "fetch in a shallow clone"
+(
+  set -e
+
+  reponame="fetch-shallow"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "shallow old" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  printf "shallow new" > a.dat
+  git add a.dat
+  git commit -m "modify a.dat"
+  git push origin master
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone --depth=1 "$GITSERVER/$reponame" "$reponame-shallow"
+  cd "$reponame-shallow"
+  [ -s .git/shallow ]
+
+  git lfs fetch 2>&1 | tee fetch.log
+  [ "0" = "$(grep -c "shallow clone" fetch.log)" ]
+  assert_local_object "$(calc_oid "shallow new")" 11
+
+  git lfs fetch --all 2>&1 | tee fetch.log
+  grep "This is a shallow clone, so Git LFS objects only in commits beyond its boundary can't be found or fetched" fetch.log
+  refute_local_object "$(calc_oid "shallow old")"
+
+  git lfs fetch --recent 2>&1 | tee fetch.log
+  grep "This is a shallow clone" fetch.log
+  refute_local_object "$(calc_oid "shallow old")"
+)
+end_test
+
+begin_test "fetch --stats"
+(
+  set -e
+
+  cd "$TRASHDIR"
+  reponame="fetch-stats"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "fetch stats" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git lfs fetch --stats 2>&1 | tee fetch.log
+  grep -E "^  batch +1 " fetch.log
+  grep -E "^  object +1 +11 " fetch.log
+  assert_local_object "$(calc_oid "fetch stats")" 11
+
+  # there's nothing left to fetch
+  git lfs fetch --stats 2>&1 | tee fetch.log
+  grep "HTTP timings: no requests were made" fetch.log
+)
+end_test
+
+begin_test "fetch links objects from the global index"
+(
+  set -e
+
+  cd "$TRASHDIR"
+  reponame="fetch-global-index"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "global index" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  oid="$(calc_oid "global index")"
+  index="$TRASHDIR/$reponame-index"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-a"
+  git config lfs.globalindex "$index"
+  git lfs fetch
+  assert_local_object "$oid" 12
+  grep "^$oid $TRASHDIR/$reponame-a/.git/lfs/objects/" "$index"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-b"
+  git config lfs.globalindex "$index"
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "lfs: linked $oid from $TRASHDIR/$reponame-a/" fetch.log
+  [ "0" = "$(grep -c "HTTP: GET" fetch.log)" ]
+  assert_local_object "$oid" 12
+
+  # an object which doesn't match its OID isn't linked
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-c"
+  git config lfs.globalindex "$index"
+  printf "not an oidx" > corrupt
+  printf "%s %s\n" "$oid" "$TRASHDIR/$reponame-c/corrupt" >> "$index"
+  printf "global indey" > corrupt
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "is stale, ignoring it" fetch.log
+  assert_local_object "$oid" 12
+  tail -n 1 "$index" | grep "^$oid $TRASHDIR/$reponame-c/.git/lfs/objects/"
+  [ ! -e "$index.lock" ]
+)
+end_test
@@ -119,3 +119,156 @@ begin_test "fsck: outside git repository"
   grep "Not in a git repository" fsck.log
 )
 end_test
+
+begin_test "fsck --include and --exclude"
+(
+  set -e
+
+  reponame="fsck-include-exclude"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  printf "include a" > a.dat
+  printf "include b" > b.dat
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  aOid=$(calc_oid "include a")
+  echo "CORRUPTION" >> ".git/lfs/objects/${aOid:0:2}/${aOid:2:2}/$aOid"
+
+  [ "Git LFS fsck OK" = "$(git lfs fsck --dry-run --exclude a.dat)" ]
+  [ "Git LFS fsck OK" = "$(git lfs fsck --dry-run --include b.dat)" ]
+  [ "Object a.dat ($aOid) is corrupt" = "$(git lfs fsck --dry-run --include a.dat)" ]
+  [ "Object a.dat ($aOid) is corrupt" = "$(git lfs fsck --dry-run)" ]
+)
+end_test
+
+begin_test "fsck with lfs.fsck.concurrency"
+(
+  set -e
+
+  reponame="fsck-concurrency"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  for i in 1 2 3 4 5; do
+    printf "concurrent $i" > "$i.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  git config lfs.fsck.concurrency 3
+
+  git lfs fsck 2>fsck.err | tee fsck.log
+  [ "Git LFS fsck OK" = "$(cat fsck.log)" ]
+  grep "Verified 5 of 5 objects (60 B)" fsck.err
+
+  twoOid=$(calc_oid "concurrent 2")
+  fourOid=$(calc_oid "concurrent 4")
+  echo "CORRUPTION" >> ".git/lfs/objects/${twoOid:0:2}/${twoOid:2:2}/$twoOid"
+  echo "CORRUPTION" >> ".git/lfs/objects/${fourOid:0:2}/${fourOid:2:2}/$fourOid"
+
+  expected="$(printf 'Object 2.dat (%s) is corrupt\nObject 4.dat (%s) is corrupt' "$twoOid" "$fourOid")"
+  [ "$expected" = "$(git lfs fsck --dry-run 2>/dev/null)" ]
+)
+end_test
+
+begin_test "fsck --pointers, --pointers-only and --objects-only"
+(
+  set -e
+
+  reponame="fsck-pointers-objects"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  printf "canonical" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "first commit"
+
+  aOid=$(calc_oid "canonical")
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize 9\n\n" "$aOid" > pointer.txt
+  blob=$(git hash-object -w pointer.txt)
+  git update-index --add --cacheinfo 100644 "$blob" b.dat
+
+  # pointers are only checked when asked for
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+  [ "Pointer b.dat ($blob) is not canonical" = "$(git lfs fsck --pointers)" ]
+  [ "Pointer b.dat ($blob) is not canonical" = "$(git lfs fsck --pointers-only)" ]
+  [ "Git LFS fsck OK" = "$(git lfs fsck --objects-only)" ]
+
+  echo "CORRUPTION" >> ".git/lfs/objects/${aOid:0:2}/${aOid:2:2}/$aOid"
+  [ "Pointer b.dat ($blob) is not canonical" = "$(git lfs fsck --dry-run --pointers-only)" ]
+  git lfs fsck --dry-run --objects-only | grep "Object .* ($aOid) is corrupt"
+
+  set +e
+  git lfs fsck --pointers-only --objects-only 2>&1 | tee fsck.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Cannot combine --pointers or --pointers-only with --objects-only" fsck.log
+)
+end_test
+
+begin_test "fsck --verify-remote"
+(
+  set -e
+
+  reponame="stored-size-fsck-verify-remote"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "fsck remote a" > a.dat
+  printf "fsck remote b" > b.dat
+  printf "status-batch-resume-206" > c.dat
+  printf "download-500" > e.dat
+  git add .gitattributes a.dat b.dat c.dat e.dat
+  git commit -m "add files"
+  git push origin master
+
+  aOid=$(calc_oid "fsck remote a")
+  bOid=$(calc_oid "fsck remote b")
+  cOid=$(calc_oid "status-batch-resume-206")
+  eOid=$(calc_oid "download-500")
+
+  git lfs fsck --verify-remote 2>&1 | tee fsck.log
+  grep "4 objects are on origin" fsck.log
+  grep "Git LFS fsck OK" fsck.log
+
+  # nothing is downloaded
+  GIT_TRACE=1 git lfs fsck --verify-remote origin 2>&1 | tee fsck.log
+  grep "Git LFS fsck OK" fsck.log
+  [ "0" = "$(grep -c "HTTP: GET .*/storage/" fsck.log)" ]
+
+  # --deep downloads each object and checks it matches its OID
+  GIT_TRACE=1 git lfs fsck --verify-remote --deep 2>&1 | tee fsck.log
+  grep "HTTP: GET .*/storage/$aOid" fsck.log
+  grep "Object c.dat ($cOid) downloaded 10 bytes from the remote, expected 23" fsck.log
+  grep "Object e.dat ($eOid) could not be downloaded from the remote: Server error: .*/storage/$eOid" fsck.log
+  [ "0" = "$(grep -c "Git LFS fsck OK" fsck.log)" ]
+
+  delete_server_object "$reponame" "$bOid"
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize 5\n" "$aOid" > pointer.txt
+  blob=$(git hash-object -w pointer.txt)
+  git update-index --add --cacheinfo 100644 "$blob" d.dat
+
+  git lfs fsck --verify-remote 2>&1 | tee fsck.log
+  grep "Object b.dat ($bOid) is missing on the remote" fsck.log
+  grep "Object d.dat ($aOid) is 13 bytes on the remote, expected 5" fsck.log
+  [ "0" = "$(grep -c "Git LFS fsck OK" fsck.log)" ]
+
+  set +e
+  git lfs fsck --deep 2>&1 | tee fsck.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep -- "--deep requires --verify-remote" fsck.log
+
+  git lfs fsck --verify-remote missing-remote 2>&1 | tee fsck.log
+  grep "Invalid remote name \"missing-remote\"" fsck.log
+)
+end_test
@@ -191,3 +191,60 @@ begin_test "install --local outside repository"
   [ "0" != "$res" ]
 )
 end_test
+
+begin_test "install with core.hooksPath"
+(
+  set -e
+
+  reponame="install-hooks-path"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config core.hooksPath custom-hooks
+  git lfs install
+  [ -x custom-hooks/pre-push ]
+  grep "git lfs pre-push" custom-hooks/pre-push
+  [ ! -e .git/hooks/pre-push ]
+
+  # an existing hook is chained to, not replaced
+  existing="#!/bin/sh
+echo \"existing hook: \$(cat)\" >&2"
+  printf "%s\n" "$existing" > custom-hooks/pre-push
+  git lfs install
+  git lfs install
+  [ "1" = "$(grep -c "BEGIN git-lfs" custom-hooks/pre-push)" ]
+  [ "#!/bin/sh" = "$(head -n 1 custom-hooks/pre-push)" ]
+  [ "echo \"existing hook: \$(cat)\" >&2" = "$(tail -n 1 custom-hooks/pre-push)" ]
+  git lfs update --manual | grep "Add the following to custom-hooks/pre-push"
+
+  git lfs track "*.dat"
+  printf "hooks path" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  grep "existing hook: refs/heads/master $(git rev-parse master) refs/heads/master" push.log
+  assert_server_object "$reponame" "$(calc_oid "hooks path")"
+
+  # a hook in another language is left alone
+  python_hook="#!/usr/bin/env python
+print('existing hook')"
+  printf "%s\n" "$python_hook" > custom-hooks/pre-push
+  set +e
+  git lfs install 2>&1 | tee install.log
+  set -e
+  grep "custom-hooks/pre-push is not a sh or bash script" install.log
+  grep "Add \`git lfs pre-push \"\$@\"\` to it by hand" install.log
+  [ "$python_hook" = "$(cat custom-hooks/pre-push)" ]
+
+  # without core.hooksPath, an existing hook is still left alone
+  git config --unset core.hooksPath
+  printf "%s\n" "$existing" > .git/hooks/pre-push
+  set +e
+  git lfs install 2>&1 | tee install.log
+  set -e
+  grep "Hook already exists: pre-push" install.log
+  [ "$existing" = "$(cat .git/hooks/pre-push)" ]
+)
+end_test
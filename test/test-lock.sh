@@ -59,3 +59,81 @@ begin_test "locking a directory"
   grep "cannot lock directory" lock.log
 )
 end_test
+
+begin_test "creating a lock with a lease"
+(
+  set -e
+
+  setup_remote_repo_with_file "lock_create_lease" "c.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs lock --lease=2h "c.dat" | tee lock.log
+  grep "'c.dat' was locked" lock.log
+  grep "Lease expires in 1h59m" lock.log
+
+  id=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  assert_server_lock $id
+  grep "lease_expires_at" http.json
+
+  GITLFSLOCKSENABLED=1 git lfs lock --renew "$id" --lease=4h | tee renew.log
+  grep "Lock $id was renewed, lease expires in 3h59m" renew.log
+
+  GITLFSLOCKSENABLED=1 git lfs lock --renew "$id" | tee renew.log
+  grep "Lock $id was renewed, lease expires in 1h59m" renew.log
+)
+end_test
+
+begin_test "lock leases expire"
+(
+  set -e
+
+  setup_remote_repo_with_file "lock_lease_expire" "d.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs lock --lease=1s "d.dat" | tee lock.log
+  grep "'d.dat' was locked" lock.log
+
+  id=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  sleep 2
+  refute_server_lock $id
+
+  GITLFSLOCKSENABLED=1 git lfs lock --renew "$id" 2>&1 | tee renew.log
+  grep "unable to find lock" renew.log
+)
+end_test
+
+begin_test "locking with a lease without server support"
+(
+  set -e
+
+  setup_remote_repo_with_file "lock_lease_unsupported" "nolease.dat"
+
+  set +e
+  GITLFSLOCKSENABLED=1 git lfs lock --lease=2h "nolease.dat" 2>&1 | tee lock.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  if [ "$res" = "0" ]; then
+    echo "expected lock with an unsupported lease to fail"
+    exit 1
+  fi
+
+  grep "Server does not support lock leases, so 'nolease.dat' was not locked." lock.log
+  [ "0" = "$(grep -c "was locked (" lock.log)" ]
+
+  GITLFSLOCKSENABLED=1 git lfs locks --path "nolease.dat" | tee locks.log
+  grep "0 lock(s) matched query" locks.log
+)
+end_test
+
+begin_test "locking with an invalid lease"
+(
+  set -e
+
+  setup_remote_repo_with_file "lock_lease_invalid" "e.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs lock --lease=soon "e.dat" 2>&1 | tee lock.log
+  grep "Invalid lease \"soon\"" lock.log
+
+  GITLFSLOCKSENABLED=1 git lfs locks --path "e.dat" | tee locks.log
+  grep "0 lock(s) matched query" locks.log
+)
+end_test
@@ -19,6 +19,21 @@ begin_test "list a single lock"
 )
 end_test
 
+begin_test "list a lock with a lease"
+(
+  set -e
+
+  setup_remote_repo_with_file "locks_list_lease" "l.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs lock --lease=30m "l.dat" | tee lock.log
+  grep "'l.dat' was locked" lock.log
+
+  GITLFSLOCKSENABLED=1 git lfs locks --path "l.dat" | tee locks.log
+  grep "1 lock(s) matched query" locks.log
+  grep "l.dat.*(lease expires in 29m" locks.log
+)
+end_test
+
 begin_test "list locks with a limit"
 (
   set -e
@@ -88,3 +103,106 @@ begin_test "list locks with pagination"
   grep "4 lock(s) matched query" locks.log
 )
 end_test
+
+begin_test "locks --verify"
+(
+  set -e
+
+  reponame="locks_verify"
+  setup_remote_repo "remote_$reponame"
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat"
+  echo "mine" > verify_mine.dat
+  echo "theirs" > verify_theirs.dat
+  echo "untouched" > verify_untouched.dat
+  git add .gitattributes verify_mine.dat verify_theirs.dat verify_untouched.dat
+  git commit -m "add files"
+  git push origin master 2>&1 | tee push.log
+  grep "master -> master" push.log
+
+  GITLFSLOCKSENABLED=1 git lfs lock "verify_mine.dat" | tee lock.log
+  mine=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  GITLFSLOCKSENABLED=1 git -c user.name="Someone Else" -c user.email="else@example.com" \
+    lfs lock "verify_theirs.dat" | tee lock.log
+  theirs=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  GITLFSLOCKSENABLED=1 git -c user.name="Someone Else" -c user.email="else@example.com" \
+    lfs lock "verify_untouched.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs locks --verify 2>&1 | tee verify.log
+  grep "No modified files are locked by others" verify.log
+
+  # an uncommitted change to our own lock, and an unpushed commit touching
+  # someone else's
+  echo "changed" > verify_mine.dat
+  echo "changed" > verify_theirs.dat
+  git add verify_theirs.dat
+  git commit -m "change theirs"
+
+  set +e
+  GITLFSLOCKSENABLED=1 git lfs locks --verify 2>&1 | tee verify.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+
+  grep "Modified files locked by you:" verify.log
+  grep "verify_mine.dat ($mine)" verify.log
+  grep "Modified files locked by others:" verify.log
+  grep "verify_theirs.dat	Someone Else <else@example.com> ($theirs)" verify.log
+  grep "1 modified file(s) locked by others" verify.log
+  [ "0" = "$(grep -c "verify_untouched.dat" verify.log)" ]
+)
+end_test
+
+begin_test "locks --porcelain"
+(
+  set -e
+
+  reponame="locks_porcelain"
+  setup_remote_repo "remote_$reponame"
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat"
+  mkdir -p "a dir"
+  echo "plain" > plain.dat
+  echo "spaced" > "a dir/with space.dat"
+  git add .gitattributes plain.dat "a dir/with space.dat"
+  git commit -m "add files"
+  git push origin master 2>&1 | tee push.log
+  grep "master -> master" push.log
+
+  GITLFSLOCKSENABLED=1 git lfs lock "plain.dat" | tee lock.log
+  plain=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  GITLFSLOCKSENABLED=1 git lfs lock "a dir/with space.dat" | tee lock.log
+  spaced=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+
+  # locks from other tests are listed too, as they share the server
+  GITLFSLOCKSENABLED=1 git lfs locks --porcelain | tee locks.log
+  [ "0" -eq "$(grep -c "matched query" locks.log)" ]
+  grep -x "$plain	plain.dat	Git LFS Tests <git-lfs@example.com>" locks.log
+  grep -x "$spaced	\"a dir/with space.dat\"	Git LFS Tests <git-lfs@example.com>" locks.log
+
+  # absolute paths are resolved against the working tree, wherever the
+  # command is run
+  root="$(pwd)"
+  cd "a dir"
+  GITLFSLOCKSENABLED=1 git lfs locks --porcelain --absolute --path "with space.dat" | tee ../locks.log
+  cd ..
+  grep -x "$spaced	\"$root/a dir/with space.dat\"	Git LFS Tests <git-lfs@example.com>" locks.log
+
+  GITLFSLOCKSENABLED=1 git lfs locks --absolute --path "plain.dat" | tee locks.log
+  grep "1 lock(s) matched query" locks.log
+  grep "$root/plain.dat	Git LFS Tests <git-lfs@example.com>" locks.log
+
+  # with -z, lines end with NUL and paths aren't quoted
+  GITLFSLOCKSENABLED=1 git lfs locks --porcelain -z > locks.log
+  [ "0" -eq "$(tr -cd '\n' < locks.log | wc -c)" ]
+  [ "2" -le "$(tr -cd '\000' < locks.log | wc -c)" ]
+  tr '\000' '\n' < locks.log | grep -x "$spaced	a dir/with space.dat	Git LFS Tests <git-lfs@example.com>"
+
+  GITLFSLOCKSENABLED=1 git lfs locks -z 2>&1 | tee locks.log
+  grep -- "-z can only be used with --porcelain" locks.log
+  GITLFSLOCKSENABLED=1 git lfs locks --verify --porcelain 2>&1 | tee locks.log
+  grep "Cannot combine --verify with --porcelain or --absolute" locks.log
+)
+end_test
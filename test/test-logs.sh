@@ -27,3 +27,44 @@ begin_test "logs"
   [ "$(cat "$logfile")" = "$(git lfs logs last)" ]
 )
 end_test
+
+begin_test "logs clear --older-than and --keep"
+(
+  set -e
+
+  mkdir logs-clear
+  cd logs-clear
+  git init
+
+  logdir=".git/lfs/objects/logs"
+  mkdir -p "$logdir"
+  for name in 20150101T120000 20160101T120000 20160301T120000.5; do
+    printf "panic" > "$logdir/$name.log"
+  done
+  now="$(date +%Y%m%dT%H%M%S)"
+  printf "recent" > "$logdir/$now.log"
+
+  git lfs logs clear --older-than=30d 2>&1 | tee clear.log
+  grep "Removed 3 of 4 logs from" clear.log
+  grep "(15 B)" clear.log
+  [ "$now.log" = "$(ls "$logdir")" ]
+
+  for name in 20150101T120000 20160101T120000 20160301T120000.5; do
+    printf "panic" > "$logdir/$name.log"
+  done
+  git lfs logs clear --keep=2 2>&1 | tee clear.log
+  grep "Removed 2 of 4 logs from" clear.log
+  [ "2" -eq "$(ls "$logdir" | wc -l)" ]
+  [ -e "$logdir/20160301T120000.5.log" ]
+
+  git lfs logs clear --older-than=30d --keep=1 2>&1 | tee clear.log
+  grep "Removed 1 of 2 logs from" clear.log
+  [ "$now.log" = "$(ls "$logdir")" ]
+
+  git lfs logs clear --older-than=soon 2>&1 | tee clear.log
+  grep "Invalid --older-than \"soon\"" clear.log
+
+  git lfs logs clear
+  [ ! -e "$logdir" ]
+)
+end_test
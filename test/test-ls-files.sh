@@ -109,3 +109,142 @@ a1fff0ffefb9eace7230c24e50731f0a91c62f9cefdfe77121c2f607125dffae * two.tgz")"
   [ "$expected" = "$(git lfs ls-files --long)" ]
 )
 end_test
+
+begin_test "ls-files: --all with --size"
+(
+  set -e
+
+  mkdir ls-files-all
+  cd ls-files-all
+  git init
+  git lfs track "*.dat"
+
+  printf "old" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  printf "newer" > a.dat
+  git add a.dat
+  git commit -m "modify a.dat"
+
+  git checkout -b other
+  git mv a.dat b.dat
+  printf "branch" > c.dat
+  git add c.dat
+  git commit -m "rename a.dat, add c.dat"
+  git checkout master
+
+  git rm a.dat
+  git commit -m "remove a.dat"
+
+  git lfs ls-files --all --size | tee ls.log
+  grep "$(calc_oid "old" | cut -b 1-10) a.dat (3 B)" ls.log
+  grep "$(calc_oid "newer" | cut -b 1-10) b.dat (5 B)" ls.log
+  grep "$(calc_oid "branch" | cut -b 1-10) c.dat (6 B)" ls.log
+  [ "3 objects, 14 B" = "$(tail -n 1 ls.log)" ]
+  [ `wc -l < ls.log` = 4 ]
+
+  [ "" = "$(git lfs ls-files)" ]
+)
+end_test
+
+begin_test "ls-files: --name-only and -z"
+(
+  set -e
+
+  mkdir ls-files-name-only
+  cd ls-files-name-only
+  git init
+  git lfs track "*.dat"
+
+  mkdir dir
+  printf "a" > a.dat
+  printf "b" > "dir/b c.dat"
+  printf "not lfs" > d.txt
+  git add .gitattributes a.dat "dir/b c.dat" d.txt
+  git commit -m "add files"
+
+  [ "a.dat
+dir/b c.dat" = "$(git lfs ls-files --name-only)" ]
+  [ "a.dat" = "$(git lfs ls-files -n | head -n 1)" ]
+
+  git lfs ls-files --name-only -z > ls.out
+  printf "a.dat\0dir/b c.dat\0" | cmp - ls.out
+
+  git lfs ls-files -z > ls.out
+  printf "%s * a.dat\0%s * dir/b c.dat\0" "$(calc_oid "a" | cut -b 1-10)" "$(calc_oid "b" | cut -b 1-10)" | cmp - ls.out
+
+  printf "a changed" > a.dat
+  git add a.dat
+  git commit -m "change a.dat"
+  [ "3" = "$(git lfs ls-files --all --name-only -z | tr '\0' '\n' | wc -l | tr -d ' ')" ]
+  git lfs ls-files --all --name-only -z | xargs -0 -n 1 echo | tee ls.log
+  grep "^dir/b c.dat$" ls.log
+
+  set +e
+  git lfs ls-files --name-only --size 2>&1 | tee ls.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Cannot combine --size with --name-only or -z" ls.log
+)
+end_test
+
+begin_test "ls-files: --json"
+(
+  set -e
+
+  mkdir ls-files-json
+  cd ls-files-json
+  git init
+  git lfs track "*.dat"
+
+  printf "present" > a.dat
+  printf "missing" > b.dat
+  printf "corrupt" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+
+  missing_oid="$(calc_oid "missing")"
+  rm ".git/lfs/objects/${missing_oid:0:2}/${missing_oid:2:2}/$missing_oid"
+  corrupt_oid="$(calc_oid "corrupt")"
+  printf "CORRUPT" > ".git/lfs/objects/${corrupt_oid:0:2}/${corrupt_oid:2:2}/$corrupt_oid"
+  rm a.dat
+
+  git lfs ls-files --json | tee ls.json
+  [ "[" = "$(head -n 1 ls.json)" ]
+  [ "]" = "$(tail -n 1 ls.json)" ]
+  [ "3" = "$(grep -c '"name": ' ls.json)" ]
+  grep -A5 '"name": "a.dat"' ls.json | grep "\"oid\": \"$(calc_oid "present")\""
+  grep -A5 '"name": "a.dat"' ls.json | grep '"size": 7'
+  grep -A5 '"name": "a.dat"' ls.json | grep '"downloaded": true'
+  grep -A5 '"name": "a.dat"' ls.json | grep '"checked_out": false'
+  grep -A5 '"name": "b.dat"' ls.json | grep '"downloaded": false'
+  grep -A5 '"name": "c.dat"' ls.json | grep '"checked_out": true'
+  [ "0" = "$(grep -c '"verified"' ls.json)" ]
+
+  git lfs ls-files --json --verify | tee ls.json
+  grep -A6 '"name": "a.dat"' ls.json | grep '"verified": true'
+  grep -A6 '"name": "b.dat"' ls.json | grep '"verified": false'
+  grep -A6 '"name": "c.dat"' ls.json | grep '"verified": false'
+
+  git lfs ls-files --all --json --size 2> size.log | tee ls.json
+  [ "3" = "$(grep -c '"name": ' ls.json)" ]
+  [ "0" = "$(grep -c '"checked_out"' ls.json)" ]
+  [ "3 objects, 21 B" = "$(cat size.log)" ]
+
+  set +e
+  git lfs ls-files --json -z 2>&1 | tee ls.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Cannot combine --json with --name-only or -z" ls.log
+
+  set +e
+  git lfs ls-files --verify 2>&1 | tee ls.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep -- "--verify requires --json" ls.log
+)
+end_test
@@ -0,0 +1,80 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "metadata: record, transfer and restore"
+(
+  set -e
+
+  # the test server only offers the metadata action to repos which ask for it
+  reponame="metadata-roundtrip"
+  setup_remote_repo "$reponame"
+  git config lfstest.metadata true
+  clone_repo "$reponame" "$reponame"
+
+  git config lfs.metadata true
+  git lfs track "*.dat"
+
+  contents="archived asset"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  touch -t 201001010000 a.dat
+  touch -t 201101010000 ../metadata-ref
+
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  sidecar=".git/lfs/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid.json"
+  [ -f "$sidecar" ]
+  grep "\"path\":\"a.dat\"" "$sidecar"
+
+  # the pointer is unchanged by the metadata
+  assert_pointer "master" "a.dat" "$contents_oid" 14
+
+  git push origin master
+  assert_server_object "$reponame" "$contents_oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git config lfs.metadata true
+  git lfs pull
+
+  [ "$contents" = "$(cat a.dat)" ]
+  [ -f "$sidecar" ]
+  grep "\"path\":\"a.dat\"" "$sidecar"
+  [ -z "$(find a.dat -newer ../metadata-ref)" ]
+)
+end_test
+
+begin_test "metadata: disabled by default"
+(
+  set -e
+
+  # the test server offers the metadata action, but it isn't used unless
+  # lfs.metadata is set
+  reponame="metadata-disabled"
+  setup_remote_repo "$reponame"
+  git config lfstest.metadata true
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents="no metadata"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  [ ! -f ".git/lfs/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid.json" ]
+
+  git push origin master
+  assert_server_object "$reponame" "$contents_oid"
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git lfs pull
+
+  [ "$contents" = "$(cat a.dat)" ]
+  [ ! -f ".git/lfs/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid.json" ]
+)
+end_test
@@ -0,0 +1,81 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "multipart upload with part URLs"
+(
+  set -e
+
+  # this repo name is the indicator to the server to ask for a multipart upload
+  reponame="multipart-part-urls"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents="multipart upload contents"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "xfer: uploading \"$contents_oid\" in 7 parts of 4 bytes" push.log
+
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
+
+begin_test "multipart upload with an init URL"
+(
+  set -e
+
+  # this repo name tells the server to hand out part URLs from an init URL
+  reponame="multipart-init"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents="multipart upload contents from init"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "xfer: uploading \"$contents_oid\" in 9 parts of 4 bytes" push.log
+
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
+
+begin_test "multipart upload retries a failed part on its own"
+(
+  set -e
+
+  # this repo name tells the server to fail the first upload of part 2
+  reponame="multipart-flaky"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents="multipart upload contents"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "xfer: retrying part 2 of \"$contents_oid\" (attempt 2)" push.log
+  [ "0" = "$(grep -c "retrying part [13-7] of" push.log)" ]
+  [ "1" = "$(grep -c "xfer: uploading \"$contents_oid\" in 7 parts" push.log)" ]
+  grep "tq: retrying object $contents_oid" push.log && exit 1
+
+  assert_server_object "$reponame" "$contents_oid"
+)
+end_test
@@ -66,7 +66,12 @@ size 123
 
 Git blob OID: 905bcc24b5dc074ab870f9944178e398eec3b470
 
-Pointers do not match"
+Pointers do not match
+
+--- STDIN
++++ some-file
+-size 123
++size 7"
 
   [ "$expected" = "$output" ]
 )
@@ -151,7 +156,12 @@ size 123
 
 Git blob OID: 905bcc24b5dc074ab870f9944178e398eec3b470
 
-Pointers do not match"
+Pointers do not match
+
+--- invalid-pointer
++++ some-file
+-size 123
++size 7"
 
   set +e
   output=$(git lfs pointer --file=some-file --pointer=invalid-pointer 2>&1)
@@ -298,3 +308,65 @@ begin_test "pointer to console"
   grep "oid sha256:e96ec1bd71eea8df78b24c64a7ab9d42dd7f821c4e503f0e2288273b9bff6c16" pointer.txt
 )
 end_test
+
+begin_test "pointer --hash"
+(
+  set -e
+
+  reponame="pointer-hash"
+  git init "$reponame"
+  cd "$reponame"
+  git lfs track "*.dat"
+
+  printf "hash me" > a.dat
+  oid="$(calc_oid "hash me")"
+
+  [ "sha256:$oid 7" = "$(git lfs pointer --hash --file=a.dat)" ]
+  [ "sha256:$oid 7" = "$(printf "hash me" | git lfs pointer --hash)" ]
+  refute_local_object "$oid"
+
+  git add a.dat
+  assert_local_object "$oid" 7
+  git show ":a.dat" | grep "oid sha256:$oid"
+  git show ":a.dat" | grep "size 7"
+
+  # content which is already a pointer is stored as-is by git add
+  [ "sha256:$oid 7" = "$(git show ":a.dat" | git lfs pointer --hash)" ]
+)
+end_test
+
+begin_test "pointer --file=- --pointer"
+(
+  set -e
+  echo "version https://git-lfs.github.com/spec/v1
+oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868
+size 7" > valid-pointer
+
+  echo "simple" | git lfs pointer --file=- --pointer=valid-pointer 2>&1 | tee output.log
+  grep "Git LFS pointer for STDIN" output.log
+
+  set +e
+  echo "simple?" | git lfs pointer --file=- --pointer=valid-pointer 2> output.log
+  status=$?
+  set -e
+
+  [ "1" = "$status" ]
+  cat output.log
+  grep "Pointers do not match" output.log
+  grep -- "--- valid-pointer" output.log
+  grep -- "+++ STDIN" output.log
+  grep -- "-oid sha256:6c17f2007cbe934aee6e309b28b2dba3c119c5dff2ef813ed124699efe319868" output.log
+  grep -- "+oid sha256:$(calc_oid "simple?
+")" output.log
+  grep -- "-size 7" output.log
+  grep -- "+size 8" output.log
+
+  set +e
+  echo "simple" | git lfs pointer --file=- --stdin 2> output.log
+  status=$?
+  set -e
+
+  [ "1" = "$status" ]
+  grep "Cannot read both --file and --stdin from STDIN." output.log
+)
+end_test
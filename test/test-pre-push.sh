@@ -516,3 +516,31 @@ begin_test "pre-push delete branch"
 
 )
 end_test
+
+begin_test "pre-push with GIT_LFS_SKIP_PUSH"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-skip-push"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "skipped" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  oid="$(calc_oid "skipped")"
+
+  GIT_LFS_SKIP_PUSH=1 git push origin master 2>&1 | tee push.log
+  grep "WARNING: GIT_LFS_SKIP_PUSH is set, so 1 Git LFS objects (7 B) were NOT uploaded to \"origin\"." push.log
+  grep "git lfs push --all origin <branch>" push.log
+
+  # the git push went ahead, without the object
+  [ "$(git rev-parse master)" = "$(git ls-remote origin refs/heads/master | cut -f 1)" ]
+  refute_server_object "$reponame" "$oid"
+
+  # and it can still be pushed afterwards
+  git lfs push --all origin master
+  assert_server_object "$reponame" "$oid"
+)
+end_test
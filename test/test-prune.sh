@@ -210,6 +210,77 @@ begin_test "prune keep unpushed"
 )
 end_test
 
+begin_test "prune include unpushed"
+(
+  set -e
+
+  reponame="prune_include_unpushed"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_unpushed1="Unpushed old 1"
+  content_unpushed2="Unpushed old 2"
+  content_head="Unpushed HEAD"
+  oid_unpushed1=$(calc_oid "$content_unpushed1")
+  oid_unpushed2=$(calc_oid "$content_unpushed2")
+  oid_head=$(calc_oid "$content_head")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -40d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_unpushed1}, \"Data\":\"$content_unpushed1\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -30d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_unpushed2}, \"Data\":\"$content_unpushed2\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -0d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_head}, \"Data\":\"$content_head\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+
+  git lfs prune 2>&1 | tee prune.log
+  grep "Nothing to prune" prune.log
+
+  # without a terminal or --yes nothing is deleted
+  set +e
+  git lfs prune --include-unpushed < /dev/null > prune.log 2>&1
+  res=$?
+  set -e
+  cat prune.log
+  [ "$res" != "0" ]
+  grep "2 files to be pruned have not been pushed" prune.log
+  grep "Aborting prune" prune.log
+  # only the commits which add the objects to be pruned are listed
+  grep " \* $(git rev-parse --short HEAD~1) " prune.log
+  grep " \* $(git rev-parse --short HEAD~2) " prune.log
+  [ "0" = "$(grep -c " \* $(git rev-parse --short HEAD) " prune.log)" ]
+  assert_local_object "$oid_unpushed1" "${#content_unpushed1}"
+  assert_local_object "$oid_unpushed2" "${#content_unpushed2}"
+
+  git lfs prune --include-unpushed --dry-run 2>&1 | tee prune.log
+  grep "2 files would be pruned" prune.log
+  assert_local_object "$oid_unpushed1" "${#content_unpushed1}"
+
+  git lfs prune --include-unpushed --yes 2>&1 | tee prune.log
+  grep "Pruning 2 files" prune.log
+  refute_local_object "$oid_unpushed1"
+  refute_local_object "$oid_unpushed2"
+  assert_local_object "$oid_head" "${#content_head}"
+)
+end_test
+
 begin_test "prune keep recent"
 (
   set -e
@@ -371,6 +442,358 @@ begin_test "prune keep recent"
 )
 end_test
 
+begin_test "prune keep days"
+(
+  set -e
+
+  reponame="prune_keep_days"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_keephead="Keep: HEAD"
+  content_keepwindowstart="Keep: state of HEAD at start of window"
+  content_keeprecentbranch="Keep: recent branch tip"
+  content_pruneold="Prune: old commit on HEAD"
+  content_pruneoldbranch="Prune: old branch tip"
+  oid_keephead=$(calc_oid "$content_keephead")
+  oid_keepwindowstart=$(calc_oid "$content_keepwindowstart")
+  oid_keeprecentbranch=$(calc_oid "$content_keeprecentbranch")
+  oid_pruneold=$(calc_oid "$content_pruneold")
+  oid_pruneoldbranch=$(calc_oid "$content_pruneoldbranch")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -20d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_pruneold}, \"Data\":\"$content_pruneold\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -15d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keepwindowstart}, \"Data\":\"$content_keepwindowstart\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -10d)\",
+    \"NewBranch\":\"branch_old\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_pruneoldbranch}, \"Data\":\"$content_pruneoldbranch\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -2d)\",
+    \"NewBranch\":\"branch_new\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keeprecentbranch}, \"Data\":\"$content_keeprecentbranch\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -1d)\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keephead}, \"Data\":\"$content_keephead\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  # the fetch recent windows alone would only keep HEAD
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneretainunreachabledays 5
+
+  git push origin master:master branch_old:branch_old branch_new:branch_new
+
+  git lfs prune --dry-run 2>&1 | tee prune.log
+  grep "5 local objects, 3 retained" prune.log
+
+  # --keep-days overrides the config for a single run
+  git lfs prune --keep-days=0 --dry-run 2>&1 | tee prune.log
+  grep "5 local objects, 1 retained" prune.log
+
+  git lfs prune --verbose 2>&1 | tee prune.log
+  grep "Pruning 2 files" prune.log
+  refute_local_object "$oid_pruneold"
+  refute_local_object "$oid_pruneoldbranch"
+  assert_local_object "$oid_keephead" "${#content_keephead}"
+  assert_local_object "$oid_keepwindowstart" "${#content_keepwindowstart}"
+  assert_local_object "$oid_keeprecentbranch" "${#content_keeprecentbranch}"
+)
+end_test
+
+begin_test "prune exclude refs"
+(
+  set -e
+
+  reponame="prune_exclude_refs"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_keephead="Keep: HEAD"
+  content_keepfeature="Keep: recent feature branch tip"
+  content_keepunpushed="Keep: unpushed release branch tip"
+  content_prunerelease1="Prune: release branch 1.0 tip"
+  content_prunerelease2="Prune: release branch 2.0 tip"
+  oid_keephead=$(calc_oid "$content_keephead")
+  oid_keepfeature=$(calc_oid "$content_keepfeature")
+  oid_keepunpushed=$(calc_oid "$content_keepunpushed")
+  oid_prunerelease1=$(calc_oid "$content_prunerelease1")
+  oid_prunerelease2=$(calc_oid "$content_prunerelease2")
+
+  git add .gitattributes
+  git commit -m "Track *.dat"
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -5d)\",
+    \"NewBranch\":\"release/1.0\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_prunerelease1}, \"Data\":\"$content_prunerelease1\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -4d)\",
+    \"NewBranch\":\"release/2.0\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_prunerelease2}, \"Data\":\"$content_prunerelease2\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -3d)\",
+    \"NewBranch\":\"release/3.0\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keepunpushed}, \"Data\":\"$content_keepunpushed\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -2d)\",
+    \"NewBranch\":\"feature\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keepfeature}, \"Data\":\"$content_keepfeature\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -1d)\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keephead}, \"Data\":\"$content_keephead\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git config lfs.fetchrecentrefsdays 10
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.pruneoffsetdays 0
+
+  # release/3.0 is left unpushed
+  git push origin master release/1.0 release/2.0 feature
+
+  git lfs prune --dry-run 2>&1 | tee prune.log
+  grep "5 local objects, 5 retained" prune.log
+  grep "Nothing to prune" prune.log
+
+  # remote branches are excluded by the same pattern, but unpushed objects
+  # are still retained
+  git lfs prune --exclude-refs="release/*" --dry-run 2>&1 | tee prune.log
+  grep "5 local objects, 3 retained" prune.log
+  grep "Excluded 5 refs from retention:" prune.log
+  grep " \* release/1.0" prune.log
+  grep " \* release/3.0" prune.log
+  grep " \* origin/release/2.0" prune.log
+  [ "0" -eq "$(grep -c " \* feature" prune.log)" ]
+  grep "2 files would be pruned only because of the excluded refs" prune.log
+  grep "2 files would be pruned" prune.log
+  assert_local_object "$oid_prunerelease1" "${#content_prunerelease1}"
+
+  git lfs prune --exclude-refs="no-such-branch" --dry-run 2>&1 | tee prune.log
+  grep "No retained refs match --exclude-refs \"no-such-branch\"" prune.log
+  grep "Nothing to prune" prune.log
+
+  git lfs prune --exclude-refs="[" 2>&1 | tee prune.log
+  grep "Invalid --exclude-refs pattern \"\[\"" prune.log
+  git lfs prune --cached-only --exclude-refs="release/*" 2>&1 | tee prune.log
+  grep "Cannot combine --cached-only with --exclude-refs" prune.log
+
+  git lfs prune --exclude-refs="release/1.0,release/2.0" 2>&1 | tee prune.log
+  grep "Excluded 4 refs from retention:" prune.log
+  grep "2 files will be pruned only because of the excluded refs" prune.log
+  grep "Pruning 2 files" prune.log
+  refute_local_object "$oid_prunerelease1"
+  refute_local_object "$oid_prunerelease2"
+  assert_local_object "$oid_keephead" "${#content_keephead}"
+  assert_local_object "$oid_keepfeature" "${#content_keepfeature}"
+  assert_local_object "$oid_keepunpushed" "${#content_keepunpushed}"
+)
+end_test
+
+begin_test "prune explain"
+(
+  set -e
+
+  reponame="prune_explain"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_unreferenced="To delete: unreferenced"
+  content_oldandpushed="To delete: pushed and too old"
+  content_oldandunchanged="Keep: pushed and created a while ago, but still current"
+  content_retain1="Retained content 1"
+  content_retain2="Retained content 2"
+  content_unpushed="Keep: not pushed yet"
+  oid_unreferenced=$(calc_oid "$content_unreferenced")
+  oid_oldandpushed=$(calc_oid "$content_oldandpushed")
+  oid_oldandunchanged=$(calc_oid "$content_oldandunchanged")
+  oid_retain1=$(calc_oid "$content_retain1")
+  oid_retain2=$(calc_oid "$content_retain2")
+  oid_unpushed=$(calc_oid "$content_unpushed")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -20d)\",
+    \"Files\":[
+      {\"Filename\":\"old.dat\",\"Size\":${#content_oldandpushed}, \"Data\":\"$content_oldandpushed\"},
+      {\"Filename\":\"stillcurrent.dat\",\"Size\":${#content_oldandunchanged}, \"Data\":\"$content_oldandunchanged\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -7d)\",
+    \"Files\":[
+      {\"Filename\":\"old.dat\",\"Size\":${#content_retain1}, \"Data\":\"$content_retain1\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -4d)\",
+    \"NewBranch\":\"branch_to_delete\",
+    \"Files\":[
+      {\"Filename\":\"unreferenced.dat\",\"Size\":${#content_unreferenced}, \"Data\":\"$content_unreferenced\"}]
+  },
+  {
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"old.dat\",\"Size\":${#content_retain2}, \"Data\":\"$content_retain2\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git push origin master
+  git branch -D branch_to_delete
+
+  git checkout -b unpushed
+  printf "$content_unpushed" > unpushed.dat
+  git add unpushed.dat
+  git commit -m "unpushed.dat"
+  git checkout master
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 3
+  git config lfs.pruneoffsetdays 2
+
+  master=$(git rev-parse --short=7 master)
+
+  git lfs prune --dry-run --explain 2>&1 | tee prune.log
+  grep "2 files would be pruned" prune.log
+
+  grep -A 1 " \* $oid_retain2" prune.log | grep -- "- at HEAD -> master ($master)"
+  grep -A 1 " \* $oid_oldandunchanged" prune.log | grep -- "- at HEAD -> master ($master)"
+  grep -A 1 " \* $oid_retain1" prune.log | grep -- "- in the 5 days of commits up to HEAD -> master ($master)"
+  grep -A 1 " \* $oid_unpushed" prune.log | grep -- "- in commits not pushed to \"origin\""
+  grep " \* $oid_oldandpushed (.*): only referenced by commits outside the retention windows" prune.log
+  grep " \* $oid_unreferenced (.*): not referenced by any commit" prune.log
+
+  # nothing is deleted
+  assert_local_object "$oid_oldandpushed" "${#content_oldandpushed}"
+  assert_local_object "$oid_unreferenced" "${#content_unreferenced}"
+
+  set +e
+  git lfs prune --explain 2>&1 | tee prune.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" -ne 0 ]
+  grep -- "--explain requires --dry-run" prune.log
+  assert_local_object "$oid_oldandpushed" "${#content_oldandpushed}"
+)
+end_test
+
+begin_test "prune cached only"
+(
+  set -e
+
+  reponame="prune_cached_only"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_keephead="Keep: HEAD"
+  content_keepunpushed="Keep: unpushed branch tip"
+  content_prunerecent="Prune: recent commit on HEAD"
+  content_prunerecentbranch="Prune: recent branch tip"
+  oid_keephead=$(calc_oid "$content_keephead")
+  oid_keepunpushed=$(calc_oid "$content_keepunpushed")
+  oid_prunerecent=$(calc_oid "$content_prunerecent")
+  oid_prunerecentbranch=$(calc_oid "$content_prunerecentbranch")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -3d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_prunerecent}, \"Data\":\"$content_prunerecent\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -2d)\",
+    \"NewBranch\":\"branch_recent\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_prunerecentbranch}, \"Data\":\"$content_prunerecentbranch\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -2d)\",
+    \"NewBranch\":\"branch_unpushed\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keepunpushed}, \"Data\":\"$content_keepunpushed\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -1d)\",
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_keephead}, \"Data\":\"$content_keephead\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git push origin master:master branch_recent:branch_recent
+
+  # by default the recent branch is kept too
+  git lfs prune --dry-run 2>&1 | tee prune.log
+  grep "4 local objects, 3 retained" prune.log
+
+  git lfs prune --cached-only --dry-run 2>&1 | tee prune.log
+  grep "4 local objects, 2 retained" prune.log
+  grep "Keeping 1 files needed by HEAD" prune.log
+  grep "2 files would be pruned" prune.log
+
+  pruned_size=$((${#content_prunerecent} + ${#content_prunerecentbranch}))
+  git lfs prune --cached-only 2>&1 | tee prune.log
+  grep "Pruning 2 files" prune.log
+  grep "Reclaimed $pruned_size B" prune.log
+  refute_local_object "$oid_prunerecent"
+  refute_local_object "$oid_prunerecentbranch"
+  assert_local_object "$oid_keephead" "${#content_keephead}"
+  assert_local_object "$oid_keepunpushed" "${#content_keepunpushed}"
+
+  git lfs prune --cached-only --include-unpushed 2>&1 | tee prune.log
+  grep "Cannot combine --cached-only with --include-unpushed" prune.log
+  git lfs prune --cached-only --keep-days=1 2>&1 | tee prune.log
+  grep "Cannot combine --cached-only with --keep-days" prune.log
+)
+end_test
+
 begin_test "prune remote tests"
 (
   set -e
@@ -537,4 +960,77 @@ begin_test "prune verify"
   refute_local_object "$oid_commit3"
 
 )
-end_test
\ No newline at end of file
+end_test
+
+begin_test "prune shallow clone"
+(
+  set -e
+
+  reponame="prune_shallow"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \*.dat" track.log
+
+  content_v0="Keep: beyond the shallow boundary 1"
+  content_v1="Keep: beyond the shallow boundary 2"
+  content_v2="Prune: old commit inside the shallow boundary"
+  content_v3="Keep: HEAD"
+  oid_v0=$(calc_oid "$content_v0")
+  oid_v1=$(calc_oid "$content_v1")
+  oid_v2=$(calc_oid "$content_v2")
+  oid_v3=$(calc_oid "$content_v3")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -40d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_v0}, \"Data\":\"$content_v0\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -30d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_v1}, \"Data\":\"$content_v1\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -20d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_v2}, \"Data\":\"$content_v2\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -10d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_v3}, \"Data\":\"$content_v3\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git push origin master
+
+  # objects from before the clone was made shallow are still in the store
+  cd "$TRASHDIR"
+  git clone --depth=2 "$GITSERVER/remote_$reponame" "shallow_$reponame"
+  cd "shallow_$reponame"
+  [ -s .git/shallow ]
+  mkdir -p .git/lfs
+  cp -r "$TRASHDIR/clone_$reponame/.git/lfs/objects" .git/lfs/
+
+  git lfs prune --dry-run 2>&1 | tee prune.log
+  grep "4 local objects, 1 retained" prune.log
+  grep "Keeping 2 files which commits beyond the shallow clone boundary may need" prune.log
+  grep "1 files would be pruned" prune.log
+
+  git lfs prune 2>&1 | tee prune.log
+  grep "Pruning 1 files" prune.log
+  refute_local_object "$oid_v2"
+  assert_local_object "$oid_v0" "${#content_v0}"
+  assert_local_object "$oid_v1" "${#content_v1}"
+  assert_local_object "$oid_v3" "${#content_v3}"
+
+  # --cached-only only keeps what HEAD needs, shallow or not
+  git lfs prune --cached-only --dry-run 2>&1 | tee prune.log
+  grep "2 files would be pruned" prune.log
+  [ "0" = "$(grep -c "shallow" prune.log)" ]
+)
+end_test
@@ -111,6 +111,99 @@ begin_test "pull"
 )
 end_test
 
+begin_test "pull: offline"
+(
+  set -e
+
+  reponame="pull-offline"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git config lfs.offline true
+
+  # seed the local store with a.dat only
+  mkdir -p .git/lfs/objects
+  cp -r "../$reponame/.git/lfs/objects/$(calc_oid "a" | cut -b 1-2)" .git/lfs/objects/
+
+  set +e
+  git lfs pull 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "$(calc_oid "b") (b.dat) is not in the local store and lfs.offline is set" pull.log
+  [ "version https://git-lfs.github.com/spec/v1" = "$(head -n 1 a.dat)" ]
+
+  git lfs pull --exclude="b.dat"
+  [ "a" = "$(cat a.dat)" ]
+
+  printf "c" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  set +e
+  git lfs push origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "lfs.offline is set, refusing to push to \"origin\"" push.log
+  refute_server_object "$reponame" "$(calc_oid "c")"
+)
+end_test
+
+begin_test "pull --paths-from"
+(
+  set -e
+
+  reponame="pull-paths-from"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir dir
+  printf "paths a" > a.dat
+  printf "paths b" > b.dat
+  printf "paths c" > "dir/c d.dat"
+  printf "plain" > plain.txt
+  git add .gitattributes a.dat b.dat "dir/c d.dat" plain.txt
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+
+  printf "a.dat\nplain.txt\n\nmissing.dat\n./dir/c d.dat\n" > ../manifest
+  git lfs pull --paths-from ../manifest 2>&1 | tee pull.log
+  grep "../manifest:2: plain.txt is not a Git LFS file, skipping" pull.log
+  grep "../manifest:4: missing.dat does not exist in master, skipping" pull.log
+
+  [ "paths a" = "$(cat a.dat)" ]
+  [ "paths c" = "$(cat "dir/c d.dat")" ]
+  [ "version https://git-lfs.github.com/spec/v1" = "$(head -n 1 b.dat)" ]
+  assert_local_object "$(calc_oid "paths a")" 7
+  refute_local_object "$(calc_oid "paths b")"
+
+  printf "b.dat\0" > ../manifest-nul
+  git lfs pull --paths-from ../manifest-nul -z
+  [ "paths b" = "$(cat b.dat)" ]
+
+  set +e
+  git lfs pull --paths-from ../manifest --include "*.dat" 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "\-\-paths-from cannot be combined with \-\-include or \-\-exclude" pull.log
+)
+end_test
+
 begin_test "pull: outside git repository"
 (
   set +e
@@ -126,3 +219,228 @@ begin_test "pull: outside git repository"
   grep "Not in a git repository" pull.log
 )
 end_test
+
+begin_test "pull: transfer errors"
+(
+  set -e
+
+  reponame="pull-transfer-errors"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "pull ok" > a.dat
+  printf "pull missing" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  delete_server_object "$reponame" "$(calc_oid "pull missing")"
+  rm -rf .git/lfs/objects a.dat b.dat
+  GIT_LFS_SKIP_SMUDGE=1 git checkout a.dat b.dat
+
+  set +e
+  git lfs pull --keep-going 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Object $(calc_oid "pull missing") does not exist" pull.log
+  grep "Warning: errors occurred" pull.log
+
+  # the files which could be fetched are still checked out
+  [ "pull ok" = "$(cat a.dat)" ]
+  [ "version https://git-lfs.github.com/spec/v1" = "$(head -n 1 b.dat)" ]
+)
+end_test
+
+begin_test "pull: transfer summary"
+(
+  set -e
+
+  reponame="pull-transfer-summary"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "summary" > a.dat
+  printf "summary two" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  git push origin master 2>&1 | tee push.log
+  grep "Git LFS: transferred 18 B in [0-9.]*m\?s ([0-9.]* [KM]\?B/s)$" push.log
+
+  rm -rf .git/lfs/objects
+  git lfs pull 2>&1 | tee pull.log
+  grep "Git LFS: transferred 18 B in [0-9.]*m\?s ([0-9.]* [KM]\?B/s)$" pull.log
+
+  # nothing to transfer, so no summary
+  git lfs pull 2>&1 | tee pull.log
+  [ "0" -eq "$(grep -c "Git LFS: transferred" pull.log)" ]
+)
+end_test
+
+begin_test "pull --skip-errors"
+(
+  set -e
+
+  reponame="pull-skip-errors"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "pull skip a" > a.dat
+  printf "pull skip b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  # only a.dat's object reaches the server
+  git lfs push --object-id origin "$(calc_oid "pull skip a")"
+  git push --no-verify origin master
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+  pointerb="$(cat b.dat)"
+
+  set +e
+  git lfs pull --skip-errors 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Skipped checking out 1 files, which were left as pointers:" pull.log
+  grep "^  b.dat" pull.log
+  [ "pull skip a" = "$(cat a.dat)" ]
+  [ "$pointerb" = "$(cat b.dat)" ]
+)
+end_test
+
+begin_test "pull --resume"
+(
+  set -e
+
+  reponame="pull-resume"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "resume a" > a.dat
+  printf "resume b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+  oida="$(calc_oid "resume a")"
+  oidb="$(calc_oid "resume b")"
+
+  delete_server_object "$reponame" "$oidb"
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  set +e
+  git lfs pull --keep-going 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Run 'git lfs pull --continue' to retry the 1 objects which failed to download" pull.log
+  [ "0" = "$(grep -c "pull --resume" pull.log)" ]
+  grep "\"name\":\"a.dat\",\"oid\":\"$oida\"" .git/lfs/pull-journal.json
+  grep "\"$oida\":\"done\"" .git/lfs/pull-journal.json
+  grep "\"$oidb\":\"failed\"" .git/lfs/pull-journal.json
+
+  # b.dat reaches the server, and the interrupted pull carries on
+  pushd "$TRASHDIR/$reponame"
+    git lfs push --object-id origin "$oidb"
+  popd
+
+  git lfs pull --resume 2>&1 | tee pull.log
+  grep "Resuming interrupted pull: 1 of 2 files already downloaded" pull.log
+  grep "1 files were part way through downloading" pull.log
+  [ "resume a" = "$(cat a.dat)" ]
+  [ "resume b" = "$(cat b.dat)" ]
+  [ ! -e .git/lfs/pull-journal.json ]
+
+  git lfs pull --resume 2>&1 | tee pull.log
+  grep "No interrupted pull to resume, starting a new one" pull.log
+
+  # a journal for other include/exclude paths is not resumed
+  delete_server_object "$reponame" "$oidb"
+  rm -rf .git/lfs/objects a.dat b.dat
+  GIT_LFS_SKIP_SMUDGE=1 git checkout a.dat b.dat
+  set +e
+  git lfs pull --keep-going > pull.log 2>&1
+  set -e
+  [ -e .git/lfs/pull-journal.json ]
+  git lfs pull --resume --include "a.dat" 2>&1 | tee pull.log
+  grep "The ref or include/exclude paths have changed since the interrupted pull, starting a new one" pull.log
+  [ ! -e .git/lfs/pull-journal.json ]
+
+  git lfs pull --resume --paths-from pull.log 2>&1 | tee pull.log
+  grep -- "--paths-from cannot be combined with --resume" pull.log
+)
+end_test
+
+begin_test "pull --continue"
+(
+  set -e
+
+  reponame="pull-continue"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "continue a" > a.dat
+  printf "continue b" > b.dat
+  cp b.dat c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+  git push origin master
+  oida="$(calc_oid "continue a")"
+  oidb="$(calc_oid "continue b")"
+
+  delete_server_object "$reponame" "$oidb"
+
+  cd "$TRASHDIR"
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git lfs pull --continue 2>&1 | tee pull.log
+  grep "No failed pull to continue" pull.log
+
+  set +e
+  git lfs pull --keep-going 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Run 'git lfs pull --continue' to retry the 1 objects which failed to download" pull.log
+  [ "continue a" = "$(cat a.dat)" ]
+  grep "\"$oida\":\"done\"" .git/lfs/pull-journal.json
+  grep "\"$oidb\":\"failed\"" .git/lfs/pull-journal.json
+
+  # still missing, so the record is kept
+  set +e
+  git lfs pull --continue 2>&1 | tee pull.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Retrying 1 objects which failed to download" pull.log
+  grep "\"$oidb\":\"failed\"" .git/lfs/pull-journal.json
+
+  pushd "$TRASHDIR/$reponame"
+    git lfs push --object-id origin "$oidb"
+  popd
+
+  # only the failed object is retried, and both files using it checked out
+  GIT_TRACE=1 git lfs pull --continue 2>&1 | tee pull.log
+  grep "fetch b.dat \[$oidb\]" pull.log
+  [ "0" = "$(grep -c "a.dat" pull.log)" ]
+  [ "continue b" = "$(cat b.dat)" ]
+  [ "continue b" = "$(cat c.dat)" ]
+  [ ! -e .git/lfs/pull-journal.json ]
+  [ "0" = "$(git status --porcelain | grep -c "dat")" ]
+
+  git lfs pull --continue --include "a.dat" 2>&1 | tee pull.log
+  grep -- "--continue cannot be combined with --include, --exclude, --paths-from or --resume" pull.log
+)
+end_test
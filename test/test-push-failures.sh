@@ -108,3 +108,37 @@ begin_test "push: upload file with api 500"
   push_fail_test "status-batch-500"
 )
 end_test
+
+begin_test "push: --fail-fast and --keep-going"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-fail-fast"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "hi" > good.dat
+  printf "status-batch-422" > bad1.dat
+  printf "status-batch-500" > bad2.dat
+  git add .gitattributes good.dat bad1.dat bad2.dat
+  git commit -m "welp"
+
+  set +e
+  git lfs push --fail-fast origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  [ "1" -eq "$(grep -c "^\[[0-9a-f]\{64\}\] welp" push.log)" ]
+  grep "Stopped after the first failed transfer, skipping" push.log
+
+  set +e
+  git lfs push --keep-going origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  [ "2" -eq "$(grep -c "^\[[0-9a-f]\{64\}\] welp" push.log)" ]
+  [ "0" -eq "$(grep -c "Stopped after the first failed transfer" push.log)" ]
+  assert_server_object "$reponame" "$(calc_oid "hi")"
+)
+end_test
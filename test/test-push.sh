@@ -162,10 +162,8 @@ begin_test "push --all (no ref args)"
   [ $(grep -c "push" push.log) -eq 6 ]
 
   git push --all origin 2>&1 | tee push.log
-  grep "(2 of 2 files, 1 skipped)" push.log
-  grep "(3 of 3 files)" push.log
-  [ $(grep -c "files)" push.log) -eq 1 ]
-  [ $(grep -c "skipped)" push.log) -eq 1 ]
+  [ $(grep -c "(2 of 2 files, 1 skipped)" push.log) -eq 1 ]
+  [ $(grep -c "(3 of 3 files)" push.log) -eq 1 ]
   assert_server_object "$reponame-$suffix-2" "$oid2"
   assert_server_object "$reponame-$suffix-2" "$oid3"
   assert_server_object "$reponame-$suffix-2" "$oid4"
@@ -542,3 +540,198 @@ begin_test "push (with invalid object size)"
   refute_server_object "$reponame" "$(calc_oid "$contents")"
 )
 end_test
+
+begin_test "push --all-remotes"
+(
+  set -e
+
+  reponame="push-all-remotes"
+  mirrorname="push-all-remotes-mirror"
+  setup_remote_repo "$reponame"
+  setup_remote_repo "$mirrorname"
+  clone_repo "$reponame" "$reponame"
+
+  git remote add mirror "$GITSERVER/$mirrorname"
+  git remote add local-path ../local-path-remote.git
+
+  git lfs track "*.dat"
+  printf "all remotes a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  printf "all remotes b" > a.dat
+  git add a.dat
+  git commit -m "update a.dat"
+
+  oid1=$(calc_oid "all remotes a")
+  oid2=$(calc_oid "all remotes b")
+
+  git lfs push --all-remotes --dry-run 2>&1 | tee push.log
+  grep "Skipping local-path: Git LFS can't push to ../local-path-remote.git/info/lfs" push.log
+  [ $(grep -c "push $oid1 => a.dat" push.log) -eq 2 ]
+  [ $(grep -c "push $oid2 => a.dat" push.log) -eq 2 ]
+  refute_server_object "$reponame" "$oid1"
+  refute_server_object "$mirrorname" "$oid1"
+
+  git lfs push --all-remotes 2>&1 | tee push.log
+  grep "Pushing to mirror ($GITSERVER/$mirrorname.git/info/lfs)" push.log
+  grep "Pushing to origin ($GITSERVER/$reponame.git/info/lfs)" push.log
+  grep "Pushed to mirror, origin" push.log
+
+  assert_server_object "$reponame" "$oid1"
+  assert_server_object "$reponame" "$oid2"
+  assert_server_object "$mirrorname" "$oid1"
+  assert_server_object "$mirrorname" "$oid2"
+)
+end_test
+
+begin_test "push --all-remotes continues after a failed remote"
+(
+  set -e
+
+  reponame="push-all-remotes-failure"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git remote add a-broken "$GITSERVER/badbatch"
+  git remote add same-endpoint "$GITSERVER/$reponame"
+
+  git lfs track "*.dat"
+  printf "all remotes failure" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  oid=$(calc_oid "all remotes failure")
+
+  set +e
+  git lfs push --all-remotes 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Pushing to a-broken" push.log
+  grep "Pushing to origin" push.log
+  grep "Skipping same-endpoint: same Git LFS endpoint as origin" push.log
+  grep "Pushed to origin" push.log
+  grep "Failed to push to a-broken" push.log
+  assert_server_object "$reponame" "$oid"
+)
+end_test
+
+begin_test "push --verify-only"
+(
+  set -e
+
+  reponame="push-verify-only"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "verify a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  printf "verify b" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+  git tag v1.0
+
+  oid1=$(calc_oid "verify a")
+  oid2=$(calc_oid "verify b")
+
+  git lfs push --object-id origin "$oid1"
+
+  set +e
+  git lfs push origin v1.0 --verify-only 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "missing $oid2 => b.dat" push.log
+  grep "1 of 2 objects are missing from origin" push.log
+  grep "$oid1" push.log && exit 1
+  refute_server_object "$reponame" "$oid2"
+
+  git lfs push origin v1.0
+  assert_server_object "$reponame" "$oid2"
+
+  # the objects are checked on the server, so need not be local
+  rm -rf .git/lfs/objects
+  git lfs push origin v1.0 --verify-only 2>&1 | tee push.log
+  grep "All 2 objects are present on origin" push.log
+  grep "missing" push.log && exit 1
+
+  git lfs push origin v1.0 --verify-only --dry-run 2>&1 | tee push.log
+  grep "cannot be combined" push.log
+)
+end_test
+
+begin_test "push --stats"
+(
+  set -e
+
+  reponame="push-stats"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "push stats" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git lfs push --stats origin master 2>&1 | tee push.log
+  grep "HTTP timings, averaged per request:" push.log
+  grep -E "^  class +requests +bytes +dns +connect +tls +ttfb +transfer$" push.log
+  grep -E "^  batch +1 " push.log
+  grep -E "^  object +1 " push.log
+  assert_server_object "$reponame" "$(calc_oid "push stats")"
+
+  # nothing is printed without --stats
+  printf "no stats" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+  git lfs push origin master 2>&1 | tee push.log
+  [ "0" = "$(grep -c "HTTP timings" push.log)" ]
+)
+end_test
+
+begin_test "push with Expect: 100-continue"
+(
+  set -e
+
+  # the server answers 417 to uploads without "Expect: 100-continue"
+  reponame="expect100-push"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "expect continue" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git push origin master 2>&1 | tee push.log
+  assert_server_object "$reponame" "$(calc_oid "expect continue")"
+
+  # a rejected upload is never sent
+  printf "status-storage-422" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+
+  set +e
+  GIT_TRACE=1 git lfs push origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" != "0" ]
+  grep "xfer: upload of $(calc_oid "status-storage-422") rejected with HTTP 422 before its content was sent" push.log
+
+  # without the header, the server refuses the upload
+  git reset --hard HEAD^
+  printf "no expect" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+
+  set +e
+  git -c lfs.transfer.expect100=false lfs push origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" != "0" ]
+  grep "HTTP 417" push.log
+  refute_server_object "$reponame" "$(calc_oid "no expect")"
+)
+end_test
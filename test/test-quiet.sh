@@ -0,0 +1,64 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "quiet transfer commands"
+(
+  set -e
+
+  reponame="quiet"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="quiet"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git lfs push --quiet origin master > push.log 2>&1
+  [ ! -s push.log ]
+  assert_server_object "$reponame" "$contents_oid"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+  git lfs fetch -q > fetch.log 2>&1
+  [ ! -s fetch.log ]
+  assert_local_object "$contents_oid" "${#contents}"
+
+  rm a.dat
+  git lfs checkout --quiet > checkout.log 2>&1
+  [ ! -s checkout.log ]
+  [ "$contents" = "$(cat a.dat)" ]
+
+  rm -rf .git/lfs/objects a.dat
+  git lfs pull --quiet > pull.log 2>&1
+  [ ! -s pull.log ]
+  [ "$contents" = "$(cat a.dat)" ]
+
+  git lfs prune --quiet > prune.log 2>&1
+  [ ! -s prune.log ]
+
+  # GIT_LFS_QUIET is the default, and the output is back without it
+  rm -rf .git/lfs/objects
+  GIT_LFS_QUIET=1 git lfs fetch > fetch.log 2>&1
+  [ ! -s fetch.log ]
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Git LFS: (1 of 1 files)" fetch.log
+  git lfs prune 2>&1 | tee prune.log
+  grep "Nothing to prune" prune.log
+
+  # errors are still printed
+  rm -rf .git/lfs/objects
+  git config lfs.url "http://127.0.0.1:1/no-server"
+  set +e
+  git lfs fetch --quiet > fetch.log 2> fetch.err
+  res=$?
+  set -e
+  [ "$res" -ne 0 ]
+  [ ! -s fetch.log ]
+  [ -s fetch.err ]
+)
+end_test
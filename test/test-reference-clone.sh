@@ -95,3 +95,82 @@ begin_test "fetch from clone reference"
   assert_same_inode "$TRASHDIR/$repo" "$TRASHDIR/$ref_repo" "$oid"
 )
 end_test
+
+begin_test "fetch --reference"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")3"
+  setup_remote_repo "$reponame"
+
+  ref_repo=fetch_reference_repo
+  ref_repo_dir=$TRASHDIR/$ref_repo
+  clone_repo "$reponame" "$ref_repo"
+  git lfs track "*.dat"
+  contents="a"
+  oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat" 2>&1
+  git push origin master
+
+  delete_server_object "$reponame" "$oid"
+
+  cd "$TRASHDIR"
+  repo=fetch_reference_test_repo
+  repo_dir=$TRASHDIR/$repo
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$repo_dir"
+  cd "$repo_dir"
+  refute_local_object "$oid"
+
+  git lfs fetch --reference "$ref_repo_dir" 2>&1 | tee fetch.log
+  assert_local_object "$oid" 1
+  assert_same_inode "$repo_dir" "$ref_repo_dir" "$oid"
+
+  set +e
+  git lfs fetch --reference "$TRASHDIR/missing" 2>&1 | tee fetch.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "No Git LFS objects found in reference repository" fetch.log
+)
+end_test
+
+begin_test "fetch with lfs.referencerepo ignores corrupt objects"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")4"
+  setup_remote_repo "$reponame"
+
+  ref_repo=fetch_reference_repo4
+  ref_repo_dir=$TRASHDIR/$ref_repo
+  clone_repo "$reponame" "$ref_repo"
+  git lfs track "*.dat"
+  contents="a"
+  oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat" 2>&1
+  git push origin master
+
+  # same size, wrong content
+  ref_object="$ref_repo_dir/.git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+  rm "$ref_object"
+  printf "b" > "$ref_object"
+
+  cd "$TRASHDIR"
+  repo=fetch_reference_test_repo4
+  repo_dir=$TRASHDIR/$repo
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$repo_dir"
+  cd "$repo_dir"
+  git config lfs.referencerepo "$ref_repo_dir"
+
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "does not match its OID, ignoring it" fetch.log
+  grep "fetch a.dat \[$oid\]" fetch.log
+  assert_local_object "$oid" 1
+)
+end_test
@@ -0,0 +1,85 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "route: push, clone and fetch objects on a routed remote"
+(
+  set -e
+
+  reponame="route-push-fetch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  # textures are stored on their own LFS server, set up in .lfsconfig so that
+  # clones use it too
+  git config -f .lfsconfig remote.textures.lfsurl "$GITSERVER/$reponame-textures.git/info/lfs"
+  git config -f .lfsconfig remote.textures.lfsroute "*.png"
+
+  git lfs track "*.dat" "*.png"
+  printf "data" > a.dat
+  printf "texture" > b.png
+  data_oid="$(calc_oid "data")"
+  texture_oid="$(calc_oid "texture")"
+  git add .lfsconfig .gitattributes a.dat b.png
+  git commit -m "add files"
+
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  grep "(2 of 2 files)" push.log
+  grep "tq: transferring 1 objects routed to remote \"textures\"" push.log
+
+  assert_server_object "$reponame" "$data_oid"
+  refute_server_object "$reponame" "$texture_oid"
+  assert_server_object "$reponame-textures" "$texture_oid"
+  refute_server_object "$reponame-textures" "$data_oid"
+
+  # smudging while cloning downloads each file from its own server
+  cd "$TRASHDIR"
+  git clone "$GITSERVER/$reponame" "$reponame-clone" 2>&1 | tee clone.log
+  cd "$reponame-clone"
+  [ "data" = "$(cat a.dat)" ]
+  [ "texture" = "$(cat b.png)" ]
+
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "(2 of 2 files)" fetch.log
+  assert_local_object "$data_oid" 4
+  assert_local_object "$texture_oid" 7
+
+  # the current remote is still used for everything else
+  git lfs env | grep "Endpoint=$GITSERVER/$reponame.git/info/lfs"
+)
+end_test
+
+begin_test "route: concurrency and bandwidth limits for a routed remote"
+(
+  set -e
+
+  reponame="route-limits"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config -f .lfsconfig remote.textures.lfsurl "$GITSERVER/$reponame-textures.git/info/lfs"
+  git config -f .lfsconfig remote.textures.lfsroute "*.png"
+  # limits come from the user's own config, not the repository's
+  git config -f .lfsconfig remote.origin.lfsconcurrenttransfers 1
+  git config remote.textures.lfsconcurrenttransfers 1
+  git config remote.textures.lfsmaxbandwidth 2k
+
+  git lfs track "*.dat" "*.png"
+  printf "data" > a.dat
+  # 3k at 2k/s takes at least a second
+  head -c 3072 /dev/zero > b.png
+  git add .lfsconfig .gitattributes a.dat b.png
+  git commit -m "add files"
+
+  start=$(date +%s)
+  GIT_TRACE=1 git push origin master 2>&1 | tee push.log
+  end=$(date +%s)
+  grep "(2 of 2 files)" push.log
+  grep "tq: 3 concurrent transfers with remote \"origin\", at most 0 bytes/s" push.log
+  grep "tq: 1 concurrent transfers with remote \"textures\", at most 2048 bytes/s" push.log
+  [ $((end - start)) -ge 1 ]
+
+  assert_server_object "$reponame-textures" "$(shasum -a 256 b.png | cut -f 1 -d " ")"
+)
+end_test
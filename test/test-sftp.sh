@@ -0,0 +1,196 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+# lfstest-sftp stands in for the sftp client, keeping the server's files under
+# $LFSTEST_SFTP_ROOT
+export LFSTEST_SFTP_ROOT="$TRASHDIR/sftp-root"
+export LFSTEST_SFTP_LOG="$TRASHDIR/sftp.log"
+sftpurl="sftp://lfs@sftp.example.com:2222/srv/lfs"
+
+begin_test "sftp: push and pull"
+(
+  set -e
+
+  reponame="sftp-push-pull"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git config lfs.url "$sftpurl"
+  git config lfs.sftpcommand lfstest-sftp
+  mkdir -p "$LFSTEST_SFTP_ROOT/srv/lfs/objects"
+
+  git lfs track "*.dat"
+  contents="sftp"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  stored="$LFSTEST_SFTP_ROOT/srv/lfs/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid"
+  [ "$contents" = "$(cat "$stored")" ]
+  grep -- "-b - -o BatchMode=yes -P 2222 -- lfs@sftp.example.com" "$LFSTEST_SFTP_LOG"
+
+  # objects already on the server aren't sent again
+  git checkout -b other
+  printf "other" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+  GIT_TRACE=1 git push origin other 2>&1 | tee push.log
+  grep "Add() for \"$(calc_oid "other")\"" push.log
+  [ "0" -eq "$(grep -c "Add() for \"$contents_oid\"" push.log)" ]
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 clone_repo "$reponame" "$reponame-clone"
+  git config lfs.url "$sftpurl"
+  git config lfs.sftpcommand lfstest-sftp
+
+  git lfs pull 2>&1 | tee pull.log
+  grep "(1 of 1 files)" pull.log
+  assert_local_object "$contents_oid" 4
+  [ "$contents" = "$(cat a.dat)" ]
+)
+end_test
+
+begin_test "sftp: missing and corrupt objects"
+(
+  set -e
+
+  reponame="sftp-missing"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git config lfs.url "$sftpurl/missing"
+  git config lfs.sftpcommand lfstest-sftp
+  mkdir -p "$LFSTEST_SFTP_ROOT/srv/lfs/missing/objects"
+
+  git lfs track "*.dat"
+  printf "missing" > a.dat
+  printf "corrupt" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add objects"
+  git push origin master
+
+  a_oid="$(calc_oid "missing")"
+  b_oid="$(calc_oid "corrupt")"
+  rm "$LFSTEST_SFTP_ROOT/srv/lfs/missing/objects/${a_oid:0:2}/${a_oid:2:2}/$a_oid"
+  printf "CORRUPT" > "$LFSTEST_SFTP_ROOT/srv/lfs/missing/objects/${b_oid:0:2}/${b_oid:2:2}/$b_oid"
+  rm -rf .git/lfs/objects
+
+  git lfs fetch 2>&1 | tee fetch.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected fetch to fail"
+    exit 1
+  fi
+  grep "\[$a_oid\] Object does not exist on the SFTP server" fetch.log
+  grep "Expected OID $b_oid" fetch.log
+  refute_local_object "$a_oid"
+  refute_local_object "$b_oid"
+)
+end_test
+
+begin_test "sftp: remote without an objects directory"
+(
+  set -e
+
+  reponame="sftp-no-layout"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git config lfs.url "$sftpurl/nowhere"
+  git config lfs.sftpcommand lfstest-sftp
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git push origin master 2>&1 | tee push.log
+  if [ "0" -eq "${PIPESTATUS[0]}" ]; then
+    echo >&2 "fatal: expected push to fail"
+    exit 1
+  fi
+  grep "SFTP remote lfs@sftp.example.com:/srv/lfs/nowhere has no Git LFS objects directory; create /srv/lfs/nowhere/objects on the server" push.log
+)
+end_test
+
+begin_test "sftp: push --all-remotes"
+(
+  set -e
+
+  reponame="sftp-all-remotes"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git config lfs.sftpcommand lfstest-sftp
+  mkdir -p "$LFSTEST_SFTP_ROOT/srv/lfs/all-remotes/objects"
+
+  git remote add sftp-mirror ../sftp-mirror.git
+  git config remote.sftp-mirror.lfsurl "$sftpurl/all-remotes"
+  git remote add file-mirror "file://$TRASHDIR/file-mirror.git"
+
+  git lfs track "*.dat"
+  contents="sftp all remotes"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git lfs push --all-remotes 2>&1 | tee push.log
+  grep "Skipping file-mirror: Git LFS can't push to file://$TRASHDIR/file-mirror.git/info/lfs" push.log
+  grep "Pushing to sftp-mirror ($sftpurl/all-remotes)" push.log
+  grep "Pushed to origin, sftp-mirror" push.log
+
+  assert_server_object "$reponame" "$contents_oid"
+  stored="$LFSTEST_SFTP_ROOT/srv/lfs/all-remotes/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid"
+  [ "$contents" = "$(cat "$stored")" ]
+)
+end_test
+
+begin_test "sftp: hosts and users can't be ssh options"
+(
+  set -e
+
+  reponame="sftp-options"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git config lfs.url "sftp://-oProxyCommand=pwned/lfs"
+  git config lfs.sftpcommand lfstest-sftp
+  rm -f "$LFSTEST_SFTP_LOG"
+
+  git lfs track "*.dat"
+  printf "options" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git push origin master 2>&1 | tee push.log
+  grep "the host can't start with '-'" push.log
+  [ ! -e "$LFSTEST_SFTP_LOG" ]
+  [ ! -e pwned ]
+)
+end_test
+
+begin_test "sftp: .lfsconfig can't set an sftp url"
+(
+  set -e
+
+  reponame="sftp-lfsconfig"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+  git config -f .lfsconfig lfs.url "$sftpurl"
+  git config -f .lfsconfig remote.origin.lfsurl "$sftpurl"
+  git config lfs.sftpcommand lfstest-sftp
+  rm -f "$LFSTEST_SFTP_LOG"
+
+  git lfs env 2>&1 | tee env.log
+  [ "0" -eq "$(grep -c "sftp://" env.log)" ]
+
+  git lfs track "*.dat"
+  contents="lfsconfig"
+  printf "$contents" > a.dat
+  git add .gitattributes .lfsconfig a.dat
+  git commit -m "add a.dat"
+
+  git push origin master 2>&1 | tee push.log
+  assert_server_object "$reponame" "$(calc_oid "$contents")"
+  [ ! -e "$LFSTEST_SFTP_LOG" ]
+)
+end_test
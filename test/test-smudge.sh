@@ -113,9 +113,11 @@ begin_test "smudge with skip"
 
   git push origin master
 
-  # Must clear the cache because smudge will use
-  # cached objects even with --skip/GIT_LFS_SKIP_SMUDGE
-  # (--skip applies to whether or not it downloads).
+  # --skip/GIT_LFS_SKIP_SMUDGE pass the pointer through, even if the object
+  # is in the local cache
+  [ "$pointer" = "$(echo "$pointer" | GIT_LFS_SKIP_SMUDGE=1 git lfs smudge)" ]
+  [ "$pointer" = "$(echo "$pointer" | git lfs smudge --skip)" ]
+
   rm -rf .git/lfs/objects
 
   [ "$pointer" = "$(echo "$pointer" | GIT_LFS_SKIP_SMUDGE=1 git lfs smudge)" ]
@@ -127,6 +129,17 @@ begin_test "smudge with skip"
   [ "$pointer" = "$(cat a.dat)" ]
   [ "0" = "$(grep -c "Downloading a.dat" clone.log)" ]
 
+  # checkout still materializes files from the local store, and clean is
+  # unaffected, so the working tree stays unmodified
+  git lfs fetch
+  git lfs checkout a.dat
+  [ "smudge a" = "$(cat a.dat)" ]
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+
+  rm a.dat
+  git checkout -- a.dat
+  [ "$pointer" = "$(cat a.dat)" ]
+
   git lfs pull
   [ "smudge a" = "$(cat a.dat)" ]
 
@@ -0,0 +1,101 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "ssl: lfs.<url>.sslcainfo"
+(
+  set -e
+
+  reponame="ssl-cainfo"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  # Only the git remote is reached over plain HTTP; the LFS API is on the
+  # TLS endpoint, whose self-signed certificate is only trusted with a custom
+  # CA file.
+  unset GIT_SSL_CAINFO
+  git config lfs.url "$SSLGITSERVER/$reponame.git/info/lfs"
+  touch "$TRASHDIR/empty-ca.pem"
+  git config http.sslcainfo "$TRASHDIR/empty-ca.pem"
+
+  git lfs track "*.dat"
+  contents="ssl cainfo"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  set +e
+  git lfs push origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  if [ "0" -eq "$res" ]; then
+    echo "push should fail without the certificate's CA"
+    exit 1
+  fi
+  refute_server_object "$reponame" "$contents_oid"
+  grep "x509" push.log
+
+  git config "lfs.$SSLGITSERVER/.sslcainfo" "$LFS_CERT_FILE"
+  git lfs push origin master 2>&1 | tee push.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+  assert_server_object "$reponame" "$contents_oid"
+  [ "0" -eq "$(grep -c "WARNING" push.log)" ]
+)
+end_test
+
+begin_test "ssl: http.<url>.sslverify"
+(
+  set -e
+
+  reponame="ssl-verify"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  unset GIT_SSL_CAINFO
+  git config lfs.url "$SSLGITSERVER/$reponame.git/info/lfs"
+  touch "$TRASHDIR/empty-ca.pem"
+  git config http.sslcainfo "$TRASHDIR/empty-ca.pem"
+
+  git lfs track "*.dat"
+  contents="ssl verify"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # a setting for another host doesn't apply
+  git config "http.https://example.com/.sslverify" false
+  set +e
+  git lfs push origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  if [ "0" -eq "$res" ]; then
+    echo "push should fail with verification on"
+    exit 1
+  fi
+  refute_server_object "$reponame" "$contents_oid"
+  grep "x509" push.log
+
+  # nor does the global setting, when the host turns verification back on
+  git config http.sslverify false
+  git config "http.$SSLGITSERVER/.sslverify" true
+  set +e
+  git lfs push origin master 2>&1 | tee push.log
+  res=${PIPESTATUS[0]}
+  set -e
+  if [ "0" -eq "$res" ]; then
+    echo "push should fail with verification on for the host"
+    exit 1
+  fi
+  refute_server_object "$reponame" "$contents_oid"
+  grep "x509" push.log
+
+  git config --unset http.sslverify
+  git config "http.$SSLGITSERVER/.sslverify" false
+  git lfs push origin master 2>&1 | tee push.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+  assert_server_object "$reponame" "$contents_oid"
+  grep "WARNING: SSL certificate verification is disabled for ${SSLGITSERVER#https://}" push.log
+)
+end_test
@@ -83,3 +83,327 @@ begin_test "status: outside git repository"
   grep "Not in a git repository" status.log
 )
 end_test
+
+begin_test "status --json with range"
+(
+  set -e
+
+  mkdir repo-json
+  cd repo-json
+  git init
+  git lfs track "*.dat"
+  echo "file1 data" > file1.dat
+  echo "file2 data" > file2.dat
+  echo "file3 data" > file3.dat
+  git add .gitattributes file1.dat file2.dat file3.dat
+  git commit -m "base"
+  git tag base
+
+  echo "file1 changed" > file1.dat
+  git rm file2.dat
+  git mv file3.dat renamed.dat
+  echo "file4 data" > file4.dat
+  git add file1.dat file4.dat
+  git commit -m "head"
+
+  git lfs status --json base..HEAD > status.json
+  cat status.json
+
+  file1_old="$(calc_oid "file1 data
+")"
+  file1_new="$(calc_oid "file1 changed
+")"
+
+  grep "\"oid\": \"$file1_old\"" status.json
+  grep "\"oid\": \"$file1_new\"" status.json
+  grep "\"name\": \"file4.dat\"" status.json
+  grep "\"name\": \"file2.dat\"" status.json
+  grep "\"from\": \"file3.dat\"" status.json
+
+  [ "1" = "$(grep -c "\"name\": \"renamed.dat\"" status.json)" ]
+)
+end_test
+
+begin_test "status --no-untracked and path filters"
+(
+  set -e
+
+  mkdir repo-filters
+  cd repo-filters
+  git init
+  git lfs track "*.dat"
+  mkdir a b
+  echo "some data" > a/file1.dat
+  git add .gitattributes a/file1.dat
+  git commit -m "a/file1.dat"
+
+  echo "other data" > a/file1.dat
+  echo "file2 data" > a/file2.dat
+  echo "file3 data" > b/file3.dat
+  git add a/file2.dat b/file3.dat
+  echo "untracked" > a/new.dat
+  echo "untracked" > a/new.txt
+
+  expected="On branch master
+
+Git LFS objects to be committed:
+
+	a/file2.dat (11 B)
+	b/file3.dat (11 B)
+
+Git LFS objects not staged for commit:
+
+	a/file1.dat
+
+Untracked files the Git LFS filter would clean:
+
+	a/new.dat"
+
+  [ "$expected" = "$(git lfs status)" ]
+
+  # modified files are still listed, only the untracked ones are left out
+  expected="On branch master
+
+Git LFS objects to be committed:
+
+	a/file2.dat (11 B)
+	b/file3.dat (11 B)
+
+Git LFS objects not staged for commit:
+
+	a/file1.dat"
+
+  [ "$expected" = "$(git lfs status --no-untracked)" ]
+
+  expected=" M a/file1.dat 10
+A  a/file2.dat 11
+?? a/new.dat 10"
+
+  [ "$expected" = "$(git lfs status --porcelain --include="a")" ]
+
+  expected=" M a/file1.dat 10
+A  a/file2.dat 11"
+
+  [ "$expected" = "$(git lfs status --porcelain --no-untracked --exclude="b")" ]
+  rm a/new.dat a/new.txt
+
+  git commit -m "add files"
+
+  git lfs status --json --exclude="a" HEAD^..HEAD > status.json
+  cat status.json
+  grep "\"name\": \"b/file3.dat\"" status.json
+  [ "0" = "$(grep -c "a/file2.dat" status.json)" ]
+)
+end_test
+
+begin_test "status --show-lock-owner"
+(
+  set -e
+
+  reponame="status-show-lock-owner"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  echo "mine" > mine.dat
+  echo "theirs" > theirs.dat
+  echo "free" > free.dat
+  git add .gitattributes mine.dat theirs.dat free.dat
+  git commit -m "add files"
+  git push origin master
+
+  GITLFSLOCKSENABLED=1 git lfs lock "mine.dat"
+  GITLFSLOCKSENABLED=1 git -c user.name="Someone Else" -c user.email="else@example.com" \
+    lfs lock "theirs.dat"
+
+  echo "mine changed" > mine.dat
+  echo "theirs changed" > theirs.dat
+  echo "free changed" > free.dat
+  echo "new" > new.dat
+  git add new.dat
+
+  expected="On branch master
+Git LFS objects to be pushed to origin/master:
+
+
+Git LFS objects to be committed:
+
+	new.dat (4 B)	[unlocked]
+
+Git LFS objects not staged for commit:
+
+	free.dat	[unlocked]
+	mine.dat	[locked by you]
+	theirs.dat	[locked by Someone Else <else@example.com>]"
+
+  [ "$expected" = "$(git lfs status --show-lock-owner)" ]
+
+  # without a reachable lock server, plain status is shown with a warning
+  git config lfs.url "http://127.0.0.1:1/$reponame.git/info/lfs"
+  git lfs status --show-lock-owner 2>status.err | tee status.log
+  grep "Warning: could not get locks from the server, not showing lock owners" status.err
+  grep "	new.dat (4 B)$" status.log
+  [ "0" = "$(grep -c "locked" status.log)" ]
+
+  set +e
+  git lfs status --show-lock-owner --porcelain 2>&1 | tee status.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "\-\-show-lock-owner cannot be combined with \-\-porcelain" status.log
+)
+end_test
+
+begin_test "status --relative-to"
+(
+  set -e
+
+  mkdir repo-relative
+  cd repo-relative
+  git init
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "track .dat files"
+
+  mkdir -p a/b c
+  echo "top data" > top.dat
+  echo "b data" > a/b/b.dat
+  echo "c data" > c/c.dat
+  git add top.dat a/b/b.dat c/c.dat
+
+  expected="A  a/b/b.dat 7
+A  c/c.dat 7
+A  top.dat 9"
+  [ "$expected" = "$(cd a && git lfs status --porcelain)" ]
+
+  expected="A  b/b.dat 7
+A  ../c/c.dat 7
+A  ../top.dat 9"
+  [ "$expected" = "$(cd a && git lfs status --porcelain --relative)" ]
+  [ "$expected" = "$(git lfs status --porcelain --relative-to=a)" ]
+  [ "$expected" = "$(cd c && git lfs status --porcelain --relative-to ../a)" ]
+
+  git lfs status --relative-to a/b | tee status.log
+  grep "	../../top.dat (9 B)" status.log
+  grep "	b.dat (7 B)" status.log
+  grep "	../../c/c.dat (7 B)" status.log
+
+  # filters still match paths from the root of the repository
+  [ "A  b/b.dat 7" = "$(git lfs status --porcelain --relative-to=a --include=a)" ]
+
+  git lfs status --relative --relative-to=a 2>&1 | tee status.log
+  grep "cannot be combined" status.log
+)
+end_test
+
+begin_test "status --ahead"
+(
+  set -e
+
+  reponame="status-ahead"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "ahead a" > a.dat
+  printf "ahead bb" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  # a.dat's object reaches the server, but the commit doesn't
+  git lfs push --object-id origin "$(calc_oid "ahead a")"
+
+  git lfs status --ahead origin 2>&1 | tee status.log
+  grep "1 Git LFS objects (8 B) to be pushed to origin" status.log
+  grep "1 more in unpushed commits are already on the server" status.log
+  [ "1 8" = "$(git lfs status --ahead origin --porcelain)" ]
+  [ "0 0" = "$(git lfs status --ahead origin --porcelain --exclude "b.dat")" ]
+
+  git push origin master
+  git lfs status --ahead origin 2>&1 | tee status.log
+  grep "0 Git LFS objects (0 B) to be pushed to origin" status.log
+  [ "1" = "$(wc -l < status.log | tr -d ' ')" ]
+
+  set +e
+  git lfs status --ahead origin --json 2>&1 | tee status.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "\-\-ahead cannot be combined with \-\-json or \-\-show-lock-owner" status.log
+
+  # a server which can't be reached is an error, not a count
+  printf "ahead c" > c.dat
+  git add c.dat
+  git commit -m "add c.dat"
+  git config lfs.url "http://127.0.0.1:1/$reponame.git/info/lfs"
+  set +e
+  git lfs status --ahead origin 2>&1 | tee status.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" = "2" ]
+  grep "Could not check which objects are on origin" status.log
+)
+end_test
+
+begin_test "status --limit and --sort"
+(
+  set -e
+
+  mkdir repo-limit
+  cd repo-limit
+  git init
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "ccc" > c.dat
+  git add .gitattributes a.dat c.dat
+  git commit -m "add files"
+
+  printf "bbbb" > b.dat
+  printf "dd" > d.dat
+  printf "eeeee" > e.dat
+  git add b.dat d.dat e.dat
+
+  expected="On branch master
+
+Git LFS objects to be committed:
+
+	e.dat (5 B)
+	b.dat (4 B)
+	... and 1 more not shown, see --limit
+
+Git LFS objects not staged for commit:"
+  [ "$expected" = "$(git lfs status --sort=size --limit=2)" ]
+
+  expected="On branch master
+
+Git LFS objects to be committed:
+
+	b.dat (4 B)
+	d.dat (2 B)
+	e.dat (5 B)
+
+Git LFS objects not staged for commit:"
+  [ "$expected" = "$(git lfs status --sort=path)" ]
+
+  # sorting applies to the other formats, limiting doesn't
+  [ "A  e.dat 5
+A  b.dat 4
+A  d.dat 2" = "$(git lfs status --porcelain --sort=size)" ]
+
+  git commit -m "add more files"
+  expected="Git LFS objects changed between HEAD~1 and HEAD:
+
+	added:    e.dat (5 B)
+	... and 2 more not shown, see --limit"
+  [ "$expected" = "$(git lfs status --sort=size --limit=1 HEAD~1..HEAD)" ]
+
+  git lfs status --json --sort=path HEAD~1..HEAD > status.json
+  [ "b.dat d.dat e.dat" = "$(grep '"name"' status.json | cut -d '"' -f 4 | xargs)" ]
+
+  git lfs status --porcelain --limit=1 2>&1 | tee status.log
+  grep -- "--limit cannot be combined with --porcelain or --json" status.log
+
+  git lfs status --sort=name 2>&1 | tee status.log
+  grep "Invalid --sort \"name\", expected size or path" status.log
+)
+end_test
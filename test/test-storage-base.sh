@@ -0,0 +1,92 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "lfs.storage.base"
+(
+  set -e
+
+  reponame="storage-base"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "base a" > a.dat
+  printf "base b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+  git push origin master
+
+  # move every object into a shared, read-only base store
+  base="$TRASHDIR/storage-base-objects"
+  mkdir -p "$base"
+  cp -R .git/lfs/objects/. "$base"
+  chmod -R a-w "$base"
+  rm -rf .git/lfs/objects
+  git config lfs.storage.base "$base"
+  base_files=$(find "$base" -type f | sort)
+
+  # reads come from the base store, without copying anything locally
+  rm a.dat b.dat
+  git checkout a.dat b.dat
+  [ "base a" = "$(cat a.dat)" ]
+  [ "base b" = "$(cat b.dat)" ]
+  refute_local_object "$(calc_oid "base a")"
+
+  git lfs fetch 2>&1 | tee fetch.log
+  refute_local_object "$(calc_oid "base a")"
+  refute_local_object "$(calc_oid "base b")"
+
+  git lfs fsck 2>&1 | tee fsck.log
+  grep "Git LFS fsck OK" fsck.log
+
+  # new objects are written to the local store
+  printf "local c" > c.dat
+  printf "base b modified" > b.dat
+  git add c.dat b.dat
+  git commit -m "add c.dat, modify b.dat"
+  assert_local_object "$(calc_oid "local c")" 7
+  assert_local_object "$(calc_oid "base b modified")" 15
+  git push origin master
+  assert_server_object "$reponame" "$(calc_oid "local c")"
+
+  # prune only deletes from the local store
+  git config lfs.pruneoffsetdays 0
+  git config lfs.fetchrecentcommitsdays 0
+  git config lfs.fetchrecentrefsdays 0
+  printf "local c modified" > c.dat
+  git add c.dat
+  git commit -m "modify c.dat"
+  git push origin master
+  git lfs prune 2>&1 | tee prune.log
+  refute_local_object "$(calc_oid "local c")"
+  assert_local_object "$(calc_oid "local c modified")" 16
+  [ "$base_files" = "$(find "$base" -type f | sort)" ]
+)
+end_test
+
+begin_test "lfs.storage.base: push objects only in the base store"
+(
+  set -e
+
+  reponame="storage-base-push"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "only in base" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  base="$TRASHDIR/storage-base-push-objects"
+  mkdir -p "$base"
+  mv .git/lfs/objects/* "$base"
+  git config lfs.storage.base "$base"
+  refute_local_object "$(calc_oid "only in base")"
+
+  git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  assert_server_object "$reponame" "$(calc_oid "only in base")"
+  refute_local_object "$(calc_oid "only in base")"
+)
+end_test
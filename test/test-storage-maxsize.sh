@@ -0,0 +1,115 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+# object_path prints the path of an object in the local store.
+object_path() {
+  local oid="$1"
+  echo ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+}
+
+begin_test "lfs.storage.maxsize"
+(
+  set -e
+
+  reponame="storage-maxsize"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  oid_a="$(calc_oid "version 01")"
+  oid_b="$(calc_oid "version 02")"
+  oid_c="$(calc_oid "version 03")"
+  oid_d="$(calc_oid "version 04")"
+
+  printf "version 01" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  printf "version 02" > a.dat
+  git add a.dat
+  git commit -m "modify a.dat"
+  printf "version 03" > a.dat
+  printf "version 04" > b.dat
+  git add a.dat b.dat
+  git commit -m "modify a.dat, add b.dat"
+  git push origin master
+
+  # the store is under the cap
+  git config lfs.storage.maxsize 40
+  git lfs fetch 2>&1 | tee fetch.log
+  [ "0" -eq "$(grep -c "Evicted" fetch.log)" ]
+
+  # using an object, as checking it out does, makes it recently used
+  touch -t 201601010000 "$(object_path "$oid_a")"
+  touch -t 201601020000 "$(object_path "$oid_b")"
+  git show HEAD~2:a.dat | git lfs smudge a.dat > smudged.dat
+  [ "version 01" = "$(cat smudged.dat)" ]
+
+  git config lfs.storage.maxsize 35
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Evicted 1 least recently used files (10 B) to keep the local store under lfs.storage.maxsize (35 B)" fetch.log
+  refute_local_object "$oid_b"
+  assert_local_object "$oid_a" 10
+
+  # objects needed by HEAD are kept, even over the cap
+  git config lfs.storage.maxsize 1
+  git lfs fetch 2>&1 | tee fetch.log
+  grep "Evicted 1 least recently used files (10 B)" fetch.log
+  grep "The local store is 20 B, over lfs.storage.maxsize (1 B)" fetch.log
+  refute_local_object "$oid_a"
+  assert_local_object "$oid_c" 10
+  assert_local_object "$oid_d" 10
+  [ "version 03" = "$(cat a.dat)" ]
+)
+end_test
+
+begin_test "lfs.storage.maxsize keeps unpushed and staged objects"
+(
+  set -e
+
+  reponame="storage-maxsize-unpushed"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  oid_pushed="$(calc_oid "pushed 001")"
+  oid_head="$(calc_oid "head 00001")"
+  oid_unpushed="$(calc_oid "unpushed 1")"
+  oid_staged="$(calc_oid "staged 001")"
+
+  printf "pushed 001" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  printf "head 00001" > a.dat
+  git add a.dat
+  git commit -m "modify a.dat"
+  git push origin master
+
+  git checkout -b unpushed
+  printf "unpushed 1" > b.dat
+  git add b.dat
+  git commit -m "add b.dat"
+  git checkout master
+
+  printf "staged 001" > c.dat
+  git add c.dat
+
+  for oid in "$oid_pushed" "$oid_head" "$oid_unpushed" "$oid_staged"; do
+    touch -t 201601010000 "$(object_path "$oid")"
+  done
+
+  git config lfs.storage.maxsize 1
+  git lfs pull 2>&1 | tee pull.log
+  grep "Evicted 1 least recently used files (10 B)" pull.log
+  grep "The local store is 30 B, over lfs.storage.maxsize (1 B)" pull.log
+  refute_local_object "$oid_pushed"
+  assert_local_object "$oid_head" 10
+  assert_local_object "$oid_unpushed" 10
+  assert_local_object "$oid_staged" 10
+
+  # an invalid cap is ignored
+  git config lfs.storage.maxsize 20gb
+  git lfs fetch 2>&1 | tee fetch.log
+  [ "0" -eq "$(grep -c "lfs.storage.maxsize" fetch.log)" ]
+)
+end_test
@@ -0,0 +1,93 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "lfs.tempdir"
+(
+  set -e
+
+  reponame="tempdir"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  scratch="$TRASHDIR/scratch"
+  git config lfs.tempdir "$scratch"
+
+  git lfs env | tee env.log
+  grep "TempDir=$scratch/git-lfs-" env.log
+
+  git lfs track "*.dat"
+  contents="tempdir"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  assert_local_object "$contents_oid" 7
+  git push origin master 2>&1 | tee push.log
+  grep "master -> master" push.log
+  assert_server_object "$reponame" "$contents_oid"
+
+  rm -rf .git/lfs/objects
+  git lfs fetch 2>&1 | tee fetch.log
+  assert_local_object "$contents_oid" 7
+
+  # nothing is written to the repository's own temp dir
+  [ ! -e .git/lfs/tmp ] || [ -z "$(find .git/lfs/tmp -type f)" ]
+  [ -d "$scratch"/git-lfs-*/incomplete ]
+)
+end_test
+
+begin_test "GIT_LFS_TMPDIR on a different filesystem"
+(
+  set -e
+
+  # /dev/shm is usually a tmpfs, so objects have to be copied into the store
+  # rather than renamed
+  scratch=$(mktemp -d /dev/shm/git-lfs-test.XXXXXX 2>/dev/null || mktemp -d "$TRASHDIR/scratch.XXXXXX")
+
+  reponame="tempdir-other-filesystem"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents="other filesystem"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  GIT_LFS_TMPDIR="$scratch" git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  assert_local_object "$contents_oid" 16
+  git push origin master 2>&1 | tee push.log
+  grep "master -> master" push.log
+
+  rm -rf .git/lfs/objects
+  GIT_LFS_TMPDIR="$scratch" git lfs fetch 2>&1 | tee fetch.log
+  assert_local_object "$contents_oid" 16
+
+  rm -rf "$scratch"
+)
+end_test
+
+begin_test "lfs.tempdir is not writable"
+(
+  set -e
+
+  reponame="tempdir-not-writable"
+  mkdir "$reponame"
+  cd "$reponame"
+  git init
+
+  touch not-a-dir
+  git config lfs.tempdir "$(pwd)/not-a-dir/tmp"
+
+  set +e
+  git lfs env 2>&1 | tee env.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" = "2" ]
+  grep "Unable to create the Git LFS temp directory" env.log
+  grep "not-a-dir/tmp" env.log
+)
+end_test
@@ -62,6 +62,35 @@ begin_test "track --verbose"
 )
 end_test
 
+begin_test "track --verbose (listing patterns)"
+(
+  set -e
+
+  reponame="track_verbose_listing"
+  mkdir "$reponame"
+  cd "$reponame"
+  git init
+
+  mkdir -p a/b
+  printf "*.jpg filter=lfs -text\n*.psd filter=lfs -text lockable\n" > .gitattributes
+  echo "*.mov filter=lfs -text" > .git/info/attributes
+  echo "*.jpg -filter" >> .git/info/attributes
+  echo "*.gif filter=lfs -text" > a/.gitattributes
+  echo "*.png filter=lfs -text" > a/b/.gitattributes
+
+  git lfs track --verbose > track.log
+  cat track.log
+
+  [ "Listing tracked patterns, highest precedence first" = "$(sed -n 1p track.log)" ]
+  [ "    *.mov ($(native_path ".git/info/attributes"):1)" = "$(sed -n 2p track.log)" ]
+  [ "    $(native_path "a/b/*.png") ($(native_path "a/b/.gitattributes"):1)" = "$(sed -n 3p track.log)" ]
+  [ "    $(native_path "a/*.gif") ($(native_path "a/.gitattributes"):1)" = "$(sed -n 4p track.log)" ]
+  [ "    *.psd (.gitattributes:2, lockable)" = "$(sed -n 5p track.log)" ]
+  [ "    *.jpg (.gitattributes:1, untracked by $(native_path ".git/info/attributes"):2)" = "$(sed -n 6p track.log)" ]
+  [ 6 -eq $(wc -l < track.log) ]
+)
+end_test
+
 begin_test "track --dry-run"
 (
   set -e
@@ -293,3 +322,85 @@ begin_test "track blocklisted files with glob"
 )
 end_test
 
+
+begin_test "track --filename"
+(
+  set -e
+
+  repo="track_filename"
+  mkdir "$repo"
+  cd "$repo"
+  git init
+
+  for name in "foo[1].bin" "star*.bin" "what?.bin" "close].bin" '!bang.bin' "#hash.bin"; do
+    printf "$name" > "$name"
+  done
+  # would be matched by the unescaped patterns above
+  printf "decoy" > "foo1.bin"
+  printf "decoy" > "starfish.bin"
+  printf "decoy" > "whatx.bin"
+  git add *.bin
+
+  git lfs track --filename "foo[1].bin" | tee track.log
+  grep -F 'Tracking foo\[1\].bin' track.log
+  git lfs track --filename "star*.bin" "what?.bin" "close].bin" '!bang.bin' "#hash.bin"
+  git lfs track --filename "foo[1].bin" | grep "already supported"
+
+  grep -F 'foo\[1\].bin filter=lfs diff=lfs merge=lfs -text' .gitattributes
+  grep -F 'star\*.bin filter=lfs' .gitattributes
+  grep -F 'what\?.bin filter=lfs' .gitattributes
+  grep -F 'close\].bin filter=lfs' .gitattributes
+  grep -F '\!bang.bin filter=lfs' .gitattributes
+  grep -F '\#hash.bin filter=lfs' .gitattributes
+
+  for name in "foo[1].bin" "star*.bin" "what?.bin" "close].bin" '!bang.bin' "#hash.bin"; do
+    [ "$name: filter: lfs" = "$(git check-attr filter -- "$name")" ]
+  done
+
+  for name in "foo1.bin" "starfish.bin" "whatx.bin"; do
+    [ "$name: filter: unspecified" = "$(git check-attr filter -- "$name")" ]
+  done
+)
+end_test
+
+begin_test "track --print-match"
+(
+  set -e
+
+  repo="track_print_match"
+  mkdir "$repo"
+  cd "$repo"
+  git init
+
+  git lfs track "*.psd" "*.dat"
+  printf "*.psd lockable\n" >> .gitattributes
+  mkdir -p art/raw
+  printf "*.psd -filter\n" > art/raw/.gitattributes
+  printf "*.dat binary\n" > .git/info/attributes
+
+  git lfs track --print-match a.psd | tee match.log
+  grep "^a.psd$" match.log
+  grep "    filter: lfs (.gitattributes:1)" match.log
+  grep "    diff: lfs (.gitattributes:1)" match.log
+  grep "    text: unset (.gitattributes:1)" match.log
+  grep "    lockable: set (.gitattributes:3)" match.log
+
+  # a deeper .gitattributes wins
+  git lfs track --print-match art/raw/b.psd | tee match.log
+  grep "    filter: unset (art/raw/.gitattributes:1)" match.log
+  grep "    lockable: set (.gitattributes:3)" match.log
+
+  # and the repository's info/attributes wins over both
+  git lfs track --print-match c.dat | tee match.log
+  grep "    filter: lfs (.gitattributes:2)" match.log
+  grep "    diff: unset (.git/info/attributes:1)" match.log
+  grep "    merge: unset (.git/info/attributes:1)" match.log
+
+  # paths are relative to the current directory
+  cd art
+  git lfs track --print-match raw/b.psd other.txt | tee match.log
+  grep "    filter: unset (art/raw/.gitattributes:1)" match.log
+  grep "^other.txt$" match.log
+  grep "    filter: unspecified$" match.log
+)
+end_test
@@ -106,3 +106,29 @@ begin_test "uninstall hooks inside repository"
   [ "git-lfs clean -- %f" = "$(git config filter.lfs.clean)" ]
 )
 end_test
+
+begin_test "uninstall hooks with core.hooksPath"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-hooks-path"
+  mkdir "$reponame"
+  cd "$reponame"
+  git init
+  git config core.hooksPath "$(pwd)/shared-hooks"
+
+  git lfs install
+  [ -f shared-hooks/pre-push ]
+  git lfs uninstall hooks
+  [ ! -e shared-hooks/pre-push ]
+
+  # only what Git LFS added to a chained hook is removed
+  existing="#!/bin/sh
+echo existing"
+  printf "%s\n" "$existing" > shared-hooks/pre-push
+  git lfs install
+  grep "git lfs pre-push" shared-hooks/pre-push
+  git lfs uninstall hooks
+  [ "$existing" = "$(cat shared-hooks/pre-push)" ]
+)
+end_test
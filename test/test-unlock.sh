@@ -50,3 +50,32 @@ begin_test "unlocking a lock without sufficient info"
   assert_server_lock $id
 )
 end_test
+
+begin_test "unlocking a lock by id after its path is deleted"
+(
+  set -e
+
+  setup_remote_repo_with_file "unlock_by_id_deleted" "f.dat"
+
+  GITLFSLOCKSENABLED=1 git lfs lock "f.dat" | tee lock.log
+
+  id=$(grep -oh "\((.*)\)" lock.log | tr -d "()")
+  assert_server_lock $id
+
+  git rm f.dat
+  git commit -m "remove f.dat"
+  [ ! -e f.dat ]
+
+  set +e
+  GITLFSLOCKSENABLED=1 git lfs unlock "f.dat" 2>&1 | tee unlock.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" != "0" ]
+  grep "Unable to unlock f.dat" unlock.log
+  assert_server_lock $id
+
+  GITLFSLOCKSENABLED=1 git lfs unlock --id="$id" --force 2>&1 | tee unlock.log
+  grep "'f.dat' was unlocked ($id)" unlock.log
+  refute_server_lock $id
+)
+end_test
@@ -50,3 +50,33 @@ begin_test "untrack outside git repo"
   fi
 )
 end_test
+
+begin_test "untrack --dry-run"
+(
+  set -e
+
+  reponame="untrack-dry-run"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.jpg" "big*" > /dev/null
+  echo "* annex.backend=SHA512E" >> .gitattributes
+  echo "a" > a.jpg
+  echo "b" > big.jpg
+  echo "c" > big.txt
+  git add .gitattributes a.jpg big.jpg big.txt
+  git commit -m "add files"
+  before="$(cat .gitattributes)"
+
+  git lfs untrack --dry-run "*.jpg" 2>&1 | tee untrack.log
+  grep "Would remove .gitattributes:1: \*.jpg filter=lfs diff=lfs merge=lfs -text" untrack.log
+  grep -A1 "Files which would no longer be Git LFS files:" untrack.log | grep "    a.jpg"
+  grep -A1 "Files which stay tracked by another pattern:" untrack.log | grep "    big.jpg (big\* at .gitattributes:2)"
+  [ "0" = "$(grep -c "big.txt" untrack.log)" ]
+  [ "$before" = "$(cat .gitattributes)" ]
+
+  git lfs untrack -d "*.png" 2>&1 | tee untrack.log
+  grep "Nothing to untrack" untrack.log
+  [ "$before" = "$(cat .gitattributes)" ]
+)
+end_test
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -59,7 +60,8 @@ func ResolveSymlinks(path string) string {
 }
 
 // RenameFileCopyPermissions moves srcfile to destfile, replacing destfile if
-// necessary and also copying the permissions of destfile if it already exists
+// necessary and also copying the permissions of destfile if it already exists.
+// If srcfile is on a different filesystem, it is copied and then removed.
 func RenameFileCopyPermissions(srcfile, destfile string) error {
 	info, err := os.Stat(destfile)
 	if os.IsNotExist(err) {
@@ -73,11 +75,53 @@ func RenameFileCopyPermissions(srcfile, destfile string) error {
 	}
 
 	if err := os.Rename(srcfile, destfile); err != nil {
+		if isCrossDeviceError(err) {
+			return moveFileAcrossDevices(srcfile, destfile)
+		}
 		return fmt.Errorf("cannot replace %q with %q: %v", destfile, srcfile, err)
 	}
 	return nil
 }
 
+// moveFileAcrossDevices copies srcfile to a temp file beside destfile, renames
+// that over destfile so that destfile is replaced atomically, and then removes
+// srcfile.
+func moveFileAcrossDevices(srcfile, destfile string) error {
+	src, err := os.Open(srcfile)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(destfile), filepath.Base(destfile))
+	if err != nil {
+		return fmt.Errorf("cannot copy %q to %q: %v", srcfile, destfile, err)
+	}
+
+	_, err = io.Copy(tmp, src)
+	if err == nil {
+		err = tmp.Chmod(info.Mode())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), destfile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cannot copy %q to %q: %v", srcfile, destfile, err)
+	}
+
+	src.Close()
+	return os.Remove(srcfile)
+}
+
 // CleanPaths splits the given `paths` argument by the delimiter argument, and
 // then "cleans" that path according to the path.Clean function (see
 // https://golang.org/pkg/path#Clean).
@@ -1,6 +1,9 @@
 package tools_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/github/git-lfs/tools"
@@ -30,3 +33,59 @@ func TestCleanPathsDefaultReturnsDefaultWhenResultsAbsent(t *testing.T) {
 
 	assert.Equal(t, []string{"/default"}, cleaned)
 }
+
+func TestRenameFileCopyPermissionsReplacesDestination(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rename")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	ioutil.WriteFile(src, []byte("new"), 0644)
+	ioutil.WriteFile(dest, []byte("old"), 0600)
+
+	assert.Nil(t, tools.RenameFileCopyPermissions(src, dest))
+
+	by, _ := ioutil.ReadFile(dest)
+	assert.Equal(t, "new", string(by))
+	info, _ := os.Stat(dest)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	_, err = os.Stat(src)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRenameFileCopyPermissionsAcrossFilesystems(t *testing.T) {
+	// /dev/shm is usually a tmpfs, separate from the default temp dir
+	if info, err := os.Stat("/dev/shm"); err != nil || !info.IsDir() {
+		t.Skip("no /dev/shm to move files from")
+	}
+
+	srcDir, err := ioutil.TempDir("/dev/shm", "rename")
+	if err != nil {
+		t.Skip("unable to write to /dev/shm")
+	}
+	defer os.RemoveAll(srcDir)
+
+	destDir, err := ioutil.TempDir("", "rename")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(destDir)
+
+	src := filepath.Join(srcDir, "src")
+	dest := filepath.Join(destDir, "dest")
+	ioutil.WriteFile(src, []byte("moved"), 0644)
+
+	assert.Nil(t, tools.RenameFileCopyPermissions(src, dest))
+
+	by, _ := ioutil.ReadFile(dest)
+	assert.Equal(t, "moved", string(by))
+	_, err = os.Stat(src)
+	assert.True(t, os.IsNotExist(err))
+
+	// only the moved file is left behind, not the temp file it was copied to
+	files, _ := ioutil.ReadDir(destDir)
+	assert.Equal(t, 1, len(files))
+}
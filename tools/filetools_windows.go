@@ -0,0 +1,19 @@
+// +build windows
+
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when moving a file to
+// a different disk drive.
+const errorNotSameDevice = syscall.Errno(17)
+
+// isCrossDeviceError returns whether err is from renaming a file onto a
+// different filesystem.
+func isCrossDeviceError(err error) bool {
+	linkErr, ok := err.(*os.LinkError)
+	return ok && linkErr.Err == errorNotSameDevice
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,3 +28,17 @@ func TestCopyWithCallback(t *testing.T) {
 	assert.Len(t, calledWritten, 1)
 	assert.Equal(t, 5, int(calledWritten[0]))
 }
+
+func TestCopyWithCallbackCountsBytesReadWithEOF(t *testing.T) {
+	// the last read returns its bytes along with io.EOF
+	reader := iotest.DataErrReader(bytes.NewBufferString("BOOYA"))
+
+	var counted int
+	n, err := CopyWithCallback(ioutil.Discard, reader, 5, func(total int64, written int64, current int) error {
+		counted += current
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, int(n))
+	assert.Equal(t, 5, counted)
+}
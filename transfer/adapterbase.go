@@ -1,10 +1,13 @@
 package transfer
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/github/git-lfs/config"
 	"github.com/github/git-lfs/errutil"
 	"github.com/rubyist/tracerx"
 )
@@ -15,6 +18,10 @@ const (
 	objectExpirationGracePeriod = 5 * time.Second
 )
 
+// ErrAborted is the error of transfers which were never started because the
+// adapter was aborted.
+var ErrAborted = errors.New("lfs/transfer: transfer aborted")
+
 // adapterBase implements the common functionality for core adapters which
 // process transfers with N workers handling an oid each, and which wait for
 // authentication to succeed on one worker before proceeding
@@ -29,6 +36,14 @@ type adapterBase struct {
 	workerWait sync.WaitGroup
 	// WaitGroup to serialise the first transfer response to perform login if needed
 	authWait sync.WaitGroup
+	// limiter adjusts how many workers transfer at once when
+	// lfs.transfer.adaptive is enabled, nil otherwise
+	limiter *adaptiveLimiter
+	// bandwidth limits the rate of the workers' transfers, nil if unlimited
+	bandwidth *BandwidthLimiter
+	// aborted is set to 1 by Abort, after which queued transfers are not
+	// started
+	aborted int32
 }
 
 // transferImplementation must be implemented to provide the actual upload/download
@@ -61,6 +76,10 @@ func (a *adapterBase) Direction() Direction {
 	return a.direction
 }
 
+func (a *adapterBase) SetBandwidthLimiter(l *BandwidthLimiter) {
+	a.bandwidth = l
+}
+
 func (a *adapterBase) Begin(maxConcurrency int, cb TransferProgressCallback, completion chan TransferResult) error {
 	a.cb = cb
 	a.outChan = completion
@@ -68,6 +87,12 @@ func (a *adapterBase) Begin(maxConcurrency int, cb TransferProgressCallback, com
 
 	tracerx.Printf("xfer: adapter %q Begin() with %d workers", a.Name(), maxConcurrency)
 
+	atomic.StoreInt32(&a.aborted, 0)
+	a.limiter = nil
+	if config.Config.AdaptiveTransfers() {
+		a.limiter = newAdaptiveLimiter(maxConcurrency)
+	}
+
 	a.workerWait.Add(maxConcurrency)
 	a.authWait.Add(1)
 	for i := 0; i < maxConcurrency; i++ {
@@ -86,6 +111,12 @@ func (a *adapterBase) Add(t *Transfer) {
 	a.jobChan <- t
 }
 
+func (a *adapterBase) Abort() {
+	if atomic.SwapInt32(&a.aborted, 1) == 0 {
+		tracerx.Printf("xfer: adapter %q Abort()", a.Name())
+	}
+}
+
 func (a *adapterBase) End() {
 	tracerx.Printf("xfer: adapter %q End()", a.Name())
 	close(a.jobChan)
@@ -114,6 +145,17 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 		tracerx.Printf("xfer: adapter %q worker %d auth signal received", a.Name(), workerNum)
 	}
 
+	// Bytes transferred wait for the bandwidth limit; those reported with
+	// advanceCallbackProgress(a.cb, ...) don't
+	cb := a.cb
+	if a.bandwidth != nil && cb != nil {
+		cb = func(name string, totalSize, readSoFar int64, readSinceLast int) error {
+			err := a.cb(name, totalSize, readSoFar, readSinceLast)
+			a.bandwidth.Wait(readSinceLast)
+			return err
+		}
+	}
+
 	for t := range a.jobChan {
 		var authCallback func()
 		if signalAuthOnResponse {
@@ -123,17 +165,27 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 			}
 		}
 		tracerx.Printf("xfer: adapter %q worker %d processing job for %q", a.Name(), workerNum, t.Object.Oid)
+		if args := t.Args(a.direction); len(args) > 0 {
+			tracerx.Printf("xfer: adapter %q worker %d job for %q has args %v", a.Name(), workerNum, t.Object.Oid, args)
+		}
 
 		// Actual transfer happens here
 		var err error
-		if t.Object.IsExpired(time.Now().Add(objectExpirationGracePeriod)) {
+		if atomic.LoadInt32(&a.aborted) == 1 {
+			tracerx.Printf("xfer: adapter %q worker %d skipping job for %q, aborted", a.Name(), workerNum, t.Object.Oid)
+			err = ErrAborted
+		} else if t.Object.IsExpired(time.Now().Add(objectExpirationGracePeriod)) {
 			tracerx.Printf("xfer: adapter %q worker %d found job for %q expired, retrying...", a.Name(), workerNum, t.Object.Oid)
 			err = errutil.NewRetriableError(fmt.Errorf("lfs/transfer: object %q has expired", t.Object.Oid))
 		} else if t.Object.Size < 0 {
 			tracerx.Printf("xfer: adapter %q worker %d found invalid size for %q (got: %d), retrying...", a.Name(), workerNum, t.Object.Oid, t.Object.Size)
 			err = fmt.Errorf("Git LFS: object %q has invalid size (got: %d)", t.Object.Oid, t.Object.Size)
+		} else if a.limiter != nil {
+			token := a.limiter.Acquire()
+			err = a.transferImpl.DoTransfer(ctx, t, cb, authCallback)
+			a.limiter.Release(token, err)
 		} else {
-			err = a.transferImpl.DoTransfer(ctx, t, a.cb, authCallback)
+			err = a.transferImpl.DoTransfer(ctx, t, cb, authCallback)
 		}
 
 		if a.outChan != nil {
@@ -152,6 +204,9 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 	a.workerWait.Done()
 }
 
+// advanceCallbackProgress reports numBytes of t as done in one go. Adapters
+// pass a.cb, rather than the callback given to DoTransfer, for bytes which
+// didn't need transferring, so that they don't wait for the bandwidth limit.
 func advanceCallbackProgress(cb TransferProgressCallback, t *Transfer, numBytes int64) {
 	if cb != nil {
 		// Must split into max int sizes since read count is int
@@ -0,0 +1,102 @@
+package transfer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/github/git-lfs/errutil"
+	"github.com/rubyist/tracerx"
+)
+
+// adaptiveLimiter limits how many of an adapter's workers may transfer at
+// once, adjusting the limit AIMD-style as the server responds:
+//
+//   - It starts at the adapter's maximum concurrency.
+//   - When a transfer fails with a 429 or 5xx response, the limit is halved,
+//     down to a minimum of 1. Transfers which started before the last
+//     decrease don't decrease it again, so a burst of failures from workers
+//     which were all in flight at the same time only halves it once.
+//   - After as many transfers in a row have succeeded as the current limit,
+//     the limit goes up by 1, up to the maximum.
+//
+// Workers call Acquire before each transfer, and Release with its result.
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	max       int
+	limit     int
+	active    int
+	successes int
+	// epoch counts the decreases, so that Release can tell whether a
+	// transfer started before the most recent one
+	epoch int
+}
+
+func newAdaptiveLimiter(max int) *adaptiveLimiter {
+	if max < 1 {
+		max = 1
+	}
+
+	l := &adaptiveLimiter{max: max, limit: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire blocks until a transfer may start, and returns a token to pass to
+// Release when it is done.
+func (l *adaptiveLimiter) Acquire() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	return l.epoch
+}
+
+// Release records the result of a transfer started with the given token, and
+// lets another transfer start.
+func (l *adaptiveLimiter) Release(token int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+
+	if isBackpressureError(err) {
+		l.successes = 0
+		if token == l.epoch && l.limit > 1 {
+			l.limit /= 2
+			l.epoch++
+			tracerx.Printf("xfer: server is struggling (%s), reducing concurrency to %d", errutil.ErrorGetContext(err, "Status"), l.limit)
+		}
+	} else if err == nil {
+		l.successes++
+		if l.successes >= l.limit && l.limit < l.max {
+			l.limit++
+			l.successes = 0
+			tracerx.Printf("xfer: increasing concurrency to %d", l.limit)
+		}
+	}
+
+	l.cond.Broadcast()
+}
+
+// Limit returns the number of transfers allowed at once.
+func (l *adaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// isBackpressureError returns whether err came from a 429 or 5xx response,
+// meaning that the server would rather be sent fewer requests.
+func isBackpressureError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	status := fmt.Sprintf("%v", errutil.ErrorGetContext(err, "Status"))
+	return strings.HasPrefix(status, "429") || (len(status) > 0 && status[0] == '5')
+}
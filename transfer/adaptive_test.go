@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/github/git-lfs/errutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func statusError(status string) error {
+	err := errutil.Error(errors.New("request failed"))
+	errutil.ErrorSetContext(err, "Status", status)
+	return err
+}
+
+func TestIsBackpressureError(t *testing.T) {
+	assert.True(t, isBackpressureError(statusError("429 Too Many Requests")))
+	assert.True(t, isBackpressureError(statusError("503 Service Unavailable")))
+	assert.True(t, isBackpressureError(errutil.NewRetriableError(statusError("500 Internal Server Error"))))
+	assert.False(t, isBackpressureError(statusError("404 Not Found")))
+	assert.False(t, isBackpressureError(errors.New("connection refused")))
+	assert.False(t, isBackpressureError(nil))
+}
+
+func TestAdaptiveLimiterHalvesOncePerBurst(t *testing.T) {
+	l := newAdaptiveLimiter(8)
+
+	var tokens []int
+	for i := 0; i < 8; i++ {
+		tokens = append(tokens, l.Acquire())
+	}
+
+	// every worker in flight sees the server struggle, but that is one
+	// burst, so the limit is only halved once
+	for _, token := range tokens {
+		l.Release(token, statusError("503 Service Unavailable"))
+	}
+	assert.Equal(t, 4, l.Limit())
+
+	// a transfer started after the decrease halves it again
+	l.Release(l.Acquire(), statusError("429 Too Many Requests"))
+	assert.Equal(t, 2, l.Limit())
+
+	l.Release(l.Acquire(), statusError("503 Service Unavailable"))
+	l.Release(l.Acquire(), statusError("503 Service Unavailable"))
+	assert.Equal(t, 1, l.Limit())
+}
+
+func TestAdaptiveLimiterRampsUpOnSustainedSuccess(t *testing.T) {
+	l := newAdaptiveLimiter(4)
+	l.Release(l.Acquire(), statusError("503 Service Unavailable"))
+	assert.Equal(t, 2, l.Limit())
+
+	l.Release(l.Acquire(), nil)
+	assert.Equal(t, 2, l.Limit())
+	l.Release(l.Acquire(), nil)
+	assert.Equal(t, 3, l.Limit())
+
+	// other errors neither count as successes nor back off
+	l.Release(l.Acquire(), errors.New("not found"))
+	assert.Equal(t, 3, l.Limit())
+
+	for i := 0; i < 3; i++ {
+		l.Release(l.Acquire(), nil)
+	}
+	assert.Equal(t, 4, l.Limit())
+
+	// never above the configured maximum
+	for i := 0; i < 20; i++ {
+		l.Release(l.Acquire(), nil)
+	}
+	assert.Equal(t, 4, l.Limit())
+}
+
+func TestAdaptiveLimiterBacksOffUnderServerBackpressure(t *testing.T) {
+	// a server which rejects requests beyond 2 at a time
+	const capacity = 2
+	const workers = 8
+	const jobs = 200
+
+	l := newAdaptiveLimiter(workers)
+	var inflight, maxInflight, rejected int32
+	jobc := make(chan int, jobs)
+	for i := 0; i < jobs; i++ {
+		jobc <- i
+	}
+	close(jobc)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for range jobc {
+				token := l.Acquire()
+				n := atomic.AddInt32(&inflight, 1)
+				for {
+					max := atomic.LoadInt32(&maxInflight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+						break
+					}
+				}
+
+				var err error
+				if n > capacity {
+					atomic.AddInt32(&rejected, 1)
+					err = statusError("429 Too Many Requests")
+				}
+				time.Sleep(time.Millisecond)
+
+				atomic.AddInt32(&inflight, -1)
+				l.Release(token, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, maxInflight <= workers)
+	assert.True(t, l.Limit() <= workers)
+	// without backing off, nearly every request beyond the first few
+	// would be rejected
+	assert.True(t, rejected < jobs/2, "%d of %d requests rejected", rejected, jobs)
+}
@@ -0,0 +1,50 @@
+package transfer
+
+import (
+	"sync"
+	"time"
+)
+
+// BandwidthLimiter limits the combined rate of the transfers sharing it. Each
+// transfer calls Wait with the bytes it has just sent or received, from its
+// progress callback, which sleeps until those bytes are within the rate. The
+// transfers then read or write no faster than the limit between them.
+type BandwidthLimiter struct {
+	mu   sync.Mutex
+	rate int64
+	// next is when the bytes waited for so far are within the rate
+	next time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewBandwidthLimiter returns a limiter for the given rate in bytes per
+// second, or nil, which doesn't limit, if the rate isn't positive.
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &BandwidthLimiter{rate: bytesPerSecond, now: time.Now, sleep: time.Sleep}
+}
+
+// Wait blocks until n more bytes may be transferred.
+func (l *BandwidthLimiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	if l.next.Before(now) {
+		// Time spent idle isn't saved up to be spent in a burst
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+
+	if delay > 0 {
+		l.sleep(delay)
+	}
+}
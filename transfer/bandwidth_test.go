@@ -0,0 +1,106 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/github/git-lfs/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClockLimiter returns a limiter whose clock only moves when it sleeps,
+// and the sleeps it made.
+func fakeClockLimiter(rate int64) (*BandwidthLimiter, *time.Time, *[]time.Duration) {
+	now := time.Unix(0, 0)
+	var sleeps []time.Duration
+
+	l := NewBandwidthLimiter(rate)
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+	return l, &now, &sleeps
+}
+
+func TestBandwidthLimiterUnlimited(t *testing.T) {
+	assert.Nil(t, NewBandwidthLimiter(0))
+
+	var l *BandwidthLimiter
+	l.Wait(1024)
+}
+
+func TestBandwidthLimiterPacesTransfers(t *testing.T) {
+	l, _, sleeps := fakeClockLimiter(1000)
+
+	l.Wait(500)
+	l.Wait(500)
+	l.Wait(250)
+
+	assert.Equal(t, []time.Duration{
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+		250 * time.Millisecond,
+	}, *sleeps)
+}
+
+func TestBandwidthLimiterSharesRate(t *testing.T) {
+	l, _, sleeps := fakeClockLimiter(1000)
+
+	// two transfers report progress at the same moment, so the second
+	// waits for the first's share too
+	l.sleep = func(d time.Duration) { *sleeps = append(*sleeps, d) }
+	l.Wait(500)
+	l.Wait(500)
+
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, *sleeps)
+}
+
+func TestBandwidthLimiterDoesNotSaveIdleTime(t *testing.T) {
+	l, now, sleeps := fakeClockLimiter(1000)
+
+	l.Wait(100)
+	*now = now.Add(time.Minute)
+	l.Wait(100)
+
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, *sleeps)
+}
+
+// localProgressAdapter reports half of each object as already held locally,
+// and transfers the other half.
+type localProgressAdapter struct {
+	*adapterBase
+}
+
+func (a *localProgressAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *localProgressAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+func (a *localProgressAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+	advanceCallbackProgress(a.cb, t, t.Object.Size/2)
+	return cb(t.Name, t.Object.Size, t.Object.Size, int(t.Object.Size/2))
+}
+
+func TestBandwidthLimiterIgnoresLocalProgress(t *testing.T) {
+	l, _, sleeps := fakeClockLimiter(1000)
+
+	a := &localProgressAdapter{newAdapterBase("local", Download, nil)}
+	a.transferImpl = a
+	a.SetBandwidthLimiter(l)
+
+	var reported int64
+	cb := func(name string, total, read int64, current int) error {
+		reported += int64(current)
+		return nil
+	}
+	a.Begin(1, cb, nil)
+	a.Add(NewTransfer("a.dat", &api.ObjectResource{Oid: "a", Size: 2000}, "a.dat"))
+	a.End()
+
+	assert.Equal(t, int64(2000), reported)
+	assert.Equal(t, []time.Duration{time.Second}, *sleeps)
+}
@@ -31,7 +31,7 @@ func (a *basicDownloadAdapter) tempDir() string {
 	// Must be dedicated to this adapter as deleted by ClearTempStorage
 	// Also make local to this repo not global, and separate to localstorage temp,
 	// which gets cleared at the end of every invocation
-	d := filepath.Join(localstorage.Objects().RootDir, "incomplete")
+	d := localstorage.IncompleteDir
 	if err := os.MkdirAll(d, 0755); err != nil {
 		return os.TempDir()
 	}
@@ -110,7 +110,8 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", fromByte, t.Object.Size-1))
 	}
 
-	res, err := httputil.DoHttpRequest(config.Config, req, true)
+	// Pre-signed URLs carry their own auth, so don't send creds along with them
+	res, err := httputil.DoHttpRequest(config.Config, req, !rel.IsSigned("download"))
 	if err != nil {
 		// Special-case status code 416 () - fall back
 		if fromByte > 0 && dlFile != nil && res.StatusCode == 416 {
@@ -152,7 +153,7 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb TransferProgressCallback
 		}
 		if rangeRequestOk {
 			tracerx.Printf("xfer: server accepted resume download request: %q from byte %d", t.Object.Oid, fromByte)
-			advanceCallbackProgress(cb, t, fromByte)
+			advanceCallbackProgress(a.cb, t, fromByte)
 		} else {
 			// Abort resume, perform regular download
 			tracerx.Printf("xfer: failed to resume download for %q from byte %d: %s. Re-downloading from start", t.Object.Oid, fromByte, failReason)
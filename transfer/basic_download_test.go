@@ -0,0 +1,73 @@
+package transfer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/auth"
+	"github.com/github/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasicDownloadOmitsCredsForSignedUrl(t *testing.T) {
+	oldCreds := auth.SetCredentialsFunc(func(cfg *config.Configuration, input auth.Creds, subCommand string) (auth.Creds, error) {
+		return auth.Creds{"username": "user", "password": "pass"}, nil
+	})
+	defer auth.SetCredentialsFunc(oldCreds)
+
+	apiServer := httptest.NewServer(http.NotFoundHandler())
+	defer apiServer.Close()
+
+	content := []byte("test")
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	var requests int
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Equal(t, "abc", r.URL.Query().Get("X-Amz-Signature"))
+		w.Write(content)
+	}))
+	defer storage.Close()
+
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.url", apiServer.URL+"/media")
+
+	dir, err := ioutil.TempDir("", "git-lfs-basic-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dlFile, err := os.Create(filepath.Join(dir, oid+".tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := &api.ObjectResource{
+		Oid:  oid,
+		Size: int64(len(content)),
+		Actions: map[string]*api.LinkRelation{
+			"download": &api.LinkRelation{
+				Href: storage.URL + "/objects/" + oid + "?X-Amz-Signature=abc",
+			},
+		},
+	}
+	tr := NewTransfer("test.dat", obj, filepath.Join(dir, "test.dat"))
+
+	a := &basicDownloadAdapter{newAdapterBase(BasicAdapterName, Download, nil)}
+	if err := a.download(tr, nil, nil, dlFile, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, requests)
+
+	by, err := ioutil.ReadFile(tr.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, content, by)
+}
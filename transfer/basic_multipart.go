@@ -0,0 +1,335 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/httputil"
+	"github.com/github/git-lfs/progress"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	// maxPartAttempts is how many times each part of a multipart upload is
+	// tried before the whole upload is given up on.
+	maxPartAttempts = 3
+)
+
+// multipartPart is a completed part of a multipart upload, as reported to the
+// server when the upload is completed.
+type multipartPart struct {
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+type multipartInitRequest struct {
+	Oid      string `json:"oid"`
+	Size     int64  `json:"size"`
+	PartSize int64  `json:"part_size"`
+	Parts    int    `json:"parts"`
+}
+
+type multipartInitResponse struct {
+	PartUrls []string `json:"part_urls"`
+}
+
+type multipartCompleteRequest struct {
+	Oid   string           `json:"oid"`
+	Size  int64            `json:"size"`
+	Parts []*multipartPart `json:"parts"`
+}
+
+// multipartProgress adds up the progress of the concurrent parts of one upload
+// and reports it through a single callback. A part which is retried is read
+// again from its start, and its bytes are not reported again until it passes
+// what the failed attempt sent.
+type multipartProgress struct {
+	mu       sync.Mutex
+	cb       progress.CopyCallback
+	size     int64
+	sent     int64
+	reported []int64
+}
+
+func (p *multipartProgress) callback(part int) progress.CopyCallback {
+	return func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if readSoFar <= p.reported[part] {
+			return nil
+		}
+
+		delta := readSoFar - p.reported[part]
+		p.reported[part] = readSoFar
+		p.sent += delta
+		return p.cb(p.size, p.sent, int(delta))
+	}
+}
+
+// isMultipartUpload returns whether the server asked for rel to be uploaded in
+// parts, by including a part size in its args.
+func isMultipartUpload(rel *api.LinkRelation) bool {
+	partSize, ok := rel.ArgInt("part_size")
+	return ok && partSize > 0
+}
+
+// multipartUpload uploads t in parts of the size given in the upload action's
+// args. The part URLs come either from the action's "part_urls" arg, or from
+// POSTing to its "init_url" arg. Parts are uploaded concurrently and retried
+// on their own, then the upload is completed by POSTing the parts' ETags to
+// the action's href. If a part still fails with a retriable error, so does the
+// upload, for the transfer queue to retry the object with fresh part URLs.
+func (a *basicUploadAdapter) multipartUpload(t *Transfer, rel *api.LinkRelation, cb TransferProgressCallback, authOkFunc func()) error {
+	partSize, _ := rel.ArgInt("part_size")
+	numParts := int((t.Object.Size + partSize - 1) / partSize)
+	if numParts == 0 {
+		numParts = 1
+	}
+
+	useCreds := !rel.IsSigned("upload")
+
+	partUrls, ok := rel.ArgStrings("part_urls")
+	if !ok {
+		var err error
+		partUrls, err = a.initMultipart(t, rel, partSize, numParts, useCreds)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(partUrls) != numParts {
+		return errutil.Errorf(nil, "Server sent %d part URLs for the %d parts of %s", len(partUrls), numParts, t.Object.Oid)
+	}
+
+	f, err := os.OpenFile(t.Path, os.O_RDONLY, 0644)
+	if err != nil {
+		return errutil.Error(err)
+	}
+	defer f.Close()
+
+	tracerx.Printf("xfer: uploading %q in %d parts of %d bytes", t.Object.Oid, numParts, partSize)
+
+	prog := &multipartProgress{
+		cb: func(totalSize int64, readSoFar int64, readSinceLast int) error {
+			if cb != nil {
+				return cb(t.Name, totalSize, readSoFar, readSinceLast)
+			}
+			return nil
+		},
+		size:     t.Object.Size,
+		reported: make([]int64, numParts),
+	}
+
+	var authOnce sync.Once
+	authOk := func() {
+		if authOkFunc != nil {
+			authOnce.Do(authOkFunc)
+		}
+	}
+
+	concurrency := config.Config.ConcurrentTransfers()
+	if concurrency > numParts {
+		concurrency = numParts
+	}
+
+	parts := make([]*multipartPart, numParts)
+	errs := make([]error, numParts)
+	partc := make(chan int, numParts)
+	for i := 0; i < numParts; i++ {
+		partc <- i
+	}
+	close(partc)
+
+	var failed bool
+	var failedMu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range partc {
+				failedMu.Lock()
+				stop := failed
+				failedMu.Unlock()
+				if stop {
+					return
+				}
+
+				offset := int64(i) * partSize
+				length := partSize
+				if offset+length > t.Object.Size {
+					length = t.Object.Size - offset
+				}
+
+				section := io.NewSectionReader(f, offset, length)
+				parts[i], errs[i] = a.uploadPart(t, i, partUrls[i], section, length, prog, authOk)
+				if errs[i] != nil {
+					failedMu.Lock()
+					failed = true
+					failedMu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.completeMultipart(t, rel, parts, useCreds)
+}
+
+// initMultipart asks the server for the URLs to upload each part of t to.
+func (a *basicUploadAdapter) initMultipart(t *Transfer, rel *api.LinkRelation, partSize int64, numParts int, useCreds bool) ([]string, error) {
+	initUrl, ok := rel.ArgString("init_url")
+	if !ok {
+		return nil, errutil.Errorf(nil, "Multipart upload of %s has neither part URLs nor an init URL", t.Object.Oid)
+	}
+
+	res, err := doMultipartJSON(initUrl, rel.Header, useCreds, &multipartInitRequest{
+		Oid:      t.Object.Oid,
+		Size:     t.Object.Size,
+		PartSize: partSize,
+		Parts:    numParts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.upload.init", res)
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return nil, errutil.Errorf(nil, "Invalid status for POST %s: %d", initUrl, res.StatusCode)
+	}
+
+	var initRes multipartInitResponse
+	if err := json.NewDecoder(res.Body).Decode(&initRes); err != nil {
+		return nil, errutil.Errorf(err, "Unable to parse the multipart upload of %s", t.Object.Oid)
+	}
+
+	return initRes.PartUrls, nil
+}
+
+// uploadPart PUTs one part of t to its pre-signed URL, retrying it up to
+// maxPartAttempts times without disturbing the other parts.
+func (a *basicUploadAdapter) uploadPart(t *Transfer, i int, partUrl string, section *io.SectionReader, length int64, prog *multipartProgress, authOk func()) (*multipartPart, error) {
+	var lastErr error
+	for attempt := 1; attempt <= maxPartAttempts; attempt++ {
+		if attempt > 1 {
+			tracerx.Printf("xfer: retrying part %d of %q (attempt %d): %s", i+1, t.Object.Oid, attempt, lastErr)
+		}
+
+		section.Seek(0, 0)
+		etag, err := a.putPart(partUrl, section, length, prog.callback(i), authOk)
+		if err == nil {
+			return &multipartPart{PartNumber: i + 1, ETag: etag}, nil
+		}
+
+		lastErr = err
+		if !errutil.IsRetriableError(err) {
+			break
+		}
+	}
+
+	return nil, errutil.Errorf(lastErr, "Error uploading part %d of %s", i+1, t.Object.Oid)
+}
+
+func (a *basicUploadAdapter) putPart(partUrl string, body io.Reader, length int64, cb progress.CopyCallback, authOk func()) (string, error) {
+	// Part URLs are pre-signed, so they are sent without the action's headers
+	// or the client's credentials.
+	req, err := httputil.NewHttpRequest("PUT", partUrl, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Length", strconv.FormatInt(length, 10))
+	req.ContentLength = length
+
+	var reader io.Reader
+	reader = &progress.CallbackReader{
+		C:         cb,
+		TotalSize: length,
+		Reader:    body,
+	}
+	reader = newStartCallbackReader(reader, func(*startCallbackReader) {
+		authOk()
+	})
+	req.Body = ioutil.NopCloser(reader)
+
+	res, err := httputil.DoHttpRequest(config.Config, req, false)
+	if err != nil {
+		return "", errutil.NewRetriableError(err)
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.upload.part", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode > 499 {
+		return "", errutil.NewRetriableError(fmt.Errorf("Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode))
+	}
+
+	if res.StatusCode > 299 {
+		return "", errutil.Errorf(nil, "Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
+	}
+
+	return res.Header.Get("ETag"), nil
+}
+
+// completeMultipart tells the server that every part of t has been uploaded.
+func (a *basicUploadAdapter) completeMultipart(t *Transfer, rel *api.LinkRelation, parts []*multipartPart, useCreds bool) error {
+	res, err := doMultipartJSON(rel.Href, rel.Header, useCreds, &multipartCompleteRequest{
+		Oid:   t.Object.Oid,
+		Size:  t.Object.Size,
+		Parts: parts,
+	})
+	if err != nil {
+		return err
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.upload.complete", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return errutil.Errorf(nil, "Invalid status for POST %s: %d", rel.Href, res.StatusCode)
+	}
+
+	return api.VerifyUpload(t.Object)
+}
+
+func doMultipartJSON(rawurl string, header map[string]string, useCreds bool, body interface{}) (*http.Response, error) {
+	by, err := json.Marshal(body)
+	if err != nil {
+		return nil, errutil.Error(err)
+	}
+
+	req, err := httputil.NewHttpRequest("POST", rawurl, header)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = ioutil.NopCloser(bytes.NewReader(by))
+
+	res, err := httputil.DoHttpRequest(config.Config, req, useCreds)
+	if err != nil {
+		return nil, errutil.NewRetriableError(err)
+	}
+	return res, nil
+}
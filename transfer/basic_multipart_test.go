@@ -0,0 +1,107 @@
+package transfer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartProgressSkipsRetriedBytes(t *testing.T) {
+	var sent []int64
+	p := &multipartProgress{
+		cb: func(totalSize int64, readSoFar int64, readSinceLast int) error {
+			assert.Equal(t, int64(8), totalSize)
+			sent = append(sent, readSoFar)
+			return nil
+		},
+		size:     8,
+		reported: make([]int64, 2),
+	}
+
+	first, second := p.callback(0), p.callback(1)
+	first(4, 2, 2)
+	second(4, 4, 4)
+
+	// part 1 is retried from the start, only its new bytes count
+	first(4, 1, 1)
+	first(4, 2, 1)
+	first(4, 4, 2)
+
+	assert.Equal(t, []int64{2, 6, 8}, sent)
+}
+
+func TestMultipartUploadRetriesFailedPartOnItsOwn(t *testing.T) {
+	var mu sync.Mutex
+	puts := make(map[string]int)
+	received := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.WriteHeader(200)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		puts[r.URL.Path]++
+		if r.URL.Path == "/part/2" && puts[r.URL.Path] == 1 {
+			w.WriteHeader(500)
+			return
+		}
+		received[r.URL.Path] = string(body)
+		w.Header().Set("ETag", "etag"+strings.TrimPrefix(r.URL.Path, "/part/"))
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.concurrenttransfers", "1")
+
+	dir, err := ioutil.TempDir("", "git-lfs-multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := "multipart"
+	path := filepath.Join(dir, "object")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rel := &api.LinkRelation{
+		Href: server.URL + "/complete?X-Amz-Signature=abc",
+		Args: map[string]interface{}{
+			"part_size": 4,
+			"part_urls": []interface{}{server.URL + "/part/1", server.URL + "/part/2", server.URL + "/part/3"},
+		},
+	}
+	obj := &api.ObjectResource{Oid: "oid", Size: int64(len(content))}
+
+	var sent int64
+	cb := func(name string, total, read int64, current int) error {
+		sent = read
+		return nil
+	}
+
+	a := &basicUploadAdapter{newAdapterBase(BasicAdapterName, Upload, nil)}
+	err = a.multipartUpload(NewTransfer("test.dat", obj, path), rel, cb, nil)
+	assert.Nil(t, err)
+
+	// only part 2 is sent again, and its bytes are only counted once
+	assert.Equal(t, map[string]int{"/part/1": 1, "/part/2": 2, "/part/3": 1}, puts)
+	for i, part := range []string{"mult", "ipar", "t"} {
+		assert.Equal(t, part, received[fmt.Sprintf("/part/%d", i+1)])
+	}
+	assert.Equal(t, int64(len(content)), sent)
+}
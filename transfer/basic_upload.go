@@ -13,6 +13,7 @@ import (
 	"github.com/github/git-lfs/errutil"
 	"github.com/github/git-lfs/httputil"
 	"github.com/github/git-lfs/progress"
+	"github.com/rubyist/tracerx"
 )
 
 const (
@@ -50,6 +51,10 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 		return fmt.Errorf("No upload action for this object.")
 	}
 
+	if isMultipartUpload(rel) {
+		return a.multipartUpload(t, rel, cb, authOkFunc)
+	}
+
 	req, err := httputil.NewHttpRequest("PUT", rel.Href, rel.Header)
 	if err != nil {
 		return err
@@ -67,6 +72,13 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 
 	req.ContentLength = t.Object.Size
 
+	// Let the server reject the request, e.g. for its auth, before the
+	// object is sent. A request which is rejected is never read from.
+	expect := config.Config.TransferExpect100() && t.Object.Size > 0
+	if expect {
+		req.Header.Set("Expect", "100-continue")
+	}
+
 	f, err := os.OpenFile(t.Path, os.O_RDONLY, 0644)
 	if err != nil {
 		return errutil.Error(err)
@@ -75,7 +87,9 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 
 	// Ensure progress callbacks made while uploading
 	// Wrap callback to give name context
+	var sent int64
 	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		sent = readSoFar
 		if cb != nil {
 			return cb(t.Name, totalSize, readSoFar, readSinceLast)
 		}
@@ -97,11 +111,17 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 
 	req.Body = ioutil.NopCloser(reader)
 
-	res, err := httputil.DoHttpRequest(config.Config, req, true)
+	// Pre-signed URLs carry their own auth, so don't send creds along with them
+	res, err := httputil.DoHttpRequest(config.Config, req, !rel.IsSigned("upload"))
+	if expect && sent == 0 && res != nil && res.StatusCode > 299 {
+		tracerx.Printf("xfer: upload of %s rejected with HTTP %d before its content was sent", t.Object.Oid, res.StatusCode)
+	}
 	if err != nil {
 		return errutil.NewRetriableError(err)
 	}
 	httputil.LogTransfer(config.Config, "lfs.data.upload", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
 
 	// A status code of 403 likely means that an authentication token for the
 	// upload has expired. This can be safely retried.
@@ -113,9 +133,6 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Transfe
 		return errutil.Errorf(nil, "Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
 	}
 
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
-
 	return api.VerifyUpload(t.Object)
 }
 
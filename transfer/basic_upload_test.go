@@ -0,0 +1,108 @@
+package transfer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasicUploadExpectContinue(t *testing.T) {
+	var expect string
+	var received []byte
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect = r.Header.Get("Expect")
+		// Reading the body sends the 100 Continue
+		received, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(200)
+	}))
+	defer storage.Close()
+
+	sent, err := testBasicUpload(t, storage.URL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "100-continue", expect)
+	assert.Equal(t, "test", string(received))
+	assert.Equal(t, int64(4), sent)
+}
+
+func TestBasicUploadExpectContinueRejected(t *testing.T) {
+	var expect string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect = r.Header.Get("Expect")
+		w.WriteHeader(422)
+	}))
+	defer storage.Close()
+
+	sent, err := testBasicUpload(t, storage.URL, nil)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "HTTP 422")
+	}
+	assert.Equal(t, "100-continue", expect)
+	assert.Equal(t, int64(0), sent)
+}
+
+func TestBasicUploadExpectContinueDisabled(t *testing.T) {
+	var expect string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect = r.Header.Get("Expect")
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(200)
+	}))
+	defer storage.Close()
+
+	_, err := testBasicUpload(t, storage.URL, map[string]string{"lfs.transfer.expect100": "false"})
+	assert.Nil(t, err)
+	assert.Empty(t, expect)
+}
+
+// testBasicUpload uploads "test" to the storage server with a signed URL, and
+// returns how many bytes of it were read to be sent.
+func testBasicUpload(t *testing.T, storageUrl string, gitConfig map[string]string) (int64, error) {
+	apiServer := httptest.NewServer(http.NotFoundHandler())
+	defer apiServer.Close()
+
+	defer config.Config.ResetConfig()
+	config.Config.SetConfig("lfs.url", apiServer.URL+"/media")
+	for key, value := range gitConfig {
+		config.Config.SetConfig(key, value)
+	}
+
+	dir, err := ioutil.TempDir("", "git-lfs-basic-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("test")
+	oid := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	path := filepath.Join(dir, oid)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := &api.ObjectResource{
+		Oid:  oid,
+		Size: int64(len(content)),
+		Actions: map[string]*api.LinkRelation{
+			"upload": &api.LinkRelation{
+				Href: storageUrl + "/objects/" + oid + "?X-Amz-Signature=abc",
+			},
+		},
+	}
+
+	var sent int64
+	cb := func(name string, total, read int64, current int) error {
+		sent = read
+		return nil
+	}
+
+	a := &basicUploadAdapter{newAdapterBase(BasicAdapterName, Upload, nil)}
+	err = a.DoTransfer(nil, NewTransfer("test.dat", obj, path), cb, nil)
+	return sent, err
+}
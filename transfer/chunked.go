@@ -0,0 +1,595 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/httputil"
+	"github.com/github/git-lfs/localstorage"
+	"github.com/github/git-lfs/progress"
+	"github.com/github/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	ChunkedAdapterName = "chunked"
+
+	// maxChunkMaxSize bounds the largest chunk a server can ask for, since a
+	// buffer that size is allocated to split objects with.
+	maxChunkMaxSize = 64 * 1024 * 1024
+)
+
+var (
+	chunkOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+	// chunkedTransfersEnabled is set by EnableChunkedTransfers.
+	chunkedTransfersEnabled bool
+)
+
+// EnableChunkedTransfers offers the chunked transfer adapter to the server.
+// It is still experimental, so it is left out unless this is called before
+// the first transfer.
+func EnableChunkedTransfers() {
+	chunkedTransfersEnabled = true
+}
+
+// chunkedObject describes an object as its content defined chunks, in order.
+type chunkedObject struct {
+	Oid    string          `json:"oid"`
+	Size   int64           `json:"size"`
+	Chunks []*chunkedChunk `json:"chunks"`
+}
+
+// chunkedChunk is a chunk of an object, named by the SHA-256 of its content.
+// The href and header say where to upload or download it, when the server
+// sends them.
+type chunkedChunk struct {
+	Oid    string            `json:"oid"`
+	Size   int64             `json:"size"`
+	Href   string            `json:"href,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+}
+
+func (c *chunkedChunk) link() *api.LinkRelation {
+	return &api.LinkRelation{Href: c.Href, Header: c.Header}
+}
+
+// chunkedUploadResponse is the server's response to an object's list of
+// chunks: the chunks it doesn't have yet, and where to commit the object once
+// they are uploaded.
+type chunkedUploadResponse struct {
+	Missing []*chunkedChunk   `json:"missing"`
+	Commit  *api.LinkRelation `json:"commit"`
+}
+
+// Adapter for transfers of objects as content defined chunks, so that only
+// the chunks which the other end doesn't have are sent. Chunks are kept in
+// the local chunk store, so that later versions of an object can be
+// downloaded by fetching only the chunks that changed.
+type chunkedAdapter struct {
+	*adapterBase
+}
+
+func (a *chunkedAdapter) ClearTempStorage() error {
+	// The chunk store is kept, only partly assembled downloads are removed
+	return os.RemoveAll(a.tempDir())
+}
+
+func (a *chunkedAdapter) tempDir() string {
+	// Must be dedicated to this adapter as deleted by ClearTempStorage.
+	// Nothing here needs to be kept to resume, since chunks which were
+	// downloaded are already in the chunk store.
+	d := filepath.Join(localstorage.TempDir, "chunked")
+	if err := os.MkdirAll(d, 0755); err != nil {
+		return os.TempDir()
+	}
+	return d
+}
+
+func (a *chunkedAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *chunkedAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *chunkedAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	if a.direction == Upload {
+		return a.upload(t, cb, authOkFunc)
+	}
+	return a.download(t, cb, authOkFunc)
+}
+
+// upload splits t into chunks, POSTs the list of them to the upload action's
+// href, PUTs the chunks the server says it is missing, then POSTs the list
+// again to the commit URL so that the server assembles the object.
+func (a *chunkedAdapter) upload(t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	rel, ok := t.Object.Rel("upload")
+	if !ok {
+		return fmt.Errorf("No upload action for this object.")
+	}
+
+	obj, err := splitChunks(t, rel)
+	if err != nil {
+		return err
+	}
+
+	useCreds := !rel.IsSigned("upload")
+	res, err := doMultipartJSON(rel.Href, rel.Header, useCreds, obj)
+	if err != nil {
+		return err
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.upload.chunks", res)
+	defer res.Body.Close()
+
+	if res.StatusCode == 403 {
+		return errutil.NewRetriableError(fmt.Errorf("Invalid status for POST %s: %d", rel.Href, res.StatusCode))
+	}
+	if res.StatusCode > 299 {
+		return errutil.Errorf(nil, "Invalid status for POST %s: %d", rel.Href, res.StatusCode)
+	}
+
+	var up chunkedUploadResponse
+	if err := json.NewDecoder(res.Body).Decode(&up); err != nil {
+		return errutil.Errorf(err, "Invalid chunk list response from POST %s", rel.Href)
+	}
+	if up.Commit == nil || len(up.Commit.Href) == 0 {
+		return errutil.Errorf(nil, "No commit URL in the chunk list response from POST %s", rel.Href)
+	}
+
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+
+	sizes := make(map[string]int64, len(obj.Chunks))
+	for _, c := range obj.Chunks {
+		sizes[c.Oid] = c.Size
+	}
+
+	var missingSize int64
+	for _, c := range up.Missing {
+		size, ok := sizes[c.Oid]
+		if !ok {
+			return errutil.Errorf(nil, "Server asked for chunk %s, which is not in %s", c.Oid, t.Object.Oid)
+		}
+		c.Size = size
+		missingSize += size
+	}
+
+	// The chunks the server already has count as sent
+	sent := t.Object.Size - missingSize
+	advanceCallbackProgress(a.cb, t, sent)
+
+	for _, c := range up.Missing {
+		by, ok := readChunk(c.Oid, c.Size)
+		if !ok {
+			return errutil.Errorf(nil, "Chunk %s of %s is missing from %s", c.Oid, t.Object.Oid, localstorage.ChunkDir)
+		}
+
+		from := sent
+		ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+			if cb != nil {
+				return cb(t.Name, t.Object.Size, from+readSoFar, readSinceLast)
+			}
+			return nil
+		}
+		if err := putChunk(c, by, ccb); err != nil {
+			return err
+		}
+		sent += c.Size
+	}
+
+	tracerx.Printf("xfer: chunked upload of %q sent %d of %d chunks", t.Object.Oid, len(up.Missing), len(obj.Chunks))
+
+	res, err = doMultipartJSON(up.Commit.Href, up.Commit.Header, !up.Commit.IsSigned("upload"), obj)
+	if err != nil {
+		return err
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.upload.commit", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return errutil.Errorf(nil, "Invalid status for POST %s: %d", up.Commit.Href, res.StatusCode)
+	}
+
+	return api.VerifyUpload(t.Object)
+}
+
+// splitChunks splits the object being uploaded into chunks of the sizes the
+// server asked for, adding each one to the local chunk store.
+func splitChunks(t *Transfer, rel *api.LinkRelation) (*chunkedObject, error) {
+	min, avg, max := chunkSizes(rel)
+
+	f, err := os.OpenFile(t.Path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, errutil.Error(err)
+	}
+	defer f.Close()
+
+	c, err := newChunker(f, min, avg, max)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &chunkedObject{Oid: t.Object.Oid, Size: t.Object.Size}
+	buf := make([]byte, 0, max)
+	for {
+		chunk, err := c.Next(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errutil.Error(err)
+		}
+
+		oid := chunkOid(chunk)
+		if err := storeChunk(oid, chunk); err != nil {
+			return nil, errutil.Errorf(err, "Error storing chunk %s of %s", oid, t.Object.Oid)
+		}
+		obj.Chunks = append(obj.Chunks, &chunkedChunk{Oid: oid, Size: int64(len(chunk))})
+	}
+
+	tracerx.Printf("xfer: split %q into %d chunks", t.Object.Oid, len(obj.Chunks))
+	storeChunkList(obj)
+	return obj, nil
+}
+
+// chunkSizes returns the chunk sizes from the "chunk_min_size",
+// "chunk_avg_size" and "chunk_max_size" args of rel, or the defaults for those
+// the server didn't send. The largest chunk is at most maxChunkMaxSize.
+func chunkSizes(rel *api.LinkRelation) (min, avg, max int) {
+	min, avg, max = defaultChunkMinSize, defaultChunkAvgSize, defaultChunkMaxSize
+	if n, ok := rel.ArgInt("chunk_min_size"); ok {
+		min = int(n)
+	}
+	if n, ok := rel.ArgInt("chunk_avg_size"); ok {
+		avg = int(n)
+	}
+	if n, ok := rel.ArgInt("chunk_max_size"); ok {
+		max = maxChunkMaxSize
+		if n < maxChunkMaxSize {
+			max = int(n)
+		}
+	}
+	return min, avg, max
+}
+
+func putChunk(c *chunkedChunk, by []byte, cb progress.CopyCallback) error {
+	req, err := httputil.NewHttpRequest("PUT", c.Href, c.Header)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Length", strconv.Itoa(len(by)))
+	req.ContentLength = int64(len(by))
+	req.Body = ioutil.NopCloser(&progress.CallbackReader{
+		C:         cb,
+		TotalSize: int64(len(by)),
+		Reader:    bytes.NewReader(by),
+	})
+
+	res, err := httputil.DoHttpRequest(config.Config, req, !c.link().IsSigned("upload"))
+	if err != nil {
+		return errutil.NewRetriableError(err)
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.upload.chunk", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode == 403 || res.StatusCode > 499 {
+		return errutil.NewRetriableError(fmt.Errorf("Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode))
+	}
+	if res.StatusCode > 299 {
+		return errutil.Errorf(nil, "Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
+	}
+	return nil
+}
+
+// download GETs the list of the object's chunks from the download action's
+// href, then assembles the object from them in order, taking those already in
+// the local chunk store from there and downloading the rest. The object is
+// only moved into place if it matches its OID.
+func (a *chunkedAdapter) download(t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	rel, ok := t.Object.Rel("download")
+	if !ok {
+		return fmt.Errorf("Object not found on the server.")
+	}
+
+	req, err := httputil.NewHttpRequest("GET", rel.Href, rel.Header)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httputil.DoHttpRequest(config.Config, req, !rel.IsSigned("download"))
+	if err != nil {
+		return errutil.NewRetriableError(err)
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.download.chunks", res)
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return errutil.Errorf(nil, "Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
+	}
+
+	var obj chunkedObject
+	if err := json.NewDecoder(res.Body).Decode(&obj); err != nil {
+		return errutil.Errorf(err, "Invalid chunk list from %s", httputil.TraceHttpReq(req))
+	}
+	if err := checkChunks(t, &obj); err != nil {
+		return err
+	}
+
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+
+	f, err := ioutil.TempFile(a.tempDir(), t.Object.Oid)
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	defer os.Remove(tmpName)
+	defer f.Close()
+
+	hash := tools.NewLfsContentHash()
+	w := io.MultiWriter(f, hash)
+
+	var written int64
+	var downloaded int
+	for _, c := range obj.Chunks {
+		by, ok := readChunk(c.Oid, c.Size)
+		if ok {
+			advanceCallbackProgress(a.cb, t, c.Size)
+		} else {
+			from := written
+			ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+				if cb != nil {
+					return cb(t.Name, t.Object.Size, from+readSoFar, readSinceLast)
+				}
+				return nil
+			}
+			if by, err = getChunk(c, ccb); err != nil {
+				return err
+			}
+			if err := storeChunk(c.Oid, by); err != nil {
+				return errutil.Errorf(err, "Error storing chunk %s of %s", c.Oid, t.Object.Oid)
+			}
+			downloaded++
+		}
+
+		if _, err := w.Write(by); err != nil {
+			return fmt.Errorf("cannot write data to tempfile %q: %v", tmpName, err)
+		}
+		written += c.Size
+	}
+
+	tracerx.Printf("xfer: chunked download of %q fetched %d of %d chunks", t.Object.Oid, downloaded, len(obj.Chunks))
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", tmpName, err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != t.Object.Oid {
+		return fmt.Errorf("Expected OID %s, got %s after %d bytes written", t.Object.Oid, actual, written)
+	}
+	storeChunkList(&obj)
+
+	return tools.RenameFileCopyPermissions(tmpName, t.Path)
+}
+
+// checkChunks returns an error if the chunk list from the server isn't for t,
+// or has chunks which can't be stored. Each chunk is downloaded into memory,
+// so none may be larger than maxChunkMaxSize.
+func checkChunks(t *Transfer, obj *chunkedObject) error {
+	if obj.Oid != t.Object.Oid {
+		return errutil.Errorf(nil, "Server sent the chunks of %s for %s", obj.Oid, t.Object.Oid)
+	}
+
+	var size int64
+	for _, c := range obj.Chunks {
+		if !chunkOidRE.MatchString(c.Oid) || c.Size < 0 {
+			return errutil.Errorf(nil, "Server sent an invalid chunk %q of %s", c.Oid, t.Object.Oid)
+		}
+		if c.Size > maxChunkMaxSize {
+			return errutil.Errorf(nil, "Server sent a %d byte chunk %s of %s, larger than the %d byte limit", c.Size, c.Oid, t.Object.Oid, maxChunkMaxSize)
+		}
+		size += c.Size
+	}
+
+	if size != t.Object.Size {
+		return errutil.Errorf(nil, "Server sent %d bytes of chunks for %s, expected %d", size, t.Object.Oid, t.Object.Size)
+	}
+	return nil
+}
+
+func getChunk(c *chunkedChunk, cb progress.CopyCallback) ([]byte, error) {
+	req, err := httputil.NewHttpRequest("GET", c.Href, c.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := httputil.DoHttpRequest(config.Config, req, !c.link().IsSigned("download"))
+	if err != nil {
+		return nil, errutil.NewRetriableError(err)
+	}
+	httputil.LogTransfer(config.Config, "lfs.data.download.chunk", res)
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return nil, errutil.Errorf(nil, "Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tools.CopyWithCallback(&buf, io.LimitReader(res.Body, c.Size+1), c.Size, cb); err != nil {
+		return nil, errutil.NewRetriableError(err)
+	}
+
+	by := buf.Bytes()
+	if int64(len(by)) != c.Size || chunkOid(by) != c.Oid {
+		return nil, errutil.NewRetriableError(fmt.Errorf("Chunk %s from %s does not match its OID", c.Oid, httputil.TraceHttpReq(req)))
+	}
+	return by, nil
+}
+
+func chunkOid(by []byte) string {
+	sum := sha256.Sum256(by)
+	return hex.EncodeToString(sum[:])
+}
+
+func chunkPath(oid string) string {
+	return filepath.Join(localstorage.ChunkDir, oid[0:2], oid[2:4], oid)
+}
+
+// readChunk returns a chunk from the local chunk store, if it is there and
+// intact.
+func readChunk(oid string, size int64) ([]byte, bool) {
+	by, err := ioutil.ReadFile(chunkPath(oid))
+	if err != nil || int64(len(by)) != size || chunkOid(by) != oid {
+		return nil, false
+	}
+	return by, true
+}
+
+// storeChunk adds a chunk to the local chunk store, unless it is already
+// there. Chunks are written to a temp file first, so that an interrupted write
+// never leaves a partial chunk behind.
+func storeChunk(oid string, by []byte) error {
+	path := chunkPath(oid)
+	if stat, err := os.Stat(path); err == nil && stat.Size() == int64(len(by)) {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, oid)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(by)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// chunkListPath returns where the list of an object's chunks is kept, under
+// the chunk store, so that prune can tell which chunks are still needed.
+func chunkListPath(oid string) string {
+	return filepath.Join(localstorage.ChunkDir, "objects", oid)
+}
+
+// storeChunkList records the chunks obj is made of, one OID per line. The
+// chunk store is only a cache, so failing to record them is just traced; the
+// chunks are pruned as unused later.
+func storeChunkList(obj *chunkedObject) {
+	var buf bytes.Buffer
+	for _, c := range obj.Chunks {
+		fmt.Fprintln(&buf, c.Oid)
+	}
+
+	path := chunkListPath(obj.Oid)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path+".tmp", buf.Bytes(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		tracerx.Printf("xfer: could not record the chunks of %q: %s", obj.Oid, err)
+	}
+}
+
+// PruneChunks removes the chunks in the local chunk store which no kept object
+// is made of, along with the chunk lists of the objects which aren't kept.
+// Chunks which no list mentions, such as those of an interrupted download, are
+// removed too. With dryRun, nothing is removed. It returns how many chunks
+// were, or would be, removed, and their total size.
+func PruneChunks(keep func(oid string) bool, dryRun bool) (int, int64, error) {
+	used := make(map[string]bool)
+
+	listDir := filepath.Join(localstorage.ChunkDir, "objects")
+	lists, err := ioutil.ReadDir(listDir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+	for _, list := range lists {
+		path := filepath.Join(listDir, list.Name())
+		if keep(list.Name()) {
+			by, err := ioutil.ReadFile(path)
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, oid := range strings.Fields(string(by)) {
+				used[oid] = true
+			}
+		} else if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	var count int
+	var size int64
+	err = filepath.Walk(localstorage.ChunkDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if path == listDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !chunkOidRE.MatchString(info.Name()) || used[info.Name()] {
+			return nil
+		}
+
+		count++
+		size += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	return count, size, err
+}
+
+func init() {
+	newfunc := func(name string, dir Direction) TransferAdapter {
+		ca := &chunkedAdapter{newAdapterBase(name, dir, nil)}
+		// self implements impl
+		ca.transferImpl = ca
+		return ca
+	}
+	RegisterNewTransferAdapterFunc(ChunkedAdapterName, Upload, newfunc)
+	RegisterNewTransferAdapterFunc(ChunkedAdapterName, Download, newfunc)
+}
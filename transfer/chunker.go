@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+const (
+	// The sizes of content defined chunks used when the server doesn't ask
+	// for others.
+	defaultChunkMinSize = 256 * 1024
+	defaultChunkAvgSize = 1024 * 1024
+	defaultChunkMaxSize = 4 * 1024 * 1024
+)
+
+// gearTable maps each byte value to a pseudo random number for the rolling
+// hash. It must never change, or objects would be split differently than
+// before and none of their chunks would be shared with earlier versions.
+var gearTable [256]uint64
+
+func init() {
+	// splitmix64, from a fixed seed
+	seed := uint64(0x6769742d6c667321)
+	for i := range gearTable {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearTable[i] = z ^ (z >> 31)
+	}
+}
+
+// chunker splits content into chunks whose boundaries are chosen by a rolling
+// hash of the last 64 bytes, rather than by offset, so that a change to one
+// region of a file only changes the chunks around it.
+type chunker struct {
+	r        *bufio.Reader
+	min, max int
+	mask     uint64
+}
+
+// newChunker returns a chunker reading from r. Chunks are at least min and at
+// most max bytes long, except for the last one, and average around avg bytes,
+// rounded down to a power of two.
+func newChunker(r io.Reader, min, avg, max int) (*chunker, error) {
+	if min < 1 || avg < min || max < avg {
+		return nil, fmt.Errorf("Invalid chunk sizes: min %d, average %d, max %d", min, avg, max)
+	}
+
+	bits := uint(0)
+	for (2 << bits) <= avg {
+		bits++
+	}
+
+	return &chunker{
+		r:    bufio.NewReader(r),
+		min:  min,
+		max:  max,
+		mask: ^uint64(0) << (64 - bits),
+	}, nil
+}
+
+// Next reads the next chunk into buf, growing it if need be, and returns it,
+// or io.EOF once there are no more.
+func (c *chunker) Next(buf []byte) ([]byte, error) {
+	buf = buf[:0]
+	var h uint64
+	for len(buf) < c.max {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, b)
+		h = (h << 1) + gearTable[b]
+		if len(buf) >= c.min && h&c.mask == 0 {
+			break
+		}
+	}
+
+	if len(buf) == 0 {
+		return nil, io.EOF
+	}
+	return buf, nil
+}
@@ -0,0 +1,213 @@
+package transfer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/github/git-lfs/api"
+	"github.com/github/git-lfs/localstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+func chunkContent(t *testing.T, content []byte, min, avg, max int) []string {
+	c, err := newChunker(bytes.NewReader(content), min, avg, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []string
+	buf := make([]byte, 0, max)
+	for {
+		chunk, err := c.Next(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+	return chunks
+}
+
+func chunkerTestContent(changed int) []byte {
+	var b bytes.Buffer
+	for i := 1; i <= 2000; i++ {
+		if i == changed {
+			fmt.Fprintln(&b, "changed")
+		} else {
+			fmt.Fprintln(&b, i)
+		}
+	}
+	return b.Bytes()
+}
+
+func TestChunkerSizes(t *testing.T) {
+	content := chunkerTestContent(0)
+	chunks := chunkContent(t, content, 64, 256, 1024)
+
+	assert.True(t, len(chunks) > 1)
+	var joined bytes.Buffer
+	for i, chunk := range chunks {
+		assert.True(t, len(chunk) <= 1024, "chunk %d is %d bytes", i, len(chunk))
+		if i < len(chunks)-1 {
+			assert.True(t, len(chunk) >= 64, "chunk %d is %d bytes", i, len(chunk))
+		}
+		joined.WriteString(chunk)
+	}
+	assert.Equal(t, content, joined.Bytes())
+}
+
+func TestChunkerMaxSize(t *testing.T) {
+	// no boundaries in a run of one byte value, so every chunk is cut at max
+	chunks := chunkContent(t, bytes.Repeat([]byte{0}, 2500), 64, 256, 1024)
+
+	assert.Equal(t, 3, len(chunks))
+	assert.Equal(t, 1024, len(chunks[0]))
+	assert.Equal(t, 1024, len(chunks[1]))
+	assert.Equal(t, 452, len(chunks[2]))
+}
+
+func TestChunkerResynchronisesAfterChange(t *testing.T) {
+	before := chunkContent(t, chunkerTestContent(0), 64, 256, 1024)
+	after := chunkContent(t, chunkerTestContent(1000), 64, 256, 1024)
+
+	had := make(map[string]bool, len(before))
+	for _, chunk := range before {
+		had[chunk] = true
+	}
+
+	var changed int
+	for _, chunk := range after {
+		if !had[chunk] {
+			changed++
+		}
+	}
+
+	assert.True(t, changed > 0)
+	assert.True(t, changed <= 2, "%d of %d chunks changed", changed, len(after))
+}
+
+func TestChunkerEmpty(t *testing.T) {
+	assert.Equal(t, 0, len(chunkContent(t, nil, 64, 256, 1024)))
+}
+
+func TestChunkerInvalidSizes(t *testing.T) {
+	_, err := newChunker(bytes.NewReader(nil), 0, 256, 1024)
+	assert.NotNil(t, err)
+
+	_, err = newChunker(bytes.NewReader(nil), 512, 256, 1024)
+	assert.NotNil(t, err)
+
+	_, err = newChunker(bytes.NewReader(nil), 64, 2048, 1024)
+	assert.NotNil(t, err)
+}
+
+func TestChunkSizesFromArgs(t *testing.T) {
+	min, avg, max := chunkSizes(&api.LinkRelation{})
+	assert.Equal(t, defaultChunkMinSize, min)
+	assert.Equal(t, defaultChunkAvgSize, avg)
+	assert.Equal(t, defaultChunkMaxSize, max)
+
+	min, avg, max = chunkSizes(&api.LinkRelation{Args: map[string]interface{}{
+		"chunk_min_size": float64(64),
+		"chunk_avg_size": "256",
+		"chunk_max_size": float64(1024),
+	}})
+	assert.Equal(t, 64, min)
+	assert.Equal(t, 256, avg)
+	assert.Equal(t, 1024, max)
+
+	_, _, max = chunkSizes(&api.LinkRelation{Args: map[string]interface{}{
+		"chunk_max_size": float64(1 << 40),
+	}})
+	assert.Equal(t, maxChunkMaxSize, max)
+}
+
+func TestCheckChunks(t *testing.T) {
+	tr := &Transfer{Object: &api.ObjectResource{Oid: "abc", Size: 3}}
+	oid := chunkOid([]byte("a"))
+
+	assert.Nil(t, checkChunks(tr, &chunkedObject{Oid: "abc", Size: 3, Chunks: []*chunkedChunk{
+		{Oid: oid, Size: 1}, {Oid: oid, Size: 2},
+	}}))
+
+	// wrong object
+	assert.NotNil(t, checkChunks(tr, &chunkedObject{Oid: "def", Size: 3, Chunks: []*chunkedChunk{
+		{Oid: oid, Size: 3},
+	}}))
+
+	// chunks don't add up to the object
+	assert.NotNil(t, checkChunks(tr, &chunkedObject{Oid: "abc", Size: 3, Chunks: []*chunkedChunk{
+		{Oid: oid, Size: 2},
+	}}))
+
+	// chunk OIDs are used as paths
+	assert.NotNil(t, checkChunks(tr, &chunkedObject{Oid: "abc", Size: 3, Chunks: []*chunkedChunk{
+		{Oid: "../../../../etc/passwd", Size: 3},
+	}}))
+
+	// chunks are downloaded into memory
+	big := &Transfer{Object: &api.ObjectResource{Oid: "abc", Size: maxChunkMaxSize + 1}}
+	assert.NotNil(t, checkChunks(big, &chunkedObject{Oid: "abc", Size: maxChunkMaxSize + 1, Chunks: []*chunkedChunk{
+		{Oid: oid, Size: maxChunkMaxSize + 1},
+	}}))
+	assert.Nil(t, checkChunks(big, &chunkedObject{Oid: "abc", Size: maxChunkMaxSize + 1, Chunks: []*chunkedChunk{
+		{Oid: oid, Size: maxChunkMaxSize}, {Oid: oid, Size: 1},
+	}}))
+}
+
+func TestPruneChunks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-lfs-chunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldChunkDir := localstorage.ChunkDir
+	localstorage.ChunkDir = dir
+	defer func() { localstorage.ChunkDir = oldChunkDir }()
+
+	shared, kept, pruned, stray := []byte("shared"), []byte("kept"), []byte("pruned"), []byte("stray")
+	for _, by := range [][]byte{shared, kept, pruned, stray} {
+		if err := storeChunk(chunkOid(by), by); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keepOid, pruneOid := chunkOid([]byte("kept object")), chunkOid([]byte("pruned object"))
+	storeChunkList(&chunkedObject{Oid: keepOid, Chunks: []*chunkedChunk{
+		{Oid: chunkOid(shared)}, {Oid: chunkOid(kept)},
+	}})
+	storeChunkList(&chunkedObject{Oid: pruneOid, Chunks: []*chunkedChunk{
+		{Oid: chunkOid(shared)}, {Oid: chunkOid(pruned)},
+	}})
+	keep := func(oid string) bool { return oid == keepOid }
+
+	count, size, err := PruneChunks(keep, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, int64(len(pruned)+len(stray)), size)
+	_, ok := readChunk(chunkOid(pruned), int64(len(pruned)))
+	assert.True(t, ok)
+
+	count, _, err = PruneChunks(keep, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	for _, by := range [][]byte{shared, kept} {
+		_, ok := readChunk(chunkOid(by), int64(len(by)))
+		assert.True(t, ok)
+	}
+	for _, by := range [][]byte{pruned, stray} {
+		_, ok := readChunk(chunkOid(by), int64(len(by)))
+		assert.False(t, ok)
+	}
+	_, err = os.Stat(chunkListPath(pruneOid))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(chunkListPath(keepOid))
+	assert.Nil(t, err)
+}
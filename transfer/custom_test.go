@@ -1,10 +1,12 @@
 package transfer
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/github/git-lfs/api"
 	"github.com/github/git-lfs/config"
 )
 
@@ -163,3 +165,19 @@ func TestCustomTransferBothConfig(t *testing.T) {
 	assert.Equal(t, cu.args, args, "args should be correct")
 	assert.Equal(t, cu.concurrent, true, "concurrent should be set")
 }
+
+func TestCustomAdapterRequestPassesArgs(t *testing.T) {
+	rel := &api.LinkRelation{
+		Href: "nfs://server/path",
+		Args: map[string]interface{}{"parts": 3},
+	}
+
+	by, err := json.Marshal(NewCustomAdapterUploadRequest("abc", 123, "/path", rel))
+	assert.Nil(t, err)
+	assert.Contains(t, string(by), `"action":{"href":"nfs://server/path","expires_at":"0001-01-01T00:00:00Z","args":{"parts":3}}`)
+
+	rel.Args = nil
+	by, err = json.Marshal(NewCustomAdapterDownloadRequest("abc", 123, rel))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(by), "args")
+}
@@ -0,0 +1,103 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/errutil"
+	"github.com/github/git-lfs/localstorage"
+	"github.com/github/git-lfs/sftp"
+	"github.com/github/git-lfs/tools"
+)
+
+// Adapter for transfers to and from an SFTP remote, used when lfs.url is an
+// sftp:// URL. Each object is transferred in its own sftp session, to the path
+// on the server derived from its OID. The sftp client doesn't report
+// progress, so each object's progress is reported once it has been
+// transferred.
+type sftpAdapter struct {
+	*adapterBase
+}
+
+func (a *sftpAdapter) ClearTempStorage() error {
+	return nil
+}
+
+func (a *sftpAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *sftpAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *sftpAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferProgressCallback, authOkFunc func()) error {
+	operation := "download"
+	if a.direction == Upload {
+		operation = "upload"
+	}
+
+	remote, err := sftp.NewRemote(config.Config.Endpoint(operation).Url)
+	if err != nil {
+		return err
+	}
+
+	if a.direction == Upload {
+		err = remote.Put(t.Path, t.Object.Oid)
+	} else {
+		err = a.download(remote, t)
+	}
+	if err != nil {
+		return errutil.NewRetriableError(err)
+	}
+
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+	advanceCallbackProgress(cb, t, t.Object.Size)
+	return nil
+}
+
+// download gets the object into a temporary file, and moves it into place
+// once its content has been checked against its OID.
+func (a *sftpAdapter) download(remote *sftp.Remote, t *Transfer) error {
+	f, err := localstorage.TempFile(t.Object.Oid)
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	f.Close()
+	defer os.Remove(tmpName)
+
+	if err := remote.Get(remote.ObjectPath(t.Object.Oid), tmpName); err != nil {
+		return err
+	}
+
+	f, err = os.Open(tmpName)
+	if err != nil {
+		return err
+	}
+	hasher := tools.NewHashingReader(f)
+	_, err = io.Copy(ioutil.Discard, hasher)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if actual := hasher.Hash(); actual != t.Object.Oid {
+		return fmt.Errorf("Expected OID %s, got %s from the SFTP server", t.Object.Oid, actual)
+	}
+
+	return tools.RenameFileCopyPermissions(tmpName, t.Path)
+}
+
+func init() {
+	newfunc := func(name string, dir Direction) TransferAdapter {
+		a := &sftpAdapter{newAdapterBase(name, dir, nil)}
+		a.transferImpl = a
+		return a
+	}
+	RegisterNewTransferAdapterFunc(sftp.AdapterName, Download, newfunc)
+	RegisterNewTransferAdapterFunc(sftp.AdapterName, Upload, newfunc)
+}
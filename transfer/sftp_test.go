@@ -0,0 +1,22 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/sftp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSftpAdapterOnlyOfferedForSftpEndpoints(t *testing.T) {
+	defer config.Config.ResetConfig()
+
+	config.Config.SetConfig("lfs.url", "https://lfs.example.com/repo")
+	assert.NotContains(t, GetDownloadAdapterNames(), sftp.AdapterName)
+	assert.NotContains(t, GetUploadAdapterNames(), sftp.AdapterName)
+	assert.NotNil(t, NewDownloadAdapter(sftp.AdapterName))
+
+	config.Config.SetConfig("lfs.url", "sftp://lfs@sftp.example.com/srv/lfs")
+	assert.Contains(t, GetDownloadAdapterNames(), sftp.AdapterName)
+	assert.Contains(t, GetUploadAdapterNames(), sftp.AdapterName)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/github/git-lfs/config"
+	"github.com/github/git-lfs/sftp"
 
 	"github.com/github/git-lfs/api"
 	"github.com/rubyist/tracerx"
@@ -58,6 +59,11 @@ type TransferAdapter interface {
 	// progress, and the completion channel will receive completion notifications
 	// Either argument may be nil if not required by the client
 	Begin(maxConcurrency int, cb TransferProgressCallback, completion chan TransferResult) error
+	// SetBandwidthLimiter limits the combined rate of the transfers started
+	// by the next Begin. Progress for bytes which didn't need transferring,
+	// such as those already held locally, doesn't count against it. A nil
+	// limiter doesn't limit.
+	SetBandwidthLimiter(l *BandwidthLimiter)
 	// Add queues a download/upload, which will complete asynchronously and
 	// notify the callbacks given to Begin()
 	Add(t *Transfer)
@@ -65,6 +71,10 @@ type TransferAdapter interface {
 	// once the queued items have completed.
 	// This call blocks until all items have been processed
 	End()
+	// Abort stops the adapter from starting any more of the transfers queued
+	// with Add. Transfers already in progress are allowed to finish, and the
+	// rest complete straight away with ErrAborted.
+	Abort()
 	// ClearTempStorage clears any temporary files, such as unfinished downloads that
 	// would otherwise be resumed
 	ClearTempStorage() error
@@ -81,6 +91,23 @@ type Transfer struct {
 	Path string
 }
 
+// Args returns the adapter specific arguments the server attached to this
+// object's action in the given direction, or nil if it sent none.
+func (t *Transfer) Args(dir Direction) map[string]interface{} {
+	name := "download"
+	if dir == Upload {
+		name = "upload"
+	}
+
+	if t.Object == nil {
+		return nil
+	}
+	if rel, ok := t.Object.Rel(name); ok {
+		return rel.Args
+	}
+	return nil
+}
+
 // NewTransfer creates a new Transfer instance
 func NewTransfer(name string, obj *api.ObjectResource, path string) *Transfer {
 	return &Transfer{name, obj, path}
@@ -118,7 +145,9 @@ func GetDownloadAdapterNames() []string {
 
 	ret := make([]string, 0, len(downloadAdapterFuncs))
 	for n, _ := range downloadAdapterFuncs {
-		ret = append(ret, n)
+		if isOffered(n, "download") {
+			ret = append(ret, n)
+		}
 	}
 	return ret
 }
@@ -137,11 +166,20 @@ func GetUploadAdapterNames() []string {
 
 	ret := make([]string, 0, len(uploadAdapterFuncs))
 	for n, _ := range uploadAdapterFuncs {
-		ret = append(ret, n)
+		if isOffered(n, "upload") {
+			ret = append(ret, n)
+		}
 	}
 	return ret
 }
 
+// isOffered returns whether the named adapter is one to offer the server for
+// the operation. The sftp adapter only works with sftp:// endpoints, so it
+// isn't offered to Git LFS API servers.
+func isOffered(name, operation string) bool {
+	return name != sftp.AdapterName || sftp.IsSftpUrl(config.Config.Endpoint(operation).Url)
+}
+
 // RegisterNewTransferAdapterFunc registers a new function for creating upload
 // or download adapters. If a function with that name & direction is already
 // registered, it is overridden
@@ -215,5 +253,11 @@ func initCoreAdaptersIfRequired() {
 		if !config.Config.TusTransfersAllowed() {
 			delete(uploadAdapterFuncs, TusAdapterName)
 		}
+
+		// chunked transfers are experimental too, see EnableChunkedTransfers
+		if !chunkedTransfersEnabled {
+			delete(uploadAdapterFuncs, ChunkedAdapterName)
+			delete(downloadAdapterFuncs, ChunkedAdapterName)
+		}
 	})
 }
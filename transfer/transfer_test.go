@@ -22,10 +22,14 @@ func (a *testAdapter) Direction() Direction {
 func (a *testAdapter) Begin(maxConcurrency int, cb TransferProgressCallback, completion chan TransferResult) error {
 	return nil
 }
+func (a *testAdapter) SetBandwidthLimiter(l *BandwidthLimiter) {
+}
 func (a *testAdapter) Add(t *Transfer) {
 }
 func (a *testAdapter) End() {
 }
+func (a *testAdapter) Abort() {
+}
 func (a *testAdapter) ClearTempStorage() error {
 	return nil
 }
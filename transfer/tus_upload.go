@@ -57,6 +57,8 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferP
 	if err != nil {
 		return errutil.NewRetriableError(err)
 	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
 
 	//    Response will contain Upload-Offset if supported
 	offHdr := res.Header.Get("Upload-Offset")
@@ -71,7 +73,7 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferP
 	// Batch API will probably already detect this, but handle just in case
 	if offset >= t.Object.Size {
 		tracerx.Printf("xfer: tus.io HEAD offset %d indicates %q is already fully uploaded, skipping", offset, t.Object.Oid)
-		advanceCallbackProgress(cb, t, t.Object.Size)
+		advanceCallbackProgress(a.cb, t, t.Object.Size)
 		return nil
 	}
 
@@ -87,7 +89,7 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferP
 		tracerx.Printf("xfer: tus.io uploading %q from start", t.Object.Oid)
 	} else {
 		tracerx.Printf("xfer: tus.io resuming upload %q from %d", t.Object.Oid, offset)
-		advanceCallbackProgress(cb, t, offset)
+		advanceCallbackProgress(a.cb, t, offset)
 		_, err := f.Seek(offset, os.SEEK_CUR)
 		if err != nil {
 			return errutil.Error(err)
@@ -139,6 +141,8 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferP
 		return errutil.NewRetriableError(err)
 	}
 	httputil.LogTransfer(config.Config, "lfs.data.upload", res)
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
 
 	// A status code of 403 likely means that an authentication token for the
 	// upload has expired. This can be safely retried.
@@ -150,9 +154,6 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb TransferP
 		return errutil.Errorf(nil, "Invalid status for %s: %d", httputil.TraceHttpReq(req), res.StatusCode)
 	}
 
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
-
 	return api.VerifyUpload(t.Object)
 }
 